@@ -0,0 +1,158 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/accentdesign/grpc/core/errs"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// ErrorDomain is the domain of the ErrorInfo details of errors returned by the interceptors.
+const ErrorDomain = "authz"
+
+var (
+	ErrPermissionDenied = errs.Error(codes.PermissionDenied, ErrorDomain, "PERMISSION_DENIED", "permission denied")
+	ErrUnauthenticated  = errs.Error(codes.Unauthenticated, ErrorDomain, "UNAUTHENTICATED", "a valid bearer token is required")
+)
+
+// Principal is the user a bearer token resolves to.
+type Principal struct {
+	UserId   string
+	UserType string
+	Scopes   []string
+}
+
+// HasScopes reports whether the principal has every one of the scopes.
+func (p *Principal) HasScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		if !slices.Contains(p.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// Resolver resolves a bearer token to a Principal, it returns an error wrapping ErrInvalidToken
+// when the token is not valid, any other error is returned to the caller as is.
+type Resolver interface {
+	Resolve(ctx context.Context, token string) (*Principal, error)
+}
+
+// Rules maps full gRPC method names, e.g. "/pkg.auth.AuthenticationAdmin/ListUsers", to the scopes
+// required to call them. A method with no scopes only requires a valid bearer token and methods
+// that are not in the map are not checked.
+type Rules map[string][]string
+
+type principalKey struct{}
+
+// NewContext returns a copy of ctx carrying the principal.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal stored in ctx by NewContext or the interceptors.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// TokenFromContext returns the token from the "authorization: Bearer <token>" metadata of an incoming request.
+func TokenFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, value := range md.Get("authorization") {
+		scheme, token, found := strings.Cut(value, " ")
+		if found && strings.EqualFold(scheme, "bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	return ""
+}
+
+// Authorize ensures the caller has every one of the scopes. The principal already in ctx is used when
+// there is one, otherwise the bearer token of the request is resolved.
+func Authorize(ctx context.Context, resolver Resolver, scopes ...string) (*Principal, error) {
+	p, ok := FromContext(ctx)
+	if !ok {
+		token := TokenFromContext(ctx)
+		if token == "" {
+			return nil, ErrUnauthenticated
+		}
+
+		var err error
+		p, err = resolver.Resolve(ctx, token)
+		if err != nil {
+			if errors.Is(err, ErrInvalidToken) {
+				return nil, ErrUnauthenticated
+			}
+			if _, ok := status.FromError(err); ok {
+				return nil, err
+			}
+			return nil, errs.Error(codes.Internal, ErrorDomain, "INTERNAL", err.Error())
+		}
+	}
+
+	if !p.HasScopes(scopes...) {
+		return nil, ErrPermissionDenied
+	}
+
+	return p, nil
+}
+
+func authorizeMethod(ctx context.Context, resolver Resolver, rules Rules, method string) (context.Context, error) {
+	scopes, ok := rules[method]
+	if !ok {
+		return ctx, nil
+	}
+
+	p, err := Authorize(ctx, resolver, scopes...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewContext(ctx, p), nil
+}
+
+// UnaryServerInterceptor authorizes unary calls against the rules, the principal is available to
+// handlers through FromContext.
+func UnaryServerInterceptor(resolver Resolver, rules Rules) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authorizeMethod(ctx, resolver, rules, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// StreamServerInterceptor authorizes streaming calls against the rules, the principal is available to
+// handlers through FromContext on the stream context.
+func StreamServerInterceptor(resolver Resolver, rules Rules) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorizeMethod(ss.Context(), resolver, rules, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
@@ -0,0 +1,193 @@
+package authz_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/accentdesign/grpc/core/authz"
+)
+
+type fakeResolver struct {
+	principals map[string]*authz.Principal
+	errors     map[string]error
+	calls      int
+}
+
+func (r *fakeResolver) Resolve(_ context.Context, token string) (*authz.Principal, error) {
+	r.calls++
+	if err, ok := r.errors[token]; ok {
+		return nil, err
+	}
+	if p, ok := r.principals[token]; ok {
+		return p, nil
+	}
+	return nil, authz.ErrInvalidToken
+}
+
+type fakeStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeStream) Context() context.Context {
+	return s.ctx
+}
+
+type TestSuite struct {
+	suite.Suite
+	resolver *fakeResolver
+}
+
+func (suite *TestSuite) SetupTest() {
+	suite.resolver = &fakeResolver{
+		principals: map[string]*authz.Principal{
+			"reader": {UserId: "1", UserType: "staff", Scopes: []string{"users:read"}},
+			"writer": {UserId: "2", UserType: "admin", Scopes: []string{"users:read", "users:write"}},
+		},
+		errors: map[string]error{
+			"inactive":    status.Error(codes.PermissionDenied, "user is inactive"),
+			"unavailable": errors.New("connection refused"),
+		},
+	}
+}
+
+func TestTestSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+func withBearer(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+}
+
+func (suite *TestSuite) TestTokenFromContext() {
+	testCases := []struct {
+		desc     string
+		ctx      context.Context
+		expected string
+	}{
+		{"no metadata", context.Background(), ""},
+		{"no authorization", metadata.NewIncomingContext(context.Background(), metadata.Pairs("other", "value")), ""},
+		{"other scheme", metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Basic abc")), ""},
+		{"bearer", withBearer("abc"), "abc"},
+		{"lower case scheme", metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "bearer abc")), "abc"},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.desc, func() {
+			suite.Equal(tc.expected, authz.TokenFromContext(tc.ctx))
+		})
+	}
+}
+
+func (suite *TestSuite) TestAuthorize() {
+	testCases := []struct {
+		desc          string
+		ctx           context.Context
+		scopes        []string
+		expectedError error
+	}{
+		{"no token", context.Background(), nil, status.Error(codes.Unauthenticated, "a valid bearer token is required")},
+		{"invalid token", withBearer("invalid"), nil, status.Error(codes.Unauthenticated, "a valid bearer token is required")},
+		{"status error", withBearer("inactive"), nil, status.Error(codes.PermissionDenied, "user is inactive")},
+		{"other error", withBearer("unavailable"), nil, status.Error(codes.Internal, "connection refused")},
+		{"missing scope", withBearer("reader"), []string{"users:write"}, status.Error(codes.PermissionDenied, "permission denied")},
+		{"missing one of the scopes", withBearer("reader"), []string{"users:read", "users:write"}, status.Error(codes.PermissionDenied, "permission denied")},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.desc, func() {
+			p, err := authz.Authorize(tc.ctx, suite.resolver, tc.scopes...)
+			suite.EqualError(err, tc.expectedError.Error())
+			suite.Nil(p)
+		})
+	}
+
+	p, err := authz.Authorize(withBearer("writer"), suite.resolver, "users:read", "users:write")
+	suite.NoError(err)
+	suite.Equal("2", p.UserId)
+
+	p, err = authz.Authorize(withBearer("reader"), suite.resolver)
+	suite.NoError(err)
+	suite.Equal("1", p.UserId)
+
+	// a principal already in the context is not resolved again
+	calls := suite.resolver.calls
+	ctx := authz.NewContext(context.Background(), &authz.Principal{UserId: "3", Scopes: []string{"users:read"}})
+	p, err = authz.Authorize(ctx, suite.resolver, "users:read")
+	suite.NoError(err)
+	suite.Equal("3", p.UserId)
+	suite.Equal(calls, suite.resolver.calls)
+}
+
+func (suite *TestSuite) TestUnaryServerInterceptor() {
+	rules := authz.Rules{
+		"/test.Service/Read":  {"users:read"},
+		"/test.Service/Write": {"users:write"},
+		"/test.Service/Any":   {},
+	}
+	interceptor := authz.UnaryServerInterceptor(suite.resolver, rules)
+
+	var principal *authz.Principal
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		principal, _ = authz.FromContext(ctx)
+		return "ok", nil
+	}
+
+	testCases := []struct {
+		desc          string
+		ctx           context.Context
+		method        string
+		expectedUser  string
+		expectedError error
+	}{
+		{"public method", context.Background(), "/test.Service/Public", "", nil},
+		{"no token", context.Background(), "/test.Service/Any", "", status.Error(codes.Unauthenticated, "a valid bearer token is required")},
+		{"any valid token", withBearer("reader"), "/test.Service/Any", "1", nil},
+		{"has scope", withBearer("reader"), "/test.Service/Read", "1", nil},
+		{"missing scope", withBearer("reader"), "/test.Service/Write", "", status.Error(codes.PermissionDenied, "permission denied")},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.desc, func() {
+			principal = nil
+			resp, err := interceptor(tc.ctx, "req", &grpc.UnaryServerInfo{FullMethod: tc.method}, handler)
+			if tc.expectedError != nil {
+				suite.EqualError(err, tc.expectedError.Error())
+				suite.Nil(resp)
+				return
+			}
+			suite.NoError(err)
+			suite.Equal("ok", resp)
+			if tc.expectedUser == "" {
+				suite.Nil(principal)
+			} else {
+				suite.Equal(tc.expectedUser, principal.UserId)
+			}
+		})
+	}
+}
+
+func (suite *TestSuite) TestStreamServerInterceptor() {
+	rules := authz.Rules{"/test.Service/Watch": {"users:read"}}
+	interceptor := authz.StreamServerInterceptor(suite.resolver, rules)
+
+	var principal *authz.Principal
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		principal, _ = authz.FromContext(ss.Context())
+		return nil
+	}
+
+	err := interceptor(nil, &fakeStream{ctx: withBearer("reader")}, &grpc.StreamServerInfo{FullMethod: "/test.Service/Watch"}, handler)
+	suite.NoError(err)
+	suite.Equal("1", principal.UserId)
+
+	err = interceptor(nil, &fakeStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/test.Service/Watch"}, handler)
+	suite.EqualError(err, status.Error(codes.Unauthenticated, "a valid bearer token is required").Error())
+}
@@ -0,0 +1,73 @@
+// Package errs builds gRPC error statuses carrying google.rpc error details, an ErrorInfo with a machine readable
+// reason on every status and a BadRequest listing the invalid fields of a request, so clients can react to
+// errors without matching on their messages.
+package errs
+
+import (
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// New returns a status with an ErrorInfo detail, reason is an UPPER_SNAKE_CASE code such as EMAIL_TAKEN,
+// unique within the domain of the service returning it.
+func New(c codes.Code, domain string, reason string, msg string) *status.Status {
+	return withDetails(status.New(c, msg), &errdetails.ErrorInfo{Reason: reason, Domain: domain})
+}
+
+// Error is New returning an error.
+func Error(c codes.Code, domain string, reason string, msg string) error {
+	return New(c, domain, reason, msg).Err()
+}
+
+// FieldError returns an error with an ErrorInfo detail and a BadRequest detail describing the invalid field with msg.
+func FieldError(c codes.Code, domain string, reason string, field string, msg string) error {
+	return WithFieldViolations(New(c, domain, reason, msg), FieldViolation(field, msg))
+}
+
+// FieldViolation describes why a field of a request is invalid, nested fields are separated by dots.
+func FieldViolation(field string, description string) *errdetails.BadRequest_FieldViolation {
+	return &errdetails.BadRequest_FieldViolation{Field: field, Description: description}
+}
+
+// WithFieldViolations adds a BadRequest detail listing the violations to the status.
+func WithFieldViolations(st *status.Status, violations ...*errdetails.BadRequest_FieldViolation) error {
+	return withDetails(st, &errdetails.BadRequest{FieldViolations: violations}).Err()
+}
+
+// WithRetryInfo adds the delay before the call can be retried to the status.
+func WithRetryInfo(st *status.Status, retryAfter time.Duration) error {
+	return withDetails(st, &errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)}).Err()
+}
+
+// Reason returns the reason of the ErrorInfo detail of err, or an empty string when it has none.
+func Reason(err error) string {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info.GetReason()
+		}
+	}
+	return ""
+}
+
+// FieldViolations returns the field violations of the BadRequest detail of err.
+func FieldViolations(err error) []*errdetails.BadRequest_FieldViolation {
+	for _, detail := range status.Convert(err).Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			return badRequest.GetFieldViolations()
+		}
+	}
+	return nil
+}
+
+func withDetails(st *status.Status, details ...protoadapt.MessageV1) *status.Status {
+	detailed, err := st.WithDetails(details...)
+	if err != nil {
+		return st
+	}
+	return detailed
+}
@@ -0,0 +1,90 @@
+package errs_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/accentdesign/grpc/core/errs"
+)
+
+type TestSuite struct {
+	suite.Suite
+}
+
+func TestTestSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+func (suite *TestSuite) TestError() {
+	err := errs.Error(codes.AlreadyExists, "auth", "EMAIL_TAKEN", "a user with this email already exists")
+
+	st := status.Convert(err)
+	suite.Equal(codes.AlreadyExists, st.Code())
+	suite.Equal("a user with this email already exists", st.Message())
+	suite.Len(st.Details(), 1)
+
+	info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+	suite.True(ok)
+	suite.Equal("EMAIL_TAKEN", info.GetReason())
+	suite.Equal("auth", info.GetDomain())
+
+	suite.Equal("EMAIL_TAKEN", errs.Reason(err))
+	suite.Nil(errs.FieldViolations(err))
+}
+
+func (suite *TestSuite) TestFieldError() {
+	err := errs.FieldError(codes.InvalidArgument, "auth", "FIELD_INVALID", "first_name", "first_name is required")
+
+	st := status.Convert(err)
+	suite.Equal(codes.InvalidArgument, st.Code())
+	suite.Equal("first_name is required", st.Message())
+	suite.Equal("FIELD_INVALID", errs.Reason(err))
+
+	violations := errs.FieldViolations(err)
+	suite.Len(violations, 1)
+	suite.Equal("first_name", violations[0].GetField())
+	suite.Equal("first_name is required", violations[0].GetDescription())
+}
+
+func (suite *TestSuite) TestWithFieldViolations() {
+	st := errs.New(codes.InvalidArgument, "auth", "PASSWORD_POLICY", "password does not meet the password policy")
+
+	err := errs.WithFieldViolations(st,
+		errs.FieldViolation("password", "must contain a digit"),
+		errs.FieldViolation("password", "is too common"),
+	)
+	suite.Equal("PASSWORD_POLICY", errs.Reason(err))
+
+	violations := errs.FieldViolations(err)
+	suite.Len(violations, 2)
+	suite.Equal("must contain a digit", violations[0].GetDescription())
+	suite.Equal("is too common", violations[1].GetDescription())
+
+	// the status is not changed
+	suite.Len(st.Details(), 1)
+}
+
+func (suite *TestSuite) TestWithRetryInfo() {
+	err := errs.WithRetryInfo(errs.New(codes.Unavailable, "auth", "LOGIN_DELAYED", "try again later"), 3*time.Second)
+	suite.Equal("LOGIN_DELAYED", errs.Reason(err))
+
+	details := status.Convert(err).Details()
+	suite.Len(details, 2)
+
+	retryInfo, ok := details[1].(*errdetails.RetryInfo)
+	suite.True(ok)
+	suite.Equal(3*time.Second, retryInfo.GetRetryDelay().AsDuration())
+}
+
+func (suite *TestSuite) TestReason_NoDetails() {
+	suite.Empty(errs.Reason(status.Error(codes.Internal, "internal")))
+	suite.Empty(errs.Reason(errors.New("plain error")))
+	suite.Empty(errs.Reason(nil))
+	suite.Nil(errs.FieldViolations(errors.New("plain error")))
+}
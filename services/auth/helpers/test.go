@@ -1,6 +1,8 @@
 package helpers
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 
 	"github.com/accentdesign/grpc/services/auth/internal/migrate"
@@ -20,9 +22,18 @@ func (h *TestHelpers) CleanDatabase() error {
 	if err := h.DB.Where("1 = 1").Delete(models.User{}).Error; err != nil {
 		return err
 	}
+	if err := h.DB.Exec("DELETE FROM auth_user_type_scopes").Error; err != nil {
+		return err
+	}
 	if err := h.DB.Where("1 = 1").Delete(models.UserType{}).Error; err != nil {
 		return err
 	}
+	if err := h.DB.Where("1 = 1").Delete(models.Scope{}).Error; err != nil {
+		return err
+	}
+	if err := h.DB.Where("1 = 1").Delete(models.LoginAttempt{}).Error; err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -55,3 +66,40 @@ func (h *TestHelpers) CreateTestUser() (*models.User, error) {
 	}
 	return &user, nil
 }
+
+func (h *TestHelpers) CreateTestAdminUser(token string, scopes ...string) (*models.User, error) {
+	userType := models.UserType{Name: "admin"}
+	for _, name := range scopes {
+		var scope models.Scope
+		if err := h.DB.FirstOrCreate(&scope, models.Scope{Name: name}).Error; err != nil {
+			return nil, err
+		}
+		userType.Scopes = append(userType.Scopes, scope)
+	}
+	if err := h.DB.Create(&userType).Error; err != nil {
+		return nil, err
+	}
+	user := models.User{
+		Email:      "admin@example.com",
+		FirstName:  "Jane",
+		LastName:   "Doe",
+		UserTypeId: userType.ID,
+		IsActive:   true,
+		IsVerified: true,
+	}
+	if err := user.SetPassword("password"); err != nil {
+		return nil, err
+	}
+	if err := h.DB.Create(&user).Error; err != nil {
+		return nil, err
+	}
+	accessToken := models.AccessToken{
+		Token:     token,
+		UserId:    user.ID,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	if err := h.DB.Create(&accessToken).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
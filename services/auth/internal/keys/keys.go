@@ -0,0 +1,204 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrKeyNotFound = errors.New("signing key not found")
+
+// AccessClaims are the claims carried by a signed access token.
+type AccessClaims struct {
+	jwt.RegisteredClaims
+	UserType string   `json:"user_type"`
+	Scopes   []string `json:"scopes"`
+}
+
+// Key is a private key used to sign access tokens, identified by its key id.
+type Key struct {
+	ID         string
+	PrivateKey crypto.Signer
+}
+
+func (k *Key) method() jwt.SigningMethod {
+	switch k.PrivateKey.(type) {
+	case ed25519.PrivateKey:
+		return jwt.SigningMethodEdDSA
+	default:
+		return jwt.SigningMethodRS256
+	}
+}
+
+// JWK is the public part of a Key in JSON Web Key form.
+type JWK struct {
+	Kty string
+	Kid string
+	Use string
+	Alg string
+	N   string
+	E   string
+	Crv string
+	X   string
+}
+
+// KeySet signs access tokens with its active key and verifies tokens signed by any of its keys,
+// which allows keys to be rotated while tokens signed by the previous key are still valid.
+type KeySet struct {
+	Issuer string
+	keys   map[string]*Key
+	ids    []string
+	active *Key
+}
+
+// ParseKey parses a PEM encoded RSA or Ed25519 private key.
+func ParseKey(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %s: no PEM data found", id)
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("key %s: unsupported PEM type %q", id, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %v", id, err)
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &Key{ID: id, PrivateKey: k}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: id, PrivateKey: k}, nil
+	default:
+		return nil, fmt.Errorf("key %s: unsupported key type %T", id, parsed)
+	}
+}
+
+// LoadKeySet loads every *.pem file in dir, the file name without its extension is used as the key id.
+// When activeID is empty the last key id in lexical order signs new tokens.
+func LoadKeySet(dir string, activeID string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []*Key
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		key, err := ParseKey(strings.TrimSuffix(filepath.Base(path), ".pem"), data)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return NewKeySet(keys, activeID)
+}
+
+// NewKeySet creates a KeySet from keys, activeID selects the signing key.
+// When activeID is empty the last key id in lexical order signs new tokens.
+func NewKeySet(keys []*Key, activeID string) (*KeySet, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no signing keys found")
+	}
+
+	ks := &KeySet{keys: make(map[string]*Key, len(keys))}
+	for _, key := range keys {
+		if _, ok := ks.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id: %s", key.ID)
+		}
+		ks.keys[key.ID] = key
+		ks.ids = append(ks.ids, key.ID)
+	}
+	sort.Strings(ks.ids)
+
+	if activeID == "" {
+		activeID = ks.ids[len(ks.ids)-1]
+	}
+	active, ok := ks.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, activeID)
+	}
+	ks.active = active
+
+	return ks, nil
+}
+
+// ActiveKeyID returns the id of the key used to sign new tokens.
+func (ks *KeySet) ActiveKeyID() string {
+	return ks.active.ID
+}
+
+// Sign signs the claims with the active key.
+func (ks *KeySet) Sign(claims *AccessClaims) (string, error) {
+	if claims.Issuer == "" {
+		claims.Issuer = ks.Issuer
+	}
+	token := jwt.NewWithClaims(ks.active.method(), claims)
+	token.Header["kid"] = ks.active.ID
+	return token.SignedString(ks.active.PrivateKey)
+}
+
+// Parse verifies a token signed by any key in the set and returns its claims.
+func (ks *KeySet) Parse(tokenStr string) (*AccessClaims, error) {
+	claims := &AccessClaims{}
+	_, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := ks.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, kid)
+		}
+		if token.Method != key.method() {
+			return nil, fmt.Errorf("unexpected signing method: %s", token.Method.Alg())
+		}
+		return key.PrivateKey.Public(), nil
+	}, jwt.WithIssuer(ks.Issuer), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// JWKS returns the public keys of the set.
+func (ks *KeySet) JWKS() []JWK {
+	jwks := make([]JWK, 0, len(ks.ids))
+	for _, id := range ks.ids {
+		key := ks.keys[id]
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.method().Alg()}
+		switch pub := key.PrivateKey.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		jwks = append(jwks, jwk)
+	}
+	return jwks
+}
@@ -0,0 +1,169 @@
+package keys_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/accentdesign/grpc/services/auth/internal/keys"
+)
+
+type TestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (suite *TestSuite) SetupSuite() {
+	suite.dir = suite.T().TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	suite.NoError(err)
+	suite.writeKey("2024-01", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	suite.NoError(err)
+	der, err := x509.MarshalPKCS8PrivateKey(edKey)
+	suite.NoError(err)
+	suite.writeKey("2024-02", "PRIVATE KEY", der)
+}
+
+func (suite *TestSuite) writeKey(id string, pemType string, der []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: pemType, Bytes: der})
+	err := os.WriteFile(filepath.Join(suite.dir, id+".pem"), data, 0600)
+	suite.NoError(err)
+}
+
+func TestTestSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+func claims() *keys.AccessClaims {
+	now := time.Now()
+	return &keys.AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "jti",
+			Subject:   "3f4b2b87-d7b1-4b9f-b207-ae00b112382f",
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+		UserType: "user",
+		Scopes:   []string{"read", "write"},
+	}
+}
+
+func (suite *TestSuite) TestLoadKeySet() {
+	ks, err := keys.LoadKeySet(suite.dir, "")
+	suite.NoError(err)
+	suite.Equal("2024-02", ks.ActiveKeyID())
+
+	ks, err = keys.LoadKeySet(suite.dir, "2024-01")
+	suite.NoError(err)
+	suite.Equal("2024-01", ks.ActiveKeyID())
+
+	ks, err = keys.LoadKeySet(suite.dir, "unknown")
+	suite.ErrorIs(err, keys.ErrKeyNotFound)
+	suite.Nil(ks)
+
+	ks, err = keys.LoadKeySet(suite.T().TempDir(), "")
+	suite.EqualError(err, "no signing keys found")
+	suite.Nil(ks)
+}
+
+func (suite *TestSuite) TestKeySet_SignAndParse() {
+	for _, kid := range []string{"2024-01", "2024-02"} {
+		suite.Run(kid, func() {
+			ks, err := keys.LoadKeySet(suite.dir, kid)
+			suite.NoError(err)
+			ks.Issuer = "auth"
+
+			signed, err := ks.Sign(claims())
+			suite.NoError(err)
+
+			parsed, err := ks.Parse(signed)
+			suite.NoError(err)
+			suite.Equal("auth", parsed.Issuer)
+			suite.Equal("3f4b2b87-d7b1-4b9f-b207-ae00b112382f", parsed.Subject)
+			suite.Equal("user", parsed.UserType)
+			suite.Equal([]string{"read", "write"}, parsed.Scopes)
+		})
+	}
+}
+
+func (suite *TestSuite) TestKeySet_Rotation() {
+	old, err := keys.LoadKeySet(suite.dir, "2024-01")
+	suite.NoError(err)
+
+	signed, err := old.Sign(claims())
+	suite.NoError(err)
+
+	// tokens signed by the previous key are still valid after rotation
+	rotated, err := keys.LoadKeySet(suite.dir, "")
+	suite.NoError(err)
+
+	_, err = rotated.Parse(signed)
+	suite.NoError(err)
+
+	// tokens signed by a removed key are not
+	data, err := os.ReadFile(filepath.Join(suite.dir, "2024-02.pem"))
+	suite.NoError(err)
+	key, err := keys.ParseKey("2024-02", data)
+	suite.NoError(err)
+	removed, err := keys.NewKeySet([]*keys.Key{key}, "")
+	suite.NoError(err)
+
+	_, err = removed.Parse(signed)
+	suite.ErrorIs(err, keys.ErrKeyNotFound)
+}
+
+func (suite *TestSuite) TestKeySet_ParseInvalid() {
+	ks, err := keys.LoadKeySet(suite.dir, "")
+	suite.NoError(err)
+	ks.Issuer = "auth"
+
+	expired := claims()
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	signed, err := ks.Sign(expired)
+	suite.NoError(err)
+	_, err = ks.Parse(signed)
+	suite.ErrorIs(err, jwt.ErrTokenExpired)
+
+	wrongIssuer := claims()
+	wrongIssuer.Issuer = "someone-else"
+	signed, err = ks.Sign(wrongIssuer)
+	suite.NoError(err)
+	_, err = ks.Parse(signed)
+	suite.ErrorIs(err, jwt.ErrTokenInvalidIssuer)
+
+	_, err = ks.Parse("not-a-jwt")
+	suite.Error(err)
+}
+
+func (suite *TestSuite) TestKeySet_JWKS() {
+	ks, err := keys.LoadKeySet(suite.dir, "")
+	suite.NoError(err)
+
+	jwks := ks.JWKS()
+	suite.Len(jwks, 2)
+
+	suite.Equal("2024-01", jwks[0].Kid)
+	suite.Equal("RSA", jwks[0].Kty)
+	suite.Equal("RS256", jwks[0].Alg)
+	suite.Equal("sig", jwks[0].Use)
+	suite.Equal("AQAB", jwks[0].E)
+	suite.NotEmpty(jwks[0].N)
+
+	suite.Equal("2024-02", jwks[1].Kid)
+	suite.Equal("OKP", jwks[1].Kty)
+	suite.Equal("EdDSA", jwks[1].Alg)
+	suite.Equal("Ed25519", jwks[1].Crv)
+	suite.NotEmpty(jwks[1].X)
+}
@@ -38,8 +38,16 @@ func (m *Migrator) migrate(db *gorm.DB) error {
 		&models.UserType{},
 		&models.User{},
 		&models.AccessToken{},
+		&models.RefreshToken{},
 		&models.ResetToken{},
 		&models.VerifyToken{},
+		&models.EmailChangeToken{},
+		&models.LoginToken{},
+		&models.MFAToken{},
+		&models.RecoveryCode{},
+		&models.LoginAttempt{},
+		&models.PasswordHistory{},
+		&models.Identity{},
 	); err != nil {
 		return err
 	}
@@ -53,8 +53,16 @@ func (suite *TestSuite) TestMigrate_MigrateDatabase() {
 		"auth_user_type_scopes",
 		"auth_users",
 		"auth_access_tokens",
+		"auth_refresh_tokens",
 		"auth_reset_tokens",
 		"auth_verify_tokens",
+		"auth_email_change_tokens",
+		"auth_login_tokens",
+		"auth_mfa_tokens",
+		"auth_recovery_codes",
+		"auth_login_attempts",
+		"auth_password_history",
+		"auth_identities",
 	} {
 		var count int64
 		err := suite.db.Raw("SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = 'public' AND table_name = ?", table).Scan(&count).Error
@@ -7,11 +7,17 @@ import (
 )
 
 type AccessToken struct {
-	Token     string    `gorm:"type:varchar(1024);primary_key"`
-	UserId    uuid.UUID `gorm:"not null;index"`
-	User      User      `gorm:"constraint:OnDelete:CASCADE"`
-	CreatedAt time.Time
-	ExpiresAt time.Time
+	Token      string     `gorm:"type:text;primary_key"`
+	Hashed     bool       `gorm:"type:boolean;not null;default:false"`
+	ID         uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex;default:gen_random_uuid()"`
+	UserId     uuid.UUID  `gorm:"not null;index"`
+	User       User       `gorm:"constraint:OnDelete:CASCADE"`
+	FamilyId   *uuid.UUID `gorm:"type:uuid;index"`
+	UserAgent  string     `gorm:"type:varchar(512);not null;default:''"`
+	IPAddress  string     `gorm:"type:varchar(64);not null;default:''"`
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	LastUsedAt *time.Time
 }
 
 func (*AccessToken) TableName() string {
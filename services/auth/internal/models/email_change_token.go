@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailChangeToken confirms a change of the email of a user to Email, the address the token is sent to.
+type EmailChangeToken struct {
+	Token     string    `gorm:"type:varchar(1024);primary_key"`
+	Hashed    bool      `gorm:"type:boolean;not null;default:false"`
+	UserId    uuid.UUID `gorm:"not null;index"`
+	User      User      `gorm:"constraint:OnDelete:CASCADE"`
+	Email     string    `gorm:"type:varchar(320);not null"`
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+func (*EmailChangeToken) TableName() string {
+	return "auth_email_change_tokens"
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Identity links a user to their account at an OpenID Connect provider, identified by the subject of the ID
+// tokens of the provider. A user can link one account of each provider. Email is the email of the account at
+// the provider when it was linked.
+type Identity struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserId    uuid.UUID `gorm:"not null;uniqueIndex:idx_auth_identities_user_provider"`
+	User      User      `gorm:"constraint:OnDelete:CASCADE"`
+	Provider  string    `gorm:"type:varchar(64);not null;uniqueIndex:idx_auth_identities_user_provider;uniqueIndex:idx_auth_identities_provider_subject"`
+	Subject   string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_auth_identities_provider_subject"`
+	Email     string    `gorm:"type:varchar(320);not null;default:''"`
+	CreatedAt time.Time
+}
+
+func (*Identity) TableName() string {
+	return "auth_identities"
+}
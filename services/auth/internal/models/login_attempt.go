@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+type LoginAttempt struct {
+	Key           string `gorm:"type:varchar(400);primary_key"`
+	Failures      int    `gorm:"not null;default:0"`
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+}
+
+func (*LoginAttempt) TableName() string {
+	return "auth_login_attempts"
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoginToken logs a user in without a password. A login link token is redeemed alone, a login code token only
+// along with its Code, the digest of the numeric code sent to the user, and is revoked after too many Attempts.
+type LoginToken struct {
+	Token     string    `gorm:"type:varchar(1024);primary_key"`
+	Hashed    bool      `gorm:"type:boolean;not null;default:false"`
+	UserId    uuid.UUID `gorm:"not null;index"`
+	User      User      `gorm:"constraint:OnDelete:CASCADE"`
+	Code      string    `gorm:"type:varchar(128);not null;default:''"`
+	Attempts  int       `gorm:"not null;default:0"`
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+func (*LoginToken) TableName() string {
+	return "auth_login_tokens"
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type MFAToken struct {
+	Token     string    `gorm:"type:varchar(1024);primary_key"`
+	Hashed    bool      `gorm:"type:boolean;not null;default:false"`
+	UserId    uuid.UUID `gorm:"not null;index"`
+	User      User      `gorm:"constraint:OnDelete:CASCADE"`
+	Attempts  int       `gorm:"not null;default:0"`
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+func (*MFAToken) TableName() string {
+	return "auth_mfa_tokens"
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PasswordHistory struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserId         uuid.UUID `gorm:"not null;index"`
+	User           User      `gorm:"constraint:OnDelete:CASCADE"`
+	HashedPassword string    `gorm:"type:varchar(1024);not null"`
+	CreatedAt      time.Time
+}
+
+func (*PasswordHistory) TableName() string {
+	return "auth_password_history"
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RecoveryCode struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserId    uuid.UUID `gorm:"not null;index"`
+	User      User      `gorm:"constraint:OnDelete:CASCADE"`
+	CodeHash  string    `gorm:"type:varchar(64);not null"`
+	CreatedAt time.Time
+	UsedAt    *time.Time
+}
+
+func (*RecoveryCode) TableName() string {
+	return "auth_recovery_codes"
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RefreshToken struct {
+	Token     string    `gorm:"type:varchar(1024);primary_key"`
+	Hashed    bool      `gorm:"type:boolean;not null;default:false"`
+	UserId    uuid.UUID `gorm:"not null;index"`
+	User      User      `gorm:"constraint:OnDelete:CASCADE"`
+	FamilyId  uuid.UUID `gorm:"type:uuid;not null;index"`
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+func (*RefreshToken) TableName() string {
+	return "auth_refresh_tokens"
+}
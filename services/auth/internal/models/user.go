@@ -3,15 +3,24 @@ package models
 import (
 	"context"
 	"fmt"
-	"github.com/asaskevich/govalidator"
+	"strconv"
 	"time"
 
+	"github.com/asaskevich/govalidator"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+
+	"github.com/accentdesign/grpc/services/auth/internal/passwords"
 )
 
+// PasswordHasher hashes new passwords. Passwords hashed by any supported algorithm can still be verified
+// after it is changed, and are rehashed by the login flow with PasswordNeedsRehash.
+var PasswordHasher passwords.Hasher = &passwords.Bcrypt{Cost: bcrypt.DefaultCost}
+
+// UserValidateError describes why a field of a user is invalid, Field is the name of the field in the api.
 type UserValidateError struct {
+	Field   string
 	Message string
 }
 
@@ -27,8 +36,11 @@ type User struct {
 	LastName       string    `gorm:"type:varchar(120);not null"`
 	UserTypeId     uuid.UUID `gorm:"not null"`
 	UserType       UserType
-	IsActive       bool `gorm:"type:boolean;not null;default:true"`
-	IsVerified     bool `gorm:"type:boolean;not null;default:false"`
+	IsActive       bool   `gorm:"type:boolean;not null;default:true"`
+	IsVerified     bool   `gorm:"type:boolean;not null;default:false"`
+	TOTPSecret     string `gorm:"type:varchar(255);not null;default:''"`
+	TOTPEnabled    bool   `gorm:"type:boolean;not null;default:false"`
+	TOTPLastStep   int64  `gorm:"not null;default:0"`
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
 }
@@ -54,12 +66,25 @@ func (u *User) AfterUpdate(tx *gorm.DB) (err error) {
 		return fmt.Errorf("could not get old user from context")
 	}
 
+	// single use tokens are kept until they expire, marked as used so they are reported as such
+	now := time.Now()
+
 	if oldUser.HashedPassword != u.HashedPassword {
-		tx.Where("user_id = ?", u.ID).Delete(&ResetToken{})
+		tx.Model(&ResetToken{}).Where("user_id = ? AND used_at IS NULL", u.ID).UpdateColumn("used_at", now)
 	}
 
 	if u.IsVerified {
-		tx.Where("user_id = ?", u.ID).Delete(&VerifyToken{})
+		tx.Model(&VerifyToken{}).Where("user_id = ? AND used_at IS NULL", u.ID).UpdateColumn("used_at", now)
+	}
+
+	if oldUser.Email != u.Email {
+		tx.Model(&EmailChangeToken{}).Where("user_id = ? AND used_at IS NULL", u.ID).UpdateColumn("used_at", now)
+		tx.Model(&LoginToken{}).Where("user_id = ? AND used_at IS NULL", u.ID).UpdateColumn("used_at", now)
+	}
+
+	if oldUser.IsActive && !u.IsActive {
+		tx.Where("user_id = ?", u.ID).Delete(&AccessToken{})
+		tx.Where("user_id = ?", u.ID).Delete(&RefreshToken{})
 	}
 
 	return nil
@@ -67,37 +92,50 @@ func (u *User) AfterUpdate(tx *gorm.DB) (err error) {
 
 func (u *User) SetPassword(password string) error {
 	if govalidator.IsNull(password) {
-		return &UserValidateError{"password is required"}
+		return &UserValidateError{"password", "password is required"}
 	}
-	if !govalidator.StringLength(password, "6", "72") {
-		return &UserValidateError{"password must be between 6 and 72 characters in length"}
+	maxLength := strconv.Itoa(PasswordHasher.MaxLength())
+	if !govalidator.StringLength(password, "6", maxLength) {
+		return &UserValidateError{"password", "password must be between 6 and " + maxLength + " characters in length"}
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := PasswordHasher.Hash(password)
 	if err != nil {
 		return err
 	}
 
-	u.HashedPassword = string(hashedPassword)
+	u.HashedPassword = hashedPassword
 
 	return nil
 }
 
 func (u *User) Validate() error {
 	if !govalidator.IsEmail(u.Email) {
-		return &UserValidateError{"invalid email format"}
+		return &UserValidateError{"email", "invalid email format"}
 	}
 	if govalidator.IsNull(u.FirstName) {
-		return &UserValidateError{"first_name is required"}
+		return &UserValidateError{"first_name", "first_name is required"}
 	}
 	if govalidator.IsNull(u.LastName) {
-		return &UserValidateError{"last_name is required"}
+		return &UserValidateError{"last_name", "last_name is required"}
 	}
 
 	return nil
 }
 
+// HasPassword reports whether the user has set a password, users created by logging in with an OpenID Connect
+// provider have none.
+func (u *User) HasPassword() bool {
+	return u.HashedPassword != ""
+}
+
 func (u *User) VerifyPassword(password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(u.HashedPassword), []byte(password))
-	return err == nil
+	ok, err := passwords.Verify(u.HashedPassword, password)
+	return err == nil && ok
+}
+
+// PasswordNeedsRehash reports whether the password was hashed with a different algorithm or parameters than
+// PasswordHasher uses.
+func (u *User) PasswordNeedsRehash() bool {
+	return PasswordHasher.NeedsRehash(u.HashedPassword)
 }
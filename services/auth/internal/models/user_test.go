@@ -3,6 +3,7 @@ package models_test
 import (
 	"errors"
 	"github.com/accentdesign/grpc/services/auth/internal/models"
+	"github.com/accentdesign/grpc/services/auth/internal/passwords"
 	"strings"
 )
 
@@ -10,21 +11,21 @@ func (suite *TestSuite) TestUserModel_Validate() {
 	testCases := []struct {
 		desc          string
 		user          *models.User
-		expectedError error
+		expectedError *models.UserValidateError
 	}{
-		{"missing email", &models.User{}, errors.New("invalid email format")},
-		{"empty email", &models.User{Email: ""}, errors.New("invalid email format")},
-		{"invalid email", &models.User{Email: "invalid"}, errors.New("invalid email format")},
-		{"missing first name", &models.User{Email: "test@example.com"}, errors.New("first_name is required")},
-		{"empty first name", &models.User{Email: "test@example.com", FirstName: ""}, errors.New("first_name is required")},
-		{"missing last name", &models.User{Email: "test@example.com", FirstName: "Some"}, errors.New("last_name is required")},
-		{"empty last name", &models.User{Email: "test@example.com", FirstName: "Some", LastName: ""}, errors.New("last_name is required")},
+		{"missing email", &models.User{}, &models.UserValidateError{Field: "email", Message: "invalid email format"}},
+		{"empty email", &models.User{Email: ""}, &models.UserValidateError{Field: "email", Message: "invalid email format"}},
+		{"invalid email", &models.User{Email: "invalid"}, &models.UserValidateError{Field: "email", Message: "invalid email format"}},
+		{"missing first name", &models.User{Email: "test@example.com"}, &models.UserValidateError{Field: "first_name", Message: "first_name is required"}},
+		{"empty first name", &models.User{Email: "test@example.com", FirstName: ""}, &models.UserValidateError{Field: "first_name", Message: "first_name is required"}},
+		{"missing last name", &models.User{Email: "test@example.com", FirstName: "Some"}, &models.UserValidateError{Field: "last_name", Message: "last_name is required"}},
+		{"empty last name", &models.User{Email: "test@example.com", FirstName: "Some", LastName: ""}, &models.UserValidateError{Field: "last_name", Message: "last_name is required"}},
 	}
 
 	for _, tc := range testCases {
 		suite.Run(tc.desc, func() {
 			err := tc.user.Validate()
-			suite.EqualError(tc.expectedError, err.Error())
+			suite.Equal(tc.expectedError, err)
 		})
 	}
 
@@ -75,3 +76,27 @@ func (suite *TestSuite) TestUserModel_VerifyPassword() {
 	verify = user.VerifyPassword("password1")
 	suite.False(verify)
 }
+
+func (suite *TestSuite) TestUserModel_PasswordHasher() {
+	user := &models.User{}
+	err := user.SetPassword("password")
+	suite.NoError(err)
+	suite.False(user.PasswordNeedsRehash())
+
+	// switching to argon2id keeps existing bcrypt passwords valid, flagging them for a rehash
+	defer func(hasher passwords.Hasher) { models.PasswordHasher = hasher }(models.PasswordHasher)
+	models.PasswordHasher = &passwords.Argon2id{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+	suite.True(user.VerifyPassword("password"))
+	suite.True(user.PasswordNeedsRehash())
+
+	// argon2id lifts the bcrypt length limit
+	err = user.SetPassword(strings.Repeat("x", 100))
+	suite.NoError(err)
+	suite.True(strings.HasPrefix(user.HashedPassword, "$argon2id$"))
+	suite.False(user.PasswordNeedsRehash())
+	suite.True(user.VerifyPassword(strings.Repeat("x", 100)))
+
+	err = user.SetPassword(strings.Repeat("x", 1025))
+	suite.EqualError(err, "password must be between 6 and 1024 characters in length")
+}
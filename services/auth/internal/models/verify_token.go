@@ -8,10 +8,12 @@ import (
 
 type VerifyToken struct {
 	Token     string    `gorm:"type:varchar(1024);primary_key"`
+	Hashed    bool      `gorm:"type:boolean;not null;default:false"`
 	UserId    uuid.UUID `gorm:"not null;index"`
 	User      User      `gorm:"constraint:OnDelete:CASCADE"`
 	CreatedAt time.Time
 	ExpiresAt time.Time
+	UsedAt    *time.Time
 }
 
 func (*VerifyToken) TableName() string {
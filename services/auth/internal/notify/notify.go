@@ -0,0 +1,93 @@
+// Package notify sends the transactional emails of the auth service, such as verification and password reset
+// links, through the email service.
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	emailpb "github.com/accentdesign/grpc/services/email/pkg/api/email"
+)
+
+// Kind is a type of notification, each kind has its own template.
+type Kind string
+
+const (
+	KindVerify               Kind = "verify"
+	KindResetPassword        Kind = "reset_password"
+	KindPasswordChanged      Kind = "password_changed"
+	KindEmailChange          Kind = "email_change"
+	KindEmailChangeRequested Kind = "email_change_requested"
+	KindEmailChanged         Kind = "email_changed"
+	KindLoginLink            Kind = "login_link"
+	KindLoginCode            Kind = "login_code"
+)
+
+// Kinds are all the kinds of notification.
+var Kinds = []Kind{KindVerify, KindResetPassword, KindPasswordChanged, KindEmailChange, KindEmailChangeRequested, KindEmailChanged, KindLoginLink, KindLoginCode}
+
+// Data is what the templates of a notification are executed with.
+type Data struct {
+	// Email is the address the notification is sent to
+	Email     string
+	FirstName string
+	LastName  string
+	// Token is the verify, reset password, email change or login link token, empty for the other kinds
+	Token string
+	// Code is the code of a login code token, for KindLoginCode
+	Code string
+	// NewEmail is the email being changed to, for KindEmailChange, KindEmailChangeRequested and KindEmailChanged
+	NewEmail string
+	// AppURL is the base url of the application the links in the notification point to
+	AppURL string
+}
+
+// Notifier sends notifications to users.
+type Notifier interface {
+	Notify(ctx context.Context, kind Kind, data Data) error
+}
+
+// EmailNotifier sends notifications as emails with the SendEmail method of the email service.
+type EmailNotifier struct {
+	Client    emailpb.EmailServiceClient
+	Templates *Templates
+	// From is the address the emails are sent from
+	From string
+	// AppURL is set as Data.AppURL
+	AppURL string
+}
+
+// Notify renders the templates of the kind and sends the email to data.Email.
+func (n *EmailNotifier) Notify(ctx context.Context, kind Kind, data Data) error {
+	data.AppURL = n.AppURL
+	message, err := n.Templates.Render(kind, data)
+	if err != nil {
+		return err
+	}
+
+	stream, err := n.Client.SendEmail(ctx)
+	if err != nil {
+		return fmt.Errorf("error sending %s email: %v", kind, err)
+	}
+	if err := stream.Send(&emailpb.EmailRequest{
+		Payload: &emailpb.EmailRequest_EmailInfo{
+			EmailInfo: &emailpb.EmailInfo{
+				FromAddress: n.From,
+				To:          []string{data.Email},
+				Subject:     message.Subject,
+				PlainText:   message.PlainText,
+				Html:        message.Html,
+			},
+		},
+	}); err != nil {
+		return fmt.Errorf("error sending %s email: %v", kind, err)
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return fmt.Errorf("error sending %s email: %v", kind, err)
+	}
+	if !resp.GetSuccess() {
+		return fmt.Errorf("error sending %s email: %s", kind, resp.GetMessage())
+	}
+	return nil
+}
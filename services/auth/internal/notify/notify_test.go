@@ -0,0 +1,145 @@
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+
+	"github.com/accentdesign/grpc/services/auth/internal/notify"
+	emailpb "github.com/accentdesign/grpc/services/email/pkg/api/email"
+)
+
+type TestSuite struct {
+	suite.Suite
+}
+
+func TestTestSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+type fakeStream struct {
+	grpc.ClientStream
+	client *fakeClient
+}
+
+func (s *fakeStream) Send(req *emailpb.EmailRequest) error {
+	s.client.sent = append(s.client.sent, req.GetEmailInfo())
+	return nil
+}
+
+func (s *fakeStream) CloseAndRecv() (*emailpb.EmailResponse, error) {
+	return s.client.resp, s.client.err
+}
+
+type fakeClient struct {
+	emailpb.EmailServiceClient
+	sent []*emailpb.EmailInfo
+	resp *emailpb.EmailResponse
+	err  error
+}
+
+func (c *fakeClient) SendEmail(_ context.Context, _ ...grpc.CallOption) (emailpb.EmailService_SendEmailClient, error) {
+	return &fakeStream{client: c}, nil
+}
+
+func (suite *TestSuite) TestEmailNotifier_Notify() {
+	templates, err := notify.DefaultTemplates()
+	suite.NoError(err)
+
+	client := &fakeClient{resp: &emailpb.EmailResponse{Success: true}}
+	notifier := &notify.EmailNotifier{
+		Client:    client,
+		Templates: templates,
+		From:      "no-reply@example.com",
+		AppURL:    "https://app.example.com",
+	}
+
+	err = notifier.Notify(context.Background(), notify.KindResetPassword, notify.Data{
+		Email:     "john@example.com",
+		FirstName: "John",
+		Token:     "reset-token",
+	})
+	suite.NoError(err)
+
+	suite.Len(client.sent, 1)
+	sent := client.sent[0]
+	suite.Equal("no-reply@example.com", sent.GetFromAddress())
+	suite.Equal([]string{"john@example.com"}, sent.GetTo())
+	suite.Equal("Reset your password", sent.GetSubject())
+	suite.Contains(sent.GetPlainText(), "Hi John,")
+	suite.Contains(sent.GetPlainText(), "https://app.example.com/reset-password?token=reset-token")
+	suite.Contains(sent.GetHtml(), `href="https://app.example.com/reset-password?token=reset-token"`)
+}
+
+func (suite *TestSuite) TestEmailNotifier_NotifyFailed() {
+	templates, err := notify.DefaultTemplates()
+	suite.NoError(err)
+
+	client := &fakeClient{resp: &emailpb.EmailResponse{Success: false, Message: "smtp unavailable"}}
+	notifier := &notify.EmailNotifier{Client: client, Templates: templates}
+
+	err = notifier.Notify(context.Background(), notify.KindVerify, notify.Data{Email: "john@example.com"})
+	suite.EqualError(err, "error sending verify email: smtp unavailable")
+
+	client.err = errors.New("connection refused")
+	err = notifier.Notify(context.Background(), notify.KindVerify, notify.Data{Email: "john@example.com"})
+	suite.EqualError(err, "error sending verify email: connection refused")
+}
+
+func (suite *TestSuite) TestTemplates_Defaults() {
+	templates, err := notify.DefaultTemplates()
+	suite.NoError(err)
+
+	for _, kind := range notify.Kinds {
+		message, err := templates.Render(kind, notify.Data{
+			Email:     "john@example.com",
+			FirstName: "John",
+			Token:     "token",
+			NewEmail:  "johnny@example.com",
+			AppURL:    "https://app.example.com",
+		})
+		suite.NoError(err, kind)
+		suite.NotEmpty(message.Subject, kind)
+		suite.NotEmpty(message.PlainText, kind)
+		suite.NotEmpty(message.Html, kind)
+	}
+}
+
+func (suite *TestSuite) TestTemplates_Load() {
+	dir := suite.T().TempDir()
+	suite.NoError(os.WriteFile(filepath.Join(dir, "verify.tmpl"), []byte(
+		`{{define "subject"}}Welcome {{.FirstName}}{{end}}{{define "text"}}Token: {{.Token}}{{end}}`,
+	), 0o600))
+
+	templates, err := notify.LoadTemplates(dir)
+	suite.NoError(err)
+
+	message, err := templates.Render(notify.KindVerify, notify.Data{FirstName: "<John>", Token: "abc"})
+	suite.NoError(err)
+	suite.Equal("Welcome <John>", message.Subject)
+	suite.Equal("Token: abc", message.PlainText)
+	suite.Empty(message.Html)
+
+	// kinds without a file keep the built in template
+	message, err = templates.Render(notify.KindResetPassword, notify.Data{FirstName: "<John>"})
+	suite.NoError(err)
+	suite.Equal("Reset your password", message.Subject)
+	suite.Contains(message.Html, "Hi &lt;John&gt;,")
+}
+
+func (suite *TestSuite) TestTemplates_LoadInvalid() {
+	dir := suite.T().TempDir()
+	suite.NoError(os.WriteFile(filepath.Join(dir, "verify.tmpl"), []byte(`{{define "subject"}}Welcome{{end}}`), 0o600))
+
+	_, err := notify.LoadTemplates(dir)
+	suite.EqualError(err, `verify template does not define "text"`)
+
+	suite.NoError(os.WriteFile(filepath.Join(dir, "verify.tmpl"), []byte(`{{define "subject"}}Welcome{{end`), 0o600))
+	_, err = notify.LoadTemplates(dir)
+	suite.ErrorContains(err, "error parsing verify template")
+}
@@ -0,0 +1,117 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+// Message is a rendered notification.
+type Message struct {
+	Subject   string
+	PlainText string
+	Html      string
+}
+
+type template struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// Templates render the notifications. The template of a kind is a file named after it, such as verify.tmpl,
+// defining a "subject" and a "text" template and optionally an "html" template, which is escaped as html.
+type Templates struct {
+	templates map[Kind]template
+}
+
+// DefaultTemplates returns the templates built into the service.
+func DefaultTemplates() (*Templates, error) {
+	return LoadTemplates("")
+}
+
+// LoadTemplates returns the built in templates, replacing those of which a file exists in dir.
+// An empty dir returns only the built in templates.
+func LoadTemplates(dir string) (*Templates, error) {
+	t := &Templates{templates: make(map[Kind]template, len(Kinds))}
+	for _, kind := range Kinds {
+		name := string(kind) + ".tmpl"
+
+		content, err := defaultTemplates.ReadFile("templates/" + name)
+		if err != nil {
+			return nil, err
+		}
+		if dir != "" {
+			custom, err := os.ReadFile(filepath.Join(dir, name))
+			switch {
+			case err == nil:
+				content = custom
+			case !errors.Is(err, os.ErrNotExist):
+				return nil, err
+			}
+		}
+
+		if err := t.parse(kind, string(content)); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+func (t *Templates) parse(kind Kind, content string) error {
+	text, err := texttemplate.New(string(kind)).Parse(content)
+	if err != nil {
+		return fmt.Errorf("error parsing %s template: %v", kind, err)
+	}
+	for _, name := range []string{"subject", "text"} {
+		if text.Lookup(name) == nil {
+			return fmt.Errorf("%s template does not define %q", kind, name)
+		}
+	}
+
+	html, err := htmltemplate.New(string(kind)).Parse(content)
+	if err != nil {
+		return fmt.Errorf("error parsing %s template: %v", kind, err)
+	}
+	if html.Lookup("html") == nil {
+		html = nil
+	}
+
+	t.templates[kind] = template{text: text, html: html}
+	return nil
+}
+
+// Render executes the templates of the kind with data.
+func (t *Templates) Render(kind Kind, data Data) (*Message, error) {
+	tmpl, ok := t.templates[kind]
+	if !ok {
+		return nil, fmt.Errorf("no template for %s", kind)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("error rendering %s subject: %v", kind, err)
+	}
+	if err := tmpl.text.ExecuteTemplate(&text, "text", data); err != nil {
+		return nil, fmt.Errorf("error rendering %s text: %v", kind, err)
+	}
+	if tmpl.html != nil {
+		if err := tmpl.html.ExecuteTemplate(&html, "html", data); err != nil {
+			return nil, fmt.Errorf("error rendering %s html: %v", kind, err)
+		}
+	}
+
+	return &Message{
+		Subject:   strings.TrimSpace(subject.String()),
+		PlainText: strings.TrimSpace(text.String()),
+		Html:      strings.TrimSpace(html.String()),
+	}, nil
+}
@@ -0,0 +1,89 @@
+package oidc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+)
+
+// providerName is the pattern of provider names, which are stored with the identities linked through them.
+var providerName = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+
+// Config is the configuration file of the OpenID Connect providers, keyed by provider name:
+//
+//	{
+//	  "providers": {
+//	    "google": {
+//	      "issuer": "https://accounts.google.com",
+//	      "client_id": "my-client-id",
+//	      "client_secret": "${GOOGLE_CLIENT_SECRET}",
+//	      "redirect_url": "https://example.com/auth/google/callback"
+//	    }
+//	  }
+//	}
+type Config struct {
+	Providers map[string]ProviderConfig `json:"providers"`
+}
+
+// ProviderConfig configures an OpenID Connect provider. The endpoints are discovered from the issuer unless set.
+type ProviderConfig struct {
+	Issuer string `json:"issuer"`
+	// ClientID and ClientSecret are issued by the provider, environment variables in ClientSecret are expanded
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	// RedirectURL is the url of the application the provider redirects to with the authorization code
+	RedirectURL string `json:"redirect_url"`
+	// Scopes requested, defaults to openid, email and profile
+	Scopes                []string `json:"scopes"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+}
+
+// LoadConfig reads the providers configuration file at path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", path, err)
+	}
+	for name, provider := range config.Providers {
+		provider.ClientSecret = os.ExpandEnv(provider.ClientSecret)
+		config.Providers[name] = provider
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// Validate checks every provider has a valid name and the settings required to log in with it.
+func (c *Config) Validate() error {
+	names := make([]string, 0, len(c.Providers))
+	for name := range c.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		provider := c.Providers[name]
+		switch {
+		case !providerName.MatchString(name):
+			return fmt.Errorf("invalid provider name %q, use lower case letters, digits, - and _", name)
+		case provider.Issuer == "":
+			return fmt.Errorf("provider %s: issuer is required", name)
+		case provider.ClientID == "":
+			return fmt.Errorf("provider %s: client_id is required", name)
+		case provider.RedirectURL == "":
+			return fmt.Errorf("provider %s: redirect_url is required", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+// Package oidc logs users in with OpenID Connect providers, using the authorization code flow with PKCE.
+// The client redirects the user to the authorization url of a provider, and the code the provider redirects
+// back with is exchanged for an ID token identifying the user.
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrInvalidGrant   = errors.New("authorization code was rejected by the provider")
+	ErrInvalidIDToken = errors.New("invalid id token")
+)
+
+// Identity is the user an ID token was issued for.
+type Identity struct {
+	// Subject identifies the user at the provider, it never changes unlike the email
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+	Name          string
+}
+
+// Authorization is what a client needs to send a user to a provider, and to exchange the code the provider
+// redirects back with. The client keeps the state, code verifier and nonce until the user returns.
+type Authorization struct {
+	URL          string
+	State        string
+	CodeVerifier string
+	Nonce        string
+}
+
+// Providers are the configured providers by name.
+type Providers map[string]*Provider
+
+// NewProviders creates the providers of the config.
+func NewProviders(config *Config) Providers {
+	providers := make(Providers, len(config.Providers))
+	for name, providerConfig := range config.Providers {
+		providers[name] = NewProvider(name, providerConfig)
+	}
+	return providers
+}
+
+// httpClient is used by providers without their own client.
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// randomString returns a url safe random string of n random bytes.
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallenge returns the S256 PKCE code challenge of the code verifier.
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
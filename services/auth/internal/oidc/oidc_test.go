@@ -0,0 +1,178 @@
+package oidc_test
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/accentdesign/grpc/services/auth/internal/oidc"
+	"github.com/accentdesign/grpc/services/auth/internal/oidc/oidctest"
+)
+
+type TestSuite struct {
+	suite.Suite
+	server *oidctest.Server
+}
+
+func (suite *TestSuite) SetupSuite() {
+	suite.server = oidctest.NewServer("client-id", "client-secret")
+}
+
+func (suite *TestSuite) TearDownSuite() {
+	suite.server.Close()
+}
+
+func TestTestSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+var claims = oidctest.Claims{
+	Subject:       "12345",
+	Email:         "John@Example.com",
+	EmailVerified: true,
+	GivenName:     "John",
+	FamilyName:    "Doe",
+	Name:          "John Doe",
+}
+
+func (suite *TestSuite) TestProvider_Authorize() {
+	provider := oidc.NewProvider("stub", suite.server.Config("https://app.example.com/callback"))
+
+	auth, err := provider.Authorize(context.Background())
+	suite.NoError(err)
+	suite.NotEmpty(auth.State)
+	suite.NotEmpty(auth.CodeVerifier)
+	suite.NotEmpty(auth.Nonce)
+
+	u, err := url.Parse(auth.URL)
+	suite.NoError(err)
+	suite.Equal(suite.server.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+
+	query := u.Query()
+	suite.Equal("code", query.Get("response_type"))
+	suite.Equal("client-id", query.Get("client_id"))
+	suite.Equal("https://app.example.com/callback", query.Get("redirect_uri"))
+	suite.Equal("openid email profile", query.Get("scope"))
+	suite.Equal(auth.State, query.Get("state"))
+	suite.Equal(auth.Nonce, query.Get("nonce"))
+	suite.Equal(oidc.CodeChallenge(auth.CodeVerifier), query.Get("code_challenge"))
+	suite.Equal("S256", query.Get("code_challenge_method"))
+	suite.Empty(query.Get("code_verifier"))
+}
+
+func (suite *TestSuite) TestProvider_Exchange() {
+	provider := oidc.NewProvider("stub", suite.server.Config("https://app.example.com/callback"))
+
+	auth, err := provider.Authorize(context.Background())
+	suite.NoError(err)
+	code, err := suite.server.Authorize(auth.URL, claims)
+	suite.NoError(err)
+
+	identity, err := provider.Exchange(context.Background(), code, auth.CodeVerifier, auth.Nonce)
+	suite.NoError(err)
+	suite.Equal(&oidc.Identity{
+		Subject:       "12345",
+		Email:         "john@example.com",
+		EmailVerified: true,
+		GivenName:     "John",
+		FamilyName:    "Doe",
+		Name:          "John Doe",
+	}, identity)
+
+	// codes are single use
+	_, err = provider.Exchange(context.Background(), code, auth.CodeVerifier, auth.Nonce)
+	suite.ErrorIs(err, oidc.ErrInvalidGrant)
+}
+
+func (suite *TestSuite) TestProvider_ExchangeInvalid() {
+	provider := oidc.NewProvider("stub", suite.server.Config("https://app.example.com/callback"))
+
+	auth, err := provider.Authorize(context.Background())
+	suite.NoError(err)
+
+	// the code verifier has to match the code challenge
+	code, err := suite.server.Authorize(auth.URL, claims)
+	suite.NoError(err)
+	_, err = provider.Exchange(context.Background(), code, "other-verifier", auth.Nonce)
+	suite.ErrorIs(err, oidc.ErrInvalidGrant)
+
+	// the nonce has to match the id token
+	code, err = suite.server.Authorize(auth.URL, claims)
+	suite.NoError(err)
+	_, err = provider.Exchange(context.Background(), code, auth.CodeVerifier, "other-nonce")
+	suite.ErrorIs(err, oidc.ErrInvalidIDToken)
+
+	// the client credentials have to be accepted
+	config := suite.server.Config("https://app.example.com/callback")
+	config.ClientSecret = "incorrect"
+	code, err = suite.server.Authorize(auth.URL, claims)
+	suite.NoError(err)
+	_, err = oidc.NewProvider("stub", config).Exchange(context.Background(), code, auth.CodeVerifier, auth.Nonce)
+	suite.ErrorContains(err, "invalid_client")
+	suite.NotErrorIs(err, oidc.ErrInvalidGrant)
+
+	// the issuer has to match the discovered issuer
+	config = suite.server.Config("https://app.example.com/callback")
+	config.Issuer = suite.server.URL + "/"
+	_, err = oidc.NewProvider("stub", config).Authorize(context.Background())
+	suite.ErrorContains(err, "does not match")
+}
+
+func (suite *TestSuite) TestLoadConfig() {
+	suite.T().Setenv("OIDCTEST_CLIENT_SECRET", "secret")
+
+	path := filepath.Join(suite.T().TempDir(), "oidc.json")
+	suite.NoError(os.WriteFile(path, []byte(`{
+		"providers": {
+			"google": {
+				"issuer": "https://accounts.google.com",
+				"client_id": "client-id",
+				"client_secret": "${OIDCTEST_CLIENT_SECRET}",
+				"redirect_url": "https://app.example.com/callback",
+				"scopes": ["openid", "email"]
+			}
+		}
+	}`), 0o600))
+
+	config, err := oidc.LoadConfig(path)
+	suite.NoError(err)
+	suite.Len(config.Providers, 1)
+	google := config.Providers["google"]
+	suite.Equal("https://accounts.google.com", google.Issuer)
+	suite.Equal("secret", google.ClientSecret)
+	suite.Equal([]string{"openid", "email"}, google.Scopes)
+
+	providers := oidc.NewProviders(config)
+	suite.Equal("google", providers["google"].Name)
+}
+
+func (suite *TestSuite) TestConfig_Validate() {
+	valid := oidc.ProviderConfig{Issuer: "https://issuer", ClientID: "id", RedirectURL: "https://app/callback"}
+
+	testCases := []struct {
+		desc     string
+		name     string
+		mutate   func(*oidc.ProviderConfig)
+		expected string
+	}{
+		{"invalid name", "Google Login", func(*oidc.ProviderConfig) {}, `invalid provider name "Google Login", use lower case letters, digits, - and _`},
+		{"missing issuer", "google", func(c *oidc.ProviderConfig) { c.Issuer = "" }, "provider google: issuer is required"},
+		{"missing client id", "google", func(c *oidc.ProviderConfig) { c.ClientID = "" }, "provider google: client_id is required"},
+		{"missing redirect url", "google", func(c *oidc.ProviderConfig) { c.RedirectURL = "" }, "provider google: redirect_url is required"},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.desc, func() {
+			provider := valid
+			tc.mutate(&provider)
+			config := &oidc.Config{Providers: map[string]oidc.ProviderConfig{tc.name: provider}}
+			suite.EqualError(config.Validate(), tc.expected)
+		})
+	}
+
+	suite.NoError((&oidc.Config{Providers: map[string]oidc.ProviderConfig{"google": valid}}).Validate())
+}
@@ -0,0 +1,358 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Provider is an OpenID Connect provider users log in with. Its endpoints are discovered from the issuer on
+// first use, and its signing keys fetched again when an ID token is signed by an unknown key id, at most once
+// every RefreshInterval.
+type Provider struct {
+	Name            string
+	Config          ProviderConfig
+	HTTPClient      *http.Client
+	RefreshInterval time.Duration
+
+	mu        sync.Mutex
+	discovery *discovery
+	keys      map[string]publicKey
+	fetched   time.Time
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type publicKey struct {
+	alg string
+	key crypto.PublicKey
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// flexBool is a boolean claim some providers send as a string.
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	*b = flexBool(strings.Trim(string(data), `"`) == "true")
+	return nil
+}
+
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce         string   `json:"nonce"`
+	Email         string   `json:"email"`
+	EmailVerified flexBool `json:"email_verified"`
+	GivenName     string   `json:"given_name"`
+	FamilyName    string   `json:"family_name"`
+	Name          string   `json:"name"`
+}
+
+// NewProvider creates a provider from its configuration, refreshing its keys at most once a minute.
+func NewProvider(name string, config ProviderConfig) *Provider {
+	return &Provider{Name: name, Config: config, RefreshInterval: time.Minute}
+}
+
+func (p *Provider) client() *http.Client {
+	if p.HTTPClient != nil {
+		return p.HTTPClient
+	}
+	return httpClient
+}
+
+func (p *Provider) getJSON(ctx context.Context, endpoint string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", endpoint, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// endpoints returns the endpoints of the provider, discovering those that are not configured.
+func (p *Provider) endpoints(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	d := &discovery{
+		Issuer:                p.Config.Issuer,
+		AuthorizationEndpoint: p.Config.AuthorizationEndpoint,
+		TokenEndpoint:         p.Config.TokenEndpoint,
+		JWKSURI:               p.Config.JWKSURI,
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		var discovered discovery
+		wellKnown := strings.TrimSuffix(p.Config.Issuer, "/") + "/.well-known/openid-configuration"
+		if err := p.getJSON(ctx, wellKnown, &discovered); err != nil {
+			return nil, fmt.Errorf("provider %s: error discovering endpoints: %v", p.Name, err)
+		}
+		if discovered.Issuer != p.Config.Issuer {
+			return nil, fmt.Errorf("provider %s: discovered issuer %q does not match %q", p.Name, discovered.Issuer, p.Config.Issuer)
+		}
+		if d.AuthorizationEndpoint == "" {
+			d.AuthorizationEndpoint = discovered.AuthorizationEndpoint
+		}
+		if d.TokenEndpoint == "" {
+			d.TokenEndpoint = discovered.TokenEndpoint
+		}
+		if d.JWKSURI == "" {
+			d.JWKSURI = discovered.JWKSURI
+		}
+	}
+
+	p.discovery = d
+	return d, nil
+}
+
+// Authorize returns the url to send the user to, with a new state, PKCE code verifier and nonce.
+func (p *Provider) Authorize(ctx context.Context) (*Authorization, error) {
+	d, err := p.endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	auth := &Authorization{}
+	for _, s := range []*string{&auth.State, &auth.CodeVerifier, &auth.Nonce} {
+		if *s, err = randomString(32); err != nil {
+			return nil, err
+		}
+	}
+
+	authURL, err := url.Parse(d.AuthorizationEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("provider %s: invalid authorization endpoint: %v", p.Name, err)
+	}
+	scopes := p.Config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.Config.ClientID)
+	query.Set("redirect_uri", p.Config.RedirectURL)
+	query.Set("scope", strings.Join(scopes, " "))
+	query.Set("state", auth.State)
+	query.Set("nonce", auth.Nonce)
+	query.Set("code_challenge", CodeChallenge(auth.CodeVerifier))
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+
+	auth.URL = authURL.String()
+	return auth, nil
+}
+
+// Exchange exchanges the authorization code the provider redirected back with for the identity of the user,
+// verifying the ID token was issued to the client for the nonce. It returns ErrInvalidGrant when the provider
+// rejects the code or code verifier, and ErrInvalidIDToken when the ID token is not valid.
+func (p *Provider) Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (*Identity, error) {
+	d, err := p.endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"code_verifier": {codeVerifier},
+		"redirect_uri":  {p.Config.RedirectURL},
+		"client_id":     {p.Config.ClientID},
+	}
+	if p.Config.ClientSecret != "" {
+		form.Set("client_secret", p.Config.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("provider %s: error exchanging code: %v", p.Name, err)
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		IDToken string `json:"id_token"`
+		Error   string `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return nil, fmt.Errorf("provider %s: error exchanging code: %s", p.Name, resp.Status)
+	}
+	switch {
+	case token.Error == "invalid_grant":
+		return nil, ErrInvalidGrant
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("provider %s: error exchanging code: %s %s", p.Name, resp.Status, token.Error)
+	case token.IDToken == "":
+		return nil, fmt.Errorf("%w: no id token returned", ErrInvalidIDToken)
+	}
+
+	return p.verify(ctx, d, token.IDToken, nonce)
+}
+
+func (p *Provider) verify(ctx context.Context, d *discovery, idToken string, nonce string) (*Identity, error) {
+	var fetchErr error
+	claims := &idTokenClaims{}
+
+	_, err := jwt.ParseWithClaims(idToken, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := p.key(ctx, d, kid)
+		if err != nil {
+			fetchErr = err
+			return nil, err
+		}
+		if key == nil {
+			return nil, fmt.Errorf("unknown key id: %s", kid)
+		}
+		if t.Method.Alg() != key.alg {
+			return nil, fmt.Errorf("unexpected signing method: %s", t.Method.Alg())
+		}
+		return key.key, nil
+	},
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuer(p.Config.Issuer),
+		jwt.WithAudience(p.Config.ClientID),
+		jwt.WithLeeway(time.Minute),
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}),
+	)
+	if fetchErr != nil {
+		return nil, fmt.Errorf("provider %s: error fetching keys: %v", p.Name, fetchErr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	if claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce does not match", ErrInvalidIDToken)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	}
+
+	return &Identity{
+		Subject:       claims.Subject,
+		Email:         strings.ToLower(strings.TrimSpace(claims.Email)),
+		EmailVerified: bool(claims.EmailVerified),
+		GivenName:     strings.TrimSpace(claims.GivenName),
+		FamilyName:    strings.TrimSpace(claims.FamilyName),
+		Name:          strings.TrimSpace(claims.Name),
+	}, nil
+}
+
+// key returns the signing key with the given id, fetching the keys again if it is not known.
+// A nil key is returned when the key id is still not known after fetching.
+func (p *Provider) key(ctx context.Context, d *discovery, kid string) (*publicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return &key, nil
+	}
+	if !p.fetched.IsZero() && time.Since(p.fetched) < p.RefreshInterval {
+		return nil, nil
+	}
+
+	var jwks struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := p.getJSON(ctx, d.JWKSURI, &jwks); err != nil {
+		return nil, err
+	}
+
+	// keys that are not used for signatures, or of an unsupported type, are skipped
+	keys := make(map[string]publicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if key, err := parseJWK(k); err == nil {
+			keys[k.Kid] = *key
+		}
+	}
+	p.keys = keys
+	p.fetched = time.Now()
+
+	if key, ok := p.keys[kid]; ok {
+		return &key, nil
+	}
+	return nil, nil
+}
+
+func parseJWK(k jwk) (*publicKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &publicKey{
+			alg: jwt.SigningMethodRS256.Alg(),
+			key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())},
+		}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &publicKey{
+			alg: jwt.SigningMethodES256.Alg(),
+			key: &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
@@ -0,0 +1,107 @@
+package passwords_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/accentdesign/grpc/services/auth/internal/passwords"
+)
+
+type TestSuite struct {
+	suite.Suite
+}
+
+func TestTestSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+// argon2id uses small parameters so the tests run quickly.
+func argon2id() *passwords.Argon2id {
+	return &passwords.Argon2id{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+}
+
+func (suite *TestSuite) TestBcrypt() {
+	hasher := &passwords.Bcrypt{Cost: bcrypt.MinCost}
+
+	hash, err := hasher.Hash("password")
+	suite.NoError(err)
+	suite.True(strings.HasPrefix(hash, "$2a$04$"))
+	suite.False(hasher.NeedsRehash(hash))
+	suite.True((&passwords.Bcrypt{Cost: bcrypt.MinCost + 1}).NeedsRehash(hash))
+	suite.True(argon2id().NeedsRehash(hash))
+
+	ok, err := passwords.Verify(hash, "password")
+	suite.NoError(err)
+	suite.True(ok)
+
+	ok, err = passwords.Verify(hash, "invalid")
+	suite.NoError(err)
+	suite.False(ok)
+}
+
+func (suite *TestSuite) TestArgon2id() {
+	hasher := argon2id()
+
+	hash, err := hasher.Hash("password")
+	suite.NoError(err)
+	suite.True(strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+	suite.False(hasher.NeedsRehash(hash))
+	suite.True((&passwords.Bcrypt{Cost: bcrypt.MinCost}).NeedsRehash(hash))
+
+	stronger := argon2id()
+	stronger.Iterations = 2
+	suite.True(stronger.NeedsRehash(hash))
+
+	// salts are random
+	again, err := hasher.Hash("password")
+	suite.NoError(err)
+	suite.NotEqual(hash, again)
+
+	ok, err := passwords.Verify(hash, "password")
+	suite.NoError(err)
+	suite.True(ok)
+
+	ok, err = passwords.Verify(hash, "invalid")
+	suite.NoError(err)
+	suite.False(ok)
+
+	// passwords longer than bcrypt accepts
+	long := strings.Repeat("a", 100)
+	hash, err = hasher.Hash(long)
+	suite.NoError(err)
+
+	ok, err = passwords.Verify(hash, long)
+	suite.NoError(err)
+	suite.True(ok)
+
+	ok, err = passwords.Verify(hash, long[:72])
+	suite.NoError(err)
+	suite.False(ok)
+}
+
+func (suite *TestSuite) TestArgon2id_KnownHash() {
+	// generated with the reference implementation: echo -n password | argon2 somesalt -id -t 2 -m 16 -p 1 -l 32 -e
+	hash := "$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"
+
+	ok, err := passwords.Verify(hash, "password")
+	suite.NoError(err)
+	suite.True(ok)
+}
+
+func (suite *TestSuite) TestVerify_Invalid() {
+	for _, hash := range []string{
+		"",
+		"plain",
+		"$argon2i$v=19$m=1024,t=1,p=1$c29tZXNhbHQ$aGFzaA",
+		"$argon2id$v=16$m=1024,t=1,p=1$c29tZXNhbHQ$aGFzaA",
+		"$argon2id$v=19$m=x,t=1,p=1$c29tZXNhbHQ$aGFzaA",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!!$aGFzaA",
+	} {
+		ok, err := passwords.Verify(hash, "password")
+		suite.ErrorIs(err, passwords.ErrUnknownHash, hash)
+		suite.False(ok)
+	}
+}
@@ -0,0 +1,133 @@
+package passwords
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+const (
+	ClassLower  = "lower"
+	ClassUpper  = "upper"
+	ClassDigit  = "digit"
+	ClassSymbol = "symbol"
+)
+
+// Policy decides whether a password is strong enough to be set.
+type Policy struct {
+	// MinLength is the minimum number of characters
+	MinLength int
+	// Classes are the character classes a password must contain, any of ClassLower, ClassUpper, ClassDigit and ClassSymbol
+	Classes []string
+	// DisallowPersonal rejects passwords containing the email or name of the user
+	DisallowPersonal bool
+	// Common is a set of lower case passwords that are rejected, such as known breached passwords
+	Common map[string]struct{}
+	// History is the number of previous passwords of a user that cannot be reused
+	History int
+}
+
+// LoadCommonPasswords reads a list of passwords, one per line, for Policy.Common.
+func LoadCommonPasswords(path string) (map[string]struct{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	common := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			common[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return common, nil
+}
+
+// Check returns a description of each way the password violates the policy, personal is the email and names
+// of the user. A password that satisfies the policy returns no violations.
+func (p *Policy) Check(password string, personal ...string) []string {
+	var violations []string
+
+	if len([]rune(password)) < p.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters in length", p.MinLength))
+	}
+
+	for _, class := range p.Classes {
+		if contains, ok := classes[class]; ok && !strings.ContainsFunc(password, contains) {
+			violations = append(violations, "must contain "+classNames[class])
+		}
+	}
+
+	lower := strings.ToLower(password)
+
+	if p.DisallowPersonal {
+		for _, value := range personalValues(personal) {
+			if strings.Contains(lower, value) {
+				violations = append(violations, "must not contain your email or name")
+				break
+			}
+		}
+	}
+
+	if _, ok := p.Common[lower]; ok {
+		violations = append(violations, "is too common")
+	}
+
+	return violations
+}
+
+// Reused reports whether the password matches any of the hashes of previous passwords.
+func (p *Policy) Reused(password string, hashes []string) bool {
+	for _, hash := range hashes {
+		if ok, err := Verify(hash, password); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidClass reports whether class is a known character class.
+func ValidClass(class string) bool {
+	_, ok := classes[class]
+	return ok
+}
+
+var classes = map[string]func(rune) bool{
+	ClassLower: unicode.IsLower,
+	ClassUpper: unicode.IsUpper,
+	ClassDigit: unicode.IsDigit,
+	ClassSymbol: func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r)
+	},
+}
+
+var classNames = map[string]string{
+	ClassLower:  "a lower case letter",
+	ClassUpper:  "an upper case letter",
+	ClassDigit:  "a digit",
+	ClassSymbol: "a symbol",
+}
+
+// personalValues returns the lower cased values worth checking, the email is also checked without its domain.
+// Values shorter than three characters are ignored, as they are too likely to appear by chance.
+func personalValues(personal []string) []string {
+	var values []string
+	for _, value := range personal {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if local, _, ok := strings.Cut(value, "@"); ok && len(local) >= 3 {
+			values = append(values, local)
+		}
+		if len(value) >= 3 {
+			values = append(values, value)
+		}
+	}
+	return values
+}
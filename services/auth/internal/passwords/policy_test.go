@@ -0,0 +1,70 @@
+package passwords_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/accentdesign/grpc/services/auth/internal/passwords"
+)
+
+func (suite *TestSuite) TestPolicy_Check() {
+	policy := &passwords.Policy{
+		MinLength:        8,
+		Classes:          []string{passwords.ClassLower, passwords.ClassUpper, passwords.ClassDigit, passwords.ClassSymbol},
+		DisallowPersonal: true,
+		Common:           map[string]struct{}{"correct-horse": {}},
+	}
+
+	testCases := []struct {
+		desc       string
+		password   string
+		violations []string
+	}{
+		{"valid", "Sunny-Day-42", nil},
+		{"too short", "Ab1!", []string{"must be at least 8 characters in length"}},
+		{"missing classes", "sunnydays", []string{
+			"must contain an upper case letter",
+			"must contain a digit",
+			"must contain a symbol",
+		}},
+		{"email", "John.Smith-99", []string{"must not contain your email or name"}},
+		{"name", "Xx-Smith-1", []string{"must not contain your email or name"}},
+		{"common", "Correct-Horse", []string{"must contain a digit", "is too common"}},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.desc, func() {
+			suite.Equal(tc.violations, policy.Check(tc.password, "john.smith@example.com", "John", "Smith"))
+		})
+	}
+
+	// short personal values are ignored
+	suite.Empty(policy.Check("Al-Sunny-42", "al@example.com", "Al", "Ng"))
+}
+
+func (suite *TestSuite) TestPolicy_Reused() {
+	policy := &passwords.Policy{History: 2}
+	hasher := argon2id()
+
+	first, err := hasher.Hash("first-password")
+	suite.NoError(err)
+	second, err := hasher.Hash("second-password")
+	suite.NoError(err)
+
+	suite.True(policy.Reused("first-password", []string{second, first}))
+	suite.False(policy.Reused("third-password", []string{second, first}))
+	suite.False(policy.Reused("first-password", nil))
+}
+
+func (suite *TestSuite) TestLoadCommonPasswords() {
+	path := filepath.Join(suite.T().TempDir(), "common.txt")
+	err := os.WriteFile(path, []byte("123456\n\n  Password \nqwerty\n"), 0600)
+	suite.NoError(err)
+
+	common, err := passwords.LoadCommonPasswords(path)
+	suite.NoError(err)
+	suite.Equal(map[string]struct{}{"123456": {}, "password": {}, "qwerty": {}}, common)
+
+	_, err = passwords.LoadCommonPasswords(filepath.Join(suite.T().TempDir(), "missing.txt"))
+	suite.Error(err)
+}
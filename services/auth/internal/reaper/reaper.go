@@ -0,0 +1,47 @@
+package reaper
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/accentdesign/grpc/services/auth/internal/repos"
+)
+
+// Reaper deletes expired tokens, which are otherwise only filtered out when looked up.
+type Reaper struct {
+	TokenRepo *repos.TokenRepository
+	// Interval is the time between runs
+	Interval time.Duration
+	// GracePeriod is how long tokens are kept after they expire
+	GracePeriod time.Duration
+	// BatchSize is the maximum number of rows deleted per statement
+	BatchSize int
+}
+
+// Reap deletes the tokens that expired more than the grace period ago, returning the number deleted.
+func (r *Reaper) Reap(ctx context.Context) (int64, error) {
+	return r.TokenRepo.DeleteExpiredTokens(ctx, time.Now().Add(-r.GracePeriod), r.BatchSize)
+}
+
+// Run reaps every interval until ctx is done.
+func (r *Reaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := r.Reap(ctx)
+			if err != nil {
+				log.Printf("error reaping expired tokens: %v", err)
+				continue
+			}
+			if deleted > 0 {
+				log.Printf("reaped %d expired tokens", deleted)
+			}
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package reaper_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+
+	"github.com/accentdesign/grpc/services/auth/helpers"
+	"github.com/accentdesign/grpc/services/auth/internal/models"
+	"github.com/accentdesign/grpc/services/auth/internal/reaper"
+	"github.com/accentdesign/grpc/services/auth/internal/repos"
+	"github.com/accentdesign/grpc/testutils"
+)
+
+type TestSuite struct {
+	suite.Suite
+	helpers *helpers.TestHelpers
+	db      *gorm.DB
+	cleanup func()
+}
+
+func (suite *TestSuite) SetupSuite() {
+	_, suite.db, suite.cleanup = testutils.SetupDockerDB()
+	suite.helpers = &helpers.TestHelpers{DB: suite.db}
+	err := suite.helpers.MigrateDatabase()
+	suite.NoError(err)
+}
+
+func (suite *TestSuite) TearDownSuite() {
+	suite.cleanup()
+}
+
+func (suite *TestSuite) Setup() func() {
+	return func() {
+		err := suite.helpers.CleanDatabase()
+		suite.NoError(err)
+	}
+}
+
+func TestTestSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+// createTokens creates an access token and a reset token for the user expiring at each time.
+func (suite *TestSuite) createTokens(user *models.User, expiresAt ...time.Time) {
+	for _, expires := range expiresAt {
+		token := uuid.NewString()
+		suite.NoError(suite.db.Create(&models.AccessToken{Token: token, ID: uuid.New(), UserId: user.ID, ExpiresAt: expires}).Error)
+		suite.NoError(suite.db.Create(&models.ResetToken{Token: token, UserId: user.ID, ExpiresAt: expires}).Error)
+	}
+}
+
+func (suite *TestSuite) count(model interface{}) int64 {
+	var count int64
+	suite.NoError(suite.db.Model(model).Count(&count).Error)
+	return count
+}
+
+func (suite *TestSuite) TestReaper_Reap() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	now := time.Now()
+	expired := now.Add(-2 * time.Hour)
+	recent := now.Add(-time.Minute)
+	valid := now.Add(time.Hour)
+	suite.createTokens(user, expired, expired, expired, expired, expired, recent, valid)
+
+	testCases := []struct {
+		name        string
+		gracePeriod time.Duration
+		batchSize   int
+		deleted     int64
+		remaining   int64
+	}{
+		// tokens that expired within the grace period are kept, a backlog larger than a batch is deleted in full
+		{name: "grace period", gracePeriod: time.Hour, batchSize: 2, deleted: 10, remaining: 2},
+		{name: "no grace period", gracePeriod: 0, batchSize: 2, deleted: 2, remaining: 1},
+		{name: "nothing expired", gracePeriod: 0, batchSize: 2, deleted: 0, remaining: 1},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			r := &reaper.Reaper{
+				TokenRepo:   &repos.TokenRepository{DB: suite.db},
+				GracePeriod: tc.gracePeriod,
+				BatchSize:   tc.batchSize,
+			}
+			deleted, err := r.Reap(context.Background())
+			suite.NoError(err)
+			suite.Equal(tc.deleted, deleted)
+			suite.Equal(tc.remaining, suite.count(&models.AccessToken{}))
+			suite.Equal(tc.remaining, suite.count(&models.ResetToken{}))
+		})
+	}
+
+	_, err = (&reaper.Reaper{TokenRepo: &repos.TokenRepository{DB: suite.db}, BatchSize: 0}).Reap(context.Background())
+	suite.EqualError(err, "invalid batch size: 0")
+}
+
+func (suite *TestSuite) TestReaper_Run() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	expired := time.Now().Add(-2 * time.Hour)
+	suite.createTokens(user, expired, expired, expired)
+
+	r := &reaper.Reaper{
+		TokenRepo:   &repos.TokenRepository{DB: suite.db},
+		Interval:    10 * time.Millisecond,
+		GracePeriod: time.Hour,
+		BatchSize:   2,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+
+	suite.Eventually(func() bool {
+		return suite.count(&models.AccessToken{}) == 0 && suite.count(&models.ResetToken{}) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// run returns once the context is done
+	cancel()
+	suite.Eventually(func() bool {
+		select {
+		case <-done:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,104 @@
+package repos
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/accentdesign/grpc/services/auth/internal/models"
+)
+
+var (
+	ErrIdentityLinked   = errors.New("identity is already linked")
+	ErrIdentityNotFound = errors.New("identity not found")
+)
+
+type IdentityRepository struct {
+	DB *gorm.DB
+}
+
+// GetUserByIdentity returns the user the identity of the provider is linked to, or ErrIdentityNotFound.
+func (r *IdentityRepository) GetUserByIdentity(provider string, subject string) (*models.User, error) {
+	var identity models.Identity
+	if err := r.DB.Preload("User").Preload("User.UserType").Preload("User.UserType.Scopes").
+		Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrIdentityNotFound, provider)
+		}
+		return nil, fmt.Errorf("error fetching identity: %v", err)
+	}
+	return &identity.User, nil
+}
+
+// ListIdentities returns the identities linked to the user, oldest first.
+func (r *IdentityRepository) ListIdentities(userId uuid.UUID) ([]models.Identity, error) {
+	var identities []models.Identity
+	if err := r.DB.Where("user_id = ?", userId).Order("created_at").Find(&identities).Error; err != nil {
+		return nil, fmt.Errorf("error fetching identities: %v", err)
+	}
+	return identities, nil
+}
+
+// LinkIdentity links the identity to its user, returning ErrIdentityLinked when it is linked to a user already,
+// or the user has linked an identity of the provider already.
+func (r *IdentityRepository) LinkIdentity(identity *models.Identity) error {
+	if err := r.DB.Create(identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrIdentityLinked
+		}
+		return err
+	}
+	return nil
+}
+
+// CreateUserWithIdentity creates a user without a password along with the identity they log in with,
+// the user gets the default user type.
+func (r *IdentityRepository) CreateUserWithIdentity(user *models.User, identity *models.Identity) (*models.User, error) {
+	if err := user.Validate(); err != nil {
+		return nil, err
+	}
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if user.UserTypeId == uuid.Nil {
+			var userType models.UserType
+			if err := tx.Where("is_default is true").First(&userType).Error; err != nil {
+				return errors.New("no default user type exists")
+			}
+			user.UserTypeId = userType.ID
+		}
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		identity.UserId = user.ID
+		if err := tx.Create(identity).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return ErrIdentityLinked
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var created models.User
+	if err := r.DB.Preload("UserType").Preload("UserType.Scopes").First(&created, "id = ?", user.ID).Error; err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// UnlinkIdentity removes the identity of the provider from the user, or returns ErrIdentityNotFound.
+func (r *IdentityRepository) UnlinkIdentity(userId uuid.UUID, provider string) error {
+	result := r.DB.Where("user_id = ? AND provider = ?", userId, provider).Delete(&models.Identity{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrIdentityNotFound, provider)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package repos_test
+
+import (
+	"github.com/accentdesign/grpc/services/auth/internal/models"
+	"github.com/accentdesign/grpc/services/auth/internal/repos"
+)
+
+func (suite *TestSuite) TestIdentityRepository_LinkIdentity() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	repo := repos.IdentityRepository{DB: suite.db}
+
+	_, err = repo.GetUserByIdentity("google", "subject")
+	suite.ErrorIs(err, repos.ErrIdentityNotFound)
+
+	identity := &models.Identity{UserId: user.ID, Provider: "google", Subject: "subject", Email: "test@gmail.com"}
+	err = repo.LinkIdentity(identity)
+	suite.NoError(err)
+	suite.NotEmpty(identity.ID)
+
+	fetched, err := repo.GetUserByIdentity("google", "subject")
+	suite.NoError(err)
+	suite.Equal(user.ID, fetched.ID)
+	suite.NotEmpty(fetched.UserType.ID)
+
+	// the same subject of another provider is another identity
+	_, err = repo.GetUserByIdentity("github", "subject")
+	suite.ErrorIs(err, repos.ErrIdentityNotFound)
+
+	// a user links one identity per provider
+	err = repo.LinkIdentity(&models.Identity{UserId: user.ID, Provider: "google", Subject: "other"})
+	suite.ErrorIs(err, repos.ErrIdentityLinked)
+
+	err = repo.LinkIdentity(&models.Identity{UserId: user.ID, Provider: "github", Subject: "subject"})
+	suite.NoError(err)
+
+	identities, err := repo.ListIdentities(user.ID)
+	suite.NoError(err)
+	suite.Len(identities, 2)
+	suite.Equal("google", identities[0].Provider)
+	suite.Equal("test@gmail.com", identities[0].Email)
+	suite.Equal("github", identities[1].Provider)
+
+	err = repo.UnlinkIdentity(user.ID, "google")
+	suite.NoError(err)
+
+	err = repo.UnlinkIdentity(user.ID, "google")
+	suite.ErrorIs(err, repos.ErrIdentityNotFound)
+
+	identities, err = repo.ListIdentities(user.ID)
+	suite.NoError(err)
+	suite.Len(identities, 1)
+}
+
+func (suite *TestSuite) TestIdentityRepository_LinkIdentity_OtherUser() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	repo := repos.IdentityRepository{DB: suite.db}
+
+	other, err := repo.CreateUserWithIdentity(
+		&models.User{Email: "other@example.com", FirstName: "Other", LastName: "User", IsActive: true},
+		&models.Identity{Provider: "google", Subject: "subject"},
+	)
+	suite.NoError(err)
+
+	// an identity is linked to one user
+	err = repo.LinkIdentity(&models.Identity{UserId: user.ID, Provider: "google", Subject: "subject"})
+	suite.ErrorIs(err, repos.ErrIdentityLinked)
+
+	fetched, err := repo.GetUserByIdentity("google", "subject")
+	suite.NoError(err)
+	suite.Equal(other.ID, fetched.ID)
+}
+
+func (suite *TestSuite) TestIdentityRepository_CreateUserWithIdentity() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	userType, err := suite.helpers.CreateTestUserType()
+	suite.NoError(err)
+
+	repo := repos.IdentityRepository{DB: suite.db}
+
+	user, err := repo.CreateUserWithIdentity(
+		&models.User{Email: "a@b.com", FirstName: "Some", LastName: "One", IsActive: true, IsVerified: true},
+		&models.Identity{Provider: "google", Subject: "subject", Email: "a@b.com"},
+	)
+	suite.NoError(err)
+	suite.NotEmpty(user.ID)
+	suite.Equal(userType.ID, user.UserType.ID)
+	suite.False(user.HasPassword())
+	suite.False(user.VerifyPassword(""))
+	suite.True(user.IsVerified)
+
+	fetched, err := repo.GetUserByIdentity("google", "subject")
+	suite.NoError(err)
+	suite.Equal(user.ID, fetched.ID)
+
+	// the user is not created when the identity is linked already
+	_, err = repo.CreateUserWithIdentity(
+		&models.User{Email: "c@d.com", FirstName: "Some", LastName: "One", IsActive: true},
+		&models.Identity{Provider: "google", Subject: "subject"},
+	)
+	suite.ErrorIs(err, repos.ErrIdentityLinked)
+
+	var count int64
+	err = suite.db.Model(&models.User{}).Where("email = ?", "c@d.com").Count(&count).Error
+	suite.NoError(err)
+	suite.Zero(count)
+
+	// deleting the user deletes its identities
+	err = suite.db.Delete(user).Error
+	suite.NoError(err)
+
+	err = suite.db.Model(&models.Identity{}).Count(&count).Error
+	suite.NoError(err)
+	suite.Zero(count)
+}
+
+func (suite *TestSuite) TestIdentityRepository_CreateUserWithIdentity_Errors() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	repo := repos.IdentityRepository{DB: suite.db}
+
+	_, err := repo.CreateUserWithIdentity(
+		&models.User{Email: "a@b.com", FirstName: "Some", LastName: "One"},
+		&models.Identity{Provider: "google", Subject: "subject"},
+	)
+	suite.EqualError(err, "no default user type exists")
+
+	_, err = suite.helpers.CreateTestUserType()
+	suite.NoError(err)
+
+	_, err = repo.CreateUserWithIdentity(
+		&models.User{Email: "", FirstName: "Some", LastName: "One"},
+		&models.Identity{Provider: "google", Subject: "subject"},
+	)
+	suite.EqualError(err, "invalid email format")
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	_, err = repo.CreateUserWithIdentity(
+		&models.User{Email: user.Email, FirstName: "Some", LastName: "One"},
+		&models.Identity{Provider: "google", Subject: "subject"},
+	)
+	suite.EqualError(err, "duplicated key not allowed")
+}
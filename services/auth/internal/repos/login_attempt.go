@@ -0,0 +1,120 @@
+package repos
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/accentdesign/grpc/services/auth/internal/models"
+)
+
+type LoginAttemptConfig struct {
+	// MaxAccountFailures is the number of failed logins for an email before it is locked, 0 disables the lockout
+	MaxAccountFailures int
+	// MaxIPFailures is the number of failed logins from an ip address before it is locked, 0 disables the lockout
+	MaxIPFailures int
+	// LockoutDuration is how long a locked email or ip address stays locked
+	LockoutDuration time.Duration
+	// FailureWindow is how long failures are remembered after the last one
+	FailureWindow time.Duration
+	// BaseDelay is the delay enforced after the first failure, doubling with every further failure
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts
+	MaxDelay time.Duration
+}
+
+type LoginAttemptRepository struct {
+	DB     *gorm.DB
+	Config *LoginAttemptConfig
+}
+
+// LoginCheck is the result of checking the failed attempts of a login.
+type LoginCheck struct {
+	// Locked is true when the email or ip address is locked out
+	Locked bool
+	// RetryAfter is how long until the next attempt is allowed, zero when an attempt is allowed now
+	RetryAfter time.Duration
+}
+
+func AccountKey(email string) string {
+	return "account:" + email
+}
+
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
+
+func (r *LoginAttemptRepository) delay(failures int) time.Duration {
+	if failures <= 0 || r.Config.BaseDelay <= 0 {
+		return 0
+	}
+	delay := r.Config.BaseDelay
+	for i := 1; i < failures && delay < r.Config.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, r.Config.MaxDelay)
+}
+
+// Check returns whether any of the keys is locked or still within its progressive delay.
+func (r *LoginAttemptRepository) Check(keys ...string) (*LoginCheck, error) {
+	var attempts []models.LoginAttempt
+	if err := r.DB.Where("key IN ?", keys).Find(&attempts).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	check := &LoginCheck{}
+	for _, attempt := range attempts {
+		if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+			check.Locked = true
+			check.RetryAfter = max(check.RetryAfter, attempt.LockedUntil.Sub(now))
+			continue
+		}
+		if now.Sub(attempt.LastFailureAt) > r.Config.FailureWindow {
+			continue
+		}
+		if retry := attempt.LastFailureAt.Add(r.delay(attempt.Failures)).Sub(now); retry > 0 {
+			check.RetryAfter = max(check.RetryAfter, retry)
+		}
+	}
+
+	return check, nil
+}
+
+// RecordFailure counts a failed login for the key, locking it once maxFailures is reached.
+// Failures older than the failure window are forgotten.
+func (r *LoginAttemptRepository) RecordFailure(key string, maxFailures int) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		var attempt models.LoginAttempt
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).First(&attempt)
+		if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return result.Error
+		}
+
+		expired := now.Sub(attempt.LastFailureAt) > r.Config.FailureWindow
+		if attempt.LockedUntil != nil && !attempt.LockedUntil.After(now) {
+			expired = true
+		}
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) || expired {
+			attempt = models.LoginAttempt{Key: key}
+		}
+
+		attempt.Failures++
+		attempt.LastFailureAt = now
+		if maxFailures > 0 && attempt.Failures >= maxFailures {
+			lockedUntil := now.Add(r.Config.LockoutDuration)
+			attempt.LockedUntil = &lockedUntil
+		}
+
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&attempt).Error
+	})
+}
+
+// Reset forgets the failed logins of the key, unlocking it.
+func (r *LoginAttemptRepository) Reset(key string) error {
+	return r.DB.Where("key = ?", key).Delete(&models.LoginAttempt{}).Error
+}
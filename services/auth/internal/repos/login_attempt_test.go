@@ -0,0 +1,93 @@
+package repos_test
+
+import (
+	"time"
+
+	"github.com/accentdesign/grpc/services/auth/internal/models"
+	"github.com/accentdesign/grpc/services/auth/internal/repos"
+)
+
+func (suite *TestSuite) TestLoginAttemptRepository_RecordFailure() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	repo := repos.LoginAttemptRepository{
+		DB: suite.db,
+		Config: &repos.LoginAttemptConfig{
+			LockoutDuration: time.Minute,
+			FailureWindow:   time.Minute,
+			BaseDelay:       time.Second,
+			MaxDelay:        3 * time.Second,
+		},
+	}
+	key := repos.AccountKey("test@example.com")
+
+	check, err := repo.Check(key)
+	suite.NoError(err)
+	suite.False(check.Locked)
+	suite.Zero(check.RetryAfter)
+
+	// failures are delayed progressively, up to the max delay
+	suite.NoError(repo.RecordFailure(key, 3))
+	check, err = repo.Check(key)
+	suite.NoError(err)
+	suite.False(check.Locked)
+	suite.InDelta(time.Second, check.RetryAfter, float64(100*time.Millisecond))
+
+	suite.NoError(repo.RecordFailure(key, 3))
+	check, err = repo.Check(key)
+	suite.NoError(err)
+	suite.False(check.Locked)
+	suite.InDelta(2*time.Second, check.RetryAfter, float64(100*time.Millisecond))
+
+	// the key is locked once the max failures is reached
+	suite.NoError(repo.RecordFailure(key, 3))
+	check, err = repo.Check(key, repos.IPKey("192.0.2.1"))
+	suite.NoError(err)
+	suite.True(check.Locked)
+	suite.InDelta(time.Minute, check.RetryAfter, float64(100*time.Millisecond))
+
+	var attempt models.LoginAttempt
+	suite.NoError(suite.db.First(&attempt, "key = ?", key).Error)
+	suite.Equal(3, attempt.Failures)
+
+	// failures outside the window are forgotten
+	suite.NoError(suite.db.Model(&attempt).Updates(map[string]interface{}{
+		"last_failure_at": time.Now().Add(-2 * time.Minute),
+		"locked_until":    time.Now().Add(-time.Minute),
+	}).Error)
+
+	check, err = repo.Check(key)
+	suite.NoError(err)
+	suite.False(check.Locked)
+	suite.Zero(check.RetryAfter)
+
+	suite.NoError(repo.RecordFailure(key, 3))
+	suite.NoError(suite.db.First(&attempt, "key = ?", key).Error)
+	suite.Equal(1, attempt.Failures)
+	suite.Nil(attempt.LockedUntil)
+}
+
+func (suite *TestSuite) TestLoginAttemptRepository_Reset() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	repo := repos.LoginAttemptRepository{
+		DB:     suite.db,
+		Config: &repos.LoginAttemptConfig{LockoutDuration: time.Minute, FailureWindow: time.Minute},
+	}
+	key := repos.IPKey("192.0.2.1")
+
+	suite.NoError(repo.RecordFailure(key, 1))
+	check, err := repo.Check(key)
+	suite.NoError(err)
+	suite.True(check.Locked)
+
+	suite.NoError(repo.Reset(key))
+	check, err = repo.Check(key)
+	suite.NoError(err)
+	suite.False(check.Locked)
+
+	// resetting an unknown key is not an error
+	suite.NoError(repo.Reset(repos.IPKey("192.0.2.2")))
+}
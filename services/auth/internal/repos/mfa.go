@@ -0,0 +1,209 @@
+package repos
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/accentdesign/grpc/services/auth/internal/models"
+	"github.com/accentdesign/grpc/services/auth/internal/secrets"
+	"github.com/accentdesign/grpc/services/auth/internal/totp"
+)
+
+var (
+	ErrMFACodeInvalid     = errors.New("invalid mfa code")
+	ErrTOTPAlreadyEnabled = errors.New("totp is already enabled")
+	ErrTOTPNotEnabled     = errors.New("totp is not enabled")
+	ErrTOTPNotEnrolled    = errors.New("totp enrollment has not been started")
+)
+
+const recoveryCodeCount = 10
+
+var totpCodePattern = regexp.MustCompile(`^[0-9]{6}$`)
+
+type MFARepository struct {
+	DB     *gorm.DB
+	Cipher *secrets.Cipher
+	Issuer string
+}
+
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateRecoveryCode() (string, error) {
+	randomBytes := make([]byte, 10)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+	code := base32.StdEncoding.EncodeToString(randomBytes)[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// replaceRecoveryCodes deletes the existing recovery codes of the user and creates new ones,
+// only the hashes are stored so the codes are returned to be shown to the user once.
+func replaceRecoveryCodes(tx *gorm.DB, user *models.User) ([]string, error) {
+	if err := tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	rows := make([]models.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		rows[i] = models.RecoveryCode{UserId: user.ID, CodeHash: hashRecoveryCode(code)}
+	}
+
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// BeginTOTPEnrollment generates and stores a new encrypted secret for the user, returning the secret
+// and its otpauth:// URI. TOTP is not enabled until the enrollment is confirmed with a valid code.
+func (r *MFARepository) BeginTOTPEnrollment(user *models.User) (string, string, error) {
+	if user.TOTPEnabled {
+		return "", "", ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	encrypted, err := r.Cipher.Encrypt(secret)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := r.DB.Model(user).UpdateColumns(map[string]interface{}{
+		"totp_secret":    encrypted,
+		"totp_last_step": 0,
+	}).Error; err != nil {
+		return "", "", err
+	}
+	user.TOTPSecret = encrypted
+	user.TOTPLastStep = 0
+
+	return secret, totp.URI(r.Issuer, user.Email, secret), nil
+}
+
+// ConfirmTOTPEnrollment enables TOTP for the user when the code is valid for the pending secret,
+// returning a new set of one time recovery codes.
+func (r *MFARepository) ConfirmTOTPEnrollment(user *models.User, code string) ([]string, error) {
+	if user.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTOTPNotEnrolled
+	}
+
+	secret, err := r.Cipher.Decrypt(user.TOTPSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok := totp.Validate(secret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return nil, ErrMFACodeInvalid
+	}
+
+	var codes []string
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).UpdateColumns(map[string]interface{}{
+			"totp_enabled":   true,
+			"totp_last_step": step,
+		}).Error; err != nil {
+			return err
+		}
+		user.TOTPEnabled = true
+		user.TOTPLastStep = step
+
+		codes, err = replaceRecoveryCodes(tx, user)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// VerifyMFA checks a TOTP code or an unused recovery code for the user. TOTP codes can only be used
+// once and recovery codes are marked as used.
+func (r *MFARepository) VerifyMFA(user *models.User, code string) error {
+	if !user.TOTPEnabled {
+		return ErrTOTPNotEnabled
+	}
+
+	code = strings.TrimSpace(code)
+	if totpCodePattern.MatchString(code) {
+		secret, err := r.Cipher.Decrypt(user.TOTPSecret)
+		if err != nil {
+			return err
+		}
+
+		step, ok := totp.Validate(secret, code, time.Now())
+		if !ok {
+			return ErrMFACodeInvalid
+		}
+
+		// only accept codes newer than the last one used, the condition guards against concurrent use
+		result := r.DB.Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			UpdateColumn("totp_last_step", step)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrMFACodeInvalid
+		}
+		user.TOTPLastStep = step
+		return nil
+	}
+
+	result := r.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashRecoveryCode(code)).
+		UpdateColumn("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMFACodeInvalid
+	}
+
+	return nil
+}
+
+// DisableTOTP removes the TOTP secret and recovery codes of the user.
+func (r *MFARepository) DisableTOTP(user *models.User) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).UpdateColumns(map[string]interface{}{
+			"totp_secret":    "",
+			"totp_enabled":   false,
+			"totp_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		user.TOTPSecret = ""
+		user.TOTPEnabled = false
+		user.TOTPLastStep = 0
+
+		return tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+}
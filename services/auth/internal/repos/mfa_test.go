@@ -0,0 +1,152 @@
+package repos_test
+
+import (
+	"strings"
+	"time"
+
+	"github.com/accentdesign/grpc/services/auth/internal/models"
+	"github.com/accentdesign/grpc/services/auth/internal/repos"
+	"github.com/accentdesign/grpc/services/auth/internal/secrets"
+	"github.com/accentdesign/grpc/services/auth/internal/totp"
+)
+
+func (suite *TestSuite) newMFARepository() *repos.MFARepository {
+	cipher, err := secrets.NewCipher([]byte(strings.Repeat("k", 32)))
+	suite.NoError(err)
+	return &repos.MFARepository{DB: suite.db, Cipher: cipher, Issuer: "auth"}
+}
+
+func (suite *TestSuite) TestMFARepository_TOTPEnrollment() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	repo := suite.newMFARepository()
+
+	_, err = repo.ConfirmTOTPEnrollment(user, "123456")
+	suite.ErrorIs(err, repos.ErrTOTPNotEnrolled)
+
+	secret, uri, err := repo.BeginTOTPEnrollment(user)
+	suite.NoError(err)
+	suite.Contains(uri, "secret="+secret)
+	suite.Contains(uri, "otpauth://totp/auth:test@example.com")
+
+	// the secret is stored encrypted and not enabled yet
+	var fetched models.User
+	err = suite.db.First(&fetched, "id = ?", user.ID).Error
+	suite.NoError(err)
+	suite.NotEmpty(fetched.TOTPSecret)
+	suite.NotContains(fetched.TOTPSecret, secret)
+	suite.False(fetched.TOTPEnabled)
+
+	_, err = repo.ConfirmTOTPEnrollment(&fetched, "000000")
+	suite.ErrorIs(err, repos.ErrMFACodeInvalid)
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	suite.NoError(err)
+
+	codes, err := repo.ConfirmTOTPEnrollment(&fetched, code)
+	suite.NoError(err)
+	suite.Len(codes, 10)
+	suite.Regexp(`^[A-Z2-7]{5}-[A-Z2-7]{5}$`, codes[0])
+
+	err = suite.db.First(&fetched, "id = ?", user.ID).Error
+	suite.NoError(err)
+	suite.True(fetched.TOTPEnabled)
+
+	var count int64
+	err = suite.db.Model(&models.RecoveryCode{}).Where("user_id = ?", user.ID).Count(&count).Error
+	suite.NoError(err)
+	suite.Equal(int64(10), count)
+
+	_, _, err = repo.BeginTOTPEnrollment(&fetched)
+	suite.ErrorIs(err, repos.ErrTOTPAlreadyEnabled)
+
+	_, err = repo.ConfirmTOTPEnrollment(&fetched, code)
+	suite.ErrorIs(err, repos.ErrTOTPAlreadyEnabled)
+}
+
+func (suite *TestSuite) TestMFARepository_VerifyMFA() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	repo := suite.newMFARepository()
+
+	err = repo.VerifyMFA(user, "123456")
+	suite.ErrorIs(err, repos.ErrTOTPNotEnabled)
+
+	secret, _, err := repo.BeginTOTPEnrollment(user)
+	suite.NoError(err)
+
+	// enroll with the code of the previous period so the current one is still unused
+	step := totp.Step(time.Now())
+	previous, err := totp.Code(secret, step-1)
+	suite.NoError(err)
+	recoveryCodes, err := repo.ConfirmTOTPEnrollment(user, previous)
+	suite.NoError(err)
+
+	current, err := totp.Code(secret, step)
+	suite.NoError(err)
+
+	err = repo.VerifyMFA(user, current)
+	suite.NoError(err)
+
+	// codes can not be reused
+	err = repo.VerifyMFA(user, current)
+	suite.ErrorIs(err, repos.ErrMFACodeInvalid)
+
+	err = repo.VerifyMFA(user, previous)
+	suite.ErrorIs(err, repos.ErrMFACodeInvalid)
+
+	err = repo.VerifyMFA(user, "000000")
+	suite.ErrorIs(err, repos.ErrMFACodeInvalid)
+
+	// recovery codes can be used once, in any case and without the dash
+	err = repo.VerifyMFA(user, strings.ToLower(strings.ReplaceAll(recoveryCodes[0], "-", "")))
+	suite.NoError(err)
+
+	err = repo.VerifyMFA(user, recoveryCodes[0])
+	suite.ErrorIs(err, repos.ErrMFACodeInvalid)
+
+	err = repo.VerifyMFA(user, recoveryCodes[1])
+	suite.NoError(err)
+
+	err = repo.VerifyMFA(user, "AAAAA-AAAAA")
+	suite.ErrorIs(err, repos.ErrMFACodeInvalid)
+}
+
+func (suite *TestSuite) TestMFARepository_DisableTOTP() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	repo := suite.newMFARepository()
+
+	secret, _, err := repo.BeginTOTPEnrollment(user)
+	suite.NoError(err)
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	suite.NoError(err)
+	_, err = repo.ConfirmTOTPEnrollment(user, code)
+	suite.NoError(err)
+
+	err = repo.DisableTOTP(user)
+	suite.NoError(err)
+
+	var fetched models.User
+	err = suite.db.First(&fetched, "id = ?", user.ID).Error
+	suite.NoError(err)
+	suite.False(fetched.TOTPEnabled)
+	suite.Empty(fetched.TOTPSecret)
+
+	var count int64
+	err = suite.db.Model(&models.RecoveryCode{}).Where("user_id = ?", user.ID).Count(&count).Error
+	suite.NoError(err)
+	suite.Equal(int64(0), count)
+}
@@ -0,0 +1,90 @@
+package repos
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/accentdesign/grpc/services/auth/internal/models"
+)
+
+var ErrScopeNotFound = errors.New("scope not found")
+
+type ScopeRepository struct {
+	DB *gorm.DB
+}
+
+func (r *ScopeRepository) GetScopeByID(id uuid.UUID) (*models.Scope, error) {
+	var scope models.Scope
+	result := r.DB.Where("id = ?", id).First(&scope)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrScopeNotFound, id)
+		}
+		return nil, fmt.Errorf("error fetching scope: %v", result.Error)
+	}
+
+	return &scope, nil
+}
+
+// GetScopesByName returns the scopes with the given names ordered by name, every name must exist.
+func (r *ScopeRepository) GetScopesByName(names []string) ([]models.Scope, error) {
+	scopes := []models.Scope{}
+	if len(names) == 0 {
+		return scopes, nil
+	}
+
+	if err := r.DB.Where("name IN ?", names).Order("name").Find(&scopes).Error; err != nil {
+		return nil, fmt.Errorf("error fetching scopes: %v", err)
+	}
+
+	found := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		found[scope.Name] = true
+	}
+	for _, name := range names {
+		if !found[name] {
+			return nil, fmt.Errorf("%w: %s", ErrScopeNotFound, name)
+		}
+	}
+
+	return scopes, nil
+}
+
+func (r *ScopeRepository) ListScopes() ([]models.Scope, error) {
+	var scopes []models.Scope
+	if err := r.DB.Order("name").Find(&scopes).Error; err != nil {
+		return nil, fmt.Errorf("error fetching scopes: %v", err)
+	}
+
+	return scopes, nil
+}
+
+func (r *ScopeRepository) CreateScope(scope *models.Scope) error {
+	return r.DB.Create(scope).Error
+}
+
+func (r *ScopeRepository) UpdateScope(scope *models.Scope) error {
+	return r.DB.Save(scope).Error
+}
+
+// DeleteScope deletes the scope, removing it from every user type it is assigned to.
+func (r *ScopeRepository) DeleteScope(id uuid.UUID) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM auth_user_type_scopes WHERE scope_id = ?", id).Error; err != nil {
+			return err
+		}
+
+		result := tx.Where("id = ?", id).Delete(&models.Scope{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: %s", ErrScopeNotFound, id)
+		}
+		return nil
+	})
+}
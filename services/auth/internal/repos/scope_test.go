@@ -0,0 +1,83 @@
+package repos_test
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/accentdesign/grpc/services/auth/internal/models"
+	"github.com/accentdesign/grpc/services/auth/internal/repos"
+)
+
+func (suite *TestSuite) TestScopeRepository_GetScopesByName() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	repo := repos.ScopeRepository{DB: suite.db}
+
+	for _, name := range []string{"write", "read"} {
+		suite.NoError(repo.CreateScope(&models.Scope{Name: name}))
+	}
+
+	scopes, err := repo.GetScopesByName([]string{"write", "read"})
+	suite.NoError(err)
+	suite.Len(scopes, 2)
+	suite.Equal("read", scopes[0].Name)
+	suite.Equal("write", scopes[1].Name)
+
+	scopes, err = repo.GetScopesByName(nil)
+	suite.NoError(err)
+	suite.Empty(scopes)
+
+	scopes, err = repo.GetScopesByName([]string{"read", "unknown"})
+	suite.ErrorIs(err, repos.ErrScopeNotFound)
+	suite.Equal("scope not found: unknown", err.Error())
+	suite.Nil(scopes)
+}
+
+func (suite *TestSuite) TestScopeRepository_UpdateScope() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	repo := repos.ScopeRepository{DB: suite.db}
+
+	scope := &models.Scope{Name: "read"}
+	suite.NoError(repo.CreateScope(scope))
+
+	scope.Name = "view"
+	err := repo.UpdateScope(scope)
+	suite.NoError(err)
+
+	fetched, err := repo.GetScopeByID(scope.ID)
+	suite.NoError(err)
+	suite.Equal("view", fetched.Name)
+
+	scopes, err := repo.ListScopes()
+	suite.NoError(err)
+	suite.Len(scopes, 1)
+}
+
+func (suite *TestSuite) TestScopeRepository_DeleteScope() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	repo := repos.ScopeRepository{DB: suite.db}
+	userTypeRepo := repos.UserTypeRepository{DB: suite.db}
+
+	scope := &models.Scope{Name: "read"}
+	suite.NoError(repo.CreateScope(scope))
+
+	userType := &models.UserType{Name: "user", Scopes: []models.Scope{*scope}}
+	suite.NoError(userTypeRepo.CreateUserType(userType))
+
+	err := repo.DeleteScope(scope.ID)
+	suite.NoError(err)
+
+	fetched, err := userTypeRepo.GetUserTypeByID(userType.ID)
+	suite.NoError(err)
+	suite.Empty(fetched.Scopes)
+
+	err = repo.DeleteScope(scope.ID)
+	suite.ErrorIs(err, repos.ErrScopeNotFound)
+
+	_, err = repo.GetScopeByID(uuid.New())
+	suite.ErrorIs(err, repos.ErrScopeNotFound)
+}
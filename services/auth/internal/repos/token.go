@@ -1,26 +1,111 @@
 package repos
 
 import (
+	"context"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"math/big"
 	"time"
+	"unicode/utf8"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"github.com/accentdesign/grpc/services/auth/internal/keys"
 	"github.com/accentdesign/grpc/services/auth/internal/models"
 )
 
+var (
+	ErrLoginCodeInvalid    = errors.New("invalid login code")
+	ErrRefreshTokenInvalid = errors.New("refresh token not found or expired")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used")
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrTokenExpired        = errors.New("token has expired")
+	ErrTokenUsed           = errors.New("token has already been used")
+)
+
+// lastUsedInterval limits how often the last used time of an access token is written.
+const lastUsedInterval = time.Minute
+
+// loginCodeDigits is the length of the numeric login codes.
+const loginCodeDigits = 6
+
 type TokenConfig struct {
-	BearerDuration time.Duration
-	ResetDuration  time.Duration
-	VerifyDuration time.Duration
+	BearerDuration      time.Duration
+	RefreshDuration     time.Duration
+	ResetDuration       time.Duration
+	VerifyDuration      time.Duration
+	EmailChangeDuration time.Duration
+	LoginDuration       time.Duration
+	MFADuration         time.Duration
+}
+
+// TokenHasher digests tokens before they are stored or looked up, so the tokens cannot be used by anyone
+// able to read the database. With a pepper tokens are digested with HMAC-SHA256, otherwise with SHA-256.
+// A nil TokenHasher digests with SHA-256.
+type TokenHasher struct {
+	Pepper []byte
+}
+
+// Hash returns the hex encoded digest of the token.
+func (h *TokenHasher) Hash(token string) string {
+	if h == nil || len(h.Pepper) == 0 {
+		sum := sha256.Sum256([]byte(token))
+		return hex.EncodeToString(sum[:])
+	}
+	mac := hmac.New(sha256.New, h.Pepper)
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// where matches the token column of a token table against the token, prefix is the alias of the table.
+// Tokens stored before hashing was introduced are matched by their raw value until they expire.
+func (h *TokenHasher) where(db *gorm.DB, prefix string, token string) *gorm.DB {
+	return db.Where(
+		fmt.Sprintf("((%[1]stoken = ? AND %[1]shashed) OR (%[1]stoken = ? AND NOT %[1]shashed))", prefix),
+		h.Hash(token), token,
+	)
+}
+
+// SessionInfo describes the client an access token is issued to.
+type SessionInfo struct {
+	// FamilyId is the family of the refresh token issued alongside the access token
+	FamilyId  *uuid.UUID
+	UserAgent string
+	IPAddress string
 }
 
 type TokenRepository struct {
 	DB     *gorm.DB
 	Config *TokenConfig
+	Signer *keys.KeySet
+	Hasher *TokenHasher
+}
+
+func (r *TokenRepository) signAccessToken(jti string, userId uuid.UUID, issuedAt time.Time, expiresAt time.Time) (string, error) {
+	var user models.User
+	if err := r.DB.Preload("UserType").Preload("UserType.Scopes").First(&user, "id = ?", userId).Error; err != nil {
+		return "", fmt.Errorf("error fetching user: %v", err)
+	}
+
+	return r.Signer.Sign(&keys.AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   userId.String(),
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		UserType: user.UserType.Name,
+		Scopes:   user.UserType.ScopeNames(),
+	})
 }
 
 func (r *TokenRepository) createToken(token interface{}, userId uuid.UUID, size int, duration time.Duration) error {
@@ -32,19 +117,49 @@ func (r *TokenRepository) createToken(token interface{}, userId uuid.UUID, size
 	tokenStr := base64.URLEncoding.EncodeToString(randomBytes)
 	now := time.Now()
 
+	// only the digest of the token is stored, the raw token is handed back to the caller
+	var stored *string
 	switch t := token.(type) {
 	case *models.AccessToken:
-		t.Token = tokenStr
+		if r.Signer != nil {
+			signed, err := r.signAccessToken(tokenStr, userId, now, now.Add(duration))
+			if err != nil {
+				return err
+			}
+			tokenStr = signed
+		}
+		stored, t.Hashed = &t.Token, true
+		t.ID = uuid.New()
+		t.UserId = userId
+		t.CreatedAt = now
+		t.ExpiresAt = now.Add(duration)
+	case *models.RefreshToken:
+		stored, t.Hashed = &t.Token, true
 		t.UserId = userId
 		t.CreatedAt = now
 		t.ExpiresAt = now.Add(duration)
 	case *models.ResetToken:
-		t.Token = tokenStr
+		stored, t.Hashed = &t.Token, true
 		t.UserId = userId
 		t.CreatedAt = now
 		t.ExpiresAt = now.Add(duration)
 	case *models.VerifyToken:
-		t.Token = tokenStr
+		stored, t.Hashed = &t.Token, true
+		t.UserId = userId
+		t.CreatedAt = now
+		t.ExpiresAt = now.Add(duration)
+	case *models.EmailChangeToken:
+		stored, t.Hashed = &t.Token, true
+		t.UserId = userId
+		t.CreatedAt = now
+		t.ExpiresAt = now.Add(duration)
+	case *models.LoginToken:
+		stored, t.Hashed = &t.Token, true
+		t.UserId = userId
+		t.CreatedAt = now
+		t.ExpiresAt = now.Add(duration)
+	case *models.MFAToken:
+		stored, t.Hashed = &t.Token, true
 		t.UserId = userId
 		t.CreatedAt = now
 		t.ExpiresAt = now.Add(duration)
@@ -52,21 +167,84 @@ func (r *TokenRepository) createToken(token interface{}, userId uuid.UUID, size
 		return fmt.Errorf("unsupported token type: %T", t)
 	}
 
+	*stored = r.Hasher.Hash(tokenStr)
 	if result := r.DB.Create(token); result.Error != nil {
 		return result.Error
 	}
+	*stored = tokenStr
 
 	return nil
 }
 
-func (r *TokenRepository) CreateAccessToken(userId uuid.UUID) (*models.AccessToken, error) {
-	accessToken := &models.AccessToken{}
+func (r *TokenRepository) CreateAccessToken(userId uuid.UUID, session SessionInfo) (*models.AccessToken, error) {
+	accessToken := &models.AccessToken{
+		FamilyId:  session.FamilyId,
+		UserAgent: truncate(session.UserAgent, 512),
+		IPAddress: truncate(session.IPAddress, 64),
+	}
 	if err := r.createToken(accessToken, userId, 64, r.Config.BearerDuration); err != nil {
 		return nil, err
 	}
 	return accessToken, nil
 }
 
+func (r *TokenRepository) CreateRefreshToken(userId uuid.UUID) (*models.RefreshToken, error) {
+	refreshToken := &models.RefreshToken{FamilyId: uuid.New()}
+	if err := r.createToken(refreshToken, userId, 64, r.Config.RefreshDuration); err != nil {
+		return nil, err
+	}
+	return refreshToken, nil
+}
+
+// RotateRefreshToken exchanges a refresh token for a new one in the same family.
+// The presented token is marked as used, if it has already been used the whole
+// family, and the access tokens issued with it, are revoked and ErrRefreshTokenReused
+// is returned.
+func (r *TokenRepository) RotateRefreshToken(token string) (*models.RefreshToken, error) {
+	var rotated *models.RefreshToken
+	reused := false
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var current models.RefreshToken
+		result := r.Hasher.where(tx.Clauses(clause.Locking{Strength: "UPDATE"}), "", token).First(&current)
+		if result.Error != nil {
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				return ErrRefreshTokenInvalid
+			}
+			return result.Error
+		}
+
+		if current.UsedAt != nil {
+			reused = true
+			if err := tx.Where("family_id = ?", current.FamilyId).Delete(&models.AccessToken{}).Error; err != nil {
+				return err
+			}
+			return tx.Where("family_id = ?", current.FamilyId).Delete(&models.RefreshToken{}).Error
+		}
+
+		now := time.Now()
+		if current.ExpiresAt.Before(now) {
+			return ErrRefreshTokenInvalid
+		}
+
+		if err := tx.Model(&current).Update("used_at", now).Error; err != nil {
+			return err
+		}
+
+		txRepo := &TokenRepository{DB: tx, Config: r.Config, Hasher: r.Hasher}
+		rotated = &models.RefreshToken{FamilyId: current.FamilyId}
+		return txRepo.createToken(rotated, current.UserId, 64, r.Config.RefreshDuration)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, ErrRefreshTokenReused
+	}
+
+	return rotated, nil
+}
+
 func (r *TokenRepository) CreateResetToken(userId uuid.UUID) (*models.ResetToken, error) {
 	resetToken := &models.ResetToken{}
 	if err := r.createToken(resetToken, userId, 64, r.Config.ResetDuration); err != nil {
@@ -83,9 +261,255 @@ func (r *TokenRepository) CreateVerifyToken(userId uuid.UUID) (*models.VerifyTok
 	return verifyToken, nil
 }
 
+// CreateEmailChangeToken creates a token confirming the change of the email of the user to email.
+func (r *TokenRepository) CreateEmailChangeToken(userId uuid.UUID, email string) (*models.EmailChangeToken, error) {
+	emailChangeToken := &models.EmailChangeToken{Email: email}
+	if err := r.createToken(emailChangeToken, userId, 64, r.Config.EmailChangeDuration); err != nil {
+		return nil, err
+	}
+	return emailChangeToken, nil
+}
+
+// useToken marks a single use token as used, filling model with the stored token. It returns ErrTokenUsed
+// when the token was used, or expired, since it was looked up, so concurrent requests cannot both use it.
+func (r *TokenRepository) useToken(token string, model interface{}) error {
+	now := time.Now()
+	result := r.Hasher.where(r.DB.Model(model).Clauses(clause.Returning{}), "", token).
+		Where("used_at IS NULL AND expires_at >= ?", now).
+		UpdateColumn("used_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTokenUsed
+	}
+	return nil
+}
+
+// UseResetToken marks the reset token as used, see useToken.
+func (r *TokenRepository) UseResetToken(token string) error {
+	return r.useToken(token, &models.ResetToken{})
+}
+
+// UseVerifyToken marks the verify token as used, see useToken.
+func (r *TokenRepository) UseVerifyToken(token string) error {
+	return r.useToken(token, &models.VerifyToken{})
+}
+
+// UseEmailChangeToken marks the email change token as used, returning it for the email it confirms, see useToken.
+func (r *TokenRepository) UseEmailChangeToken(token string) (*models.EmailChangeToken, error) {
+	emailChangeToken := &models.EmailChangeToken{}
+	if err := r.useToken(token, emailChangeToken); err != nil {
+		return nil, err
+	}
+	return emailChangeToken, nil
+}
+
+// CreateLoginLinkToken creates a token that logs the user in when redeemed, to be sent to the user as a link.
+func (r *TokenRepository) CreateLoginLinkToken(userId uuid.UUID) (*models.LoginToken, error) {
+	loginToken := &models.LoginToken{}
+	if err := r.createToken(loginToken, userId, 64, r.Config.LoginDuration); err != nil {
+		return nil, err
+	}
+	return loginToken, nil
+}
+
+// CreateLoginCodeToken creates a token that logs the user in when redeemed along with the returned numeric code,
+// the code is sent to the user while the token is kept by the client that requested it.
+func (r *TokenRepository) CreateLoginCodeToken(userId uuid.UUID) (*models.LoginToken, string, error) {
+	limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(loginCodeDigits), nil)
+	n, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	code := fmt.Sprintf("%0*d", loginCodeDigits, n)
+
+	loginToken := &models.LoginToken{Code: r.Hasher.Hash(code)}
+	if err := r.createToken(loginToken, userId, 64, r.Config.LoginDuration); err != nil {
+		return nil, "", err
+	}
+	return loginToken, code, nil
+}
+
+// UseLoginToken marks the login token as used, see useToken. A login code token is only used when code matches,
+// otherwise ErrLoginCodeInvalid is returned and the token is revoked once maxAttempts have been made.
+func (r *TokenRepository) UseLoginToken(token string, code string, maxAttempts int) error {
+	// the attempt is counted before the code is compared, so concurrent guesses cannot exceed maxAttempts
+	var loginToken models.LoginToken
+	result := r.Hasher.where(r.DB.Model(&loginToken).Clauses(clause.Returning{}), "", token).
+		Where("used_at IS NULL AND expires_at >= ?", time.Now()).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTokenUsed
+	}
+
+	if loginToken.Code != "" && subtle.ConstantTimeCompare([]byte(loginToken.Code), []byte(r.Hasher.Hash(code))) != 1 {
+		if loginToken.Attempts >= maxAttempts {
+			if err := r.DB.Delete(&loginToken).Error; err != nil {
+				return err
+			}
+		}
+		return ErrLoginCodeInvalid
+	}
+
+	return r.useToken(token, &models.LoginToken{})
+}
+
+func (r *TokenRepository) CreateMFAToken(userId uuid.UUID) (*models.MFAToken, error) {
+	mfaToken := &models.MFAToken{}
+	if err := r.createToken(mfaToken, userId, 64, r.Config.MFADuration); err != nil {
+		return nil, err
+	}
+	return mfaToken, nil
+}
+
+// RecordMFAFailure counts a failed attempt to complete the challenge of an mfa token,
+// the token is revoked once it reaches maxAttempts.
+func (r *TokenRepository) RecordMFAFailure(token string, maxAttempts int) error {
+	if err := r.Hasher.where(r.DB.Model(&models.MFAToken{}), "", token).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1")).Error; err != nil {
+		return err
+	}
+	return r.Hasher.where(r.DB, "", token).Where("attempts >= ?", maxAttempts).Delete(&models.MFAToken{}).Error
+}
+
+func (r *TokenRepository) RevokeMFAToken(token string) error {
+	if result := r.Hasher.where(r.DB, "", token).Delete(&models.MFAToken{}); result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
 func (r *TokenRepository) RevokeBearerToken(token string) error {
-	if result := r.DB.Where("token = ?", token).Delete(&models.AccessToken{}); result.Error != nil {
+	if result := r.Hasher.where(r.DB, "", token).Delete(&models.AccessToken{}); result.Error != nil {
 		return result.Error
 	}
 	return nil
 }
+
+// TouchAccessToken records that the access token has been used, at most once every lastUsedInterval.
+func (r *TokenRepository) TouchAccessToken(token string) error {
+	now := time.Now()
+	return r.Hasher.where(r.DB.Model(&models.AccessToken{}), "", token).
+		Where("(last_used_at IS NULL OR last_used_at < ?)", now.Add(-lastUsedInterval)).
+		UpdateColumn("last_used_at", now).Error
+}
+
+// MatchesAccessToken reports whether the stored access token is the given raw token.
+func (r *TokenRepository) MatchesAccessToken(stored *models.AccessToken, token string) bool {
+	if stored.Hashed {
+		return subtle.ConstantTimeCompare([]byte(stored.Token), []byte(r.Hasher.Hash(token))) == 1
+	}
+	return subtle.ConstantTimeCompare([]byte(stored.Token), []byte(token)) == 1
+}
+
+// ListSessions returns the unexpired access tokens of the user, newest first.
+func (r *TokenRepository) ListSessions(userId uuid.UUID) ([]models.AccessToken, error) {
+	var tokens []models.AccessToken
+	if err := r.DB.Where("user_id = ? AND expires_at >= ?", userId, time.Now()).
+		Order("created_at desc").Find(&tokens).Error; err != nil {
+		return nil, fmt.Errorf("error fetching sessions: %v", err)
+	}
+	return tokens, nil
+}
+
+// RevokeSession revokes an access token of the user by its id,
+// along with the refresh token family it was issued with so the session cannot be refreshed.
+func (r *TokenRepository) RevokeSession(userId uuid.UUID, id uuid.UUID) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var session models.AccessToken
+		result := tx.Where("id = ? AND user_id = ?", id, userId).First(&session)
+		if result.Error != nil {
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+			}
+			return result.Error
+		}
+
+		if session.FamilyId == nil {
+			return tx.Delete(&session).Error
+		}
+
+		if err := tx.Where("user_id = ? AND family_id = ?", userId, session.FamilyId).Delete(&models.AccessToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ? AND family_id = ?", userId, session.FamilyId).Delete(&models.RefreshToken{}).Error
+	})
+}
+
+// RevokeAllSessions revokes every access and refresh token of the user.
+// When exceptToken is set that access token, and the refresh token family it was issued with, are kept.
+func (r *TokenRepository) RevokeAllSessions(userId uuid.UUID, exceptToken string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		accessTokens := tx.Where("user_id = ?", userId)
+		refreshTokens := tx.Where("user_id = ?", userId)
+
+		if exceptToken != "" {
+			var current models.AccessToken
+			result := r.Hasher.where(tx, "", exceptToken).Where("user_id = ?", userId).First(&current)
+			if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				return result.Error
+			}
+			accessTokens = accessTokens.Where("token <> ?", current.Token)
+			if current.FamilyId != nil {
+				accessTokens = accessTokens.Where("(family_id IS NULL OR family_id <> ?)", current.FamilyId)
+				refreshTokens = refreshTokens.Where("family_id <> ?", current.FamilyId)
+			}
+		}
+
+		if err := accessTokens.Delete(&models.AccessToken{}).Error; err != nil {
+			return err
+		}
+		return refreshTokens.Delete(&models.RefreshToken{}).Error
+	})
+}
+
+// DeleteExpiredTokens deletes tokens that expired before the given time, at most batchSize rows per statement
+// so large backlogs do not hold long running locks. It returns the number of deleted tokens.
+func (r *TokenRepository) DeleteExpiredTokens(ctx context.Context, before time.Time, batchSize int) (int64, error) {
+	if batchSize <= 0 {
+		return 0, fmt.Errorf("invalid batch size: %d", batchSize)
+	}
+
+	var total int64
+	for _, model := range []interface{ TableName() string }{
+		&models.AccessToken{},
+		&models.RefreshToken{},
+		&models.ResetToken{},
+		&models.VerifyToken{},
+		&models.EmailChangeToken{},
+		&models.LoginToken{},
+		&models.MFAToken{},
+	} {
+		for {
+			if err := ctx.Err(); err != nil {
+				return total, err
+			}
+
+			expired := r.DB.Model(model).Select("token").Where("expires_at < ?", before).Limit(batchSize)
+			result := r.DB.WithContext(ctx).Where("token IN (?)", expired).Delete(model)
+			if result.Error != nil {
+				return total, fmt.Errorf("error deleting expired tokens from %s: %v", model.TableName(), result.Error)
+			}
+			total += result.RowsAffected
+			if result.RowsAffected < int64(batchSize) {
+				break
+			}
+		}
+	}
+	return total, nil
+}
+
+// truncate shortens s to at most n bytes, without splitting a utf-8 sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
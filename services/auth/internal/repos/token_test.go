@@ -1,20 +1,29 @@
 package repos_test
 
 import (
+	"context"
+	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/google/uuid"
+
 	"github.com/accentdesign/grpc/services/auth/internal/models"
 	"github.com/accentdesign/grpc/services/auth/internal/repos"
 )
 
+// hasher digests tokens as the repositories do without a pepper.
+var hasher *repos.TokenHasher
+
 func (suite *TestSuite) TestTokenRepository_CreateAccessToken() {
 	teardown := suite.Setup()
 	defer teardown()
 
 	config := &repos.TokenConfig{
-		BearerDuration: time.Hour,
-		ResetDuration:  time.Hour,
-		VerifyDuration: time.Hour,
+		BearerDuration:  time.Hour,
+		RefreshDuration: time.Hour,
+		ResetDuration:   time.Hour,
+		VerifyDuration:  time.Hour,
 	}
 
 	repo := repos.TokenRepository{
@@ -25,26 +34,138 @@ func (suite *TestSuite) TestTokenRepository_CreateAccessToken() {
 	user, err := suite.helpers.CreateTestUser()
 	suite.NoError(err)
 
-	token, tokenErr := repo.CreateAccessToken(user.ID)
+	token, tokenErr := repo.CreateAccessToken(user.ID, repos.SessionInfo{})
 	suite.NoError(tokenErr)
 
 	var found models.AccessToken
-	err = suite.db.Where("token = ?", token.Token).First(&found).Error
+	err = suite.db.Where("token = ?", hasher.Hash(token.Token)).First(&found).Error
 	suite.NoError(err)
 
-	suite.Equal(token.Token, found.Token)
+	suite.Equal(hasher.Hash(token.Token), found.Token)
+	suite.True(found.Hashed)
 	suite.Equal(token.UserId, found.UserId)
 	suite.WithinDuration(time.Now().Add(config.BearerDuration), found.ExpiresAt, 10*time.Second)
 }
 
+func (suite *TestSuite) TestTokenRepository_CreateRefreshToken() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	config := &repos.TokenConfig{
+		BearerDuration:  time.Hour,
+		RefreshDuration: time.Hour,
+		ResetDuration:   time.Hour,
+		VerifyDuration:  time.Hour,
+	}
+
+	repo := repos.TokenRepository{
+		DB:     suite.db,
+		Config: config,
+	}
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	token, tokenErr := repo.CreateRefreshToken(user.ID)
+	suite.NoError(tokenErr)
+
+	var found models.RefreshToken
+	err = suite.db.Where("token = ?", hasher.Hash(token.Token)).First(&found).Error
+	suite.NoError(err)
+
+	suite.Equal(hasher.Hash(token.Token), found.Token)
+	suite.True(found.Hashed)
+	suite.Equal(token.UserId, found.UserId)
+	suite.Equal(token.FamilyId, found.FamilyId)
+	suite.NotEqual(uuid.Nil, found.FamilyId)
+	suite.Nil(found.UsedAt)
+	suite.WithinDuration(time.Now().Add(config.RefreshDuration), found.ExpiresAt, 10*time.Second)
+}
+
+func (suite *TestSuite) TestTokenRepository_RotateRefreshToken() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	config := &repos.TokenConfig{
+		BearerDuration:  time.Hour,
+		RefreshDuration: time.Hour,
+		ResetDuration:   time.Hour,
+		VerifyDuration:  time.Hour,
+	}
+
+	repo := repos.TokenRepository{
+		DB:     suite.db,
+		Config: config,
+	}
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	// unknown token
+	rotated, err := repo.RotateRefreshToken("unknown")
+	suite.ErrorIs(err, repos.ErrRefreshTokenInvalid)
+	suite.Nil(rotated)
+
+	// expired token
+	expired := &models.RefreshToken{UserId: user.ID, FamilyId: uuid.New(), Token: "expired", ExpiresAt: time.Now().Add(-1 * time.Second)}
+	err = suite.db.Create(expired).Error
+	suite.NoError(err)
+
+	rotated, err = repo.RotateRefreshToken(expired.Token)
+	suite.ErrorIs(err, repos.ErrRefreshTokenInvalid)
+	suite.Nil(rotated)
+
+	// valid token
+	token, err := repo.CreateRefreshToken(user.ID)
+	suite.NoError(err)
+
+	rotated, err = repo.RotateRefreshToken(token.Token)
+	suite.NoError(err)
+	suite.NotEqual(token.Token, rotated.Token)
+	suite.Equal(token.FamilyId, rotated.FamilyId)
+	suite.Equal(user.ID, rotated.UserId)
+
+	var used models.RefreshToken
+	err = suite.db.Where("token = ?", hasher.Hash(token.Token)).First(&used).Error
+	suite.NoError(err)
+	suite.NotNil(used.UsedAt)
+
+	// access tokens issued with the family before the reuse
+	accessToken, err := repo.CreateAccessToken(user.ID, repos.SessionInfo{FamilyId: &token.FamilyId})
+	suite.NoError(err)
+	otherToken, err := repo.CreateAccessToken(user.ID, repos.SessionInfo{})
+	suite.NoError(err)
+
+	userRepo := repos.UserRepository{DB: suite.db}
+	_, err = userRepo.GetUserByAccessToken(accessToken.Token)
+	suite.NoError(err)
+
+	// reused token revokes the family
+	again, err := repo.RotateRefreshToken(token.Token)
+	suite.ErrorIs(err, repos.ErrRefreshTokenReused)
+	suite.Nil(again)
+
+	var count int64
+	err = suite.db.Model(&models.RefreshToken{}).Where("family_id = ?", token.FamilyId).Count(&count).Error
+	suite.NoError(err)
+	suite.Equal(int64(0), count)
+
+	// and its access tokens, other sessions are kept
+	_, err = userRepo.GetUserByAccessToken(accessToken.Token)
+	suite.ErrorIs(err, repos.ErrUserNotFound)
+	_, err = userRepo.GetUserByAccessToken(otherToken.Token)
+	suite.NoError(err)
+}
+
 func (suite *TestSuite) TestTokenRepository_CreateResetToken() {
 	teardown := suite.Setup()
 	defer teardown()
 
 	config := &repos.TokenConfig{
-		BearerDuration: time.Hour,
-		ResetDuration:  time.Hour,
-		VerifyDuration: time.Hour,
+		BearerDuration:  time.Hour,
+		RefreshDuration: time.Hour,
+		ResetDuration:   time.Hour,
+		VerifyDuration:  time.Hour,
 	}
 
 	repo := repos.TokenRepository{
@@ -59,22 +180,148 @@ func (suite *TestSuite) TestTokenRepository_CreateResetToken() {
 	suite.NoError(tokenErr)
 
 	var found models.ResetToken
-	err = suite.db.Where("token = ?", token.Token).First(&found).Error
+	err = suite.db.Where("token = ?", hasher.Hash(token.Token)).First(&found).Error
 	suite.NoError(err)
 
-	suite.Equal(token.Token, found.Token)
+	suite.Equal(hasher.Hash(token.Token), found.Token)
+	suite.True(found.Hashed)
 	suite.Equal(token.UserId, found.UserId)
 	suite.WithinDuration(time.Now().Add(config.ResetDuration), found.ExpiresAt, 10*time.Second)
 }
 
+func (suite *TestSuite) TestTokenRepository_UseResetToken() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	repo := repos.TokenRepository{
+		DB:     suite.db,
+		Config: &repos.TokenConfig{ResetDuration: time.Hour},
+	}
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	token, err := repo.CreateResetToken(user.ID)
+	suite.NoError(err)
+
+	suite.NoError(repo.UseResetToken(token.Token))
+
+	var found models.ResetToken
+	suite.NoError(suite.db.Where("token = ?", hasher.Hash(token.Token)).First(&found).Error)
+	suite.NotNil(found.UsedAt)
+
+	// a token can only be used once
+	suite.ErrorIs(repo.UseResetToken(token.Token), repos.ErrTokenUsed)
+
+	// expired and unknown tokens cannot be used
+	expired := &models.ResetToken{UserId: user.ID, Token: "expired", ExpiresAt: time.Now().Add(-time.Second)}
+	suite.NoError(suite.db.Create(expired).Error)
+	suite.ErrorIs(repo.UseResetToken(expired.Token), repos.ErrTokenUsed)
+	suite.ErrorIs(repo.UseResetToken("unknown"), repos.ErrTokenUsed)
+}
+
+func (suite *TestSuite) TestTokenRepository_EmailChangeToken() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	config := &repos.TokenConfig{EmailChangeDuration: time.Hour}
+
+	repo := repos.TokenRepository{
+		DB:     suite.db,
+		Config: config,
+	}
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	token, err := repo.CreateEmailChangeToken(user.ID, "new@example.com")
+	suite.NoError(err)
+
+	var found models.EmailChangeToken
+	suite.NoError(suite.db.Where("token = ?", hasher.Hash(token.Token)).First(&found).Error)
+	suite.True(found.Hashed)
+	suite.Equal(user.ID, found.UserId)
+	suite.Equal("new@example.com", found.Email)
+	suite.Nil(found.UsedAt)
+	suite.WithinDuration(time.Now().Add(config.EmailChangeDuration), found.ExpiresAt, 10*time.Second)
+
+	used, err := repo.UseEmailChangeToken(token.Token)
+	suite.NoError(err)
+	suite.Equal("new@example.com", used.Email)
+	suite.NotNil(used.UsedAt)
+
+	_, err = repo.UseEmailChangeToken(token.Token)
+	suite.ErrorIs(err, repos.ErrTokenUsed)
+}
+
+// otherCode returns a login code that is not code.
+func otherCode(code string) string {
+	n, _ := strconv.Atoi(code)
+	return fmt.Sprintf("%06d", (n+1)%1000000)
+}
+
+func (suite *TestSuite) TestTokenRepository_LoginToken() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	config := &repos.TokenConfig{LoginDuration: 15 * time.Minute}
+
+	repo := repos.TokenRepository{
+		DB:     suite.db,
+		Config: config,
+	}
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	// a login link token is used without a code
+	link, err := repo.CreateLoginLinkToken(user.ID)
+	suite.NoError(err)
+
+	var found models.LoginToken
+	suite.NoError(suite.db.Where("token = ?", hasher.Hash(link.Token)).First(&found).Error)
+	suite.True(found.Hashed)
+	suite.Equal(user.ID, found.UserId)
+	suite.Empty(found.Code)
+	suite.WithinDuration(time.Now().Add(config.LoginDuration), found.ExpiresAt, 10*time.Second)
+
+	suite.NoError(repo.UseLoginToken(link.Token, "", 3))
+	suite.ErrorIs(repo.UseLoginToken(link.Token, "", 3), repos.ErrTokenUsed)
+
+	// a login code token is only used with its code
+	token, code, err := repo.CreateLoginCodeToken(user.ID)
+	suite.NoError(err)
+	suite.Len(code, 6)
+
+	suite.NoError(suite.db.Where("token = ?", hasher.Hash(token.Token)).First(&found).Error)
+	suite.Equal(hasher.Hash(code), found.Code)
+
+	suite.ErrorIs(repo.UseLoginToken(token.Token, otherCode(code), 3), repos.ErrLoginCodeInvalid)
+	suite.NoError(repo.UseLoginToken(token.Token, code, 3))
+	suite.ErrorIs(repo.UseLoginToken(token.Token, code, 3), repos.ErrTokenUsed)
+
+	// the token is revoked after the maximum attempts
+	token, code, err = repo.CreateLoginCodeToken(user.ID)
+	suite.NoError(err)
+	for i := 0; i < 3; i++ {
+		suite.ErrorIs(repo.UseLoginToken(token.Token, otherCode(code), 3), repos.ErrLoginCodeInvalid)
+	}
+	suite.ErrorIs(repo.UseLoginToken(token.Token, code, 3), repos.ErrTokenUsed)
+
+	var count int64
+	suite.NoError(suite.db.Model(&models.LoginToken{}).Where("token = ?", hasher.Hash(token.Token)).Count(&count).Error)
+	suite.Zero(count)
+}
+
 func (suite *TestSuite) TestTokenRepository_CreateVerifyToken() {
 	teardown := suite.Setup()
 	defer teardown()
 
 	config := &repos.TokenConfig{
-		BearerDuration: time.Hour,
-		ResetDuration:  time.Hour,
-		VerifyDuration: time.Hour,
+		BearerDuration:  time.Hour,
+		RefreshDuration: time.Hour,
+		ResetDuration:   time.Hour,
+		VerifyDuration:  time.Hour,
 	}
 
 	repo := repos.TokenRepository{
@@ -89,10 +336,11 @@ func (suite *TestSuite) TestTokenRepository_CreateVerifyToken() {
 	suite.NoError(tokenErr)
 
 	var found models.VerifyToken
-	err = suite.db.Where("token = ?", token.Token).First(&found).Error
+	err = suite.db.Where("token = ?", hasher.Hash(token.Token)).First(&found).Error
 	suite.NoError(err)
 
-	suite.Equal(token.Token, found.Token)
+	suite.Equal(hasher.Hash(token.Token), found.Token)
+	suite.True(found.Hashed)
 	suite.Equal(token.UserId, found.UserId)
 	suite.WithinDuration(time.Now().Add(config.VerifyDuration), found.ExpiresAt, 10*time.Second)
 }
@@ -102,9 +350,10 @@ func (suite *TestSuite) TestTokenRepository_RevokeBearerToken() {
 	defer teardown()
 
 	config := &repos.TokenConfig{
-		BearerDuration: time.Hour,
-		ResetDuration:  time.Hour,
-		VerifyDuration: time.Hour,
+		BearerDuration:  time.Hour,
+		RefreshDuration: time.Hour,
+		ResetDuration:   time.Hour,
+		VerifyDuration:  time.Hour,
 	}
 
 	repo := repos.TokenRepository{
@@ -131,3 +380,196 @@ func (suite *TestSuite) TestTokenRepository_RevokeBearerToken() {
 	foundErr := suite.db.Where("token = ?", token.Token).First(&found).Error
 	suite.Error(foundErr)
 }
+
+func (suite *TestSuite) TestTokenRepository_RecordMFAFailure() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	repo := repos.TokenRepository{DB: suite.db, Config: &repos.TokenConfig{MFADuration: time.Minute}}
+
+	token, err := repo.CreateMFAToken(user.ID)
+	suite.NoError(err)
+
+	for i := 1; i < 3; i++ {
+		err = repo.RecordMFAFailure(token.Token, 3)
+		suite.NoError(err)
+
+		var fetched models.MFAToken
+		err = suite.db.First(&fetched, "token = ?", hasher.Hash(token.Token)).Error
+		suite.NoError(err)
+		suite.Equal(i, fetched.Attempts)
+	}
+
+	// the token is revoked once it reaches the max attempts
+	err = repo.RecordMFAFailure(token.Token, 3)
+	suite.NoError(err)
+
+	var count int64
+	err = suite.db.Model(&models.MFAToken{}).Where("token = ?", hasher.Hash(token.Token)).Count(&count).Error
+	suite.NoError(err)
+	suite.Equal(int64(0), count)
+}
+
+func (suite *TestSuite) TestTokenRepository_Sessions() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	repo := repos.TokenRepository{
+		DB:     suite.db,
+		Config: &repos.TokenConfig{BearerDuration: time.Hour, RefreshDuration: time.Hour},
+	}
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	// two sessions, each with a refresh token family
+	var sessions []*models.AccessToken
+	var refreshTokens []*models.RefreshToken
+	for _, agent := range []string{"phone", "laptop"} {
+		refreshToken, err := repo.CreateRefreshToken(user.ID)
+		suite.NoError(err)
+		session, err := repo.CreateAccessToken(user.ID, repos.SessionInfo{
+			FamilyId:  &refreshToken.FamilyId,
+			UserAgent: agent,
+			IPAddress: "192.0.2.1",
+		})
+		suite.NoError(err)
+		sessions = append(sessions, session)
+		refreshTokens = append(refreshTokens, refreshToken)
+	}
+
+	listed, err := repo.ListSessions(user.ID)
+	suite.NoError(err)
+	suite.Len(listed, 2)
+	suite.Equal("laptop", listed[0].UserAgent)
+	suite.Equal("192.0.2.1", listed[0].IPAddress)
+	suite.Nil(listed[0].LastUsedAt)
+
+	suite.NoError(repo.TouchAccessToken(sessions[0].Token))
+	var touched models.AccessToken
+	suite.NoError(suite.db.First(&touched, "token = ?", hasher.Hash(sessions[0].Token)).Error)
+	suite.NotNil(touched.LastUsedAt)
+
+	// sessions of other users cannot be revoked
+	err = repo.RevokeSession(uuid.New(), sessions[0].ID)
+	suite.ErrorIs(err, repos.ErrSessionNotFound)
+
+	// revoking a session revokes its refresh tokens
+	suite.NoError(repo.RevokeSession(user.ID, sessions[0].ID))
+	err = suite.db.First(&models.AccessToken{}, "token = ?", hasher.Hash(sessions[0].Token)).Error
+	suite.EqualError(err, "record not found")
+	err = suite.db.First(&models.RefreshToken{}, "token = ?", hasher.Hash(refreshTokens[0].Token)).Error
+	suite.EqualError(err, "record not found")
+	err = suite.db.First(&models.RefreshToken{}, "token = ?", hasher.Hash(refreshTokens[1].Token)).Error
+	suite.NoError(err)
+
+	// revoking all sessions can keep the current one
+	other, err := repo.CreateAccessToken(user.ID, repos.SessionInfo{})
+	suite.NoError(err)
+	suite.NoError(repo.RevokeAllSessions(user.ID, sessions[1].Token))
+
+	listed, err = repo.ListSessions(user.ID)
+	suite.NoError(err)
+	suite.Len(listed, 1)
+	suite.Equal(sessions[1].ID, listed[0].ID)
+	err = suite.db.First(&models.AccessToken{}, "token = ?", hasher.Hash(other.Token)).Error
+	suite.EqualError(err, "record not found")
+	err = suite.db.First(&models.RefreshToken{}, "token = ?", hasher.Hash(refreshTokens[1].Token)).Error
+	suite.NoError(err)
+
+	suite.NoError(repo.RevokeAllSessions(user.ID, ""))
+	listed, err = repo.ListSessions(user.ID)
+	suite.NoError(err)
+	suite.Empty(listed)
+	err = suite.db.First(&models.RefreshToken{}, "token = ?", hasher.Hash(refreshTokens[1].Token)).Error
+	suite.EqualError(err, "record not found")
+}
+
+func (suite *TestSuite) TestTokenRepository_DeleteExpiredTokens() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	repo := repos.TokenRepository{DB: suite.db}
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	now := time.Now()
+	expired := now.Add(-2 * time.Hour)
+	recent := now.Add(-time.Minute)
+	valid := now.Add(time.Hour)
+
+	for i, expiresAt := range []time.Time{expired, expired, expired, recent, valid} {
+		token := fmt.Sprintf("token-%d", i)
+		suite.NoError(suite.db.Create(&models.AccessToken{Token: token, ID: uuid.New(), UserId: user.ID, ExpiresAt: expiresAt}).Error)
+		suite.NoError(suite.db.Create(&models.ResetToken{Token: token, UserId: user.ID, ExpiresAt: expiresAt}).Error)
+		suite.NoError(suite.db.Create(&models.VerifyToken{Token: token, UserId: user.ID, ExpiresAt: expiresAt}).Error)
+	}
+
+	_, err = repo.DeleteExpiredTokens(context.Background(), now, 0)
+	suite.EqualError(err, "invalid batch size: 0")
+
+	// tokens within the grace period are kept, batches smaller than the backlog still delete it all
+	deleted, err := repo.DeleteExpiredTokens(context.Background(), now.Add(-time.Hour), 2)
+	suite.NoError(err)
+	suite.Equal(int64(9), deleted)
+
+	var count int64
+	suite.NoError(suite.db.Model(&models.AccessToken{}).Count(&count).Error)
+	suite.Equal(int64(2), count)
+	suite.NoError(suite.db.Model(&models.ResetToken{}).Count(&count).Error)
+	suite.Equal(int64(2), count)
+	suite.NoError(suite.db.Model(&models.VerifyToken{}).Count(&count).Error)
+	suite.Equal(int64(2), count)
+
+	deleted, err = repo.DeleteExpiredTokens(context.Background(), now, 2)
+	suite.NoError(err)
+	suite.Equal(int64(3), deleted)
+}
+
+func (suite *TestSuite) TestTokenRepository_Hasher() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	peppered := &repos.TokenHasher{Pepper: []byte("pepper")}
+	suite.NotEqual(hasher.Hash("token"), peppered.Hash("token"))
+	suite.Equal(peppered.Hash("token"), peppered.Hash("token"))
+
+	tokenRepo := repos.TokenRepository{DB: suite.db, Config: &repos.TokenConfig{BearerDuration: time.Hour}, Hasher: peppered}
+	userRepo := repos.UserRepository{DB: suite.db, Hasher: peppered}
+
+	token, err := tokenRepo.CreateAccessToken(user.ID, repos.SessionInfo{})
+	suite.NoError(err)
+
+	var found models.AccessToken
+	suite.NoError(suite.db.First(&found, "user_id = ?", user.ID).Error)
+	suite.Equal(peppered.Hash(token.Token), found.Token)
+	suite.True(tokenRepo.MatchesAccessToken(&found, token.Token))
+
+	fetchedUser, err := userRepo.GetUserByAccessToken(token.Token)
+	suite.NoError(err)
+	suite.Equal(user.ID, fetchedUser.ID)
+
+	// the stored digest is not a valid token
+	_, err = userRepo.GetUserByAccessToken(found.Token)
+	suite.ErrorIs(err, repos.ErrUserNotFound)
+
+	// tokens stored before hashing are still valid until they expire
+	legacy := &models.AccessToken{Token: "legacy-token", ID: uuid.New(), UserId: user.ID, ExpiresAt: time.Now().Add(time.Hour)}
+	suite.NoError(suite.db.Create(legacy).Error)
+	suite.True(tokenRepo.MatchesAccessToken(legacy, "legacy-token"))
+
+	fetchedUser, err = userRepo.GetUserByAccessToken("legacy-token")
+	suite.NoError(err)
+	suite.Equal(user.ID, fetchedUser.ID)
+
+	suite.NoError(tokenRepo.RevokeBearerToken("legacy-token"))
+	_, err = userRepo.GetUserByAccessToken("legacy-token")
+	suite.ErrorIs(err, repos.ErrUserNotFound)
+}
@@ -3,15 +3,27 @@ package repos
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 
 	"github.com/accentdesign/grpc/services/auth/internal/models"
 )
 
+var ErrUserNotFound = errors.New("user not found")
+
 type UserRepository struct {
-	DB *gorm.DB
+	DB     *gorm.DB
+	Hasher *TokenHasher
+}
+
+type UserFilter struct {
+	Search     string
+	UserType   string
+	IsActive   *bool
+	IsVerified *bool
 }
 
 func (r *UserRepository) getDefaultUserType() (*models.UserType, error) {
@@ -23,34 +35,63 @@ func (r *UserRepository) getDefaultUserType() (*models.UserType, error) {
 	return &userType, nil
 }
 
-func (r *UserRepository) getUserByToken(token string, tokenTable interface{}) (*models.User, error) {
-	var user models.User
-	now := time.Now()
+// tokenState is the part of a stored token that tells whether it can still be used.
+type tokenState struct {
+	UserId    uuid.UUID
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
 
-	var joinTable string
-	switch tokenTable.(type) {
+// getUserByToken returns the user the token belongs to. An unknown token returns ErrUserNotFound, an expired
+// token ErrTokenExpired and a single use token that was already used ErrTokenUsed.
+func (r *UserRepository) getUserByToken(token string, tokenTable interface{}) (*models.User, error) {
+	var table string
+	var singleUse bool
+	switch t := tokenTable.(type) {
 	case *models.AccessToken:
-		t := tokenTable.(*models.AccessToken)
-		joinTable = t.TableName()
+		table = t.TableName()
+	case *models.RefreshToken:
+		table = t.TableName()
 	case *models.ResetToken:
-		t := tokenTable.(*models.ResetToken)
-		joinTable = t.TableName()
+		table, singleUse = t.TableName(), true
 	case *models.VerifyToken:
-		t := tokenTable.(*models.VerifyToken)
-		joinTable = t.TableName()
+		table, singleUse = t.TableName(), true
+	case *models.EmailChangeToken:
+		table, singleUse = t.TableName(), true
+	case *models.LoginToken:
+		table, singleUse = t.TableName(), true
+	case *models.MFAToken:
+		table = t.TableName()
 	default:
 		return nil, fmt.Errorf("unsupported token type: %T", tokenTable)
 	}
 
-	result := r.DB.Joins(fmt.Sprintf("JOIN %s t ON %s.ID = t.user_id", joinTable, user.TableName())).
-		Where("t.token = ? AND t.expires_at >= ?", token, now).
-		Preload("UserType").Preload("UserType.Scopes").First(&user)
+	columns := "user_id, expires_at"
+	if singleUse {
+		columns += ", used_at"
+	}
 
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("user not found for token: %s", token)
+	var state tokenState
+	if err := r.Hasher.where(r.DB.Table(table).Select(columns), "", token).Take(&state).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w for token", ErrUserNotFound)
 		}
-		return nil, fmt.Errorf("error fetching user: %v", result.Error)
+		return nil, fmt.Errorf("error fetching token: %v", err)
+	}
+
+	if state.UsedAt != nil {
+		return nil, ErrTokenUsed
+	}
+	if state.ExpiresAt.Before(time.Now()) {
+		return nil, ErrTokenExpired
+	}
+
+	var user models.User
+	if err := r.DB.Preload("UserType").Preload("UserType.Scopes").First(&user, "id = ?", state.UserId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w for token", ErrUserNotFound)
+		}
+		return nil, fmt.Errorf("error fetching user: %v", err)
 	}
 
 	return &user, nil
@@ -67,21 +108,36 @@ func (r *UserRepository) CreateUser(email string, password string, firstName str
 		FirstName:  firstName,
 		LastName:   lastName,
 		UserTypeId: userType.ID,
-		CreatedAt:  time.Time{},
+		IsActive:   true,
 	}
 
+	return r.InsertUser(&user, password)
+}
+
+// InsertUser validates and creates the user with the given password, returning the created user.
+// A user created without a password can only log in with a login token or a provider.
+func (r *UserRepository) InsertUser(user *models.User, password string) (*models.User, error) {
 	if err := user.Validate(); err != nil {
 		return nil, err
 	}
 
-	if err := user.SetPassword(password); err != nil {
-		return nil, err
+	if password != "" {
+		if err := user.SetPassword(password); err != nil {
+			return nil, err
+		}
 	}
 
-	if err := r.DB.Create(&user).Error; err != nil {
+	if err := r.DB.Create(user).Error; err != nil {
 		return nil, err
 	}
 
+	// is_active defaults to true in the database, so a false value is skipped on create
+	if !user.IsActive {
+		if err := r.DB.Model(user).UpdateColumn("is_active", false).Error; err != nil {
+			return nil, err
+		}
+	}
+
 	var fetchUser models.User
 	if err := r.DB.Preload("UserType").Preload("UserType.Scopes").First(&fetchUser, "id = ?", user.ID).Error; err != nil {
 		return nil, err
@@ -96,7 +152,21 @@ func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
 
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("user not found: %s", email)
+			return nil, fmt.Errorf("%w: %s", ErrUserNotFound, email)
+		}
+		return nil, fmt.Errorf("error fetching user: %v", result.Error)
+	}
+
+	return &user, nil
+}
+
+func (r *UserRepository) GetUserByID(id uuid.UUID) (*models.User, error) {
+	var user models.User
+	result := r.DB.Preload("UserType").Preload("UserType.Scopes").First(&user, "id = ?", id)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrUserNotFound, id)
 		}
 		return nil, fmt.Errorf("error fetching user: %v", result.Error)
 	}
@@ -108,6 +178,10 @@ func (r *UserRepository) GetUserByAccessToken(token string) (*models.User, error
 	return r.getUserByToken(token, &models.AccessToken{})
 }
 
+func (r *UserRepository) GetUserByRefreshToken(token string) (*models.User, error) {
+	return r.getUserByToken(token, &models.RefreshToken{})
+}
+
 func (r *UserRepository) GetUserByResetToken(token string) (*models.User, error) {
 	return r.getUserByToken(token, &models.ResetToken{})
 }
@@ -116,6 +190,18 @@ func (r *UserRepository) GetUserByVerifyToken(token string) (*models.User, error
 	return r.getUserByToken(token, &models.VerifyToken{})
 }
 
+func (r *UserRepository) GetUserByEmailChangeToken(token string) (*models.User, error) {
+	return r.getUserByToken(token, &models.EmailChangeToken{})
+}
+
+func (r *UserRepository) GetUserByLoginToken(token string) (*models.User, error) {
+	return r.getUserByToken(token, &models.LoginToken{})
+}
+
+func (r *UserRepository) GetUserByMFAToken(token string) (*models.User, error) {
+	return r.getUserByToken(token, &models.MFAToken{})
+}
+
 func (r *UserRepository) UpdateUser(user *models.User) error {
 	if err := user.Validate(); err != nil {
 		return err
@@ -126,3 +212,87 @@ func (r *UserRepository) UpdateUser(user *models.User) error {
 	}
 	return nil
 }
+
+// UpdatePasswordHash stores a new hash of the unchanged password, skipping the hooks run when a password changes.
+func (r *UserRepository) UpdatePasswordHash(user *models.User) error {
+	return r.DB.Model(user).UpdateColumn("hashed_password", user.HashedPassword).Error
+}
+
+// GetPasswordHistory returns the hashes of up to limit previous passwords of the user, newest first.
+func (r *UserRepository) GetPasswordHistory(userId uuid.UUID, limit int) ([]string, error) {
+	var hashes []string
+	if limit <= 0 {
+		return hashes, nil
+	}
+	if err := r.DB.Model(&models.PasswordHistory{}).Where("user_id = ?", userId).
+		Order("created_at desc").Limit(limit).Pluck("hashed_password", &hashes).Error; err != nil {
+		return nil, fmt.Errorf("error fetching password history: %v", err)
+	}
+	return hashes, nil
+}
+
+// AddPasswordHistory records the hash of a replaced password, keeping only the newest keep entries of the user.
+func (r *UserRepository) AddPasswordHistory(userId uuid.UUID, hashedPassword string, keep int) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.PasswordHistory{UserId: userId, HashedPassword: hashedPassword}).Error; err != nil {
+			return err
+		}
+		kept := tx.Model(&models.PasswordHistory{}).Select("id").Where("user_id = ?", userId).
+			Order("created_at desc").Limit(keep)
+		return tx.Where("user_id = ? AND id NOT IN (?)", userId, kept).Delete(&models.PasswordHistory{}).Error
+	})
+}
+
+func (r *UserRepository) ListUsers(filter UserFilter, offset int, limit int) ([]models.User, int64, error) {
+	var user models.User
+	query := r.DB.Model(&models.User{})
+
+	if filter.Search != "" {
+		search := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(filter.Search) + "%"
+		query = query.Where(
+			fmt.Sprintf("(%[1]s.email ILIKE ? OR %[1]s.first_name ILIKE ? OR %[1]s.last_name ILIKE ?)", user.TableName()),
+			search, search, search,
+		)
+	}
+	if filter.UserType != "" {
+		var userType models.UserType
+		query = query.
+			Joins(fmt.Sprintf("JOIN %s ut ON ut.id = %s.user_type_id", userType.TableName(), user.TableName())).
+			Where("ut.name = ?", filter.UserType)
+	}
+	if filter.IsActive != nil {
+		query = query.Where(fmt.Sprintf("%s.is_active = ?", user.TableName()), *filter.IsActive)
+	}
+	if filter.IsVerified != nil {
+		query = query.Where(fmt.Sprintf("%s.is_verified = ?", user.TableName()), *filter.IsVerified)
+	}
+
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("error counting users: %v", err)
+	}
+
+	var users []models.User
+	if err := query.
+		Preload("UserType").Preload("UserType.Scopes").
+		Order(fmt.Sprintf("%s.email", user.TableName())).
+		Offset(offset).Limit(limit).
+		Find(&users).Error; err != nil {
+		return nil, 0, fmt.Errorf("error fetching users: %v", err)
+	}
+
+	return users, total, nil
+}
+
+func (r *UserRepository) DeleteUser(id uuid.UUID) error {
+	result := r.DB.Where("id = ?", id).Delete(&models.User{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrUserNotFound, id)
+	}
+	return nil
+}
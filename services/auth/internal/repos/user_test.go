@@ -3,6 +3,8 @@ package repos_test
 import (
 	"time"
 
+	"github.com/google/uuid"
+
 	"github.com/accentdesign/grpc/services/auth/internal/models"
 	"github.com/accentdesign/grpc/services/auth/internal/repos"
 )
@@ -77,6 +79,38 @@ func (suite *TestSuite) TestUserRepository_CreateUser_DBError() {
 	suite.Nil(duplicate)
 }
 
+func (suite *TestSuite) TestUserRepository_InsertUser() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	userType, err := suite.helpers.CreateTestUserType()
+	suite.NoError(err)
+
+	repo := repos.UserRepository{DB: suite.db}
+
+	user, err := repo.InsertUser(&models.User{
+		Email:      "a@b.com",
+		FirstName:  "Some",
+		LastName:   "One",
+		UserTypeId: userType.ID,
+		IsActive:   false,
+		IsVerified: true,
+	}, "password")
+	suite.NoError(err)
+
+	suite.NotEmpty(user.ID)
+	suite.Equal(userType.ID, user.UserType.ID)
+	suite.True(user.VerifyPassword("password"))
+	suite.False(user.IsActive)
+	suite.True(user.IsVerified)
+
+	// a user can be created without a password
+	user, err = repo.InsertUser(&models.User{Email: "c@d.com", FirstName: "Some", LastName: "One", UserTypeId: userType.ID}, "")
+	suite.NoError(err)
+	suite.False(user.HasPassword())
+	suite.False(user.VerifyPassword(""))
+}
+
 func (suite *TestSuite) TestUserRepository_GetUserByEmail() {
 	teardown := suite.Setup()
 	defer teardown()
@@ -103,6 +137,27 @@ func (suite *TestSuite) TestUserRepository_GetUserByEmail_Error() {
 	suite.Nil(user)
 }
 
+func (suite *TestSuite) TestUserRepository_GetUserByID() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	repo := repos.UserRepository{DB: suite.db}
+
+	fetchedUser, err := repo.GetUserByID(user.ID)
+	suite.NoError(err)
+	suite.Equal(user.ID, fetchedUser.ID)
+	suite.Equal("standard", fetchedUser.UserType.Name)
+
+	id := uuid.New()
+	fetchedUser, err = repo.GetUserByID(id)
+	suite.ErrorIs(err, repos.ErrUserNotFound)
+	suite.Equal("user not found: "+id.String(), err.Error())
+	suite.Nil(fetchedUser)
+}
+
 func (suite *TestSuite) TestUserRepository_GetUserByAccessToken() {
 	teardown := suite.Setup()
 	defer teardown()
@@ -137,8 +192,7 @@ func (suite *TestSuite) TestUserRepository_GetUserByAccessToken_Expired() {
 
 	fetchedUser, err := repo.GetUserByAccessToken(token.Token)
 
-	suite.Error(err)
-	suite.Equal("user not found for token: test_token", err.Error())
+	suite.ErrorIs(err, repos.ErrTokenExpired)
 	suite.Nil(fetchedUser)
 }
 
@@ -176,8 +230,32 @@ func (suite *TestSuite) TestUserRepository_GetUserByResetToken_Expired() {
 
 	fetchedUser, err := repo.GetUserByResetToken(token.Token)
 
-	suite.Error(err)
-	suite.Equal("user not found for token: test_token", err.Error())
+	suite.ErrorIs(err, repos.ErrTokenExpired)
+	suite.Nil(fetchedUser)
+}
+
+func (suite *TestSuite) TestUserRepository_GetUserByResetToken_Used() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	usedAt := time.Now()
+	token := &models.ResetToken{UserId: user.ID, Token: "test_token", ExpiresAt: time.Now().Add(24 * time.Hour), UsedAt: &usedAt}
+	tokenErr := suite.db.Create(token).Error
+	suite.NoError(tokenErr)
+
+	repo := repos.UserRepository{DB: suite.db}
+
+	fetchedUser, err := repo.GetUserByResetToken(token.Token)
+
+	suite.ErrorIs(err, repos.ErrTokenUsed)
+	suite.Nil(fetchedUser)
+
+	fetchedUser, err = repo.GetUserByResetToken("unknown")
+
+	suite.ErrorIs(err, repos.ErrUserNotFound)
 	suite.Nil(fetchedUser)
 }
 
@@ -215,11 +293,53 @@ func (suite *TestSuite) TestUserRepository_GetUserByVerifyToken_Expired() {
 
 	fetchedUser, err := repo.GetUserByVerifyToken(token.Token)
 
-	suite.Error(err)
-	suite.Equal("user not found for token: test_token", err.Error())
+	suite.ErrorIs(err, repos.ErrTokenExpired)
 	suite.Nil(fetchedUser)
 }
 
+func (suite *TestSuite) TestUserRepository_GetUserByRefreshToken() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	token := &models.RefreshToken{UserId: user.ID, FamilyId: uuid.New(), Token: "test_token", ExpiresAt: time.Now().Add(24 * time.Hour)}
+	tokenErr := suite.db.Create(token).Error
+	suite.NoError(tokenErr)
+
+	repo := repos.UserRepository{DB: suite.db}
+
+	fetchedUser, err := repo.GetUserByRefreshToken(token.Token)
+
+	suite.NoError(err)
+	suite.Equal(user.ID, fetchedUser.ID)
+}
+
+func (suite *TestSuite) TestUserRepository_UpdateUser_Deactivate() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	accessToken := &models.AccessToken{UserId: user.ID, Token: "access_token", ExpiresAt: time.Now().Add(24 * time.Hour)}
+	suite.NoError(suite.db.Create(accessToken).Error)
+	refreshToken := &models.RefreshToken{UserId: user.ID, FamilyId: uuid.New(), Token: "refresh_token", ExpiresAt: time.Now().Add(24 * time.Hour)}
+	suite.NoError(suite.db.Create(refreshToken).Error)
+
+	repo := repos.UserRepository{DB: suite.db}
+
+	user.IsActive = false
+	err = repo.UpdateUser(user)
+	suite.NoError(err)
+
+	_, err = repo.GetUserByAccessToken(accessToken.Token)
+	suite.Error(err)
+	_, err = repo.GetUserByRefreshToken(refreshToken.Token)
+	suite.Error(err)
+}
+
 func (suite *TestSuite) TestUserRepository_UpdateUser() {
 	teardown := suite.Setup()
 	defer teardown()
@@ -240,3 +360,98 @@ func (suite *TestSuite) TestUserRepository_UpdateUser() {
 	suite.Error(err)
 	suite.Equal("invalid email format", err.Error())
 }
+
+func (suite *TestSuite) TestUserRepository_ListUsers() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	userType, err := suite.helpers.CreateTestUserType()
+	suite.NoError(err)
+
+	repo := repos.UserRepository{DB: suite.db}
+
+	for _, email := range []string{"c@example.com", "a@example.com", "b@example.com"} {
+		_, err := repo.InsertUser(&models.User{Email: email, FirstName: "Some", LastName: "One", UserTypeId: userType.ID, IsActive: email != "b@example.com"}, "password")
+		suite.NoError(err)
+	}
+
+	users, total, err := repo.ListUsers(repos.UserFilter{}, 0, 2)
+	suite.NoError(err)
+	suite.Equal(int64(3), total)
+	suite.Len(users, 2)
+	suite.Equal("a@example.com", users[0].Email)
+	suite.Equal("b@example.com", users[1].Email)
+	suite.Equal("standard", users[0].UserType.Name)
+
+	users, total, err = repo.ListUsers(repos.UserFilter{}, 2, 2)
+	suite.NoError(err)
+	suite.Equal(int64(3), total)
+	suite.Len(users, 1)
+	suite.Equal("c@example.com", users[0].Email)
+
+	active := false
+	users, total, err = repo.ListUsers(repos.UserFilter{IsActive: &active}, 0, 10)
+	suite.NoError(err)
+	suite.Equal(int64(1), total)
+	suite.Equal("b@example.com", users[0].Email)
+
+	users, total, err = repo.ListUsers(repos.UserFilter{Search: "c@", UserType: "standard"}, 0, 10)
+	suite.NoError(err)
+	suite.Equal(int64(1), total)
+	suite.Equal("c@example.com", users[0].Email)
+
+	users, total, err = repo.ListUsers(repos.UserFilter{UserType: "unknown"}, 0, 10)
+	suite.NoError(err)
+	suite.Equal(int64(0), total)
+	suite.Empty(users)
+}
+
+func (suite *TestSuite) TestUserRepository_DeleteUser() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	repo := repos.UserRepository{DB: suite.db}
+
+	err = repo.DeleteUser(user.ID)
+	suite.NoError(err)
+
+	_, err = repo.GetUserByID(user.ID)
+	suite.ErrorIs(err, repos.ErrUserNotFound)
+
+	err = repo.DeleteUser(user.ID)
+	suite.ErrorIs(err, repos.ErrUserNotFound)
+}
+
+func (suite *TestSuite) TestUserRepository_PasswordHistory() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	repo := repos.UserRepository{DB: suite.db}
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	hashes, err := repo.GetPasswordHistory(user.ID, 2)
+	suite.NoError(err)
+	suite.Empty(hashes)
+
+	for _, hash := range []string{"first", "second", "third"} {
+		suite.NoError(repo.AddPasswordHistory(user.ID, hash, 2))
+	}
+
+	// only the newest entries are kept
+	hashes, err = repo.GetPasswordHistory(user.ID, 5)
+	suite.NoError(err)
+	suite.Equal([]string{"third", "second"}, hashes)
+
+	hashes, err = repo.GetPasswordHistory(user.ID, 1)
+	suite.NoError(err)
+	suite.Equal([]string{"third"}, hashes)
+
+	hashes, err = repo.GetPasswordHistory(user.ID, 0)
+	suite.NoError(err)
+	suite.Empty(hashes)
+}
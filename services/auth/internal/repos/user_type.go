@@ -0,0 +1,168 @@
+package repos
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/accentdesign/grpc/services/auth/internal/models"
+)
+
+var (
+	ErrUserTypeNotFound  = errors.New("user type not found")
+	ErrUserTypeIsDefault = errors.New("user type is the default")
+	ErrUserTypeInUse     = errors.New("user type is in use")
+)
+
+type UserTypeRepository struct {
+	DB *gorm.DB
+}
+
+func orderByName(db *gorm.DB) *gorm.DB {
+	return db.Order("name")
+}
+
+func (r *UserTypeRepository) GetUserTypeByID(id uuid.UUID) (*models.UserType, error) {
+	var userType models.UserType
+	result := r.DB.Preload("Scopes", orderByName).Where("id = ?", id).First(&userType)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrUserTypeNotFound, id)
+		}
+		return nil, fmt.Errorf("error fetching user type: %v", result.Error)
+	}
+
+	return &userType, nil
+}
+
+func (r *UserTypeRepository) GetUserTypeByName(name string) (*models.UserType, error) {
+	var userType models.UserType
+	result := r.DB.Preload("Scopes", orderByName).Where("name = ?", name).First(&userType)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrUserTypeNotFound, name)
+		}
+		return nil, fmt.Errorf("error fetching user type: %v", result.Error)
+	}
+
+	return &userType, nil
+}
+
+func (r *UserTypeRepository) GetDefaultUserType() (*models.UserType, error) {
+	var userType models.UserType
+	result := r.DB.Preload("Scopes", orderByName).Where("is_default is true").First(&userType)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: no default user type exists", ErrUserTypeNotFound)
+		}
+		return nil, fmt.Errorf("error fetching user type: %v", result.Error)
+	}
+
+	return &userType, nil
+}
+
+func (r *UserTypeRepository) ListUserTypes() ([]models.UserType, error) {
+	var userTypes []models.UserType
+	if err := r.DB.Preload("Scopes", orderByName).Order("name").Find(&userTypes).Error; err != nil {
+		return nil, fmt.Errorf("error fetching user types: %v", err)
+	}
+
+	return userTypes, nil
+}
+
+// CreateUserType creates the user type along with its scopes, the first user type created is always the default.
+func (r *UserTypeRepository) CreateUserType(userType *models.UserType) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var defaults int64
+		if err := tx.Model(&models.UserType{}).Where("is_default is true").Count(&defaults).Error; err != nil {
+			return err
+		}
+
+		if defaults == 0 {
+			userType.IsDefault = true
+		}
+
+		if userType.IsDefault {
+			if err := unsetDefaultUserType(tx); err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(userType).Error
+	})
+}
+
+// UpdateUserType updates the name and default state of the user type, there must always be one default user type
+// so making a user type the default unsets the previous one, and the default can not be unset directly.
+func (r *UserTypeRepository) UpdateUserType(userType *models.UserType) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var current models.UserType
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", userType.ID).First(&current).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: %s", ErrUserTypeNotFound, userType.ID)
+			}
+			return err
+		}
+
+		if current.IsDefault && !userType.IsDefault {
+			return fmt.Errorf("%w: %s", ErrUserTypeIsDefault, current.Name)
+		}
+
+		if userType.IsDefault && !current.IsDefault {
+			if err := unsetDefaultUserType(tx); err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&current).Updates(map[string]interface{}{
+			"name":       userType.Name,
+			"is_default": userType.IsDefault,
+		}).Error
+	})
+}
+
+func (r *UserTypeRepository) SetUserTypeScopes(userType *models.UserType, scopes []models.Scope) error {
+	if err := r.DB.Model(userType).Association("Scopes").Replace(scopes); err != nil {
+		return err
+	}
+
+	userType.Scopes = scopes
+	return nil
+}
+
+// DeleteUserType deletes the user type, the default user type and user types that are assigned to users can not be deleted.
+func (r *UserTypeRepository) DeleteUserType(id uuid.UUID) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var userType models.UserType
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&userType).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: %s", ErrUserTypeNotFound, id)
+			}
+			return err
+		}
+
+		if userType.IsDefault {
+			return fmt.Errorf("%w: %s", ErrUserTypeIsDefault, userType.Name)
+		}
+
+		var users int64
+		if err := tx.Model(&models.User{}).Where("user_type_id = ?", id).Count(&users).Error; err != nil {
+			return err
+		}
+		if users > 0 {
+			return fmt.Errorf("%w: %s", ErrUserTypeInUse, userType.Name)
+		}
+
+		return tx.Select("Scopes").Delete(&userType).Error
+	})
+}
+
+func unsetDefaultUserType(tx *gorm.DB) error {
+	return tx.Model(&models.UserType{}).Where("is_default is true").Update("is_default", false).Error
+}
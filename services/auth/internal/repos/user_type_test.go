@@ -0,0 +1,170 @@
+package repos_test
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/accentdesign/grpc/services/auth/internal/models"
+	"github.com/accentdesign/grpc/services/auth/internal/repos"
+)
+
+func (suite *TestSuite) TestUserTypeRepository_GetUserTypeByName() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	userType, err := suite.helpers.CreateTestUserType()
+	suite.NoError(err)
+
+	repo := repos.UserTypeRepository{DB: suite.db}
+
+	fetched, err := repo.GetUserTypeByName(userType.Name)
+	suite.NoError(err)
+	suite.Equal(userType.ID, fetched.ID)
+
+	fetched, err = repo.GetUserTypeByName("unknown")
+	suite.ErrorIs(err, repos.ErrUserTypeNotFound)
+	suite.Equal("user type not found: unknown", err.Error())
+	suite.Nil(fetched)
+}
+
+func (suite *TestSuite) TestUserTypeRepository_GetDefaultUserType() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	repo := repos.UserTypeRepository{DB: suite.db}
+
+	fetched, err := repo.GetDefaultUserType()
+	suite.ErrorIs(err, repos.ErrUserTypeNotFound)
+	suite.Nil(fetched)
+
+	userType, err := suite.helpers.CreateTestUserType()
+	suite.NoError(err)
+
+	fetched, err = repo.GetDefaultUserType()
+	suite.NoError(err)
+	suite.Equal(userType.ID, fetched.ID)
+	suite.True(fetched.IsDefault)
+}
+
+func (suite *TestSuite) TestUserTypeRepository_CreateUserType() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	repo := repos.UserTypeRepository{DB: suite.db}
+
+	// the first user type is always the default
+	first := &models.UserType{Name: "first"}
+	err := repo.CreateUserType(first)
+	suite.NoError(err)
+	suite.True(first.IsDefault)
+
+	second := &models.UserType{Name: "second"}
+	err = repo.CreateUserType(second)
+	suite.NoError(err)
+	suite.False(second.IsDefault)
+
+	third := &models.UserType{Name: "third", IsDefault: true}
+	err = repo.CreateUserType(third)
+	suite.NoError(err)
+
+	fetched, err := repo.GetDefaultUserType()
+	suite.NoError(err)
+	suite.Equal(third.ID, fetched.ID)
+
+	userTypes, err := repo.ListUserTypes()
+	suite.NoError(err)
+	suite.Len(userTypes, 3)
+	suite.False(userTypes[0].IsDefault)
+	suite.False(userTypes[1].IsDefault)
+	suite.True(userTypes[2].IsDefault)
+}
+
+func (suite *TestSuite) TestUserTypeRepository_UpdateUserType() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	repo := repos.UserTypeRepository{DB: suite.db}
+
+	first := &models.UserType{Name: "first"}
+	suite.NoError(repo.CreateUserType(first))
+
+	second := &models.UserType{Name: "second"}
+	suite.NoError(repo.CreateUserType(second))
+
+	first.IsDefault = false
+	err := repo.UpdateUserType(first)
+	suite.ErrorIs(err, repos.ErrUserTypeIsDefault)
+
+	second.Name = "renamed"
+	second.IsDefault = true
+	err = repo.UpdateUserType(second)
+	suite.NoError(err)
+
+	fetched, err := repo.GetDefaultUserType()
+	suite.NoError(err)
+	suite.Equal(second.ID, fetched.ID)
+	suite.Equal("renamed", fetched.Name)
+
+	err = repo.UpdateUserType(&models.UserType{ID: uuid.New(), Name: "unknown"})
+	suite.ErrorIs(err, repos.ErrUserTypeNotFound)
+}
+
+func (suite *TestSuite) TestUserTypeRepository_SetUserTypeScopes() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	repo := repos.UserTypeRepository{DB: suite.db}
+	scopeRepo := repos.ScopeRepository{DB: suite.db}
+
+	read := &models.Scope{Name: "read"}
+	suite.NoError(scopeRepo.CreateScope(read))
+	write := &models.Scope{Name: "write"}
+	suite.NoError(scopeRepo.CreateScope(write))
+
+	userType := &models.UserType{Name: "user", Scopes: []models.Scope{*read}}
+	suite.NoError(repo.CreateUserType(userType))
+
+	err := repo.SetUserTypeScopes(userType, []models.Scope{*write})
+	suite.NoError(err)
+
+	fetched, err := repo.GetUserTypeByID(userType.ID)
+	suite.NoError(err)
+	suite.Equal([]string{"write"}, fetched.ScopeNames())
+
+	err = repo.SetUserTypeScopes(userType, []models.Scope{})
+	suite.NoError(err)
+
+	fetched, err = repo.GetUserTypeByID(userType.ID)
+	suite.NoError(err)
+	suite.Empty(fetched.Scopes)
+}
+
+func (suite *TestSuite) TestUserTypeRepository_DeleteUserType() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	repo := repos.UserTypeRepository{DB: suite.db}
+
+	err = repo.DeleteUserType(user.UserTypeId)
+	suite.ErrorIs(err, repos.ErrUserTypeIsDefault)
+
+	other := &models.UserType{Name: "other", IsDefault: true}
+	suite.NoError(repo.CreateUserType(other))
+
+	err = repo.DeleteUserType(user.UserTypeId)
+	suite.ErrorIs(err, repos.ErrUserTypeInUse)
+
+	unused := &models.UserType{Name: "unused", Scopes: []models.Scope{{Name: "read"}}}
+	suite.NoError(repo.CreateUserType(unused))
+
+	err = repo.DeleteUserType(unused.ID)
+	suite.NoError(err)
+
+	_, err = repo.GetUserTypeByID(unused.ID)
+	suite.ErrorIs(err, repos.ErrUserTypeNotFound)
+
+	err = repo.DeleteUserType(unused.ID)
+	suite.ErrorIs(err, repos.ErrUserTypeNotFound)
+}
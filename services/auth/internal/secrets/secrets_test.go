@@ -0,0 +1,81 @@
+package secrets_test
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/accentdesign/grpc/services/auth/internal/secrets"
+)
+
+type TestSuite struct {
+	suite.Suite
+	cipher *secrets.Cipher
+}
+
+func (suite *TestSuite) SetupSuite() {
+	key, err := secrets.ParseKey(base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))))
+	suite.NoError(err)
+
+	suite.cipher, err = secrets.NewCipher(key)
+	suite.NoError(err)
+}
+
+func TestTestSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+func (suite *TestSuite) TestParseKey() {
+	_, err := secrets.ParseKey("not base64!")
+	suite.Error(err)
+
+	_, err = secrets.ParseKey(base64.StdEncoding.EncodeToString([]byte("short")))
+	suite.EqualError(err, "invalid key: must be 32 bytes, got 5")
+}
+
+func (suite *TestSuite) TestEncryptDecrypt() {
+	encrypted, err := suite.cipher.Encrypt("secret")
+	suite.NoError(err)
+	suite.NotContains(encrypted, "secret")
+
+	// every encryption uses a new nonce
+	other, err := suite.cipher.Encrypt("secret")
+	suite.NoError(err)
+	suite.NotEqual(encrypted, other)
+
+	decrypted, err := suite.cipher.Decrypt(encrypted)
+	suite.NoError(err)
+	suite.Equal("secret", decrypted)
+}
+
+func (suite *TestSuite) TestDecryptInvalid() {
+	encrypted, err := suite.cipher.Encrypt("secret")
+	suite.NoError(err)
+
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	suite.NoError(err)
+	sealed[len(sealed)-1] ^= 1
+
+	testCases := []struct {
+		desc  string
+		value string
+	}{
+		{"not base64", "not base64!"},
+		{"too short", base64.StdEncoding.EncodeToString([]byte("short"))},
+		{"tampered", base64.StdEncoding.EncodeToString(sealed)},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.desc, func() {
+			_, err := suite.cipher.Decrypt(tc.value)
+			suite.ErrorIs(err, secrets.ErrInvalidCiphertext)
+		})
+	}
+
+	otherKey, err := secrets.NewCipher([]byte(strings.Repeat("o", 32)))
+	suite.NoError(err)
+	_, err = otherKey.Decrypt(encrypted)
+	suite.ErrorIs(err, secrets.ErrInvalidCiphertext)
+}
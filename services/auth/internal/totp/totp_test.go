@@ -0,0 +1,106 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/accentdesign/grpc/services/auth/internal/totp"
+)
+
+// rfcSecret is the SHA1 secret of the RFC 6238 test vectors
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+type TestSuite struct {
+	suite.Suite
+}
+
+func TestTestSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+func (suite *TestSuite) TestCode() {
+	testCases := []struct {
+		time     int64
+		expected string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tc := range testCases {
+		code, err := totp.Code(rfcSecret, totp.Step(time.Unix(tc.time, 0)))
+		suite.NoError(err)
+		suite.Equal(tc.expected, code)
+	}
+
+	_, err := totp.Code("not base32!", 1)
+	suite.Error(err)
+}
+
+func (suite *TestSuite) TestValidate() {
+	now := time.Unix(1234567890, 0)
+
+	step, ok := totp.Validate(rfcSecret, "005924", now)
+	suite.True(ok)
+	suite.Equal(totp.Step(now), step)
+
+	// codes from the previous and next period are accepted
+	previous, err := totp.Code(rfcSecret, totp.Step(now)-1)
+	suite.NoError(err)
+	step, ok = totp.Validate(rfcSecret, previous, now)
+	suite.True(ok)
+	suite.Equal(totp.Step(now)-1, step)
+
+	next, err := totp.Code(rfcSecret, totp.Step(now)+1)
+	suite.NoError(err)
+	_, ok = totp.Validate(rfcSecret, next, now)
+	suite.True(ok)
+
+	// but not from further away
+	old, err := totp.Code(rfcSecret, totp.Step(now)-2)
+	suite.NoError(err)
+	_, ok = totp.Validate(rfcSecret, old, now)
+	suite.False(ok)
+
+	_, ok = totp.Validate(rfcSecret, "000000", now)
+	suite.False(ok)
+
+	_, ok = totp.Validate(rfcSecret, "5924", now)
+	suite.False(ok)
+}
+
+func (suite *TestSuite) TestGenerateSecret() {
+	secret, err := totp.GenerateSecret()
+	suite.NoError(err)
+	suite.Len(secret, 32)
+
+	other, err := totp.GenerateSecret()
+	suite.NoError(err)
+	suite.NotEqual(secret, other)
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	suite.NoError(err)
+	_, ok := totp.Validate(secret, code, time.Now())
+	suite.True(ok)
+}
+
+func (suite *TestSuite) TestURI() {
+	uri := totp.URI("Accent Design", "someone@example.com", rfcSecret)
+
+	parsed, err := url.Parse(uri)
+	suite.NoError(err)
+	suite.Equal("otpauth", parsed.Scheme)
+	suite.Equal("totp", parsed.Host)
+	suite.Equal("/Accent Design:someone@example.com", parsed.Path)
+	suite.Equal(rfcSecret, parsed.Query().Get("secret"))
+	suite.Equal("Accent Design", parsed.Query().Get("issuer"))
+	suite.Equal("6", parsed.Query().Get("digits"))
+	suite.Equal("30", parsed.Query().Get("period"))
+}
@@ -23,6 +23,7 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type AuthenticationClient interface {
 	BearerToken(ctx context.Context, in *BearerTokenRequest, opts ...grpc.CallOption) (*BearerTokenResponse, error)
+	RefreshBearerToken(ctx context.Context, in *Token, opts ...grpc.CallOption) (*BearerTokenResponse, error)
 	RevokeBearerToken(ctx context.Context, in *Token, opts ...grpc.CallOption) (*Empty, error)
 	Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*UserResponse, error)
 	ResetPassword(ctx context.Context, in *ResetPasswordRequest, opts ...grpc.CallOption) (*Empty, error)
@@ -31,6 +32,24 @@ type AuthenticationClient interface {
 	UpdateUser(ctx context.Context, in *UpdateUserRequest, opts ...grpc.CallOption) (*UserResponse, error)
 	VerifyUser(ctx context.Context, in *Token, opts ...grpc.CallOption) (*UserResponse, error)
 	VerifyUserToken(ctx context.Context, in *VerifyUserTokenRequest, opts ...grpc.CallOption) (*TokenWithEmail, error)
+	GetJWKS(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*JWKSResponse, error)
+	CompleteMFA(ctx context.Context, in *CompleteMFARequest, opts ...grpc.CallOption) (*BearerTokenResponse, error)
+	BeginTOTPEnrollment(ctx context.Context, in *Token, opts ...grpc.CallOption) (*TOTPEnrollmentResponse, error)
+	ConfirmTOTPEnrollment(ctx context.Context, in *TOTPCodeRequest, opts ...grpc.CallOption) (*RecoveryCodesResponse, error)
+	DisableTOTP(ctx context.Context, in *TOTPCodeRequest, opts ...grpc.CallOption) (*Empty, error)
+	ListSessions(ctx context.Context, in *Token, opts ...grpc.CallOption) (*ListSessionsResponse, error)
+	RevokeSession(ctx context.Context, in *RevokeSessionRequest, opts ...grpc.CallOption) (*Empty, error)
+	RevokeAllSessions(ctx context.Context, in *RevokeAllSessionsRequest, opts ...grpc.CallOption) (*Empty, error)
+	RequestEmailChange(ctx context.Context, in *EmailChangeRequest, opts ...grpc.CallOption) (*EmailChangeTokenResponse, error)
+	ConfirmEmailChange(ctx context.Context, in *Token, opts ...grpc.CallOption) (*UserResponse, error)
+	RequestLoginLink(ctx context.Context, in *LoginTokenRequest, opts ...grpc.CallOption) (*TokenWithEmail, error)
+	RequestLoginCode(ctx context.Context, in *LoginTokenRequest, opts ...grpc.CallOption) (*LoginCodeResponse, error)
+	RedeemLoginToken(ctx context.Context, in *RedeemLoginTokenRequest, opts ...grpc.CallOption) (*BearerTokenResponse, error)
+	BeginOIDCLogin(ctx context.Context, in *OIDCProviderRequest, opts ...grpc.CallOption) (*OIDCAuthorizationResponse, error)
+	OIDCLogin(ctx context.Context, in *OIDCCodeRequest, opts ...grpc.CallOption) (*BearerTokenResponse, error)
+	LinkIdentity(ctx context.Context, in *OIDCCodeRequest, opts ...grpc.CallOption) (*IdentityResponse, error)
+	UnlinkIdentity(ctx context.Context, in *UnlinkIdentityRequest, opts ...grpc.CallOption) (*Empty, error)
+	ListIdentities(ctx context.Context, in *Token, opts ...grpc.CallOption) (*ListIdentitiesResponse, error)
 }
 
 type authenticationClient struct {
@@ -50,6 +69,15 @@ func (c *authenticationClient) BearerToken(ctx context.Context, in *BearerTokenR
 	return out, nil
 }
 
+func (c *authenticationClient) RefreshBearerToken(ctx context.Context, in *Token, opts ...grpc.CallOption) (*BearerTokenResponse, error) {
+	out := new(BearerTokenResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.Authentication/RefreshBearerToken", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *authenticationClient) RevokeBearerToken(ctx context.Context, in *Token, opts ...grpc.CallOption) (*Empty, error) {
 	out := new(Empty)
 	err := c.cc.Invoke(ctx, "/pkg.auth.Authentication/RevokeBearerToken", in, out, opts...)
@@ -122,11 +150,174 @@ func (c *authenticationClient) VerifyUserToken(ctx context.Context, in *VerifyUs
 	return out, nil
 }
 
+func (c *authenticationClient) GetJWKS(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*JWKSResponse, error) {
+	out := new(JWKSResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.Authentication/GetJWKS", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationClient) CompleteMFA(ctx context.Context, in *CompleteMFARequest, opts ...grpc.CallOption) (*BearerTokenResponse, error) {
+	out := new(BearerTokenResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.Authentication/CompleteMFA", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationClient) BeginTOTPEnrollment(ctx context.Context, in *Token, opts ...grpc.CallOption) (*TOTPEnrollmentResponse, error) {
+	out := new(TOTPEnrollmentResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.Authentication/BeginTOTPEnrollment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationClient) ConfirmTOTPEnrollment(ctx context.Context, in *TOTPCodeRequest, opts ...grpc.CallOption) (*RecoveryCodesResponse, error) {
+	out := new(RecoveryCodesResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.Authentication/ConfirmTOTPEnrollment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationClient) DisableTOTP(ctx context.Context, in *TOTPCodeRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/pkg.auth.Authentication/DisableTOTP", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationClient) ListSessions(ctx context.Context, in *Token, opts ...grpc.CallOption) (*ListSessionsResponse, error) {
+	out := new(ListSessionsResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.Authentication/ListSessions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationClient) RevokeSession(ctx context.Context, in *RevokeSessionRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/pkg.auth.Authentication/RevokeSession", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationClient) RevokeAllSessions(ctx context.Context, in *RevokeAllSessionsRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/pkg.auth.Authentication/RevokeAllSessions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationClient) RequestEmailChange(ctx context.Context, in *EmailChangeRequest, opts ...grpc.CallOption) (*EmailChangeTokenResponse, error) {
+	out := new(EmailChangeTokenResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.Authentication/RequestEmailChange", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationClient) ConfirmEmailChange(ctx context.Context, in *Token, opts ...grpc.CallOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.Authentication/ConfirmEmailChange", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationClient) RequestLoginLink(ctx context.Context, in *LoginTokenRequest, opts ...grpc.CallOption) (*TokenWithEmail, error) {
+	out := new(TokenWithEmail)
+	err := c.cc.Invoke(ctx, "/pkg.auth.Authentication/RequestLoginLink", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationClient) RequestLoginCode(ctx context.Context, in *LoginTokenRequest, opts ...grpc.CallOption) (*LoginCodeResponse, error) {
+	out := new(LoginCodeResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.Authentication/RequestLoginCode", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationClient) RedeemLoginToken(ctx context.Context, in *RedeemLoginTokenRequest, opts ...grpc.CallOption) (*BearerTokenResponse, error) {
+	out := new(BearerTokenResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.Authentication/RedeemLoginToken", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationClient) BeginOIDCLogin(ctx context.Context, in *OIDCProviderRequest, opts ...grpc.CallOption) (*OIDCAuthorizationResponse, error) {
+	out := new(OIDCAuthorizationResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.Authentication/BeginOIDCLogin", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationClient) OIDCLogin(ctx context.Context, in *OIDCCodeRequest, opts ...grpc.CallOption) (*BearerTokenResponse, error) {
+	out := new(BearerTokenResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.Authentication/OIDCLogin", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationClient) LinkIdentity(ctx context.Context, in *OIDCCodeRequest, opts ...grpc.CallOption) (*IdentityResponse, error) {
+	out := new(IdentityResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.Authentication/LinkIdentity", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationClient) UnlinkIdentity(ctx context.Context, in *UnlinkIdentityRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/pkg.auth.Authentication/UnlinkIdentity", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationClient) ListIdentities(ctx context.Context, in *Token, opts ...grpc.CallOption) (*ListIdentitiesResponse, error) {
+	out := new(ListIdentitiesResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.Authentication/ListIdentities", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AuthenticationServer is the server API for Authentication service.
 // All implementations must embed UnimplementedAuthenticationServer
 // for forward compatibility
 type AuthenticationServer interface {
 	BearerToken(context.Context, *BearerTokenRequest) (*BearerTokenResponse, error)
+	RefreshBearerToken(context.Context, *Token) (*BearerTokenResponse, error)
 	RevokeBearerToken(context.Context, *Token) (*Empty, error)
 	Register(context.Context, *RegisterRequest) (*UserResponse, error)
 	ResetPassword(context.Context, *ResetPasswordRequest) (*Empty, error)
@@ -135,6 +326,24 @@ type AuthenticationServer interface {
 	UpdateUser(context.Context, *UpdateUserRequest) (*UserResponse, error)
 	VerifyUser(context.Context, *Token) (*UserResponse, error)
 	VerifyUserToken(context.Context, *VerifyUserTokenRequest) (*TokenWithEmail, error)
+	GetJWKS(context.Context, *Empty) (*JWKSResponse, error)
+	CompleteMFA(context.Context, *CompleteMFARequest) (*BearerTokenResponse, error)
+	BeginTOTPEnrollment(context.Context, *Token) (*TOTPEnrollmentResponse, error)
+	ConfirmTOTPEnrollment(context.Context, *TOTPCodeRequest) (*RecoveryCodesResponse, error)
+	DisableTOTP(context.Context, *TOTPCodeRequest) (*Empty, error)
+	ListSessions(context.Context, *Token) (*ListSessionsResponse, error)
+	RevokeSession(context.Context, *RevokeSessionRequest) (*Empty, error)
+	RevokeAllSessions(context.Context, *RevokeAllSessionsRequest) (*Empty, error)
+	RequestEmailChange(context.Context, *EmailChangeRequest) (*EmailChangeTokenResponse, error)
+	ConfirmEmailChange(context.Context, *Token) (*UserResponse, error)
+	RequestLoginLink(context.Context, *LoginTokenRequest) (*TokenWithEmail, error)
+	RequestLoginCode(context.Context, *LoginTokenRequest) (*LoginCodeResponse, error)
+	RedeemLoginToken(context.Context, *RedeemLoginTokenRequest) (*BearerTokenResponse, error)
+	BeginOIDCLogin(context.Context, *OIDCProviderRequest) (*OIDCAuthorizationResponse, error)
+	OIDCLogin(context.Context, *OIDCCodeRequest) (*BearerTokenResponse, error)
+	LinkIdentity(context.Context, *OIDCCodeRequest) (*IdentityResponse, error)
+	UnlinkIdentity(context.Context, *UnlinkIdentityRequest) (*Empty, error)
+	ListIdentities(context.Context, *Token) (*ListIdentitiesResponse, error)
 	mustEmbedUnimplementedAuthenticationServer()
 }
 
@@ -145,6 +354,9 @@ type UnimplementedAuthenticationServer struct {
 func (UnimplementedAuthenticationServer) BearerToken(context.Context, *BearerTokenRequest) (*BearerTokenResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method BearerToken not implemented")
 }
+func (UnimplementedAuthenticationServer) RefreshBearerToken(context.Context, *Token) (*BearerTokenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RefreshBearerToken not implemented")
+}
 func (UnimplementedAuthenticationServer) RevokeBearerToken(context.Context, *Token) (*Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RevokeBearerToken not implemented")
 }
@@ -169,6 +381,60 @@ func (UnimplementedAuthenticationServer) VerifyUser(context.Context, *Token) (*U
 func (UnimplementedAuthenticationServer) VerifyUserToken(context.Context, *VerifyUserTokenRequest) (*TokenWithEmail, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method VerifyUserToken not implemented")
 }
+func (UnimplementedAuthenticationServer) GetJWKS(context.Context, *Empty) (*JWKSResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetJWKS not implemented")
+}
+func (UnimplementedAuthenticationServer) CompleteMFA(context.Context, *CompleteMFARequest) (*BearerTokenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CompleteMFA not implemented")
+}
+func (UnimplementedAuthenticationServer) BeginTOTPEnrollment(context.Context, *Token) (*TOTPEnrollmentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BeginTOTPEnrollment not implemented")
+}
+func (UnimplementedAuthenticationServer) ConfirmTOTPEnrollment(context.Context, *TOTPCodeRequest) (*RecoveryCodesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConfirmTOTPEnrollment not implemented")
+}
+func (UnimplementedAuthenticationServer) DisableTOTP(context.Context, *TOTPCodeRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DisableTOTP not implemented")
+}
+func (UnimplementedAuthenticationServer) ListSessions(context.Context, *Token) (*ListSessionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSessions not implemented")
+}
+func (UnimplementedAuthenticationServer) RevokeSession(context.Context, *RevokeSessionRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeSession not implemented")
+}
+func (UnimplementedAuthenticationServer) RevokeAllSessions(context.Context, *RevokeAllSessionsRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeAllSessions not implemented")
+}
+func (UnimplementedAuthenticationServer) RequestEmailChange(context.Context, *EmailChangeRequest) (*EmailChangeTokenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestEmailChange not implemented")
+}
+func (UnimplementedAuthenticationServer) ConfirmEmailChange(context.Context, *Token) (*UserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConfirmEmailChange not implemented")
+}
+func (UnimplementedAuthenticationServer) RequestLoginLink(context.Context, *LoginTokenRequest) (*TokenWithEmail, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestLoginLink not implemented")
+}
+func (UnimplementedAuthenticationServer) RequestLoginCode(context.Context, *LoginTokenRequest) (*LoginCodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestLoginCode not implemented")
+}
+func (UnimplementedAuthenticationServer) RedeemLoginToken(context.Context, *RedeemLoginTokenRequest) (*BearerTokenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RedeemLoginToken not implemented")
+}
+func (UnimplementedAuthenticationServer) BeginOIDCLogin(context.Context, *OIDCProviderRequest) (*OIDCAuthorizationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BeginOIDCLogin not implemented")
+}
+func (UnimplementedAuthenticationServer) OIDCLogin(context.Context, *OIDCCodeRequest) (*BearerTokenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method OIDCLogin not implemented")
+}
+func (UnimplementedAuthenticationServer) LinkIdentity(context.Context, *OIDCCodeRequest) (*IdentityResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LinkIdentity not implemented")
+}
+func (UnimplementedAuthenticationServer) UnlinkIdentity(context.Context, *UnlinkIdentityRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnlinkIdentity not implemented")
+}
+func (UnimplementedAuthenticationServer) ListIdentities(context.Context, *Token) (*ListIdentitiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListIdentities not implemented")
+}
 func (UnimplementedAuthenticationServer) mustEmbedUnimplementedAuthenticationServer() {}
 
 // UnsafeAuthenticationServer may be embedded to opt out of forward compatibility for this service.
@@ -200,6 +466,24 @@ func _Authentication_BearerToken_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Authentication_RefreshBearerToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Token)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationServer).RefreshBearerToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.Authentication/RefreshBearerToken",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationServer).RefreshBearerToken(ctx, req.(*Token))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Authentication_RevokeBearerToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(Token)
 	if err := dec(in); err != nil {
@@ -344,48 +628,1182 @@ func _Authentication_VerifyUserToken_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
-// Authentication_ServiceDesc is the grpc.ServiceDesc for Authentication service.
-// It's only intended for direct use with grpc.RegisterService,
-// and not to be introspected or modified (even as a copy)
-var Authentication_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "pkg.auth.Authentication",
-	HandlerType: (*AuthenticationServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "BearerToken",
-			Handler:    _Authentication_BearerToken_Handler,
-		},
-		{
-			MethodName: "RevokeBearerToken",
-			Handler:    _Authentication_RevokeBearerToken_Handler,
-		},
-		{
-			MethodName: "Register",
-			Handler:    _Authentication_Register_Handler,
-		},
-		{
-			MethodName: "ResetPassword",
-			Handler:    _Authentication_ResetPassword_Handler,
-		},
-		{
-			MethodName: "ResetPasswordToken",
-			Handler:    _Authentication_ResetPasswordToken_Handler,
-		},
-		{
-			MethodName: "User",
-			Handler:    _Authentication_User_Handler,
-		},
-		{
-			MethodName: "UpdateUser",
-			Handler:    _Authentication_UpdateUser_Handler,
-		},
-		{
-			MethodName: "VerifyUser",
-			Handler:    _Authentication_VerifyUser_Handler,
-		},
-		{
-			MethodName: "VerifyUserToken",
-			Handler:    _Authentication_VerifyUserToken_Handler,
+func _Authentication_GetJWKS_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationServer).GetJWKS(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.Authentication/GetJWKS",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationServer).GetJWKS(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authentication_CompleteMFA_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompleteMFARequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationServer).CompleteMFA(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.Authentication/CompleteMFA",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationServer).CompleteMFA(ctx, req.(*CompleteMFARequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authentication_BeginTOTPEnrollment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Token)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationServer).BeginTOTPEnrollment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.Authentication/BeginTOTPEnrollment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationServer).BeginTOTPEnrollment(ctx, req.(*Token))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authentication_ConfirmTOTPEnrollment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TOTPCodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationServer).ConfirmTOTPEnrollment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.Authentication/ConfirmTOTPEnrollment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationServer).ConfirmTOTPEnrollment(ctx, req.(*TOTPCodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authentication_DisableTOTP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TOTPCodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationServer).DisableTOTP(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.Authentication/DisableTOTP",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationServer).DisableTOTP(ctx, req.(*TOTPCodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authentication_ListSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Token)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationServer).ListSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.Authentication/ListSessions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationServer).ListSessions(ctx, req.(*Token))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authentication_RevokeSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationServer).RevokeSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.Authentication/RevokeSession",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationServer).RevokeSession(ctx, req.(*RevokeSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authentication_RevokeAllSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeAllSessionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationServer).RevokeAllSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.Authentication/RevokeAllSessions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationServer).RevokeAllSessions(ctx, req.(*RevokeAllSessionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authentication_RequestEmailChange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmailChangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationServer).RequestEmailChange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.Authentication/RequestEmailChange",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationServer).RequestEmailChange(ctx, req.(*EmailChangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authentication_ConfirmEmailChange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Token)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationServer).ConfirmEmailChange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.Authentication/ConfirmEmailChange",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationServer).ConfirmEmailChange(ctx, req.(*Token))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authentication_RequestLoginLink_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoginTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationServer).RequestLoginLink(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.Authentication/RequestLoginLink",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationServer).RequestLoginLink(ctx, req.(*LoginTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authentication_RequestLoginCode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoginTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationServer).RequestLoginCode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.Authentication/RequestLoginCode",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationServer).RequestLoginCode(ctx, req.(*LoginTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authentication_RedeemLoginToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RedeemLoginTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationServer).RedeemLoginToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.Authentication/RedeemLoginToken",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationServer).RedeemLoginToken(ctx, req.(*RedeemLoginTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authentication_BeginOIDCLogin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OIDCProviderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationServer).BeginOIDCLogin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.Authentication/BeginOIDCLogin",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationServer).BeginOIDCLogin(ctx, req.(*OIDCProviderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authentication_OIDCLogin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OIDCCodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationServer).OIDCLogin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.Authentication/OIDCLogin",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationServer).OIDCLogin(ctx, req.(*OIDCCodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authentication_LinkIdentity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OIDCCodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationServer).LinkIdentity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.Authentication/LinkIdentity",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationServer).LinkIdentity(ctx, req.(*OIDCCodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authentication_UnlinkIdentity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnlinkIdentityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationServer).UnlinkIdentity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.Authentication/UnlinkIdentity",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationServer).UnlinkIdentity(ctx, req.(*UnlinkIdentityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Authentication_ListIdentities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Token)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationServer).ListIdentities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.Authentication/ListIdentities",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationServer).ListIdentities(ctx, req.(*Token))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Authentication_ServiceDesc is the grpc.ServiceDesc for Authentication service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Authentication_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pkg.auth.Authentication",
+	HandlerType: (*AuthenticationServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "BearerToken",
+			Handler:    _Authentication_BearerToken_Handler,
+		},
+		{
+			MethodName: "RefreshBearerToken",
+			Handler:    _Authentication_RefreshBearerToken_Handler,
+		},
+		{
+			MethodName: "RevokeBearerToken",
+			Handler:    _Authentication_RevokeBearerToken_Handler,
+		},
+		{
+			MethodName: "Register",
+			Handler:    _Authentication_Register_Handler,
+		},
+		{
+			MethodName: "ResetPassword",
+			Handler:    _Authentication_ResetPassword_Handler,
+		},
+		{
+			MethodName: "ResetPasswordToken",
+			Handler:    _Authentication_ResetPasswordToken_Handler,
+		},
+		{
+			MethodName: "User",
+			Handler:    _Authentication_User_Handler,
+		},
+		{
+			MethodName: "UpdateUser",
+			Handler:    _Authentication_UpdateUser_Handler,
+		},
+		{
+			MethodName: "VerifyUser",
+			Handler:    _Authentication_VerifyUser_Handler,
+		},
+		{
+			MethodName: "VerifyUserToken",
+			Handler:    _Authentication_VerifyUserToken_Handler,
+		},
+		{
+			MethodName: "GetJWKS",
+			Handler:    _Authentication_GetJWKS_Handler,
+		},
+		{
+			MethodName: "CompleteMFA",
+			Handler:    _Authentication_CompleteMFA_Handler,
+		},
+		{
+			MethodName: "BeginTOTPEnrollment",
+			Handler:    _Authentication_BeginTOTPEnrollment_Handler,
+		},
+		{
+			MethodName: "ConfirmTOTPEnrollment",
+			Handler:    _Authentication_ConfirmTOTPEnrollment_Handler,
+		},
+		{
+			MethodName: "DisableTOTP",
+			Handler:    _Authentication_DisableTOTP_Handler,
+		},
+		{
+			MethodName: "ListSessions",
+			Handler:    _Authentication_ListSessions_Handler,
+		},
+		{
+			MethodName: "RevokeSession",
+			Handler:    _Authentication_RevokeSession_Handler,
+		},
+		{
+			MethodName: "RevokeAllSessions",
+			Handler:    _Authentication_RevokeAllSessions_Handler,
+		},
+		{
+			MethodName: "RequestEmailChange",
+			Handler:    _Authentication_RequestEmailChange_Handler,
+		},
+		{
+			MethodName: "ConfirmEmailChange",
+			Handler:    _Authentication_ConfirmEmailChange_Handler,
+		},
+		{
+			MethodName: "RequestLoginLink",
+			Handler:    _Authentication_RequestLoginLink_Handler,
+		},
+		{
+			MethodName: "RequestLoginCode",
+			Handler:    _Authentication_RequestLoginCode_Handler,
+		},
+		{
+			MethodName: "RedeemLoginToken",
+			Handler:    _Authentication_RedeemLoginToken_Handler,
+		},
+		{
+			MethodName: "BeginOIDCLogin",
+			Handler:    _Authentication_BeginOIDCLogin_Handler,
+		},
+		{
+			MethodName: "OIDCLogin",
+			Handler:    _Authentication_OIDCLogin_Handler,
+		},
+		{
+			MethodName: "LinkIdentity",
+			Handler:    _Authentication_LinkIdentity_Handler,
+		},
+		{
+			MethodName: "UnlinkIdentity",
+			Handler:    _Authentication_UnlinkIdentity_Handler,
+		},
+		{
+			MethodName: "ListIdentities",
+			Handler:    _Authentication_ListIdentities_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "auth.proto",
+}
+
+// AuthenticationAdminClient is the client API for AuthenticationAdmin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AuthenticationAdminClient interface {
+	ListUsers(ctx context.Context, in *ListUsersRequest, opts ...grpc.CallOption) (*ListUsersResponse, error)
+	GetUser(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*UserResponse, error)
+	CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*UserResponse, error)
+	UpdateUser(ctx context.Context, in *AdminUpdateUserRequest, opts ...grpc.CallOption) (*UserResponse, error)
+	DeleteUser(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*Empty, error)
+	DeactivateUser(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*UserResponse, error)
+	ReactivateUser(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*UserResponse, error)
+	UnlockUser(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*UserResponse, error)
+	SetUserType(ctx context.Context, in *SetUserTypeRequest, opts ...grpc.CallOption) (*UserResponse, error)
+	ListUserTypes(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListUserTypesResponse, error)
+	GetUserType(ctx context.Context, in *UserTypeId, opts ...grpc.CallOption) (*UserTypeResponse, error)
+	CreateUserType(ctx context.Context, in *CreateUserTypeRequest, opts ...grpc.CallOption) (*UserTypeResponse, error)
+	UpdateUserType(ctx context.Context, in *UpdateUserTypeRequest, opts ...grpc.CallOption) (*UserTypeResponse, error)
+	SetUserTypeScopes(ctx context.Context, in *SetUserTypeScopesRequest, opts ...grpc.CallOption) (*UserTypeResponse, error)
+	DeleteUserType(ctx context.Context, in *UserTypeId, opts ...grpc.CallOption) (*Empty, error)
+	ListScopes(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListScopesResponse, error)
+	CreateScope(ctx context.Context, in *CreateScopeRequest, opts ...grpc.CallOption) (*ScopeResponse, error)
+	UpdateScope(ctx context.Context, in *UpdateScopeRequest, opts ...grpc.CallOption) (*ScopeResponse, error)
+	DeleteScope(ctx context.Context, in *ScopeId, opts ...grpc.CallOption) (*Empty, error)
+}
+
+type authenticationAdminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAuthenticationAdminClient(cc grpc.ClientConnInterface) AuthenticationAdminClient {
+	return &authenticationAdminClient{cc}
+}
+
+func (c *authenticationAdminClient) ListUsers(ctx context.Context, in *ListUsersRequest, opts ...grpc.CallOption) (*ListUsersResponse, error) {
+	out := new(ListUsersResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.AuthenticationAdmin/ListUsers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationAdminClient) GetUser(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.AuthenticationAdmin/GetUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationAdminClient) CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.AuthenticationAdmin/CreateUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationAdminClient) UpdateUser(ctx context.Context, in *AdminUpdateUserRequest, opts ...grpc.CallOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.AuthenticationAdmin/UpdateUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationAdminClient) DeleteUser(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/pkg.auth.AuthenticationAdmin/DeleteUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationAdminClient) DeactivateUser(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.AuthenticationAdmin/DeactivateUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationAdminClient) ReactivateUser(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.AuthenticationAdmin/ReactivateUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationAdminClient) UnlockUser(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.AuthenticationAdmin/UnlockUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationAdminClient) SetUserType(ctx context.Context, in *SetUserTypeRequest, opts ...grpc.CallOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.AuthenticationAdmin/SetUserType", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationAdminClient) ListUserTypes(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListUserTypesResponse, error) {
+	out := new(ListUserTypesResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.AuthenticationAdmin/ListUserTypes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationAdminClient) GetUserType(ctx context.Context, in *UserTypeId, opts ...grpc.CallOption) (*UserTypeResponse, error) {
+	out := new(UserTypeResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.AuthenticationAdmin/GetUserType", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationAdminClient) CreateUserType(ctx context.Context, in *CreateUserTypeRequest, opts ...grpc.CallOption) (*UserTypeResponse, error) {
+	out := new(UserTypeResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.AuthenticationAdmin/CreateUserType", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationAdminClient) UpdateUserType(ctx context.Context, in *UpdateUserTypeRequest, opts ...grpc.CallOption) (*UserTypeResponse, error) {
+	out := new(UserTypeResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.AuthenticationAdmin/UpdateUserType", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationAdminClient) SetUserTypeScopes(ctx context.Context, in *SetUserTypeScopesRequest, opts ...grpc.CallOption) (*UserTypeResponse, error) {
+	out := new(UserTypeResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.AuthenticationAdmin/SetUserTypeScopes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationAdminClient) DeleteUserType(ctx context.Context, in *UserTypeId, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/pkg.auth.AuthenticationAdmin/DeleteUserType", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationAdminClient) ListScopes(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListScopesResponse, error) {
+	out := new(ListScopesResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.AuthenticationAdmin/ListScopes", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationAdminClient) CreateScope(ctx context.Context, in *CreateScopeRequest, opts ...grpc.CallOption) (*ScopeResponse, error) {
+	out := new(ScopeResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.AuthenticationAdmin/CreateScope", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationAdminClient) UpdateScope(ctx context.Context, in *UpdateScopeRequest, opts ...grpc.CallOption) (*ScopeResponse, error) {
+	out := new(ScopeResponse)
+	err := c.cc.Invoke(ctx, "/pkg.auth.AuthenticationAdmin/UpdateScope", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authenticationAdminClient) DeleteScope(ctx context.Context, in *ScopeId, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/pkg.auth.AuthenticationAdmin/DeleteScope", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AuthenticationAdminServer is the server API for AuthenticationAdmin service.
+// All implementations must embed UnimplementedAuthenticationAdminServer
+// for forward compatibility
+type AuthenticationAdminServer interface {
+	ListUsers(context.Context, *ListUsersRequest) (*ListUsersResponse, error)
+	GetUser(context.Context, *UserId) (*UserResponse, error)
+	CreateUser(context.Context, *CreateUserRequest) (*UserResponse, error)
+	UpdateUser(context.Context, *AdminUpdateUserRequest) (*UserResponse, error)
+	DeleteUser(context.Context, *UserId) (*Empty, error)
+	DeactivateUser(context.Context, *UserId) (*UserResponse, error)
+	ReactivateUser(context.Context, *UserId) (*UserResponse, error)
+	UnlockUser(context.Context, *UserId) (*UserResponse, error)
+	SetUserType(context.Context, *SetUserTypeRequest) (*UserResponse, error)
+	ListUserTypes(context.Context, *Empty) (*ListUserTypesResponse, error)
+	GetUserType(context.Context, *UserTypeId) (*UserTypeResponse, error)
+	CreateUserType(context.Context, *CreateUserTypeRequest) (*UserTypeResponse, error)
+	UpdateUserType(context.Context, *UpdateUserTypeRequest) (*UserTypeResponse, error)
+	SetUserTypeScopes(context.Context, *SetUserTypeScopesRequest) (*UserTypeResponse, error)
+	DeleteUserType(context.Context, *UserTypeId) (*Empty, error)
+	ListScopes(context.Context, *Empty) (*ListScopesResponse, error)
+	CreateScope(context.Context, *CreateScopeRequest) (*ScopeResponse, error)
+	UpdateScope(context.Context, *UpdateScopeRequest) (*ScopeResponse, error)
+	DeleteScope(context.Context, *ScopeId) (*Empty, error)
+	mustEmbedUnimplementedAuthenticationAdminServer()
+}
+
+// UnimplementedAuthenticationAdminServer must be embedded to have forward compatible implementations.
+type UnimplementedAuthenticationAdminServer struct {
+}
+
+func (UnimplementedAuthenticationAdminServer) ListUsers(context.Context, *ListUsersRequest) (*ListUsersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUsers not implemented")
+}
+func (UnimplementedAuthenticationAdminServer) GetUser(context.Context, *UserId) (*UserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUser not implemented")
+}
+func (UnimplementedAuthenticationAdminServer) CreateUser(context.Context, *CreateUserRequest) (*UserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateUser not implemented")
+}
+func (UnimplementedAuthenticationAdminServer) UpdateUser(context.Context, *AdminUpdateUserRequest) (*UserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateUser not implemented")
+}
+func (UnimplementedAuthenticationAdminServer) DeleteUser(context.Context, *UserId) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteUser not implemented")
+}
+func (UnimplementedAuthenticationAdminServer) DeactivateUser(context.Context, *UserId) (*UserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeactivateUser not implemented")
+}
+func (UnimplementedAuthenticationAdminServer) ReactivateUser(context.Context, *UserId) (*UserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReactivateUser not implemented")
+}
+func (UnimplementedAuthenticationAdminServer) UnlockUser(context.Context, *UserId) (*UserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnlockUser not implemented")
+}
+func (UnimplementedAuthenticationAdminServer) SetUserType(context.Context, *SetUserTypeRequest) (*UserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetUserType not implemented")
+}
+func (UnimplementedAuthenticationAdminServer) ListUserTypes(context.Context, *Empty) (*ListUserTypesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUserTypes not implemented")
+}
+func (UnimplementedAuthenticationAdminServer) GetUserType(context.Context, *UserTypeId) (*UserTypeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserType not implemented")
+}
+func (UnimplementedAuthenticationAdminServer) CreateUserType(context.Context, *CreateUserTypeRequest) (*UserTypeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateUserType not implemented")
+}
+func (UnimplementedAuthenticationAdminServer) UpdateUserType(context.Context, *UpdateUserTypeRequest) (*UserTypeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateUserType not implemented")
+}
+func (UnimplementedAuthenticationAdminServer) SetUserTypeScopes(context.Context, *SetUserTypeScopesRequest) (*UserTypeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetUserTypeScopes not implemented")
+}
+func (UnimplementedAuthenticationAdminServer) DeleteUserType(context.Context, *UserTypeId) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteUserType not implemented")
+}
+func (UnimplementedAuthenticationAdminServer) ListScopes(context.Context, *Empty) (*ListScopesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListScopes not implemented")
+}
+func (UnimplementedAuthenticationAdminServer) CreateScope(context.Context, *CreateScopeRequest) (*ScopeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateScope not implemented")
+}
+func (UnimplementedAuthenticationAdminServer) UpdateScope(context.Context, *UpdateScopeRequest) (*ScopeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateScope not implemented")
+}
+func (UnimplementedAuthenticationAdminServer) DeleteScope(context.Context, *ScopeId) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteScope not implemented")
+}
+func (UnimplementedAuthenticationAdminServer) mustEmbedUnimplementedAuthenticationAdminServer() {}
+
+// UnsafeAuthenticationAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AuthenticationAdminServer will
+// result in compilation errors.
+type UnsafeAuthenticationAdminServer interface {
+	mustEmbedUnimplementedAuthenticationAdminServer()
+}
+
+func RegisterAuthenticationAdminServer(s grpc.ServiceRegistrar, srv AuthenticationAdminServer) {
+	s.RegisterService(&AuthenticationAdmin_ServiceDesc, srv)
+}
+
+func _AuthenticationAdmin_ListUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUsersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationAdminServer).ListUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.AuthenticationAdmin/ListUsers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationAdminServer).ListUsers(ctx, req.(*ListUsersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthenticationAdmin_GetUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationAdminServer).GetUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.AuthenticationAdmin/GetUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationAdminServer).GetUser(ctx, req.(*UserId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthenticationAdmin_CreateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationAdminServer).CreateUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.AuthenticationAdmin/CreateUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationAdminServer).CreateUser(ctx, req.(*CreateUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthenticationAdmin_UpdateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdminUpdateUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationAdminServer).UpdateUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.AuthenticationAdmin/UpdateUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationAdminServer).UpdateUser(ctx, req.(*AdminUpdateUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthenticationAdmin_DeleteUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationAdminServer).DeleteUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.AuthenticationAdmin/DeleteUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationAdminServer).DeleteUser(ctx, req.(*UserId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthenticationAdmin_DeactivateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationAdminServer).DeactivateUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.AuthenticationAdmin/DeactivateUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationAdminServer).DeactivateUser(ctx, req.(*UserId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthenticationAdmin_ReactivateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationAdminServer).ReactivateUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.AuthenticationAdmin/ReactivateUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationAdminServer).ReactivateUser(ctx, req.(*UserId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthenticationAdmin_UnlockUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationAdminServer).UnlockUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.AuthenticationAdmin/UnlockUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationAdminServer).UnlockUser(ctx, req.(*UserId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthenticationAdmin_SetUserType_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetUserTypeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationAdminServer).SetUserType(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.AuthenticationAdmin/SetUserType",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationAdminServer).SetUserType(ctx, req.(*SetUserTypeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthenticationAdmin_ListUserTypes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationAdminServer).ListUserTypes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.AuthenticationAdmin/ListUserTypes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationAdminServer).ListUserTypes(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthenticationAdmin_GetUserType_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserTypeId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationAdminServer).GetUserType(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.AuthenticationAdmin/GetUserType",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationAdminServer).GetUserType(ctx, req.(*UserTypeId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthenticationAdmin_CreateUserType_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateUserTypeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationAdminServer).CreateUserType(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.AuthenticationAdmin/CreateUserType",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationAdminServer).CreateUserType(ctx, req.(*CreateUserTypeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthenticationAdmin_UpdateUserType_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateUserTypeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationAdminServer).UpdateUserType(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.AuthenticationAdmin/UpdateUserType",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationAdminServer).UpdateUserType(ctx, req.(*UpdateUserTypeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthenticationAdmin_SetUserTypeScopes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetUserTypeScopesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationAdminServer).SetUserTypeScopes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.AuthenticationAdmin/SetUserTypeScopes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationAdminServer).SetUserTypeScopes(ctx, req.(*SetUserTypeScopesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthenticationAdmin_DeleteUserType_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserTypeId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationAdminServer).DeleteUserType(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.AuthenticationAdmin/DeleteUserType",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationAdminServer).DeleteUserType(ctx, req.(*UserTypeId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthenticationAdmin_ListScopes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationAdminServer).ListScopes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.AuthenticationAdmin/ListScopes",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationAdminServer).ListScopes(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthenticationAdmin_CreateScope_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateScopeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationAdminServer).CreateScope(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.AuthenticationAdmin/CreateScope",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationAdminServer).CreateScope(ctx, req.(*CreateScopeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthenticationAdmin_UpdateScope_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateScopeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationAdminServer).UpdateScope(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.AuthenticationAdmin/UpdateScope",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationAdminServer).UpdateScope(ctx, req.(*UpdateScopeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthenticationAdmin_DeleteScope_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScopeId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthenticationAdminServer).DeleteScope(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.auth.AuthenticationAdmin/DeleteScope",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthenticationAdminServer).DeleteScope(ctx, req.(*ScopeId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AuthenticationAdmin_ServiceDesc is the grpc.ServiceDesc for AuthenticationAdmin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AuthenticationAdmin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pkg.auth.AuthenticationAdmin",
+	HandlerType: (*AuthenticationAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListUsers",
+			Handler:    _AuthenticationAdmin_ListUsers_Handler,
+		},
+		{
+			MethodName: "GetUser",
+			Handler:    _AuthenticationAdmin_GetUser_Handler,
+		},
+		{
+			MethodName: "CreateUser",
+			Handler:    _AuthenticationAdmin_CreateUser_Handler,
+		},
+		{
+			MethodName: "UpdateUser",
+			Handler:    _AuthenticationAdmin_UpdateUser_Handler,
+		},
+		{
+			MethodName: "DeleteUser",
+			Handler:    _AuthenticationAdmin_DeleteUser_Handler,
+		},
+		{
+			MethodName: "DeactivateUser",
+			Handler:    _AuthenticationAdmin_DeactivateUser_Handler,
+		},
+		{
+			MethodName: "ReactivateUser",
+			Handler:    _AuthenticationAdmin_ReactivateUser_Handler,
+		},
+		{
+			MethodName: "UnlockUser",
+			Handler:    _AuthenticationAdmin_UnlockUser_Handler,
+		},
+		{
+			MethodName: "SetUserType",
+			Handler:    _AuthenticationAdmin_SetUserType_Handler,
+		},
+		{
+			MethodName: "ListUserTypes",
+			Handler:    _AuthenticationAdmin_ListUserTypes_Handler,
+		},
+		{
+			MethodName: "GetUserType",
+			Handler:    _AuthenticationAdmin_GetUserType_Handler,
+		},
+		{
+			MethodName: "CreateUserType",
+			Handler:    _AuthenticationAdmin_CreateUserType_Handler,
+		},
+		{
+			MethodName: "UpdateUserType",
+			Handler:    _AuthenticationAdmin_UpdateUserType_Handler,
+		},
+		{
+			MethodName: "SetUserTypeScopes",
+			Handler:    _AuthenticationAdmin_SetUserTypeScopes_Handler,
+		},
+		{
+			MethodName: "DeleteUserType",
+			Handler:    _AuthenticationAdmin_DeleteUserType_Handler,
+		},
+		{
+			MethodName: "ListScopes",
+			Handler:    _AuthenticationAdmin_ListScopes_Handler,
+		},
+		{
+			MethodName: "CreateScope",
+			Handler:    _AuthenticationAdmin_CreateScope_Handler,
+		},
+		{
+			MethodName: "UpdateScope",
+			Handler:    _AuthenticationAdmin_UpdateScope_Handler,
+		},
+		{
+			MethodName: "DeleteScope",
+			Handler:    _AuthenticationAdmin_DeleteScope_Handler,
 		},
 	},
 	Streams:  []grpc.StreamDesc{},
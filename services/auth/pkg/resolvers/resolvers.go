@@ -0,0 +1,191 @@
+// Package resolvers resolves bearer tokens to the authz.Principal of their user with the auth service.
+package resolvers
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/accentdesign/grpc/core/authz"
+	pb "github.com/accentdesign/grpc/services/auth/pkg/api/auth"
+)
+
+// AuthServiceResolver implements authz.Resolver, it resolves tokens by calling the User rpc of the auth service,
+// so revoked tokens and deactivated users are rejected straight away.
+type AuthServiceResolver struct {
+	Client pb.AuthenticationClient
+}
+
+func (r *AuthServiceResolver) Resolve(ctx context.Context, token string) (*authz.Principal, error) {
+	user, err := r.Client.User(ctx, &pb.Token{Token: token})
+	if err != nil {
+		if status.Code(err) == codes.InvalidArgument {
+			return nil, fmt.Errorf("%w: %s", authz.ErrInvalidToken, status.Convert(err).Message())
+		}
+		return nil, err
+	}
+
+	return &authz.Principal{
+		UserId:   user.GetId(),
+		UserType: user.GetUserType().GetName(),
+		Scopes:   user.GetUserType().GetScopes(),
+	}, nil
+}
+
+type accessClaims struct {
+	jwt.RegisteredClaims
+	UserType string   `json:"user_type"`
+	Scopes   []string `json:"scopes"`
+}
+
+type publicKey struct {
+	alg string
+	key crypto.PublicKey
+}
+
+// JWKSResolver implements authz.Resolver, it validates jwt access tokens locally against the public keys published
+// by the GetJWKS rpc of the auth service. The keys are fetched again when a token is signed by an unknown key id,
+// at most once every RefreshInterval. Tokens stay valid until they expire even when revoked.
+type JWKSResolver struct {
+	Client          pb.AuthenticationClient
+	Issuer          string
+	RefreshInterval time.Duration
+
+	mu      sync.RWMutex
+	keys    map[string]publicKey
+	fetched time.Time
+}
+
+// NewJWKSResolver creates a JWKSResolver that checks the issuer of tokens and refreshes its keys at most once a minute.
+func NewJWKSResolver(client pb.AuthenticationClient, issuer string) *JWKSResolver {
+	return &JWKSResolver{
+		Client:          client,
+		Issuer:          issuer,
+		RefreshInterval: time.Minute,
+	}
+}
+
+func (r *JWKSResolver) Resolve(ctx context.Context, token string) (*authz.Principal, error) {
+	var fetchErr error
+	claims := &accessClaims{}
+
+	opts := []jwt.ParserOption{
+		jwt.WithExpirationRequired(),
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+	}
+	if r.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(r.Issuer))
+	}
+
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := r.key(ctx, kid)
+		if err != nil {
+			fetchErr = err
+			return nil, err
+		}
+		if key == nil {
+			return nil, fmt.Errorf("unknown key id: %s", kid)
+		}
+		if t.Method.Alg() != key.alg {
+			return nil, fmt.Errorf("unexpected signing method: %s", t.Method.Alg())
+		}
+		return key.key, nil
+	}, opts...)
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", authz.ErrInvalidToken, err)
+	}
+
+	return &authz.Principal{
+		UserId:   claims.Subject,
+		UserType: claims.UserType,
+		Scopes:   claims.Scopes,
+	}, nil
+}
+
+// key returns the public key with the given id, fetching the keys again if it is not known.
+// A nil key is returned when the key id is still not known after fetching.
+func (r *JWKSResolver) key(ctx context.Context, kid string) (*publicKey, error) {
+	r.mu.RLock()
+	key, ok := r.keys[kid]
+	r.mu.RUnlock()
+	if ok {
+		return &key, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if key, ok := r.keys[kid]; ok {
+		return &key, nil
+	}
+	if !r.fetched.IsZero() && time.Since(r.fetched) < r.RefreshInterval {
+		return nil, nil
+	}
+
+	resp, err := r.Client.GetJWKS(ctx, &pb.Empty{})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]publicKey, len(resp.GetKeys()))
+	for _, jwk := range resp.GetKeys() {
+		key, err := parseJWK(jwk)
+		if err != nil {
+			return nil, err
+		}
+		keys[jwk.GetKid()] = *key
+	}
+	r.keys = keys
+	r.fetched = time.Now()
+
+	if key, ok := r.keys[kid]; ok {
+		return &key, nil
+	}
+	return nil, nil
+}
+
+func parseJWK(jwk *pb.JWK) (*publicKey, error) {
+	switch jwk.GetKty() {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.GetN())
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %v", jwk.GetKid(), err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.GetE())
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %v", jwk.GetKid(), err)
+		}
+		return &publicKey{
+			alg: jwt.SigningMethodRS256.Alg(),
+			key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())},
+		}, nil
+	case "OKP":
+		if jwk.GetCrv() != "Ed25519" {
+			return nil, fmt.Errorf("key %s: unsupported curve %q", jwk.GetKid(), jwk.GetCrv())
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.GetX())
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %v", jwk.GetKid(), err)
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("key %s: invalid Ed25519 public key", jwk.GetKid())
+		}
+		return &publicKey{alg: jwt.SigningMethodEdDSA.Alg(), key: ed25519.PublicKey(x)}, nil
+	default:
+		return nil, fmt.Errorf("key %s: unsupported key type %q", jwk.GetKid(), jwk.GetKty())
+	}
+}
@@ -0,0 +1,174 @@
+package resolvers_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/accentdesign/grpc/core/authz"
+	pb "github.com/accentdesign/grpc/services/auth/pkg/api/auth"
+	"github.com/accentdesign/grpc/services/auth/pkg/resolvers"
+)
+
+type TestSuite struct {
+	suite.Suite
+}
+
+func TestTestSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+type fakeAuthClient struct {
+	pb.AuthenticationClient
+	users     map[string]*pb.UserResponse
+	jwks      *pb.JWKSResponse
+	jwksCalls int
+}
+
+func (c *fakeAuthClient) User(_ context.Context, in *pb.Token, _ ...grpc.CallOption) (*pb.UserResponse, error) {
+	if user, ok := c.users[in.GetToken()]; ok {
+		return user, nil
+	}
+	return nil, status.Error(codes.InvalidArgument, "invalid token")
+}
+
+func (c *fakeAuthClient) GetJWKS(_ context.Context, _ *pb.Empty, _ ...grpc.CallOption) (*pb.JWKSResponse, error) {
+	c.jwksCalls++
+	if c.jwks == nil {
+		return nil, status.Error(codes.Unavailable, "connection refused")
+	}
+	return c.jwks, nil
+}
+
+func (suite *TestSuite) TestAuthServiceResolver() {
+	client := &fakeAuthClient{
+		users: map[string]*pb.UserResponse{
+			"valid": {Id: "1", UserType: &pb.UserType{Name: "admin", Scopes: []string{"users:read"}}},
+		},
+	}
+	resolver := &resolvers.AuthServiceResolver{Client: client}
+
+	p, err := resolver.Resolve(context.Background(), "valid")
+	suite.NoError(err)
+	suite.Equal(&authz.Principal{UserId: "1", UserType: "admin", Scopes: []string{"users:read"}}, p)
+
+	p, err = resolver.Resolve(context.Background(), "invalid")
+	suite.ErrorIs(err, authz.ErrInvalidToken)
+	suite.Nil(p)
+}
+
+func signToken(method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		panic(err)
+	}
+	return signed
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":       "auth",
+		"sub":       "3f4b2b87-d7b1-4b9f-b207-ae00b112382f",
+		"exp":       time.Now().Add(time.Hour).Unix(),
+		"user_type": "admin",
+		"scopes":    []string{"users:read"},
+	}
+}
+
+func (suite *TestSuite) TestJWKSResolver() {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	suite.NoError(err)
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	suite.NoError(err)
+
+	rsaJWK := &pb.JWK{
+		Kty: "RSA",
+		Kid: "rsa",
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+	}
+	edJWK := &pb.JWK{
+		Kty: "OKP",
+		Kid: "ed",
+		Use: "sig",
+		Alg: "EdDSA",
+		Crv: "Ed25519",
+		X:   base64.RawURLEncoding.EncodeToString(edPub),
+	}
+
+	client := &fakeAuthClient{jwks: &pb.JWKSResponse{Keys: []*pb.JWK{rsaJWK}}}
+	resolver := resolvers.NewJWKSResolver(client, "auth")
+
+	p, err := resolver.Resolve(context.Background(), signToken(jwt.SigningMethodRS256, "rsa", rsaKey, validClaims()))
+	suite.NoError(err)
+	suite.Equal(&authz.Principal{UserId: "3f4b2b87-d7b1-4b9f-b207-ae00b112382f", UserType: "admin", Scopes: []string{"users:read"}}, p)
+	suite.Equal(1, client.jwksCalls)
+
+	// known keys are not fetched again
+	_, err = resolver.Resolve(context.Background(), signToken(jwt.SigningMethodRS256, "rsa", rsaKey, validClaims()))
+	suite.NoError(err)
+	suite.Equal(1, client.jwksCalls)
+
+	// unknown keys are only fetched again once the refresh interval has passed
+	edToken := signToken(jwt.SigningMethodEdDSA, "ed", edKey, validClaims())
+	client.jwks.Keys = append(client.jwks.Keys, edJWK)
+
+	_, err = resolver.Resolve(context.Background(), edToken)
+	suite.ErrorIs(err, authz.ErrInvalidToken)
+	suite.Equal(1, client.jwksCalls)
+
+	resolver.RefreshInterval = 0
+	p, err = resolver.Resolve(context.Background(), edToken)
+	suite.NoError(err)
+	suite.Equal("admin", p.UserType)
+	suite.Equal(2, client.jwksCalls)
+
+	expired := validClaims()
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+
+	wrongIssuer := validClaims()
+	wrongIssuer["iss"] = "someone-else"
+
+	noExpiry := validClaims()
+	delete(noExpiry, "exp")
+
+	testCases := []struct {
+		desc  string
+		token string
+	}{
+		{"expired", signToken(jwt.SigningMethodRS256, "rsa", rsaKey, expired)},
+		{"wrong issuer", signToken(jwt.SigningMethodRS256, "rsa", rsaKey, wrongIssuer)},
+		{"no expiry", signToken(jwt.SigningMethodRS256, "rsa", rsaKey, noExpiry)},
+		{"wrong key", signToken(jwt.SigningMethodEdDSA, "rsa", edKey, validClaims())},
+		{"not a jwt", "not-a-jwt"},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.desc, func() {
+			p, err := resolver.Resolve(context.Background(), tc.token)
+			suite.ErrorIs(err, authz.ErrInvalidToken)
+			suite.Nil(p)
+		})
+	}
+
+	// errors fetching the keys are returned as is
+	client.jwks = nil
+	p, err = resolvers.NewJWKSResolver(client, "auth").Resolve(context.Background(), edToken)
+	suite.Equal(codes.Unavailable, status.Code(err))
+	suite.Nil(p)
+}
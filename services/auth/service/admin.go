@@ -0,0 +1,789 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"gorm.io/gorm"
+
+	"github.com/accentdesign/grpc/core/authz"
+	"github.com/accentdesign/grpc/core/errs"
+	"github.com/accentdesign/grpc/services/auth/internal/models"
+	"github.com/accentdesign/grpc/services/auth/internal/passwords"
+	"github.com/accentdesign/grpc/services/auth/internal/repos"
+	pb "github.com/accentdesign/grpc/services/auth/pkg/api/auth"
+)
+
+const (
+	ScopeUsersRead      = "users:read"
+	ScopeUsersWrite     = "users:write"
+	ScopeUserTypesRead  = "user_types:read"
+	ScopeUserTypesWrite = "user_types:write"
+)
+
+var (
+	ErrNameInvalid           = errs.FieldError(codes.InvalidArgument, ErrorDomain, "NAME_INVALID", "name", "name must be between 1 and 120 characters in length")
+	ErrPermissionDenied      = authz.ErrPermissionDenied
+	ErrScopeAlreadyExists    = errs.Error(codes.AlreadyExists, ErrorDomain, "SCOPE_ALREADY_EXISTS", "a scope with this name already exists")
+	ErrScopeIdInvalid        = errs.Error(codes.InvalidArgument, ErrorDomain, "SCOPE_ID_INVALID", "invalid scope id")
+	ErrScopeInvalid          = errs.Error(codes.InvalidArgument, ErrorDomain, "SCOPE_INVALID", "invalid scope")
+	ErrScopeNotFound         = errs.Error(codes.NotFound, ErrorDomain, "SCOPE_NOT_FOUND", "scope not found")
+	ErrUnauthenticated       = authz.ErrUnauthenticated
+	ErrUserIdInvalid         = errs.Error(codes.InvalidArgument, ErrorDomain, "USER_ID_INVALID", "invalid user id")
+	ErrUserTypeAlreadyExists = errs.Error(codes.AlreadyExists, ErrorDomain, "USER_TYPE_ALREADY_EXISTS", "a user type with this name already exists")
+	ErrUserTypeIdInvalid     = errs.Error(codes.InvalidArgument, ErrorDomain, "USER_TYPE_ID_INVALID", "invalid user type id")
+	ErrUserTypeInUse         = errs.Error(codes.FailedPrecondition, ErrorDomain, "USER_TYPE_IN_USE", "user type is assigned to users")
+	ErrUserTypeInvalid       = errs.Error(codes.InvalidArgument, ErrorDomain, "USER_TYPE_INVALID", "invalid user type")
+	ErrUserTypeIsDefault     = errs.Error(codes.FailedPrecondition, ErrorDomain, "USER_TYPE_IS_DEFAULT", "a default user type is required, make another user type the default instead")
+	ErrUserTypeNotFound      = errs.Error(codes.NotFound, ErrorDomain, "USER_TYPE_NOT_FOUND", "user type not found")
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type AdminService struct {
+	pb.UnimplementedAuthenticationAdminServer
+	UserRepo     *repos.UserRepository
+	UserTypeRepo *repos.UserTypeRepository
+	ScopeRepo    *repos.ScopeRepository
+	TokenRepo    *repos.TokenRepository
+	// LoginAttemptRepo is optional, UnlockUser is a no-op without it.
+	LoginAttemptRepo *repos.LoginAttemptRepository
+	// PasswordPolicy is optional, passwords set by CreateUser and UpdateUser are checked against it.
+	PasswordPolicy *passwords.Policy
+}
+
+// AdminRules declares the scopes required by each AuthenticationAdmin rpc, for use with the authz interceptors.
+var AdminRules = authz.Rules{
+	"/pkg.auth.AuthenticationAdmin/ListUsers":         {ScopeUsersRead},
+	"/pkg.auth.AuthenticationAdmin/GetUser":           {ScopeUsersRead},
+	"/pkg.auth.AuthenticationAdmin/CreateUser":        {ScopeUsersWrite},
+	"/pkg.auth.AuthenticationAdmin/UpdateUser":        {ScopeUsersWrite},
+	"/pkg.auth.AuthenticationAdmin/DeleteUser":        {ScopeUsersWrite},
+	"/pkg.auth.AuthenticationAdmin/DeactivateUser":    {ScopeUsersWrite},
+	"/pkg.auth.AuthenticationAdmin/ReactivateUser":    {ScopeUsersWrite},
+	"/pkg.auth.AuthenticationAdmin/UnlockUser":        {ScopeUsersWrite},
+	"/pkg.auth.AuthenticationAdmin/SetUserType":       {ScopeUsersWrite},
+	"/pkg.auth.AuthenticationAdmin/ListUserTypes":     {ScopeUserTypesRead},
+	"/pkg.auth.AuthenticationAdmin/GetUserType":       {ScopeUserTypesRead},
+	"/pkg.auth.AuthenticationAdmin/CreateUserType":    {ScopeUserTypesWrite},
+	"/pkg.auth.AuthenticationAdmin/UpdateUserType":    {ScopeUserTypesWrite},
+	"/pkg.auth.AuthenticationAdmin/SetUserTypeScopes": {ScopeUserTypesWrite},
+	"/pkg.auth.AuthenticationAdmin/DeleteUserType":    {ScopeUserTypesWrite},
+	"/pkg.auth.AuthenticationAdmin/ListScopes":        {ScopeUserTypesRead},
+	"/pkg.auth.AuthenticationAdmin/CreateScope":       {ScopeUserTypesWrite},
+	"/pkg.auth.AuthenticationAdmin/UpdateScope":       {ScopeUserTypesWrite},
+	"/pkg.auth.AuthenticationAdmin/DeleteScope":       {ScopeUserTypesWrite},
+}
+
+// Resolve implements authz.Resolver by looking the access token up in the database, failing to look it up is an
+// internal error rather than an invalid token. The user stored in the context by the UserInterceptor of the auth
+// service is used when there is one.
+func (s *AdminService) Resolve(ctx context.Context, token string) (*authz.Principal, error) {
+	user, ok := UserFromContext(ctx)
+	if !ok {
+		var err error
+		user, err = s.UserRepo.GetUserByAccessToken(token)
+		if err != nil {
+			if errors.Is(err, repos.ErrUserNotFound) || errors.Is(err, repos.ErrTokenExpired) || errors.Is(err, repos.ErrTokenUsed) {
+				return nil, fmt.Errorf("%w: %v", authz.ErrInvalidToken, err)
+			}
+			return nil, ErrInternal(err)
+		}
+	}
+
+	if !user.IsActive {
+		return nil, ErrUserInactive
+	}
+
+	return &authz.Principal{
+		UserId:   user.ID.String(),
+		UserType: user.UserType.Name,
+		Scopes:   user.UserType.ScopeNames(),
+	}, nil
+}
+
+// authorize ensures the caller is an active user whose user type has the given scope.
+func (s *AdminService) authorize(ctx context.Context, scope string) error {
+	_, err := authz.Authorize(ctx, s, scope)
+	return err
+}
+
+// checkPassword applies the password policy to a password set by an admin, as AuthService.checkPassword does.
+func (s *AdminService) checkPassword(user *models.User, password string, history bool) error {
+	return applyPasswordPolicy(s.PasswordPolicy, s.UserRepo, user, password, history)
+}
+
+// recordPasswordHistory keeps the hash of a password replaced by an admin, for the reuse check of the password policy.
+func (s *AdminService) recordPasswordHistory(userId uuid.UUID, previousHash string) error {
+	return addPasswordHistory(s.PasswordPolicy, s.UserRepo, userId, previousHash)
+}
+
+func (s *AdminService) getUser(id string) (*models.User, error) {
+	userId, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrUserIdInvalid
+	}
+
+	user, err := s.UserRepo.GetUserByID(userId)
+	if err != nil {
+		switch {
+		case errors.Is(err, repos.ErrUserNotFound):
+			return nil, ErrUserNotFound
+		default:
+			return nil, ErrInternal(err)
+		}
+	}
+
+	return user, nil
+}
+
+func (s *AdminService) getUserType(name string) (*models.UserType, error) {
+	var userType *models.UserType
+	var err error
+	if name == "" {
+		userType, err = s.UserTypeRepo.GetDefaultUserType()
+	} else {
+		userType, err = s.UserTypeRepo.GetUserTypeByName(name)
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, repos.ErrUserTypeNotFound):
+			return nil, ErrUserTypeInvalid
+		default:
+			return nil, ErrInternal(err)
+		}
+	}
+
+	return userType, nil
+}
+
+func userTypeToResponse(userType *models.UserType) *pb.UserTypeResponse {
+	return &pb.UserTypeResponse{
+		Id:        userType.ID.String(),
+		Name:      userType.Name,
+		IsDefault: userType.IsDefault,
+		Scopes:    userType.ScopeNames(),
+	}
+}
+
+func scopeToResponse(scope *models.Scope) *pb.ScopeResponse {
+	return &pb.ScopeResponse{
+		Id:   scope.ID.String(),
+		Name: scope.Name,
+	}
+}
+
+// validName trims the name and ensures it fits the name columns of user types and scopes.
+func validName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if !govalidator.StringLength(name, "1", "120") {
+		return "", ErrNameInvalid
+	}
+	return name, nil
+}
+
+func (s *AdminService) getUserTypeByID(id string) (*models.UserType, error) {
+	userTypeId, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrUserTypeIdInvalid
+	}
+
+	userType, err := s.UserTypeRepo.GetUserTypeByID(userTypeId)
+	if err != nil {
+		switch {
+		case errors.Is(err, repos.ErrUserTypeNotFound):
+			return nil, ErrUserTypeNotFound
+		default:
+			return nil, ErrInternal(err)
+		}
+	}
+
+	return userType, nil
+}
+
+func (s *AdminService) getScopes(names []string) ([]models.Scope, error) {
+	trimmed := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name != "" && !slices.Contains(trimmed, name) {
+			trimmed = append(trimmed, name)
+		}
+	}
+
+	scopes, err := s.ScopeRepo.GetScopesByName(trimmed)
+	if err != nil {
+		switch {
+		case errors.Is(err, repos.ErrScopeNotFound):
+			return nil, ErrScopeInvalid
+		default:
+			return nil, ErrInternal(err)
+		}
+	}
+
+	return scopes, nil
+}
+
+func (s *AdminService) setActive(ctx context.Context, id string, active bool) (*pb.UserResponse, error) {
+	if err := s.authorize(ctx, ScopeUsersWrite); err != nil {
+		return nil, err
+	}
+
+	user, err := s.getUser(id)
+	if err != nil {
+		return nil, err
+	}
+
+	user.IsActive = active
+	if err := s.UserRepo.UpdateUser(user); err != nil {
+		return nil, ErrInternal(err)
+	}
+
+	return userToResponse(user), nil
+}
+
+// DeactivateUser deactivates a user account, revoking all of its access and refresh tokens.
+// It takes in a context and a UserId, and returns a UserResponse and an error.
+func (s *AdminService) DeactivateUser(ctx context.Context, in *pb.UserId) (*pb.UserResponse, error) {
+	return s.setActive(ctx, in.GetId(), false)
+}
+
+// ReactivateUser reactivates a previously deactivated user account.
+// It takes in a context and a UserId, and returns a UserResponse and an error.
+func (s *AdminService) ReactivateUser(ctx context.Context, in *pb.UserId) (*pb.UserResponse, error) {
+	return s.setActive(ctx, in.GetId(), true)
+}
+
+// UnlockUser clears the failed login attempts of a user account, lifting any lockout.
+// It takes in a context and a UserId, and returns a UserResponse and an error.
+func (s *AdminService) UnlockUser(ctx context.Context, in *pb.UserId) (*pb.UserResponse, error) {
+	if err := s.authorize(ctx, ScopeUsersWrite); err != nil {
+		return nil, err
+	}
+
+	user, err := s.getUser(in.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	if s.LoginAttemptRepo != nil {
+		if err := s.LoginAttemptRepo.Reset(repos.AccountKey(user.Email)); err != nil {
+			return nil, ErrInternal(err)
+		}
+	}
+
+	return userToResponse(user), nil
+}
+
+// ListUsers lists users a page at a time, optionally filtered.
+// It takes in a context and a ListUsersRequest, and returns a ListUsersResponse and an error.
+func (s *AdminService) ListUsers(ctx context.Context, in *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	if err := s.authorize(ctx, ScopeUsersRead); err != nil {
+		return nil, err
+	}
+
+	page := max(in.GetPage(), 1)
+	pageSize := in.GetPageSize()
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	pageSize = min(pageSize, maxPageSize)
+
+	filter := repos.UserFilter{
+		Search:     strings.TrimSpace(in.GetSearch()),
+		UserType:   strings.TrimSpace(in.GetUserType()),
+		IsActive:   in.IsActive,
+		IsVerified: in.IsVerified,
+	}
+
+	users, total, err := s.UserRepo.ListUsers(filter, int((page-1)*pageSize), int(pageSize))
+	if err != nil {
+		return nil, ErrInternal(err)
+	}
+
+	resp := &pb.ListUsersResponse{
+		Users:    make([]*pb.UserResponse, len(users)),
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}
+	for i := range users {
+		resp.Users[i] = userToResponse(&users[i])
+	}
+
+	return resp, nil
+}
+
+// GetUser retrieves a user by their id.
+// It takes in a context and a UserId, and returns a UserResponse and an error.
+func (s *AdminService) GetUser(ctx context.Context, in *pb.UserId) (*pb.UserResponse, error) {
+	if err := s.authorize(ctx, ScopeUsersRead); err != nil {
+		return nil, err
+	}
+
+	user, err := s.getUser(in.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	return userToResponse(user), nil
+}
+
+// CreateUser creates a user with the chosen user type, the default user type is used when none is given.
+// It takes in a context and a CreateUserRequest, and returns a UserResponse and an error.
+func (s *AdminService) CreateUser(ctx context.Context, in *pb.CreateUserRequest) (*pb.UserResponse, error) {
+	if err := s.authorize(ctx, ScopeUsersWrite); err != nil {
+		return nil, err
+	}
+
+	userType, err := s.getUserType(strings.TrimSpace(in.GetUserType()))
+	if err != nil {
+		return nil, err
+	}
+
+	if in.GetPassword() == "" {
+		return nil, ErrPasswordRequired
+	}
+
+	isActive := true
+	if in.IsActive != nil {
+		isActive = in.GetIsActive()
+	}
+
+	user := &models.User{
+		Email:      strings.TrimSpace(strings.ToLower(in.GetEmail())),
+		FirstName:  strings.TrimSpace(in.GetFirstName()),
+		LastName:   strings.TrimSpace(in.GetLastName()),
+		UserTypeId: userType.ID,
+		IsActive:   isActive,
+		IsVerified: in.GetIsVerified(),
+	}
+
+	if err := s.checkPassword(user, in.GetPassword(), false); err != nil {
+		return nil, err
+	}
+
+	user, err = s.UserRepo.InsertUser(user, in.GetPassword())
+
+	if err != nil {
+		var ve *models.UserValidateError
+		switch {
+		case errors.As(err, &ve):
+			return nil, ErrInvalidArgument(err)
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			return nil, ErrEmailAlreadyExists
+		default:
+			return nil, ErrInternal(err)
+		}
+	}
+
+	return userToResponse(user), nil
+}
+
+// UpdateUser updates the given fields of a user, including their user type, active and verified state.
+// It takes in a context and an AdminUpdateUserRequest, and returns a UserResponse and an error.
+func (s *AdminService) UpdateUser(ctx context.Context, in *pb.AdminUpdateUserRequest) (*pb.UserResponse, error) {
+	if err := s.authorize(ctx, ScopeUsersWrite); err != nil {
+		return nil, err
+	}
+
+	user, err := s.getUser(in.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	if in.Email != nil {
+		user.Email = strings.TrimSpace(strings.ToLower(in.GetEmail()))
+	}
+	if in.FirstName != nil {
+		user.FirstName = strings.TrimSpace(in.GetFirstName())
+	}
+	if in.LastName != nil {
+		user.LastName = strings.TrimSpace(in.GetLastName())
+	}
+	if in.UserType != nil {
+		userType, err := s.getUserType(strings.TrimSpace(in.GetUserType()))
+		if err != nil {
+			return nil, err
+		}
+		user.UserTypeId = userType.ID
+		user.UserType = *userType
+	}
+	if in.IsActive != nil {
+		user.IsActive = in.GetIsActive()
+	}
+	if in.IsVerified != nil {
+		user.IsVerified = in.GetIsVerified()
+	}
+
+	if err := user.Validate(); err != nil {
+		return nil, ErrInvalidArgument(err)
+	}
+
+	previousHash := user.HashedPassword
+	if in.Password != nil {
+		if err := s.checkPassword(user, in.GetPassword(), true); err != nil {
+			return nil, err
+		}
+		if err := user.SetPassword(in.GetPassword()); err != nil {
+			var ve *models.UserValidateError
+			switch {
+			case errors.As(err, &ve):
+				return nil, ErrInvalidArgument(err)
+			default:
+				return nil, ErrInternal(err)
+			}
+		}
+	}
+
+	if err := s.UserRepo.UpdateUser(user); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			return nil, ErrEmailAlreadyExists
+		default:
+			return nil, ErrInternal(err)
+		}
+	}
+
+	if in.Password != nil {
+		if err := s.recordPasswordHistory(user.ID, previousHash); err != nil {
+			return nil, ErrInternal(err)
+		}
+		if err := s.TokenRepo.RevokeAllSessions(user.ID, ""); err != nil {
+			return nil, ErrInternal(err)
+		}
+	}
+
+	return userToResponse(user), nil
+}
+
+// DeleteUser deletes a user along with all of their tokens.
+// It takes in a context and a UserId, and returns an Empty response and an error.
+func (s *AdminService) DeleteUser(ctx context.Context, in *pb.UserId) (*pb.Empty, error) {
+	if err := s.authorize(ctx, ScopeUsersWrite); err != nil {
+		return nil, err
+	}
+
+	userId, err := uuid.Parse(in.GetId())
+	if err != nil {
+		return nil, ErrUserIdInvalid
+	}
+
+	if err := s.UserRepo.DeleteUser(userId); err != nil {
+		switch {
+		case errors.Is(err, repos.ErrUserNotFound):
+			return nil, ErrUserNotFound
+		default:
+			return nil, ErrInternal(err)
+		}
+	}
+
+	return &pb.Empty{}, nil
+}
+
+// SetUserType changes the user type of a user.
+// It takes in a context and a SetUserTypeRequest, and returns a UserResponse and an error.
+func (s *AdminService) SetUserType(ctx context.Context, in *pb.SetUserTypeRequest) (*pb.UserResponse, error) {
+	if err := s.authorize(ctx, ScopeUsersWrite); err != nil {
+		return nil, err
+	}
+
+	user, err := s.getUser(in.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(in.GetUserType())
+	if name == "" {
+		return nil, ErrUserTypeInvalid
+	}
+
+	userType, err := s.getUserType(name)
+	if err != nil {
+		return nil, err
+	}
+
+	user.UserTypeId = userType.ID
+	user.UserType = *userType
+	if err := s.UserRepo.UpdateUser(user); err != nil {
+		return nil, ErrInternal(err)
+	}
+
+	return userToResponse(user), nil
+}
+
+// ListUserTypes lists all user types along with their scopes.
+// It takes in a context and an Empty request, and returns a ListUserTypesResponse and an error.
+func (s *AdminService) ListUserTypes(ctx context.Context, _ *pb.Empty) (*pb.ListUserTypesResponse, error) {
+	if err := s.authorize(ctx, ScopeUserTypesRead); err != nil {
+		return nil, err
+	}
+
+	userTypes, err := s.UserTypeRepo.ListUserTypes()
+	if err != nil {
+		return nil, ErrInternal(err)
+	}
+
+	resp := &pb.ListUserTypesResponse{UserTypes: make([]*pb.UserTypeResponse, len(userTypes))}
+	for i := range userTypes {
+		resp.UserTypes[i] = userTypeToResponse(&userTypes[i])
+	}
+
+	return resp, nil
+}
+
+// GetUserType retrieves a user type by its id.
+// It takes in a context and a UserTypeId, and returns a UserTypeResponse and an error.
+func (s *AdminService) GetUserType(ctx context.Context, in *pb.UserTypeId) (*pb.UserTypeResponse, error) {
+	if err := s.authorize(ctx, ScopeUserTypesRead); err != nil {
+		return nil, err
+	}
+
+	userType, err := s.getUserTypeByID(in.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	return userTypeToResponse(userType), nil
+}
+
+// CreateUserType creates a user type with the given scopes, making it the default replaces the previous default.
+// It takes in a context and a CreateUserTypeRequest, and returns a UserTypeResponse and an error.
+func (s *AdminService) CreateUserType(ctx context.Context, in *pb.CreateUserTypeRequest) (*pb.UserTypeResponse, error) {
+	if err := s.authorize(ctx, ScopeUserTypesWrite); err != nil {
+		return nil, err
+	}
+
+	name, err := validName(in.GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	scopes, err := s.getScopes(in.GetScopes())
+	if err != nil {
+		return nil, err
+	}
+
+	userType := &models.UserType{
+		Name:      name,
+		IsDefault: in.GetIsDefault(),
+		Scopes:    scopes,
+	}
+	if err := s.UserTypeRepo.CreateUserType(userType); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			return nil, ErrUserTypeAlreadyExists
+		default:
+			return nil, ErrInternal(err)
+		}
+	}
+
+	return userTypeToResponse(userType), nil
+}
+
+// UpdateUserType renames a user type or makes it the default, replacing the previous default.
+// It takes in a context and an UpdateUserTypeRequest, and returns a UserTypeResponse and an error.
+func (s *AdminService) UpdateUserType(ctx context.Context, in *pb.UpdateUserTypeRequest) (*pb.UserTypeResponse, error) {
+	if err := s.authorize(ctx, ScopeUserTypesWrite); err != nil {
+		return nil, err
+	}
+
+	userType, err := s.getUserTypeByID(in.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	if in.Name != nil {
+		if userType.Name, err = validName(in.GetName()); err != nil {
+			return nil, err
+		}
+	}
+	if in.IsDefault != nil {
+		userType.IsDefault = in.GetIsDefault()
+	}
+
+	if err := s.UserTypeRepo.UpdateUserType(userType); err != nil {
+		switch {
+		case errors.Is(err, repos.ErrUserTypeNotFound):
+			return nil, ErrUserTypeNotFound
+		case errors.Is(err, repos.ErrUserTypeIsDefault):
+			return nil, ErrUserTypeIsDefault
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			return nil, ErrUserTypeAlreadyExists
+		default:
+			return nil, ErrInternal(err)
+		}
+	}
+
+	return userTypeToResponse(userType), nil
+}
+
+// SetUserTypeScopes replaces the scopes of a user type.
+// It takes in a context and a SetUserTypeScopesRequest, and returns a UserTypeResponse and an error.
+func (s *AdminService) SetUserTypeScopes(ctx context.Context, in *pb.SetUserTypeScopesRequest) (*pb.UserTypeResponse, error) {
+	if err := s.authorize(ctx, ScopeUserTypesWrite); err != nil {
+		return nil, err
+	}
+
+	userType, err := s.getUserTypeByID(in.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	scopes, err := s.getScopes(in.GetScopes())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.UserTypeRepo.SetUserTypeScopes(userType, scopes); err != nil {
+		return nil, ErrInternal(err)
+	}
+
+	return userTypeToResponse(userType), nil
+}
+
+// DeleteUserType deletes a user type, the default user type and user types assigned to users can not be deleted.
+// It takes in a context and a UserTypeId, and returns an Empty response and an error.
+func (s *AdminService) DeleteUserType(ctx context.Context, in *pb.UserTypeId) (*pb.Empty, error) {
+	if err := s.authorize(ctx, ScopeUserTypesWrite); err != nil {
+		return nil, err
+	}
+
+	userTypeId, err := uuid.Parse(in.GetId())
+	if err != nil {
+		return nil, ErrUserTypeIdInvalid
+	}
+
+	if err := s.UserTypeRepo.DeleteUserType(userTypeId); err != nil {
+		switch {
+		case errors.Is(err, repos.ErrUserTypeNotFound):
+			return nil, ErrUserTypeNotFound
+		case errors.Is(err, repos.ErrUserTypeIsDefault):
+			return nil, ErrUserTypeIsDefault
+		case errors.Is(err, repos.ErrUserTypeInUse):
+			return nil, ErrUserTypeInUse
+		default:
+			return nil, ErrInternal(err)
+		}
+	}
+
+	return &pb.Empty{}, nil
+}
+
+// ListScopes lists all scopes.
+// It takes in a context and an Empty request, and returns a ListScopesResponse and an error.
+func (s *AdminService) ListScopes(ctx context.Context, _ *pb.Empty) (*pb.ListScopesResponse, error) {
+	if err := s.authorize(ctx, ScopeUserTypesRead); err != nil {
+		return nil, err
+	}
+
+	scopes, err := s.ScopeRepo.ListScopes()
+	if err != nil {
+		return nil, ErrInternal(err)
+	}
+
+	resp := &pb.ListScopesResponse{Scopes: make([]*pb.ScopeResponse, len(scopes))}
+	for i := range scopes {
+		resp.Scopes[i] = scopeToResponse(&scopes[i])
+	}
+
+	return resp, nil
+}
+
+// CreateScope creates a scope.
+// It takes in a context and a CreateScopeRequest, and returns a ScopeResponse and an error.
+func (s *AdminService) CreateScope(ctx context.Context, in *pb.CreateScopeRequest) (*pb.ScopeResponse, error) {
+	if err := s.authorize(ctx, ScopeUserTypesWrite); err != nil {
+		return nil, err
+	}
+
+	name, err := validName(in.GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	scope := &models.Scope{Name: name}
+	if err := s.ScopeRepo.CreateScope(scope); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			return nil, ErrScopeAlreadyExists
+		default:
+			return nil, ErrInternal(err)
+		}
+	}
+
+	return scopeToResponse(scope), nil
+}
+
+// UpdateScope renames a scope.
+// It takes in a context and an UpdateScopeRequest, and returns a ScopeResponse and an error.
+func (s *AdminService) UpdateScope(ctx context.Context, in *pb.UpdateScopeRequest) (*pb.ScopeResponse, error) {
+	if err := s.authorize(ctx, ScopeUserTypesWrite); err != nil {
+		return nil, err
+	}
+
+	scopeId, err := uuid.Parse(in.GetId())
+	if err != nil {
+		return nil, ErrScopeIdInvalid
+	}
+
+	name, err := validName(in.GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	scope, err := s.ScopeRepo.GetScopeByID(scopeId)
+	if err != nil {
+		switch {
+		case errors.Is(err, repos.ErrScopeNotFound):
+			return nil, ErrScopeNotFound
+		default:
+			return nil, ErrInternal(err)
+		}
+	}
+
+	scope.Name = name
+	if err := s.ScopeRepo.UpdateScope(scope); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			return nil, ErrScopeAlreadyExists
+		default:
+			return nil, ErrInternal(err)
+		}
+	}
+
+	return scopeToResponse(scope), nil
+}
+
+// DeleteScope deletes a scope, removing it from every user type it is assigned to.
+// It takes in a context and a ScopeId, and returns an Empty response and an error.
+func (s *AdminService) DeleteScope(ctx context.Context, in *pb.ScopeId) (*pb.Empty, error) {
+	if err := s.authorize(ctx, ScopeUserTypesWrite); err != nil {
+		return nil, err
+	}
+
+	scopeId, err := uuid.Parse(in.GetId())
+	if err != nil {
+		return nil, ErrScopeIdInvalid
+	}
+
+	if err := s.ScopeRepo.DeleteScope(scopeId); err != nil {
+		switch {
+		case errors.Is(err, repos.ErrScopeNotFound):
+			return nil, ErrScopeNotFound
+		default:
+			return nil, ErrInternal(err)
+		}
+	}
+
+	return &pb.Empty{}, nil
+}
@@ -0,0 +1,781 @@
+package service_test
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/accentdesign/grpc/core/authz"
+	"github.com/accentdesign/grpc/core/errs"
+	"github.com/accentdesign/grpc/services/auth/internal/models"
+	"github.com/accentdesign/grpc/services/auth/internal/passwords"
+	"github.com/accentdesign/grpc/services/auth/internal/repos"
+	pb "github.com/accentdesign/grpc/services/auth/pkg/api/auth"
+	"github.com/accentdesign/grpc/services/auth/service"
+)
+
+func withBearer(ctx context.Context, token string) context.Context {
+	return metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token))
+}
+
+func (suite *TestSuite) newAdminService() *service.AdminService {
+	return &service.AdminService{
+		UserRepo:     &repos.UserRepository{DB: suite.db},
+		UserTypeRepo: &repos.UserTypeRepository{DB: suite.db},
+		ScopeRepo:    &repos.ScopeRepository{DB: suite.db},
+		TokenRepo: &repos.TokenRepository{
+			DB: suite.db,
+			Config: &repos.TokenConfig{
+				BearerDuration:  3600 * time.Second,
+				RefreshDuration: 3600 * time.Second,
+				ResetDuration:   3600 * time.Second,
+				VerifyDuration:  3600 * time.Second,
+			},
+		},
+		LoginAttemptRepo: &repos.LoginAttemptRepository{
+			DB:     suite.db,
+			Config: &repos.LoginAttemptConfig{MaxAccountFailures: 1, LockoutDuration: time.Minute, FailureWindow: time.Minute},
+		},
+	}
+}
+
+func (suite *TestSuite) TestAdminService_Authorization() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	adminService := suite.newAdminService()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	_, err = suite.helpers.CreateTestAdminUser("admin-token", service.ScopeUsersRead)
+	suite.NoError(err)
+
+	ctx := context.Background()
+
+	testCases := []struct {
+		desc          string
+		ctx           context.Context
+		expectedError error
+	}{
+		{"missing token", ctx, status.Error(codes.Unauthenticated, "a valid bearer token is required")},
+		{"wrong scheme", metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Basic admin-token")), status.Error(codes.Unauthenticated, "a valid bearer token is required")},
+		{"invalid token", withBearer(ctx, "invalid"), status.Error(codes.Unauthenticated, "a valid bearer token is required")},
+		{"missing scope", withBearer(ctx, "admin-token"), status.Error(codes.PermissionDenied, "permission denied")},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.desc, func() {
+			resp, err := adminService.DeactivateUser(tc.ctx, &pb.UserId{Id: user.ID.String()})
+			suite.EqualError(err, tc.expectedError.Error())
+			suite.Nil(resp)
+		})
+	}
+
+	// Test a principal set by the authz interceptor is used as is
+	resp, err := adminService.DeactivateUser(authz.NewContext(ctx, &authz.Principal{Scopes: []string{service.ScopeUsersWrite}}), &pb.UserId{Id: user.ID.String()})
+	suite.NoError(err)
+	suite.False(resp.IsActive)
+}
+
+func (suite *TestSuite) TestAdminService_Resolve() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	adminService := suite.newAdminService()
+
+	admin, err := suite.helpers.CreateTestAdminUser("admin-token", service.ScopeUsersRead)
+	suite.NoError(err)
+
+	p, err := adminService.Resolve(context.Background(), "admin-token")
+	suite.NoError(err)
+	suite.Equal(&authz.Principal{UserId: admin.ID.String(), UserType: "admin", Scopes: []string{service.ScopeUsersRead}}, p)
+
+	p, err = adminService.Resolve(context.Background(), "invalid")
+	suite.ErrorIs(err, authz.ErrInvalidToken)
+	suite.Nil(p)
+
+	// failing to look the token up is not an invalid token
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	unavailable := suite.newAdminService()
+	unavailable.UserRepo = &repos.UserRepository{DB: suite.db.WithContext(ctx)}
+
+	p, err = unavailable.Resolve(context.Background(), "admin-token")
+	suite.NotErrorIs(err, authz.ErrInvalidToken)
+	suite.Equal(codes.Internal, status.Code(err))
+	suite.Nil(p)
+
+	err = suite.db.Model(admin).UpdateColumn("is_active", false).Error
+	suite.NoError(err)
+
+	p, err = adminService.Resolve(context.Background(), "admin-token")
+	suite.EqualError(err, status.Error(codes.PermissionDenied, "user is inactive").Error())
+	suite.Nil(p)
+}
+
+func (suite *TestSuite) TestAdminRules() {
+	for _, method := range pb.AuthenticationAdmin_ServiceDesc.Methods {
+		fullMethod := "/" + pb.AuthenticationAdmin_ServiceDesc.ServiceName + "/" + method.MethodName
+		suite.Contains(service.AdminRules, fullMethod)
+		suite.NotEmpty(service.AdminRules[fullMethod])
+	}
+	suite.Len(service.AdminRules, len(pb.AuthenticationAdmin_ServiceDesc.Methods))
+}
+
+func (suite *TestSuite) TestAdminService_DeactivateUser() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	adminService := suite.newAdminService()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	_, err = suite.helpers.CreateTestAdminUser("admin-token", service.ScopeUsersWrite)
+	suite.NoError(err)
+
+	ctx := withBearer(context.Background(), "admin-token")
+
+	testCases := []struct {
+		desc          string
+		request       *pb.UserId
+		expectedError error
+	}{
+		{"missing id", &pb.UserId{}, status.Error(codes.InvalidArgument, "invalid user id")},
+		{"invalid id", &pb.UserId{Id: "invalid"}, status.Error(codes.InvalidArgument, "invalid user id")},
+		{"unknown id", &pb.UserId{Id: "3f4b2b87-d7b1-4b9f-b207-ae00b112382f"}, status.Error(codes.NotFound, "user not found")},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.desc, func() {
+			resp, err := adminService.DeactivateUser(ctx, tc.request)
+			suite.EqualError(err, tc.expectedError.Error())
+			suite.Nil(resp)
+		})
+	}
+
+	token := &models.AccessToken{UserId: user.ID, Token: "user-token", ExpiresAt: time.Now().Add(time.Minute)}
+	err = suite.db.Create(token).Error
+	suite.NoError(err)
+
+	// Test deactivate
+	resp, err := adminService.DeactivateUser(ctx, &pb.UserId{Id: user.ID.String()})
+	suite.NoError(err)
+	suite.Equal(user.ID.String(), resp.Id)
+	suite.False(resp.IsActive)
+
+	var fetchedUser models.User
+	err = suite.db.First(&fetchedUser, "id = ?", user.ID).Error
+	suite.NoError(err)
+	suite.False(fetchedUser.IsActive)
+
+	err = suite.db.First(token).Error
+	suite.EqualError(err, "record not found")
+
+	// Test reactivate
+	resp, err = adminService.ReactivateUser(ctx, &pb.UserId{Id: user.ID.String()})
+	suite.NoError(err)
+	suite.True(resp.IsActive)
+
+	err = suite.db.First(&fetchedUser, "id = ?", user.ID).Error
+	suite.NoError(err)
+	suite.True(fetchedUser.IsActive)
+}
+
+func (suite *TestSuite) TestAdminService_UnlockUser() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	adminService := suite.newAdminService()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	_, err = suite.helpers.CreateTestAdminUser("admin-token", service.ScopeUsersWrite)
+	suite.NoError(err)
+
+	ctx := withBearer(context.Background(), "admin-token")
+
+	testCases := []struct {
+		desc          string
+		request       *pb.UserId
+		expectedError error
+	}{
+		{"missing id", &pb.UserId{}, status.Error(codes.InvalidArgument, "invalid user id")},
+		{"invalid id", &pb.UserId{Id: "invalid"}, status.Error(codes.InvalidArgument, "invalid user id")},
+		{"unknown id", &pb.UserId{Id: "3f4b2b87-d7b1-4b9f-b207-ae00b112382f"}, status.Error(codes.NotFound, "user not found")},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.desc, func() {
+			resp, err := adminService.UnlockUser(ctx, tc.request)
+			suite.EqualError(err, tc.expectedError.Error())
+			suite.Nil(resp)
+		})
+	}
+
+	key := repos.AccountKey(user.Email)
+	err = adminService.LoginAttemptRepo.RecordFailure(key, 1)
+	suite.NoError(err)
+
+	check, err := adminService.LoginAttemptRepo.Check(key)
+	suite.NoError(err)
+	suite.True(check.Locked)
+
+	resp, err := adminService.UnlockUser(ctx, &pb.UserId{Id: user.ID.String()})
+	suite.NoError(err)
+	suite.Equal(user.ID.String(), resp.Id)
+
+	check, err = adminService.LoginAttemptRepo.Check(key)
+	suite.NoError(err)
+	suite.False(check.Locked)
+}
+
+func (suite *TestSuite) TestAdminService_ListUsers() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	adminService := suite.newAdminService()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	admin, err := suite.helpers.CreateTestAdminUser("admin-token", service.ScopeUsersRead)
+	suite.NoError(err)
+
+	ctx := withBearer(context.Background(), "admin-token")
+
+	// Test unauthorized
+	resp, err := adminService.ListUsers(context.Background(), &pb.ListUsersRequest{})
+	suite.EqualError(err, status.Error(codes.Unauthenticated, "a valid bearer token is required").Error())
+	suite.Nil(resp)
+
+	testCases := []struct {
+		desc     string
+		request  *pb.ListUsersRequest
+		expected []string
+		total    int64
+	}{
+		{"all", &pb.ListUsersRequest{}, []string{admin.Email, user.Email}, 2},
+		{"first page", &pb.ListUsersRequest{Page: 1, PageSize: 1}, []string{admin.Email}, 2},
+		{"second page", &pb.ListUsersRequest{Page: 2, PageSize: 1}, []string{user.Email}, 2},
+		{"out of range page", &pb.ListUsersRequest{Page: 3, PageSize: 1}, []string{}, 2},
+		{"search email", &pb.ListUsersRequest{Search: "TEST@"}, []string{user.Email}, 1},
+		{"search name", &pb.ListUsersRequest{Search: "jane"}, []string{admin.Email}, 1},
+		{"search wildcard", &pb.ListUsersRequest{Search: "%"}, []string{}, 0},
+		{"user type", &pb.ListUsersRequest{UserType: "admin"}, []string{admin.Email}, 1},
+		{"is verified", &pb.ListUsersRequest{IsVerified: proto.Bool(false)}, []string{user.Email}, 1},
+		{"is active", &pb.ListUsersRequest{IsActive: proto.Bool(false)}, []string{}, 0},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.desc, func() {
+			resp, err := adminService.ListUsers(ctx, tc.request)
+			suite.NoError(err)
+			suite.Equal(tc.total, resp.Total)
+
+			emails := []string{}
+			for _, u := range resp.Users {
+				emails = append(emails, u.Email)
+			}
+			suite.Equal(tc.expected, emails)
+		})
+	}
+
+	// Test page defaults
+	resp, err = adminService.ListUsers(ctx, &pb.ListUsersRequest{PageSize: 1000})
+	suite.NoError(err)
+	suite.Equal(int32(1), resp.Page)
+	suite.Equal(int32(100), resp.PageSize)
+}
+
+func (suite *TestSuite) TestAdminService_GetUser() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	adminService := suite.newAdminService()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	_, err = suite.helpers.CreateTestAdminUser("admin-token", service.ScopeUsersRead)
+	suite.NoError(err)
+
+	ctx := withBearer(context.Background(), "admin-token")
+
+	testCases := []struct {
+		desc          string
+		request       *pb.UserId
+		expectedError error
+	}{
+		{"invalid id", &pb.UserId{Id: "invalid"}, status.Error(codes.InvalidArgument, "invalid user id")},
+		{"unknown id", &pb.UserId{Id: "3f4b2b87-d7b1-4b9f-b207-ae00b112382f"}, status.Error(codes.NotFound, "user not found")},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.desc, func() {
+			resp, err := adminService.GetUser(ctx, tc.request)
+			suite.EqualError(err, tc.expectedError.Error())
+			suite.Nil(resp)
+		})
+	}
+
+	resp, err := adminService.GetUser(ctx, &pb.UserId{Id: user.ID.String()})
+	suite.NoError(err)
+	suite.Equal(&pb.UserResponse{
+		Id:        user.ID.String(),
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Email:     user.Email,
+		UserType: &pb.UserType{
+			Name:   "standard",
+			Scopes: []string{},
+		},
+		IsActive:   true,
+		IsVerified: false,
+	}, resp)
+}
+
+func (suite *TestSuite) TestAdminService_CreateUser() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	adminService := suite.newAdminService()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	_, err = suite.helpers.CreateTestAdminUser("admin-token", service.ScopeUsersWrite)
+	suite.NoError(err)
+
+	ctx := withBearer(context.Background(), "admin-token")
+
+	testCases := []struct {
+		desc          string
+		request       *pb.CreateUserRequest
+		expectedError error
+	}{
+		{"invalid email", &pb.CreateUserRequest{Email: "invalid"}, status.Error(codes.InvalidArgument, "invalid email format")},
+		{"missing password", &pb.CreateUserRequest{Email: "new@example.com", FirstName: "Some", LastName: "One"}, status.Error(codes.InvalidArgument, "password is required")},
+		{"unknown user type", &pb.CreateUserRequest{Email: "new@example.com", Password: "password", FirstName: "Some", LastName: "One", UserType: "unknown"}, status.Error(codes.InvalidArgument, "invalid user type")},
+		{"existing email", &pb.CreateUserRequest{Email: user.Email, Password: "password", FirstName: "Some", LastName: "One"}, status.Error(codes.AlreadyExists, "a user with this email already exists")},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.desc, func() {
+			resp, err := adminService.CreateUser(ctx, tc.request)
+			suite.EqualError(err, tc.expectedError.Error())
+			suite.Nil(resp)
+		})
+	}
+
+	// Test default user type
+	resp, err := adminService.CreateUser(ctx, &pb.CreateUserRequest{Email: " New@Example.com ", Password: "password", FirstName: "Some", LastName: "One"})
+	suite.NoError(err)
+	suite.Equal("new@example.com", resp.Email)
+	suite.Equal("standard", resp.UserType.Name)
+	suite.True(resp.IsActive)
+	suite.False(resp.IsVerified)
+
+	// Test chosen user type and state
+	resp, err = adminService.CreateUser(ctx, &pb.CreateUserRequest{Email: "other@example.com", Password: "password", FirstName: "Some", LastName: "One", UserType: "admin", IsActive: proto.Bool(false), IsVerified: true})
+	suite.NoError(err)
+	suite.Equal("admin", resp.UserType.Name)
+	suite.Equal([]string{service.ScopeUsersWrite}, resp.UserType.Scopes)
+	suite.False(resp.IsActive)
+	suite.True(resp.IsVerified)
+
+	var fetchedUser models.User
+	err = suite.db.First(&fetchedUser, "email = ?", "other@example.com").Error
+	suite.NoError(err)
+	suite.False(fetchedUser.IsActive)
+	suite.True(fetchedUser.IsVerified)
+	suite.True(fetchedUser.VerifyPassword("password"))
+}
+
+func (suite *TestSuite) TestAdminService_UpdateUser() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	adminService := suite.newAdminService()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	admin, err := suite.helpers.CreateTestAdminUser("admin-token", service.ScopeUsersWrite)
+	suite.NoError(err)
+
+	ctx := withBearer(context.Background(), "admin-token")
+
+	testCases := []struct {
+		desc          string
+		request       *pb.AdminUpdateUserRequest
+		expectedError error
+	}{
+		{"invalid id", &pb.AdminUpdateUserRequest{Id: "invalid"}, status.Error(codes.InvalidArgument, "invalid user id")},
+		{"unknown id", &pb.AdminUpdateUserRequest{Id: "3f4b2b87-d7b1-4b9f-b207-ae00b112382f"}, status.Error(codes.NotFound, "user not found")},
+		{"invalid email", &pb.AdminUpdateUserRequest{Id: user.ID.String(), Email: proto.String("invalid")}, status.Error(codes.InvalidArgument, "invalid email format")},
+		{"empty first name", &pb.AdminUpdateUserRequest{Id: user.ID.String(), FirstName: proto.String("")}, status.Error(codes.InvalidArgument, "first_name is required")},
+		{"short password", &pb.AdminUpdateUserRequest{Id: user.ID.String(), Password: proto.String("short")}, status.Error(codes.InvalidArgument, "password must be between 6 and 72 characters in length")},
+		{"unknown user type", &pb.AdminUpdateUserRequest{Id: user.ID.String(), UserType: proto.String("unknown")}, status.Error(codes.InvalidArgument, "invalid user type")},
+		{"existing email", &pb.AdminUpdateUserRequest{Id: user.ID.String(), Email: proto.String(admin.Email)}, status.Error(codes.AlreadyExists, "a user with this email already exists")},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.desc, func() {
+			resp, err := adminService.UpdateUser(ctx, tc.request)
+			suite.EqualError(err, tc.expectedError.Error())
+			suite.Nil(resp)
+		})
+	}
+
+	resp, err := adminService.UpdateUser(ctx, &pb.AdminUpdateUserRequest{
+		Id:         user.ID.String(),
+		Email:      proto.String("changed@example.com"),
+		Password:   proto.String("changed"),
+		FirstName:  proto.String("Some"),
+		UserType:   proto.String("admin"),
+		IsVerified: proto.Bool(true),
+	})
+	suite.NoError(err)
+	suite.Equal("changed@example.com", resp.Email)
+	suite.Equal("Some", resp.FirstName)
+	suite.Equal(user.LastName, resp.LastName)
+	suite.Equal("admin", resp.UserType.Name)
+	suite.True(resp.IsActive)
+	suite.True(resp.IsVerified)
+
+	var fetchedUser models.User
+	err = suite.db.Preload("UserType").First(&fetchedUser, "id = ?", user.ID).Error
+	suite.NoError(err)
+	suite.Equal("changed@example.com", fetchedUser.Email)
+	suite.Equal("admin", fetchedUser.UserType.Name)
+	suite.True(fetchedUser.IsVerified)
+	suite.True(fetchedUser.VerifyPassword("changed"))
+
+	resp, err = adminService.UpdateUser(ctx, &pb.AdminUpdateUserRequest{Id: user.ID.String(), IsActive: proto.Bool(false)})
+	suite.NoError(err)
+	suite.False(resp.IsActive)
+}
+
+func (suite *TestSuite) TestAdminService_PasswordPolicy() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	adminService := suite.newAdminService()
+	adminService.PasswordPolicy = &passwords.Policy{
+		MinLength: 8,
+		Classes:   []string{passwords.ClassDigit},
+		History:   3,
+	}
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	_, err = suite.helpers.CreateTestAdminUser("admin-token", service.ScopeUsersWrite)
+	suite.NoError(err)
+
+	ctx := withBearer(context.Background(), "admin-token")
+
+	violations := func(err error) []string {
+		suite.Equal("PASSWORD_POLICY", errs.Reason(err))
+		var descriptions []string
+		for _, violation := range errs.FieldViolations(err) {
+			suite.Equal("password", violation.GetField())
+			descriptions = append(descriptions, violation.GetDescription())
+		}
+		return descriptions
+	}
+
+	// create
+	_, err = adminService.CreateUser(ctx, &pb.CreateUserRequest{Email: "new@example.com", Password: "short", FirstName: "Some", LastName: "One"})
+	suite.Equal([]string{"must be at least 8 characters in length", "must contain a digit"}, violations(err))
+
+	_, err = adminService.CreateUser(ctx, &pb.CreateUserRequest{Email: "new@example.com", Password: "sunny-day-42", FirstName: "Some", LastName: "One"})
+	suite.NoError(err)
+
+	// update, the current and previous passwords cannot be reused
+	_, err = adminService.UpdateUser(ctx, &pb.AdminUpdateUserRequest{Id: user.ID.String(), Password: proto.String("password")})
+	suite.Equal([]string{"must contain a digit", "must not be one of your last 3 passwords"}, violations(err))
+
+	_, err = adminService.UpdateUser(ctx, &pb.AdminUpdateUserRequest{Id: user.ID.String(), Password: proto.String("rainy-day-1")})
+	suite.NoError(err)
+
+	_, err = adminService.UpdateUser(ctx, &pb.AdminUpdateUserRequest{Id: user.ID.String(), Password: proto.String("snowy-day-2")})
+	suite.NoError(err)
+
+	_, err = adminService.UpdateUser(ctx, &pb.AdminUpdateUserRequest{Id: user.ID.String(), Password: proto.String("rainy-day-1")})
+	suite.Equal([]string{"must not be one of your last 3 passwords"}, violations(err))
+
+	var fetchedUser models.User
+	suite.NoError(suite.db.First(&fetchedUser, "id = ?", user.ID).Error)
+	suite.True(fetchedUser.VerifyPassword("snowy-day-2"))
+}
+
+func (suite *TestSuite) TestAdminService_DeleteUser() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	adminService := suite.newAdminService()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	_, err = suite.helpers.CreateTestAdminUser("admin-token", service.ScopeUsersWrite)
+	suite.NoError(err)
+
+	ctx := withBearer(context.Background(), "admin-token")
+
+	testCases := []struct {
+		desc          string
+		request       *pb.UserId
+		expectedError error
+	}{
+		{"invalid id", &pb.UserId{Id: "invalid"}, status.Error(codes.InvalidArgument, "invalid user id")},
+		{"unknown id", &pb.UserId{Id: "3f4b2b87-d7b1-4b9f-b207-ae00b112382f"}, status.Error(codes.NotFound, "user not found")},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.desc, func() {
+			resp, err := adminService.DeleteUser(ctx, tc.request)
+			suite.EqualError(err, tc.expectedError.Error())
+			suite.Nil(resp)
+		})
+	}
+
+	token := &models.AccessToken{UserId: user.ID, Token: "user-token", ExpiresAt: time.Now().Add(time.Minute)}
+	err = suite.db.Create(token).Error
+	suite.NoError(err)
+
+	resp, err := adminService.DeleteUser(ctx, &pb.UserId{Id: user.ID.String()})
+	suite.NoError(err)
+	suite.Equal(&pb.Empty{}, resp)
+
+	err = suite.db.First(&models.User{}, "id = ?", user.ID).Error
+	suite.EqualError(err, "record not found")
+
+	err = suite.db.First(token).Error
+	suite.EqualError(err, "record not found")
+}
+
+func (suite *TestSuite) TestAdminService_SetUserType() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	adminService := suite.newAdminService()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	_, err = suite.helpers.CreateTestAdminUser("admin-token", service.ScopeUsersWrite)
+	suite.NoError(err)
+
+	ctx := withBearer(context.Background(), "admin-token")
+
+	testCases := []struct {
+		desc          string
+		request       *pb.SetUserTypeRequest
+		expectedError error
+	}{
+		{"invalid id", &pb.SetUserTypeRequest{UserId: "invalid", UserType: "admin"}, status.Error(codes.InvalidArgument, "invalid user id")},
+		{"empty user type", &pb.SetUserTypeRequest{UserId: user.ID.String()}, status.Error(codes.InvalidArgument, "invalid user type")},
+		{"unknown user type", &pb.SetUserTypeRequest{UserId: user.ID.String(), UserType: "unknown"}, status.Error(codes.InvalidArgument, "invalid user type")},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.desc, func() {
+			resp, err := adminService.SetUserType(ctx, tc.request)
+			suite.EqualError(err, tc.expectedError.Error())
+			suite.Nil(resp)
+		})
+	}
+
+	resp, err := adminService.SetUserType(ctx, &pb.SetUserTypeRequest{UserId: user.ID.String(), UserType: "admin"})
+	suite.NoError(err)
+	suite.Equal("admin", resp.UserType.Name)
+	suite.Equal([]string{service.ScopeUsersWrite}, resp.UserType.Scopes)
+
+	var fetchedUser models.User
+	err = suite.db.Preload("UserType").First(&fetchedUser, "id = ?", user.ID).Error
+	suite.NoError(err)
+	suite.Equal("admin", fetchedUser.UserType.Name)
+}
+
+func (suite *TestSuite) TestAdminService_UserTypes() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	adminService := suite.newAdminService()
+
+	_, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	_, err = suite.helpers.CreateTestAdminUser("admin-token", service.ScopeUserTypesRead, service.ScopeUserTypesWrite)
+	suite.NoError(err)
+
+	ctx := withBearer(context.Background(), "admin-token")
+
+	// Test create
+	created, err := adminService.CreateUserType(ctx, &pb.CreateUserTypeRequest{Name: " staff ", Scopes: []string{service.ScopeUserTypesRead, " ", service.ScopeUserTypesRead}})
+	suite.NoError(err)
+	suite.Equal("staff", created.Name)
+	suite.False(created.IsDefault)
+	suite.Equal([]string{service.ScopeUserTypesRead}, created.Scopes)
+
+	createCases := []struct {
+		desc          string
+		request       *pb.CreateUserTypeRequest
+		expectedError error
+	}{
+		{"empty name", &pb.CreateUserTypeRequest{Name: " "}, status.Error(codes.InvalidArgument, "name must be between 1 and 120 characters in length")},
+		{"unknown scope", &pb.CreateUserTypeRequest{Name: "other", Scopes: []string{"unknown"}}, status.Error(codes.InvalidArgument, "invalid scope")},
+		{"existing name", &pb.CreateUserTypeRequest{Name: "staff"}, status.Error(codes.AlreadyExists, "a user type with this name already exists")},
+	}
+
+	for _, tc := range createCases {
+		suite.Run(tc.desc, func() {
+			resp, err := adminService.CreateUserType(ctx, tc.request)
+			suite.EqualError(err, tc.expectedError.Error())
+			suite.Nil(resp)
+		})
+	}
+
+	// Test list
+	list, err := adminService.ListUserTypes(ctx, &pb.Empty{})
+	suite.NoError(err)
+	names := []string{}
+	for _, userType := range list.UserTypes {
+		names = append(names, userType.Name)
+	}
+	suite.Equal([]string{"admin", "staff", "standard"}, names)
+
+	// Test get
+	fetched, err := adminService.GetUserType(ctx, &pb.UserTypeId{Id: created.Id})
+	suite.NoError(err)
+	suite.Equal(created.Id, fetched.Id)
+
+	resp, err := adminService.GetUserType(ctx, &pb.UserTypeId{Id: "invalid"})
+	suite.EqualError(err, status.Error(codes.InvalidArgument, "invalid user type id").Error())
+	suite.Nil(resp)
+
+	resp, err = adminService.GetUserType(ctx, &pb.UserTypeId{Id: "3f4b2b87-d7b1-4b9f-b207-ae00b112382f"})
+	suite.EqualError(err, status.Error(codes.NotFound, "user type not found").Error())
+	suite.Nil(resp)
+
+	// Test set scopes
+	resp, err = adminService.SetUserTypeScopes(ctx, &pb.SetUserTypeScopesRequest{Id: created.Id, Scopes: []string{service.ScopeUserTypesWrite, service.ScopeUserTypesRead}})
+	suite.NoError(err)
+	suite.Equal([]string{service.ScopeUserTypesRead, service.ScopeUserTypesWrite}, resp.Scopes)
+
+	resp, err = adminService.SetUserTypeScopes(ctx, &pb.SetUserTypeScopesRequest{Id: created.Id})
+	suite.NoError(err)
+	suite.Empty(resp.Scopes)
+
+	// Test making a user type the default replaces the previous default
+	resp, err = adminService.UpdateUserType(ctx, &pb.UpdateUserTypeRequest{Id: created.Id, Name: proto.String("staff members"), IsDefault: proto.Bool(true)})
+	suite.NoError(err)
+	suite.Equal("staff members", resp.Name)
+	suite.True(resp.IsDefault)
+
+	var defaults []models.UserType
+	err = suite.db.Where("is_default is true").Find(&defaults).Error
+	suite.NoError(err)
+	suite.Len(defaults, 1)
+	suite.Equal("staff members", defaults[0].Name)
+
+	// Test the default can not be unset or deleted
+	resp, err = adminService.UpdateUserType(ctx, &pb.UpdateUserTypeRequest{Id: created.Id, IsDefault: proto.Bool(false)})
+	suite.EqualError(err, service.ErrUserTypeIsDefault.Error())
+	suite.Nil(resp)
+
+	_, err = adminService.DeleteUserType(ctx, &pb.UserTypeId{Id: created.Id})
+	suite.EqualError(err, service.ErrUserTypeIsDefault.Error())
+
+	// Test a user type assigned to users can not be deleted
+	standard, err := suite.helpers.CreateTestUserType()
+	suite.NoError(err)
+
+	_, err = adminService.DeleteUserType(ctx, &pb.UserTypeId{Id: standard.ID.String()})
+	suite.EqualError(err, status.Error(codes.FailedPrecondition, "user type is assigned to users").Error())
+
+	// Test delete
+	unused, err := adminService.CreateUserType(ctx, &pb.CreateUserTypeRequest{Name: "unused", Scopes: []string{service.ScopeUserTypesRead}})
+	suite.NoError(err)
+
+	empty, err := adminService.DeleteUserType(ctx, &pb.UserTypeId{Id: unused.Id})
+	suite.NoError(err)
+	suite.Equal(&pb.Empty{}, empty)
+
+	_, err = adminService.DeleteUserType(ctx, &pb.UserTypeId{Id: unused.Id})
+	suite.EqualError(err, status.Error(codes.NotFound, "user type not found").Error())
+}
+
+func (suite *TestSuite) TestAdminService_Scopes() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	adminService := suite.newAdminService()
+
+	_, err := suite.helpers.CreateTestAdminUser("admin-token", service.ScopeUserTypesRead, service.ScopeUserTypesWrite)
+	suite.NoError(err)
+
+	ctx := withBearer(context.Background(), "admin-token")
+
+	// Test create
+	created, err := adminService.CreateScope(ctx, &pb.CreateScopeRequest{Name: " reports:read "})
+	suite.NoError(err)
+	suite.Equal("reports:read", created.Name)
+
+	resp, err := adminService.CreateScope(ctx, &pb.CreateScopeRequest{Name: "reports:read"})
+	suite.EqualError(err, status.Error(codes.AlreadyExists, "a scope with this name already exists").Error())
+	suite.Nil(resp)
+
+	resp, err = adminService.CreateScope(ctx, &pb.CreateScopeRequest{})
+	suite.EqualError(err, status.Error(codes.InvalidArgument, "name must be between 1 and 120 characters in length").Error())
+	suite.Nil(resp)
+
+	// Test list
+	list, err := adminService.ListScopes(ctx, &pb.Empty{})
+	suite.NoError(err)
+	names := []string{}
+	for _, scope := range list.Scopes {
+		names = append(names, scope.Name)
+	}
+	suite.Equal([]string{"reports:read", service.ScopeUserTypesRead, service.ScopeUserTypesWrite}, names)
+
+	// Test update
+	resp, err = adminService.UpdateScope(ctx, &pb.UpdateScopeRequest{Id: created.Id, Name: "reports:view"})
+	suite.NoError(err)
+	suite.Equal("reports:view", resp.Name)
+
+	resp, err = adminService.UpdateScope(ctx, &pb.UpdateScopeRequest{Id: created.Id, Name: service.ScopeUserTypesRead})
+	suite.EqualError(err, status.Error(codes.AlreadyExists, "a scope with this name already exists").Error())
+	suite.Nil(resp)
+
+	resp, err = adminService.UpdateScope(ctx, &pb.UpdateScopeRequest{Id: "3f4b2b87-d7b1-4b9f-b207-ae00b112382f", Name: "other"})
+	suite.EqualError(err, status.Error(codes.NotFound, "scope not found").Error())
+	suite.Nil(resp)
+
+	// Test delete removes the scope from user types
+	userType, err := adminService.CreateUserType(ctx, &pb.CreateUserTypeRequest{Name: "reporter", Scopes: []string{"reports:view"}})
+	suite.NoError(err)
+
+	empty, err := adminService.DeleteScope(ctx, &pb.ScopeId{Id: created.Id})
+	suite.NoError(err)
+	suite.Equal(&pb.Empty{}, empty)
+
+	fetched, err := adminService.GetUserType(ctx, &pb.UserTypeId{Id: userType.Id})
+	suite.NoError(err)
+	suite.Empty(fetched.Scopes)
+
+	_, err = adminService.DeleteScope(ctx, &pb.ScopeId{Id: created.Id})
+	suite.EqualError(err, status.Error(codes.NotFound, "scope not found").Error())
+
+	_, err = adminService.DeleteScope(ctx, &pb.ScopeId{Id: "invalid"})
+	suite.EqualError(err, status.Error(codes.InvalidArgument, "invalid scope id").Error())
+}
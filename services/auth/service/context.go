@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/accentdesign/grpc/core/authz"
+	"github.com/accentdesign/grpc/services/auth/internal/models"
+	"github.com/accentdesign/grpc/services/auth/internal/repos"
+)
+
+type userKey struct{}
+
+// NewContextWithUser returns a copy of ctx carrying the authenticated user.
+func NewContextWithUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFromContext returns the authenticated user stored in ctx by NewContextWithUser or UserInterceptor.
+func UserFromContext(ctx context.Context) (*models.User, bool) {
+	user, ok := ctx.Value(userKey{}).(*models.User)
+	return user, ok
+}
+
+// clientIP returns the ip address of the peer that sent the request, or an empty string when it is not known.
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// sessionInfo describes the client of the request, for recording against the access tokens issued to it.
+func sessionInfo(ctx context.Context) repos.SessionInfo {
+	info := repos.SessionInfo{IPAddress: clientIP(ctx)}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("user-agent"); len(values) > 0 {
+			info.UserAgent = values[0]
+		}
+	}
+	return info
+}
+
+// bearerToken returns the token from the "authorization: Bearer <token>" metadata of the request,
+// falling back to the token given in the request message.
+func bearerToken(ctx context.Context, fieldToken string) string {
+	if token := authz.TokenFromContext(ctx); token != "" {
+		return token
+	}
+	return fieldToken
+}
+
+// authenticate returns the active user the access token of the request belongs to.
+// The token is taken from the metadata of the request, falling back to the token given in the request message.
+func (s *AuthService) authenticate(ctx context.Context, fieldToken string) (*models.User, error) {
+	if user, ok := UserFromContext(ctx); ok {
+		return user, nil
+	}
+
+	token := bearerToken(ctx, fieldToken)
+	if token == "" {
+		return nil, ErrTokenRequired
+	}
+
+	user, err := s.UserRepo.GetUserByAccessToken(token)
+	if err != nil {
+		return nil, ErrToken(err)
+	}
+
+	if !user.IsActive {
+		return nil, ErrUserInactive
+	}
+
+	if err := s.TokenRepo.TouchAccessToken(token); err != nil {
+		return nil, ErrInternal(err)
+	}
+
+	return user, nil
+}
+
+// UserInterceptor stores the active user of a valid "authorization: Bearer <token>" metadata header in the
+// context of the request, where handlers can read it with UserFromContext. Requests without a valid header
+// are passed on as is so handlers can reject them.
+func (s *AuthService) UserInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if authz.TokenFromContext(ctx) != "" {
+			if user, err := s.authenticate(ctx, ""); err == nil {
+				ctx = NewContextWithUser(ctx, user)
+			}
+		}
+		return handler(ctx, req)
+	}
+}
@@ -3,45 +3,262 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log"
 	"strings"
 	"time"
 
 	"github.com/asaskevich/govalidator"
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 	"gorm.io/gorm"
 
+	"github.com/accentdesign/grpc/core/errs"
 	"github.com/accentdesign/grpc/services/auth/internal/models"
+	"github.com/accentdesign/grpc/services/auth/internal/notify"
+	"github.com/accentdesign/grpc/services/auth/internal/oidc"
+	"github.com/accentdesign/grpc/services/auth/internal/passwords"
 	"github.com/accentdesign/grpc/services/auth/internal/repos"
 	pb "github.com/accentdesign/grpc/services/auth/pkg/api/auth"
 )
 
+// ErrorDomain is the domain of the ErrorInfo details of errors returned by the service.
+const ErrorDomain = "auth"
+
 var (
-	ErrEmailAlreadyExists  = status.Error(codes.AlreadyExists, "a user with this email already exists")
-	ErrEmailInvalid        = status.Error(codes.InvalidArgument, "invalid email format")
-	ErrInvalidCredentials  = status.Error(codes.InvalidArgument, "invalid credentials")
-	ErrPasswordRequired    = status.Error(codes.InvalidArgument, "password is required")
-	ErrTokenInvalid        = status.Error(codes.InvalidArgument, "invalid token")
-	ErrTokenRequired       = status.Error(codes.InvalidArgument, "token is required")
-	ErrUserAlreadyVerified = status.Error(codes.FailedPrecondition, "user is already verified")
-	ErrUserNotFound        = status.Error(codes.NotFound, "user not found")
+	ErrCurrentPasswordInvalid  = errs.FieldError(codes.InvalidArgument, ErrorDomain, "CURRENT_PASSWORD_INVALID", "current_password", "current password is incorrect")
+	ErrCurrentPasswordRequired = errs.FieldError(codes.InvalidArgument, ErrorDomain, "CURRENT_PASSWORD_REQUIRED", "current_password", "current_password is required")
+	ErrEmailAlreadyExists      = errs.Error(codes.AlreadyExists, ErrorDomain, "EMAIL_TAKEN", "a user with this email already exists")
+	ErrEmailChangeRequired     = errs.FieldError(codes.InvalidArgument, ErrorDomain, "EMAIL_CHANGE_REQUIRED", "email", "email can only be changed with RequestEmailChange")
+	ErrEmailUnchanged          = errs.FieldError(codes.InvalidArgument, ErrorDomain, "EMAIL_UNCHANGED", "email", "email is unchanged")
+	ErrEmailInvalid            = errs.FieldError(codes.InvalidArgument, ErrorDomain, "EMAIL_INVALID", "email", "invalid email format")
+	ErrInvalidCredentials      = errs.Error(codes.InvalidArgument, ErrorDomain, "INVALID_CREDENTIALS", "invalid credentials")
+	ErrIdentityAlreadyLinked   = errs.Error(codes.AlreadyExists, ErrorDomain, "IDENTITY_ALREADY_LINKED", "an account of this provider is already linked")
+	ErrIdentityNotFound        = errs.Error(codes.NotFound, ErrorDomain, "IDENTITY_NOT_FOUND", "identity not found")
+	ErrIdentityNotLinked       = errs.Error(codes.FailedPrecondition, ErrorDomain, "IDENTITY_NOT_LINKED", "a user with this email already exists, log in to link the account")
+	ErrJWTNotEnabled           = errs.Error(codes.FailedPrecondition, ErrorDomain, "JWT_NOT_ENABLED", "jwt access tokens are not enabled")
+	ErrLoginCodeInvalid        = errs.FieldError(codes.InvalidArgument, ErrorDomain, "LOGIN_CODE_INVALID", "code", "invalid login code")
+	ErrLoginDelayed            = errs.New(codes.Unavailable, ErrorDomain, "LOGIN_DELAYED", "too many failed login attempts, try again later")
+	ErrLoginLocked             = errs.New(codes.ResourceExhausted, ErrorDomain, "LOGIN_LOCKED", "too many failed login attempts, login is temporarily locked")
+	ErrMFACodeInvalid          = errs.FieldError(codes.InvalidArgument, ErrorDomain, "MFA_CODE_INVALID", "code", "invalid code")
+	ErrMFANotEnabled           = errs.Error(codes.FailedPrecondition, ErrorDomain, "MFA_NOT_ENABLED", "two-factor authentication is not enabled")
+	ErrNotificationFailed      = errs.Error(codes.Unavailable, ErrorDomain, "NOTIFICATION_FAILED", "the email could not be sent, try again later")
+	ErrOIDCCodeInvalid         = errs.FieldError(codes.InvalidArgument, ErrorDomain, "OIDC_CODE_INVALID", "code", "invalid authorization code")
+	ErrOIDCEmailRequired       = errs.Error(codes.FailedPrecondition, ErrorDomain, "OIDC_EMAIL_REQUIRED", "the provider did not return an email")
+	ErrOIDCNotEnabled          = errs.Error(codes.FailedPrecondition, ErrorDomain, "OIDC_NOT_ENABLED", "oidc login is not enabled")
+	ErrOIDCProviderInvalid     = errs.FieldError(codes.InvalidArgument, ErrorDomain, "OIDC_PROVIDER_INVALID", "provider", "unknown provider")
+	ErrOIDCProviderUnavailable = errs.Error(codes.Unavailable, ErrorDomain, "OIDC_PROVIDER_UNAVAILABLE", "the provider could not be reached, try again later")
+	ErrPasswordNotSet          = errs.Error(codes.FailedPrecondition, ErrorDomain, "PASSWORD_NOT_SET", "set a password with a password reset to make this change")
+	ErrPasswordPolicy          = errs.New(codes.InvalidArgument, ErrorDomain, "PASSWORD_POLICY", "password does not meet the password policy")
+	ErrPasswordRequired        = errs.FieldError(codes.InvalidArgument, ErrorDomain, "PASSWORD_REQUIRED", "password", "password is required")
+	ErrSessionIdInvalid        = errs.FieldError(codes.InvalidArgument, ErrorDomain, "SESSION_ID_INVALID", "id", "invalid session id")
+	ErrSessionNotFound         = errs.Error(codes.NotFound, ErrorDomain, "SESSION_NOT_FOUND", "session not found")
+	ErrTokenExpired            = errs.Error(codes.InvalidArgument, ErrorDomain, "TOKEN_EXPIRED", "token has expired")
+	ErrTokenInvalid            = errs.Error(codes.InvalidArgument, ErrorDomain, "TOKEN_INVALID", "invalid token")
+	ErrTokenRequired           = errs.FieldError(codes.InvalidArgument, ErrorDomain, "TOKEN_REQUIRED", "token", "token is required")
+	ErrTokenUsed               = errs.Error(codes.InvalidArgument, ErrorDomain, "TOKEN_USED", "token has already been used")
+	ErrTOTPAlreadyEnabled      = errs.Error(codes.FailedPrecondition, ErrorDomain, "TOTP_ALREADY_ENABLED", "totp is already enabled")
+	ErrTOTPNotEnabled          = errs.Error(codes.FailedPrecondition, ErrorDomain, "TOTP_NOT_ENABLED", "totp is not enabled")
+	ErrTOTPNotEnrolled         = errs.Error(codes.FailedPrecondition, ErrorDomain, "TOTP_NOT_ENROLLED", "totp enrollment has not been started")
+	ErrUserAlreadyVerified     = errs.Error(codes.FailedPrecondition, ErrorDomain, "USER_ALREADY_VERIFIED", "user is already verified")
+	ErrUserInactive            = errs.Error(codes.PermissionDenied, ErrorDomain, "USER_INACTIVE", "user is inactive")
+	ErrUserNotFound            = errs.Error(codes.NotFound, ErrorDomain, "USER_NOT_FOUND", "user not found")
 )
 
 func ErrInternal(err error) error {
-	return status.Error(codes.Internal, err.Error())
+	return errs.Error(codes.Internal, ErrorDomain, "INTERNAL", err.Error())
 }
 
+// ErrInvalidArgument returns an InvalidArgument error, a UserValidateError also describes the invalid field.
 func ErrInvalidArgument(err error) error {
-	return status.Error(codes.InvalidArgument, err.Error())
+	var ve *models.UserValidateError
+	if errors.As(err, &ve) {
+		return errs.FieldError(codes.InvalidArgument, ErrorDomain, "FIELD_INVALID", ve.Field, ve.Message)
+	}
+	return errs.Error(codes.InvalidArgument, ErrorDomain, "INVALID_ARGUMENT", err.Error())
+}
+
+// ErrToken returns the error for a token that failed to look up, telling expired and used tokens apart
+// from unknown ones. Any other failure, such as the database being unavailable, is an internal error.
+func ErrToken(err error) error {
+	switch {
+	case errors.Is(err, repos.ErrTokenExpired):
+		return ErrTokenExpired
+	case errors.Is(err, repos.ErrTokenUsed):
+		return ErrTokenUsed
+	case errors.Is(err, repos.ErrUserNotFound):
+		return ErrTokenInvalid
+	default:
+		return ErrInternal(err)
+	}
 }
 
+// maxMFAAttempts is the number of invalid codes allowed before an mfa token is revoked.
+const maxMFAAttempts = 5
+
+// defaultLoginCodeAttempts is the number of attempts allowed to redeem a login code token when not configured.
+const defaultLoginCodeAttempts = 5
+
 type AuthService struct {
 	pb.UnimplementedAuthenticationServer
-	UserRepo  *repos.UserRepository
-	TokenRepo *repos.TokenRepository
+	UserRepo         *repos.UserRepository
+	TokenRepo        *repos.TokenRepository
+	MFARepo          *repos.MFARepository
+	LoginAttemptRepo *repos.LoginAttemptRepository
+	IdentityRepo     *repos.IdentityRepository
+	PasswordPolicy   *passwords.Policy
+	// OIDCProviders are the OpenID Connect providers users can log in with, oidc login is disabled when empty
+	OIDCProviders oidc.Providers
+	// Notifier sends the verify, reset password and email change tokens to the user instead of returning them,
+	// and notifies the user of password and email changes. Tokens are returned when it is nil.
+	Notifier notify.Notifier
+	// LoginCodeMaxAttempts is the number of attempts allowed to redeem a login code token before it is revoked
+	LoginCodeMaxAttempts int
+}
+
+// notify tells the user at email of a change that has been made, a failure is logged rather than returned as
+// the change stands.
+func (s *AuthService) notify(ctx context.Context, kind notify.Kind, email string, user *models.User, newEmail string) {
+	if s.Notifier == nil {
+		return
+	}
+	data := notify.Data{Email: email, FirstName: user.FirstName, LastName: user.LastName, NewEmail: newEmail}
+	if err := s.Notifier.Notify(ctx, kind, data); err != nil {
+		log.Printf("error sending %s notification to %s: %v", kind, email, err)
+	}
+}
+
+// sendToken sends a token, or the code of a login code token, to the email of the user, it reports whether
+// a notifier is configured to send it.
+func (s *AuthService) sendToken(ctx context.Context, kind notify.Kind, email string, user *models.User, token string) (bool, error) {
+	if s.Notifier == nil {
+		return false, nil
+	}
+	data := notify.Data{Email: email, FirstName: user.FirstName, LastName: user.LastName}
+	switch kind {
+	case notify.KindLoginCode:
+		data.Code = token
+	case notify.KindEmailChange:
+		data.Token, data.NewEmail = token, email
+	default:
+		data.Token = token
+	}
+	if err := s.Notifier.Notify(ctx, kind, data); err != nil {
+		log.Printf("error sending %s notification to %s: %v", kind, email, err)
+		return true, ErrNotificationFailed
+	}
+	return true, nil
+}
+
+func durationSeconds(d time.Duration) int32 {
+	return int32(float64(d) / float64(time.Second))
+}
+
+// checkPassword applies the password policy to a new password of the user, returning an error with a field
+// violation for each way the password falls short. When history is true the previous passwords of the user
+// cannot be reused.
+func (s *AuthService) checkPassword(user *models.User, password string, history bool) error {
+	return applyPasswordPolicy(s.PasswordPolicy, s.UserRepo, user, password, history)
+}
+
+// recordPasswordHistory keeps the hash of a replaced password, for the reuse check of the password policy.
+func (s *AuthService) recordPasswordHistory(userRepo *repos.UserRepository, userId uuid.UUID, previousHash string) error {
+	return addPasswordHistory(s.PasswordPolicy, userRepo, userId, previousHash)
+}
+
+// applyPasswordPolicy checks a new password of the user against the policy, which is optional, for checkPassword
+// of both services.
+func applyPasswordPolicy(policy *passwords.Policy, userRepo *repos.UserRepository, user *models.User, password string, history bool) error {
+	if policy == nil || password == "" {
+		return nil
+	}
+
+	violations := policy.Check(password, user.Email, user.FirstName, user.LastName)
+
+	if history && policy.History > 0 {
+		hashes, err := userRepo.GetPasswordHistory(user.ID, policy.History-1)
+		if err != nil {
+			return ErrInternal(err)
+		}
+		if policy.Reused(password, append([]string{user.HashedPassword}, hashes...)) {
+			violations = append(violations, fmt.Sprintf("must not be one of your last %d passwords", policy.History))
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+	fieldViolations := make([]*errdetails.BadRequest_FieldViolation, 0, len(violations))
+	for _, violation := range violations {
+		fieldViolations = append(fieldViolations, errs.FieldViolation("password", violation))
+	}
+	return errs.WithFieldViolations(ErrPasswordPolicy, fieldViolations...)
+}
+
+// addPasswordHistory keeps the hash of a replaced password when the policy checks reuse, for recordPasswordHistory
+// of both services.
+func addPasswordHistory(policy *passwords.Policy, userRepo *repos.UserRepository, userId uuid.UUID, previousHash string) error {
+	if policy == nil || policy.History <= 1 || previousHash == "" {
+		return nil
+	}
+	return userRepo.AddPasswordHistory(userId, previousHash, policy.History-1)
+}
+
+// transaction runs fn with user and token repositories sharing a database transaction, so a single use token
+// is only used up when the change it was sent for is saved.
+func (s *AuthService) transaction(fn func(userRepo *repos.UserRepository, tokenRepo *repos.TokenRepository) error) error {
+	return s.UserRepo.DB.Transaction(func(tx *gorm.DB) error {
+		userRepo := &repos.UserRepository{DB: tx, Hasher: s.UserRepo.Hasher}
+		tokenRepo := &repos.TokenRepository{DB: tx, Config: s.TokenRepo.Config, Signer: s.TokenRepo.Signer, Hasher: s.TokenRepo.Hasher}
+		return fn(userRepo, tokenRepo)
+	})
+}
+
+// issueBearerToken creates an access token along with a refresh token for the user.
+// When refreshToken is nil a new refresh token family is started.
+// The client of the request is recorded against the access token for listing sessions.
+func (s *AuthService) issueBearerToken(ctx context.Context, userId uuid.UUID, refreshToken *models.RefreshToken) (*pb.BearerTokenResponse, error) {
+	var err error
+	if refreshToken == nil {
+		refreshToken, err = s.TokenRepo.CreateRefreshToken(userId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	session := sessionInfo(ctx)
+	session.FamilyId = &refreshToken.FamilyId
+	token, err := s.TokenRepo.CreateAccessToken(userId, session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.BearerTokenResponse{
+		AccessToken:   token.Token,
+		TokenType:     "bearer",
+		Expiry:        durationSeconds(s.TokenRepo.Config.BearerDuration),
+		RefreshToken:  refreshToken.Token,
+		RefreshExpiry: durationSeconds(s.TokenRepo.Config.RefreshDuration),
+	}, nil
 }
 
-func (s *AuthService) userToResponse(user *models.User) *pb.UserResponse {
+func sessionToResponse(session *models.AccessToken, current bool) *pb.SessionResponse {
+	resp := &pb.SessionResponse{
+		Id:        session.ID.String(),
+		UserAgent: session.UserAgent,
+		IpAddress: session.IPAddress,
+		CreatedAt: session.CreatedAt.Unix(),
+		ExpiresAt: session.ExpiresAt.Unix(),
+		Current:   current,
+	}
+	if session.LastUsedAt != nil {
+		resp.LastUsedAt = session.LastUsedAt.Unix()
+	}
+	return resp
+}
+
+func userToResponse(user *models.User) *pb.UserResponse {
 	return &pb.UserResponse{
 		Id:        user.ID.String(),
 		FirstName: user.FirstName,
@@ -51,14 +268,115 @@ func (s *AuthService) userToResponse(user *models.User) *pb.UserResponse {
 			Name:   user.UserType.Name,
 			Scopes: user.UserType.ScopeNames(),
 		},
-		IsActive:   user.IsActive,
-		IsVerified: user.IsVerified,
+		IsActive:    user.IsActive,
+		IsVerified:  user.IsVerified,
+		TotpEnabled: user.TOTPEnabled,
+	}
+}
+
+// checkLoginAttempts rejects a login when the email or the ip address of the client has too many recent failures.
+func (s *AuthService) checkLoginAttempts(ctx context.Context, email string) error {
+	if s.LoginAttemptRepo == nil {
+		return nil
+	}
+
+	keys := []string{repos.AccountKey(email)}
+	if ip := clientIP(ctx); ip != "" {
+		keys = append(keys, repos.IPKey(ip))
+	}
+
+	check, err := s.LoginAttemptRepo.Check(keys...)
+	if err != nil {
+		return ErrInternal(err)
+	}
+
+	switch {
+	case check.Locked:
+		return errs.WithRetryInfo(ErrLoginLocked, check.RetryAfter)
+	case check.RetryAfter > 0:
+		return errs.WithRetryInfo(ErrLoginDelayed, check.RetryAfter)
+	default:
+		return nil
+	}
+}
+
+// recordLoginFailure counts a failed login against the email and the ip address of the client,
+// returning the invalid credentials error.
+func (s *AuthService) recordLoginFailure(ctx context.Context, email string) error {
+	if s.LoginAttemptRepo == nil {
+		return ErrInvalidCredentials
+	}
+
+	if err := s.LoginAttemptRepo.RecordFailure(repos.AccountKey(email), s.LoginAttemptRepo.Config.MaxAccountFailures); err != nil {
+		return ErrInternal(err)
+	}
+	if ip := clientIP(ctx); ip != "" {
+		if err := s.LoginAttemptRepo.RecordFailure(repos.IPKey(ip), s.LoginAttemptRepo.Config.MaxIPFailures); err != nil {
+			return ErrInternal(err)
+		}
+	}
+
+	return ErrInvalidCredentials
+}
+
+// resetLoginFailures forgets the failed logins of the email once the user has completed a login.
+func (s *AuthService) resetLoginFailures(email string) error {
+	if s.LoginAttemptRepo == nil {
+		return nil
+	}
+	return s.LoginAttemptRepo.Reset(repos.AccountKey(email))
+}
+
+// verifyMFACode checks a TOTP or recovery code of the user, an incorrect code counts as a failed login against the
+// email of the account so codes cannot be guessed without limit.
+func (s *AuthService) verifyMFACode(ctx context.Context, user *models.User, code string) error {
+	if err := s.checkLoginAttempts(ctx, user.Email); err != nil {
+		return err
+	}
+
+	if err := s.MFARepo.VerifyMFA(user, code); err != nil {
+		if errors.Is(err, repos.ErrMFACodeInvalid) {
+			if err := s.recordLoginFailure(ctx, user.Email); !errors.Is(err, ErrInvalidCredentials) {
+				return err
+			}
+		}
+		return mfaError(err)
 	}
+
+	return nil
+}
+
+// verifyCurrentPassword confirms a sensitive change to the account of the user with their current password.
+// Users without a password cannot confirm a change, they have to set a password with a password reset first,
+// which proves they own the email of the account. Incorrect passwords count as failed logins against the email
+// of the account, so they are throttled along with BearerToken.
+func (s *AuthService) verifyCurrentPassword(ctx context.Context, email string, user *models.User, password string) error {
+	if !user.HasPassword() {
+		return ErrPasswordNotSet
+	}
+
+	if govalidator.IsNull(password) {
+		return ErrCurrentPasswordRequired
+	}
+
+	if err := s.checkLoginAttempts(ctx, email); err != nil {
+		return err
+	}
+
+	if !user.VerifyPassword(password) {
+		if err := s.recordLoginFailure(ctx, email); !errors.Is(err, ErrInvalidCredentials) {
+			return err
+		}
+		return ErrCurrentPasswordInvalid
+	}
+
+	return nil
 }
 
 // BearerToken generates a bearer token for a user, based on their provided credentials.
+// When the user has two-factor authentication enabled an mfa token is returned instead, to be completed with CompleteMFA.
 // It takes in a context and a BearerTokenRequest, and returns a BearerTokenResponse and an error.
-func (s *AuthService) BearerToken(_ context.Context, in *pb.BearerTokenRequest) (*pb.BearerTokenResponse, error) {
+func (s *AuthService) BearerToken(ctx context.Context, in *pb.BearerTokenRequest) (*pb.BearerTokenResponse, error) {
 	email := strings.TrimSpace(strings.ToLower(in.GetEmail()))
 	if !govalidator.IsEmail(email) {
 		return nil, ErrEmailInvalid
@@ -69,33 +387,106 @@ func (s *AuthService) BearerToken(_ context.Context, in *pb.BearerTokenRequest)
 		return nil, ErrPasswordRequired
 	}
 
+	if err := s.checkLoginAttempts(ctx, email); err != nil {
+		return nil, err
+	}
+
 	user, err := s.UserRepo.GetUserByEmail(email)
 	if err != nil {
-		return nil, ErrInvalidCredentials
+		return nil, s.recordLoginFailure(ctx, email)
 	}
 
-	if !user.VerifyPassword(password) {
-		return nil, ErrInvalidCredentials
+	// users without a password log in with a login token or a provider
+	if !user.HasPassword() || !user.VerifyPassword(password) {
+		return nil, s.recordLoginFailure(ctx, email)
+	}
+
+	// upgrade the hash when the password hasher has changed since it was set,
+	// a password the current hasher does not accept keeps its old hash
+	if user.PasswordNeedsRehash() {
+		if err := user.SetPassword(password); err == nil {
+			if err := s.UserRepo.UpdatePasswordHash(user); err != nil {
+				return nil, ErrInternal(err)
+			}
+		}
+	}
+
+	if !user.IsActive {
+		return nil, ErrUserInactive
+	}
+
+	return s.login(ctx, user)
+}
+
+// login issues a bearer token to a user who has proven who they are, or an mfa token when the user has
+// two-factor authentication enabled. The failed logins of the user are only reset once the login is complete,
+// so the code of a two-factor login cannot be guessed by logging in again.
+func (s *AuthService) login(ctx context.Context, user *models.User) (*pb.BearerTokenResponse, error) {
+	if user.TOTPEnabled {
+		mfaToken, err := s.TokenRepo.CreateMFAToken(user.ID)
+		if err != nil {
+			return nil, ErrInternal(err)
+		}
+
+		return &pb.BearerTokenResponse{
+			MfaRequired: true,
+			MfaToken:    mfaToken.Token,
+			MfaExpiry:   durationSeconds(s.TokenRepo.Config.MFADuration),
+		}, nil
+	}
+
+	if err := s.resetLoginFailures(user.Email); err != nil {
+		return nil, ErrInternal(err)
 	}
 
-	token, err := s.TokenRepo.CreateAccessToken(user.ID)
+	resp, err := s.issueBearerToken(ctx, user.ID, nil)
 	if err != nil {
 		return nil, ErrInternal(err)
 	}
 
-	seconds := float64(s.TokenRepo.Config.BearerDuration) / float64(time.Second)
+	return resp, nil
+}
 
-	return &pb.BearerTokenResponse{
-		AccessToken: token.Token,
-		TokenType:   "bearer",
-		Expiry:      int32(seconds),
-	}, nil
+// RefreshBearerToken exchanges a refresh token for a new bearer token.
+// The refresh token is rotated on every use, reusing a refresh token revokes its whole family.
+// It takes in a context and a Token, and returns a BearerTokenResponse and an error.
+func (s *AuthService) RefreshBearerToken(ctx context.Context, in *pb.Token) (*pb.BearerTokenResponse, error) {
+	token := in.GetToken()
+	if govalidator.IsNull(token) {
+		return nil, ErrTokenRequired
+	}
+
+	user, err := s.UserRepo.GetUserByRefreshToken(token)
+	if err != nil {
+		return nil, ErrTokenInvalid
+	}
+
+	if !user.IsActive {
+		return nil, ErrUserInactive
+	}
+
+	refreshToken, err := s.TokenRepo.RotateRefreshToken(token)
+	if err != nil {
+		switch {
+		case errors.Is(err, repos.ErrRefreshTokenInvalid), errors.Is(err, repos.ErrRefreshTokenReused):
+			return nil, ErrTokenInvalid
+		default:
+			return nil, ErrInternal(err)
+		}
+	}
+
+	resp, err := s.issueBearerToken(ctx, refreshToken.UserId, refreshToken)
+	if err != nil {
+		return nil, ErrInternal(err)
+	}
+
+	return resp, nil
 }
 
-// RevokeBearerToken revokes a user's bearer token.
+// RevokeBearerToken revokes the bearer token of the request, or the provided token.
 // It takes in a context and a Token, and returns an Empty response and an error.
-func (s *AuthService) RevokeBearerToken(_ context.Context, in *pb.Token) (*pb.Empty, error) {
-	token := in.GetToken()
+func (s *AuthService) RevokeBearerToken(ctx context.Context, in *pb.Token) (*pb.Empty, error) {
+	token := bearerToken(ctx, in.GetToken())
 	if govalidator.IsNull(token) {
 		return nil, ErrTokenRequired
 	}
@@ -108,14 +499,18 @@ func (s *AuthService) RevokeBearerToken(_ context.Context, in *pb.Token) (*pb.Em
 }
 
 // Register creates a new user account based on the provided registration details.
+// The password is optional, a user registered without one logs in with RequestLoginLink or RequestLoginCode.
 // It takes in a context and a RegisterRequest, and returns a UserResponse and an error.
 func (s *AuthService) Register(_ context.Context, in *pb.RegisterRequest) (*pb.UserResponse, error) {
-	user, err := s.UserRepo.CreateUser(
-		strings.TrimSpace(strings.ToLower(in.GetEmail())),
-		in.GetPassword(),
-		strings.TrimSpace(in.GetFirstName()),
-		strings.TrimSpace(in.GetLastName()),
-	)
+	email := strings.TrimSpace(strings.ToLower(in.GetEmail()))
+	firstName := strings.TrimSpace(in.GetFirstName())
+	lastName := strings.TrimSpace(in.GetLastName())
+
+	if err := s.checkPassword(&models.User{Email: email, FirstName: firstName, LastName: lastName}, in.GetPassword(), false); err != nil {
+		return nil, err
+	}
+
+	user, err := s.UserRepo.CreateUser(email, in.GetPassword(), firstName, lastName)
 
 	if err != nil {
 		var ve *models.UserValidateError
@@ -129,12 +524,13 @@ func (s *AuthService) Register(_ context.Context, in *pb.RegisterRequest) (*pb.U
 		}
 	}
 
-	return s.userToResponse(user), nil
+	return userToResponse(user), nil
 }
 
 // ResetPassword resets a user's password, given the provided reset password details.
+// A reset token can only be used once, expired and used tokens are rejected with ErrTokenExpired and ErrTokenUsed.
 // It takes in a context and a ResetPasswordRequest, and returns an Empty response and an error.
-func (s *AuthService) ResetPassword(_ context.Context, in *pb.ResetPasswordRequest) (*pb.Empty, error) {
+func (s *AuthService) ResetPassword(ctx context.Context, in *pb.ResetPasswordRequest) (*pb.Empty, error) {
 	token := in.GetToken()
 	if govalidator.IsNull(token) {
 		return nil, ErrTokenRequired
@@ -144,9 +540,18 @@ func (s *AuthService) ResetPassword(_ context.Context, in *pb.ResetPasswordReque
 
 	user, err := s.UserRepo.GetUserByResetToken(token)
 	if err != nil {
-		return nil, ErrTokenInvalid
+		return nil, ErrToken(err)
+	}
+
+	if !user.IsActive {
+		return nil, ErrUserInactive
+	}
+
+	if err := s.checkPassword(user, password, true); err != nil {
+		return nil, err
 	}
 
+	previousHash := user.HashedPassword
 	if err := user.SetPassword(password); err != nil {
 		var ve *models.UserValidateError
 		switch {
@@ -157,16 +562,34 @@ func (s *AuthService) ResetPassword(_ context.Context, in *pb.ResetPasswordReque
 		}
 	}
 
-	if err := s.UserRepo.UpdateUser(user); err != nil {
+	err = s.transaction(func(userRepo *repos.UserRepository, tokenRepo *repos.TokenRepository) error {
+		if err := tokenRepo.UseResetToken(token); err != nil {
+			return err
+		}
+		if err := userRepo.UpdateUser(user); err != nil {
+			return err
+		}
+		if err := s.recordPasswordHistory(userRepo, user.ID, previousHash); err != nil {
+			return err
+		}
+		return tokenRepo.RevokeAllSessions(user.ID, "")
+	})
+	if err != nil {
+		if errors.Is(err, repos.ErrTokenUsed) {
+			return nil, ErrTokenUsed
+		}
 		return nil, ErrInternal(err)
 	}
 
+	s.notify(ctx, notify.KindPasswordChanged, user.Email, user, "")
+
 	return &pb.Empty{}, nil
 }
 
 // ResetPasswordToken generates a reset password token for a user based on their email.
+// When a notifier is configured the token is emailed to the user and left out of the response.
 // It takes in a context and a ResetPasswordTokenRequest, and returns a TokenWithEmail and an error.
-func (s *AuthService) ResetPasswordToken(_ context.Context, in *pb.ResetPasswordTokenRequest) (*pb.TokenWithEmail, error) {
+func (s *AuthService) ResetPasswordToken(ctx context.Context, in *pb.ResetPasswordTokenRequest) (*pb.TokenWithEmail, error) {
 	email := strings.TrimSpace(strings.ToLower(in.GetEmail()))
 	if !govalidator.IsEmail(email) {
 		return nil, ErrEmailInvalid
@@ -177,11 +600,23 @@ func (s *AuthService) ResetPasswordToken(_ context.Context, in *pb.ResetPassword
 		return nil, ErrUserNotFound
 	}
 
+	if !user.IsActive {
+		return nil, ErrUserInactive
+	}
+
 	token, err := s.TokenRepo.CreateResetToken(user.ID)
 	if err != nil {
 		return nil, ErrInternal(err)
 	}
 
+	sent, err := s.sendToken(ctx, notify.KindResetPassword, user.Email, user, token.Token)
+	if err != nil {
+		return nil, err
+	}
+	if sent {
+		token.Token = ""
+	}
+
 	return &pb.TokenWithEmail{
 		Token:     token.Token,
 		Email:     user.Email,
@@ -190,43 +625,36 @@ func (s *AuthService) ResetPasswordToken(_ context.Context, in *pb.ResetPassword
 	}, nil
 }
 
-// User retrieves user details based on the provided token.
+// User retrieves user details based on the bearer token of the request, or the provided token.
 // It takes in a context and a Token, and returns a UserResponse and an error.
-func (s *AuthService) User(_ context.Context, in *pb.Token) (*pb.UserResponse, error) {
-	token := in.GetToken()
-	if govalidator.IsNull(token) {
-		return nil, ErrTokenRequired
-	}
-
-	user, err := s.UserRepo.GetUserByAccessToken(token)
+func (s *AuthService) User(ctx context.Context, in *pb.Token) (*pb.UserResponse, error) {
+	user, err := s.authenticate(ctx, in.GetToken())
 	if err != nil {
-		return nil, ErrTokenInvalid
+		return nil, err
 	}
 
-	return s.userToResponse(user), nil
+	return userToResponse(user), nil
 }
 
-// UpdateUser updates a user based on the provided bearer token.
+// UpdateUser updates a user based on the bearer token of the request, or the provided token.
+// Changing the password requires the current password, the email is changed with RequestEmailChange so the new
+// address is confirmed before it is used.
 // It takes in a context and a UpdateUserRequest, and returns a UserResponse and an error.
-func (s *AuthService) UpdateUser(_ context.Context, in *pb.UpdateUserRequest) (*pb.UserResponse, error) {
-	token := in.GetToken()
-	if govalidator.IsNull(token) {
-		return nil, ErrTokenRequired
+func (s *AuthService) UpdateUser(ctx context.Context, in *pb.UpdateUserRequest) (*pb.UserResponse, error) {
+	user, err := s.authenticate(ctx, in.GetToken())
+	if err != nil {
+		return nil, err
 	}
 
-	user, err := s.UserRepo.GetUserByAccessToken(token)
-	if err != nil {
-		return nil, ErrTokenInvalid
+	// the email is only changed once the new address is confirmed, an unchanged email is accepted
+	if email := strings.TrimSpace(strings.ToLower(in.GetEmail())); email != "" && email != user.Email {
+		return nil, ErrEmailChangeRequired
 	}
 
-	email := strings.TrimSpace(strings.ToLower(in.GetEmail()))
 	firstName := strings.TrimSpace(in.GetFirstName())
 	lastName := strings.TrimSpace(in.GetLastName())
 	password := in.GetPassword()
 
-	if email != "" {
-		user.Email = email
-	}
 	if firstName != "" {
 		user.FirstName = firstName
 	}
@@ -238,6 +666,17 @@ func (s *AuthService) UpdateUser(_ context.Context, in *pb.UpdateUserRequest) (*
 		return nil, ErrInvalidArgument(err)
 	}
 
+	if password != "" {
+		if err := s.verifyCurrentPassword(ctx, user.Email, user, in.GetCurrentPassword()); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.checkPassword(user, password, true); err != nil {
+		return nil, err
+	}
+
+	previousHash := user.HashedPassword
 	if password != "" {
 		if err := user.SetPassword(password); err != nil {
 			var ve *models.UserValidateError
@@ -251,42 +690,125 @@ func (s *AuthService) UpdateUser(_ context.Context, in *pb.UpdateUserRequest) (*
 	}
 
 	if err := s.UserRepo.UpdateUser(user); err != nil {
-		switch {
-		case errors.Is(err, gorm.ErrDuplicatedKey):
-			return nil, ErrEmailAlreadyExists
-		default:
+		return nil, ErrInternal(err)
+	}
+
+	// changing the password signs out every other session
+	if password != "" {
+		if err := s.recordPasswordHistory(s.UserRepo, user.ID, previousHash); err != nil {
+			return nil, ErrInternal(err)
+		}
+		if err := s.TokenRepo.RevokeAllSessions(user.ID, bearerToken(ctx, in.GetToken())); err != nil {
 			return nil, ErrInternal(err)
 		}
+		s.notify(ctx, notify.KindPasswordChanged, user.Email, user, "")
 	}
 
-	return s.userToResponse(user), nil
+	return userToResponse(user), nil
 }
 
-// VerifyUser verifies a user's account, given the provided token.
+// RequestEmailChange starts changing the email of the user of the bearer token of the request, or the provided token.
+// The current password is required, users without a password have to set one first. The token is confirmed with
+// ConfirmEmailChange. When a notifier is configured the token is emailed to the new email and left out of the
+// response, and the previous email is notified of the request. Otherwise the caller sends the token to the new
+// email and notifies the previous_email of the response of the request.
+// It takes in a context and an EmailChangeRequest, and returns an EmailChangeTokenResponse and an error.
+func (s *AuthService) RequestEmailChange(ctx context.Context, in *pb.EmailChangeRequest) (*pb.EmailChangeTokenResponse, error) {
+	user, err := s.authenticate(ctx, in.GetToken())
+	if err != nil {
+		return nil, err
+	}
+
+	email := strings.TrimSpace(strings.ToLower(in.GetEmail()))
+	if !govalidator.IsEmail(email) {
+		return nil, ErrEmailInvalid
+	}
+
+	if email == user.Email {
+		return nil, ErrEmailUnchanged
+	}
+
+	if err := s.verifyCurrentPassword(ctx, user.Email, user, in.GetCurrentPassword()); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.UserRepo.GetUserByEmail(email); err == nil {
+		return nil, ErrEmailAlreadyExists
+	} else if !errors.Is(err, repos.ErrUserNotFound) {
+		return nil, ErrInternal(err)
+	}
+
+	token, err := s.TokenRepo.CreateEmailChangeToken(user.ID, email)
+	if err != nil {
+		return nil, ErrInternal(err)
+	}
+
+	sent, err := s.sendToken(ctx, notify.KindEmailChange, email, user, token.Token)
+	if err != nil {
+		return nil, err
+	}
+	if sent {
+		token.Token = ""
+	}
+
+	s.notify(ctx, notify.KindEmailChangeRequested, user.Email, user, email)
+
+	return &pb.EmailChangeTokenResponse{
+		Token:         token.Token,
+		Email:         email,
+		PreviousEmail: user.Email,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+	}, nil
+}
+
+// ConfirmEmailChange changes the email of a user to the email the provided token was sent to, marking it verified.
+// The token can only be used once, expired and used tokens are rejected with ErrTokenExpired and ErrTokenUsed.
 // It takes in a context and a Token, and returns a UserResponse and an error.
-func (s *AuthService) VerifyUser(_ context.Context, in *pb.Token) (*pb.UserResponse, error) {
+func (s *AuthService) ConfirmEmailChange(ctx context.Context, in *pb.Token) (*pb.UserResponse, error) {
 	token := in.GetToken()
 	if govalidator.IsNull(token) {
 		return nil, ErrTokenRequired
 	}
 
-	user, err := s.UserRepo.GetUserByVerifyToken(token)
+	user, err := s.UserRepo.GetUserByEmailChangeToken(token)
 	if err != nil {
-		return nil, ErrTokenInvalid
+		return nil, ErrToken(err)
 	}
 
-	user.IsVerified = true
-	if err := s.UserRepo.UpdateUser(user); err != nil {
-		return nil, ErrInternal(err)
+	if !user.IsActive {
+		return nil, ErrUserInactive
+	}
+
+	previousEmail := user.Email
+	err = s.transaction(func(userRepo *repos.UserRepository, tokenRepo *repos.TokenRepository) error {
+		emailChange, err := tokenRepo.UseEmailChangeToken(token)
+		if err != nil {
+			return err
+		}
+		user.Email = emailChange.Email
+		user.IsVerified = true
+		return userRepo.UpdateUser(user)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, repos.ErrTokenUsed):
+			return nil, ErrTokenUsed
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			return nil, ErrEmailAlreadyExists
+		default:
+			return nil, ErrInternal(err)
+		}
 	}
 
-	return s.userToResponse(user), nil
+	s.notify(ctx, notify.KindEmailChanged, previousEmail, user, user.Email)
+
+	return userToResponse(user), nil
 }
 
-// VerifyUserToken generates a user verification token based on their email.
-// It takes in a context and a VerifyUserTokenRequest, and returns a TokenWithEmail and an error.
-func (s *AuthService) VerifyUserToken(_ context.Context, in *pb.VerifyUserTokenRequest) (*pb.TokenWithEmail, error) {
-	email := strings.TrimSpace(strings.ToLower(in.GetEmail()))
+// loginTokenUser returns the active user a login token is requested for.
+func (s *AuthService) loginTokenUser(email string) (*models.User, error) {
+	email = strings.TrimSpace(strings.ToLower(email))
 	if !govalidator.IsEmail(email) {
 		return nil, ErrEmailInvalid
 	}
@@ -296,15 +818,36 @@ func (s *AuthService) VerifyUserToken(_ context.Context, in *pb.VerifyUserTokenR
 		return nil, ErrUserNotFound
 	}
 
-	if user.IsVerified {
-		return nil, ErrUserAlreadyVerified
+	if !user.IsActive {
+		return nil, ErrUserInactive
 	}
 
-	token, err := s.TokenRepo.CreateVerifyToken(user.ID)
+	return user, nil
+}
+
+// RequestLoginLink generates a single use token logging a user in without a password, to be sent to their email
+// as a link and redeemed with RedeemLoginToken. When a notifier is configured the token is emailed to the user and
+// left out of the response.
+// It takes in a context and a LoginTokenRequest, and returns a TokenWithEmail and an error.
+func (s *AuthService) RequestLoginLink(ctx context.Context, in *pb.LoginTokenRequest) (*pb.TokenWithEmail, error) {
+	user, err := s.loginTokenUser(in.GetEmail())
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.TokenRepo.CreateLoginLinkToken(user.ID)
 	if err != nil {
 		return nil, ErrInternal(err)
 	}
 
+	sent, err := s.sendToken(ctx, notify.KindLoginLink, user.Email, user, token.Token)
+	if err != nil {
+		return nil, err
+	}
+	if sent {
+		token.Token = ""
+	}
+
 	return &pb.TokenWithEmail{
 		Token:     token.Token,
 		Email:     user.Email,
@@ -312,3 +855,624 @@ func (s *AuthService) VerifyUserToken(_ context.Context, in *pb.VerifyUserTokenR
 		LastName:  user.LastName,
 	}, nil
 }
+
+// RequestLoginCode generates a single use token and a numeric code logging a user in without a password. The code
+// is sent to their email, and redeemed with RedeemLoginToken along with the token kept by the client. When a
+// notifier is configured the code is emailed to the user and left out of the response.
+// It takes in a context and a LoginTokenRequest, and returns a LoginCodeResponse and an error.
+func (s *AuthService) RequestLoginCode(ctx context.Context, in *pb.LoginTokenRequest) (*pb.LoginCodeResponse, error) {
+	user, err := s.loginTokenUser(in.GetEmail())
+	if err != nil {
+		return nil, err
+	}
+
+	token, code, err := s.TokenRepo.CreateLoginCodeToken(user.ID)
+	if err != nil {
+		return nil, ErrInternal(err)
+	}
+
+	sent, err := s.sendToken(ctx, notify.KindLoginCode, user.Email, user, code)
+	if err != nil {
+		return nil, err
+	}
+	if sent {
+		code = ""
+	}
+
+	return &pb.LoginCodeResponse{
+		Token:     token.Token,
+		Code:      code,
+		Email:     user.Email,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+	}, nil
+}
+
+// RedeemLoginToken logs a user in with a login link token, or a login code token and its code, returning a bearer
+// token, or an mfa token when the user has two-factor authentication enabled. Redeeming a token verifies the email
+// of the user. Invalid codes count as failed logins for the brute-force protection, and the token is revoked after
+// too many attempts.
+// It takes in a context and a RedeemLoginTokenRequest, and returns a BearerTokenResponse and an error.
+func (s *AuthService) RedeemLoginToken(ctx context.Context, in *pb.RedeemLoginTokenRequest) (*pb.BearerTokenResponse, error) {
+	token := in.GetToken()
+	if govalidator.IsNull(token) {
+		return nil, ErrTokenRequired
+	}
+
+	user, err := s.UserRepo.GetUserByLoginToken(token)
+	if err != nil {
+		return nil, ErrToken(err)
+	}
+
+	if err := s.checkLoginAttempts(ctx, user.Email); err != nil {
+		return nil, err
+	}
+
+	if !user.IsActive {
+		return nil, ErrUserInactive
+	}
+
+	maxAttempts := s.LoginCodeMaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultLoginCodeAttempts
+	}
+
+	if err := s.TokenRepo.UseLoginToken(token, strings.TrimSpace(in.GetCode()), maxAttempts); err != nil {
+		switch {
+		case errors.Is(err, repos.ErrLoginCodeInvalid):
+			if err := s.recordLoginFailure(ctx, user.Email); !errors.Is(err, ErrInvalidCredentials) {
+				return nil, err
+			}
+			return nil, ErrLoginCodeInvalid
+		case errors.Is(err, repos.ErrTokenUsed):
+			return nil, ErrTokenUsed
+		default:
+			return nil, ErrInternal(err)
+		}
+	}
+
+	// the token was sent to the email of the user, so redeeming it proves they own it
+	if !user.IsVerified {
+		user.IsVerified = true
+		if err := s.UserRepo.UpdateUser(user); err != nil {
+			return nil, ErrInternal(err)
+		}
+	}
+
+	return s.login(ctx, user)
+}
+
+func identityToResponse(identity *models.Identity) *pb.IdentityResponse {
+	return &pb.IdentityResponse{
+		Provider:  identity.Provider,
+		Subject:   identity.Subject,
+		Email:     identity.Email,
+		CreatedAt: identity.CreatedAt.Unix(),
+	}
+}
+
+// identityNames returns the first and last name of a user created for an identity. Providers that do not return
+// both names fall back to the full name, then the local part of the email for the first name and the first name
+// for the last name, as users need both.
+func identityNames(identity *oidc.Identity) (string, string) {
+	firstName, lastName := identity.GivenName, identity.FamilyName
+	if firstName == "" && lastName == "" {
+		firstName = identity.Name
+		if i := strings.LastIndex(identity.Name, " "); i > 0 {
+			firstName, lastName = strings.TrimSpace(identity.Name[:i]), identity.Name[i+1:]
+		}
+	}
+	if firstName == "" {
+		firstName, _, _ = strings.Cut(identity.Email, "@")
+	}
+	if lastName == "" {
+		lastName = firstName
+	}
+	return firstName, lastName
+}
+
+// oidcProvider returns the configured provider with the given name.
+func (s *AuthService) oidcProvider(name string) (*oidc.Provider, error) {
+	if s.IdentityRepo == nil || len(s.OIDCProviders) == 0 {
+		return nil, ErrOIDCNotEnabled
+	}
+	provider, ok := s.OIDCProviders[name]
+	if !ok {
+		return nil, ErrOIDCProviderInvalid
+	}
+	return provider, nil
+}
+
+// exchangeOIDCCode exchanges the authorization code of the request for the identity of the user at the provider.
+func (s *AuthService) exchangeOIDCCode(ctx context.Context, in *pb.OIDCCodeRequest) (*oidc.Provider, *oidc.Identity, error) {
+	provider, err := s.oidcProvider(in.GetProvider())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if govalidator.IsNull(in.GetCode()) {
+		return nil, nil, ErrOIDCCodeInvalid
+	}
+
+	identity, err := provider.Exchange(ctx, in.GetCode(), in.GetCodeVerifier(), in.GetNonce())
+	if err != nil {
+		if errors.Is(err, oidc.ErrInvalidGrant) || errors.Is(err, oidc.ErrInvalidIDToken) {
+			return nil, nil, ErrOIDCCodeInvalid
+		}
+		log.Printf("error exchanging %s authorization code: %v", provider.Name, err)
+		return nil, nil, ErrOIDCProviderUnavailable
+	}
+
+	return provider, identity, nil
+}
+
+// BeginOIDCLogin returns the url of a provider to send the user to, to log in or link their account. The client
+// keeps the state, to check against the state the provider redirects back with, and the code verifier and nonce
+// to exchange the code with OIDCLogin or LinkIdentity.
+// It takes in a context and an OIDCProviderRequest, and returns an OIDCAuthorizationResponse and an error.
+func (s *AuthService) BeginOIDCLogin(ctx context.Context, in *pb.OIDCProviderRequest) (*pb.OIDCAuthorizationResponse, error) {
+	provider, err := s.oidcProvider(in.GetProvider())
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := provider.Authorize(ctx)
+	if err != nil {
+		log.Printf("error authorizing with %s: %v", provider.Name, err)
+		return nil, ErrOIDCProviderUnavailable
+	}
+
+	return &pb.OIDCAuthorizationResponse{
+		AuthorizationUrl: auth.URL,
+		State:            auth.State,
+		CodeVerifier:     auth.CodeVerifier,
+		Nonce:            auth.Nonce,
+	}, nil
+}
+
+// OIDCLogin logs a user in with the authorization code of a provider, returning a bearer token, or an mfa token
+// when the user has two-factor authentication enabled. A user without a password is created for an identity that
+// is not linked, unless a user with its email exists, who has to log in and link it with LinkIdentity.
+// It takes in a context and an OIDCCodeRequest, and returns a BearerTokenResponse and an error.
+func (s *AuthService) OIDCLogin(ctx context.Context, in *pb.OIDCCodeRequest) (*pb.BearerTokenResponse, error) {
+	provider, identity, err := s.exchangeOIDCCode(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.IdentityRepo.GetUserByIdentity(provider.Name, identity.Subject)
+	switch {
+	case err == nil:
+	case errors.Is(err, repos.ErrIdentityNotFound):
+		user, err = s.createIdentityUser(provider.Name, identity)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrInternal(err)
+	}
+
+	if !user.IsActive {
+		return nil, ErrUserInactive
+	}
+
+	return s.login(ctx, user)
+}
+
+// createIdentityUser creates a user for an identity that is not linked, its email is verified when the
+// provider has verified it.
+func (s *AuthService) createIdentityUser(provider string, identity *oidc.Identity) (*models.User, error) {
+	if !govalidator.IsEmail(identity.Email) {
+		return nil, ErrOIDCEmailRequired
+	}
+
+	// linking an identity to an existing user by email would let anyone able to register the email with the
+	// provider take over the user
+	if _, err := s.UserRepo.GetUserByEmail(identity.Email); err == nil {
+		return nil, ErrIdentityNotLinked
+	} else if !errors.Is(err, repos.ErrUserNotFound) {
+		return nil, ErrInternal(err)
+	}
+
+	firstName, lastName := identityNames(identity)
+	user, err := s.IdentityRepo.CreateUserWithIdentity(
+		&models.User{
+			Email:      identity.Email,
+			FirstName:  firstName,
+			LastName:   lastName,
+			IsActive:   true,
+			IsVerified: identity.EmailVerified,
+		},
+		&models.Identity{Provider: provider, Subject: identity.Subject, Email: identity.Email},
+	)
+	if err != nil {
+		var ve *models.UserValidateError
+		switch {
+		case errors.As(err, &ve):
+			return nil, ErrInvalidArgument(err)
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			return nil, ErrIdentityNotLinked
+		case errors.Is(err, repos.ErrIdentityLinked):
+			return nil, ErrIdentityAlreadyLinked
+		default:
+			return nil, ErrInternal(err)
+		}
+	}
+
+	return user, nil
+}
+
+// LinkIdentity links the account of the user at a provider, given the authorization code of the provider, to the
+// user of the bearer token of the request, or the provided token, so they can log in with it.
+// It takes in a context and an OIDCCodeRequest, and returns an IdentityResponse and an error.
+func (s *AuthService) LinkIdentity(ctx context.Context, in *pb.OIDCCodeRequest) (*pb.IdentityResponse, error) {
+	user, err := s.authenticate(ctx, in.GetToken())
+	if err != nil {
+		return nil, err
+	}
+
+	provider, identity, err := s.exchangeOIDCCode(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+
+	linked := &models.Identity{UserId: user.ID, Provider: provider.Name, Subject: identity.Subject, Email: identity.Email}
+	if err := s.IdentityRepo.LinkIdentity(linked); err != nil {
+		if errors.Is(err, repos.ErrIdentityLinked) {
+			return nil, ErrIdentityAlreadyLinked
+		}
+		return nil, ErrInternal(err)
+	}
+
+	return identityToResponse(linked), nil
+}
+
+// UnlinkIdentity removes the account of a provider from the user of the bearer token of the request, or the
+// provided token.
+// It takes in a context and an UnlinkIdentityRequest, and returns an Empty response and an error.
+func (s *AuthService) UnlinkIdentity(ctx context.Context, in *pb.UnlinkIdentityRequest) (*pb.Empty, error) {
+	user, err := s.authenticate(ctx, in.GetToken())
+	if err != nil {
+		return nil, err
+	}
+
+	if s.IdentityRepo == nil {
+		return nil, ErrOIDCNotEnabled
+	}
+
+	if err := s.IdentityRepo.UnlinkIdentity(user.ID, in.GetProvider()); err != nil {
+		if errors.Is(err, repos.ErrIdentityNotFound) {
+			return nil, ErrIdentityNotFound
+		}
+		return nil, ErrInternal(err)
+	}
+
+	return &pb.Empty{}, nil
+}
+
+// ListIdentities lists the provider accounts linked to the user of the bearer token of the request, or the
+// provided token.
+// It takes in a context and a Token, and returns a ListIdentitiesResponse and an error.
+func (s *AuthService) ListIdentities(ctx context.Context, in *pb.Token) (*pb.ListIdentitiesResponse, error) {
+	user, err := s.authenticate(ctx, in.GetToken())
+	if err != nil {
+		return nil, err
+	}
+
+	if s.IdentityRepo == nil {
+		return nil, ErrOIDCNotEnabled
+	}
+
+	identities, err := s.IdentityRepo.ListIdentities(user.ID)
+	if err != nil {
+		return nil, ErrInternal(err)
+	}
+
+	resp := &pb.ListIdentitiesResponse{Identities: make([]*pb.IdentityResponse, 0, len(identities))}
+	for i := range identities {
+		resp.Identities = append(resp.Identities, identityToResponse(&identities[i]))
+	}
+	return resp, nil
+}
+
+// VerifyUser verifies a user's account, given the provided token.
+// A verify token can only be used once, expired and used tokens are rejected with ErrTokenExpired and ErrTokenUsed.
+// It takes in a context and a Token, and returns a UserResponse and an error.
+func (s *AuthService) VerifyUser(_ context.Context, in *pb.Token) (*pb.UserResponse, error) {
+	token := in.GetToken()
+	if govalidator.IsNull(token) {
+		return nil, ErrTokenRequired
+	}
+
+	user, err := s.UserRepo.GetUserByVerifyToken(token)
+	if err != nil {
+		return nil, ErrToken(err)
+	}
+
+	if !user.IsActive {
+		return nil, ErrUserInactive
+	}
+
+	err = s.transaction(func(userRepo *repos.UserRepository, tokenRepo *repos.TokenRepository) error {
+		if err := tokenRepo.UseVerifyToken(token); err != nil {
+			return err
+		}
+		user.IsVerified = true
+		return userRepo.UpdateUser(user)
+	})
+	if err != nil {
+		if errors.Is(err, repos.ErrTokenUsed) {
+			return nil, ErrTokenUsed
+		}
+		return nil, ErrInternal(err)
+	}
+
+	return userToResponse(user), nil
+}
+
+// VerifyUserToken generates a user verification token based on their email.
+// When a notifier is configured the token is emailed to the user and left out of the response.
+// It takes in a context and a VerifyUserTokenRequest, and returns a TokenWithEmail and an error.
+func (s *AuthService) VerifyUserToken(ctx context.Context, in *pb.VerifyUserTokenRequest) (*pb.TokenWithEmail, error) {
+	email := strings.TrimSpace(strings.ToLower(in.GetEmail()))
+	if !govalidator.IsEmail(email) {
+		return nil, ErrEmailInvalid
+	}
+
+	user, err := s.UserRepo.GetUserByEmail(email)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if !user.IsActive {
+		return nil, ErrUserInactive
+	}
+
+	if user.IsVerified {
+		return nil, ErrUserAlreadyVerified
+	}
+
+	token, err := s.TokenRepo.CreateVerifyToken(user.ID)
+	if err != nil {
+		return nil, ErrInternal(err)
+	}
+
+	sent, err := s.sendToken(ctx, notify.KindVerify, user.Email, user, token.Token)
+	if err != nil {
+		return nil, err
+	}
+	if sent {
+		token.Token = ""
+	}
+
+	return &pb.TokenWithEmail{
+		Token:     token.Token,
+		Email:     user.Email,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+	}, nil
+}
+
+// GetJWKS returns the public keys used to sign JWT access tokens, so they can be validated offline.
+// It takes in a context and an Empty request, and returns a JWKSResponse and an error.
+func (s *AuthService) GetJWKS(_ context.Context, _ *pb.Empty) (*pb.JWKSResponse, error) {
+	if s.TokenRepo.Signer == nil {
+		return nil, ErrJWTNotEnabled
+	}
+
+	jwks := s.TokenRepo.Signer.JWKS()
+	resp := &pb.JWKSResponse{Keys: make([]*pb.JWK, len(jwks))}
+	for i, jwk := range jwks {
+		resp.Keys[i] = &pb.JWK{
+			Kty: jwk.Kty,
+			Kid: jwk.Kid,
+			Use: jwk.Use,
+			Alg: jwk.Alg,
+			N:   jwk.N,
+			E:   jwk.E,
+			Crv: jwk.Crv,
+			X:   jwk.X,
+		}
+	}
+
+	return resp, nil
+}
+
+// mfaError converts the errors of the mfa repository.
+func mfaError(err error) error {
+	switch {
+	case errors.Is(err, repos.ErrMFACodeInvalid):
+		return ErrMFACodeInvalid
+	case errors.Is(err, repos.ErrTOTPAlreadyEnabled):
+		return ErrTOTPAlreadyEnabled
+	case errors.Is(err, repos.ErrTOTPNotEnabled):
+		return ErrTOTPNotEnabled
+	case errors.Is(err, repos.ErrTOTPNotEnrolled):
+		return ErrTOTPNotEnrolled
+	default:
+		return ErrInternal(err)
+	}
+}
+
+// CompleteMFA completes a two-factor login with a TOTP or recovery code, issuing the bearer token.
+// Invalid codes count as failed logins, so the account is locked like it is by BearerToken.
+// It takes in a context and a CompleteMFARequest, and returns a BearerTokenResponse and an error.
+func (s *AuthService) CompleteMFA(ctx context.Context, in *pb.CompleteMFARequest) (*pb.BearerTokenResponse, error) {
+	if s.MFARepo == nil {
+		return nil, ErrMFANotEnabled
+	}
+
+	token := in.GetMfaToken()
+	if govalidator.IsNull(token) {
+		return nil, ErrTokenRequired
+	}
+
+	user, err := s.UserRepo.GetUserByMFAToken(token)
+	if err != nil {
+		return nil, ErrTokenInvalid
+	}
+
+	if !user.IsActive {
+		return nil, ErrUserInactive
+	}
+
+	if err := s.verifyMFACode(ctx, user, in.GetCode()); err != nil {
+		if errors.Is(err, ErrMFACodeInvalid) {
+			if err := s.TokenRepo.RecordMFAFailure(token, maxMFAAttempts); err != nil {
+				return nil, ErrInternal(err)
+			}
+		}
+		return nil, err
+	}
+
+	if err := s.TokenRepo.RevokeMFAToken(token); err != nil {
+		return nil, ErrInternal(err)
+	}
+
+	if err := s.resetLoginFailures(user.Email); err != nil {
+		return nil, ErrInternal(err)
+	}
+
+	resp, err := s.issueBearerToken(ctx, user.ID, nil)
+	if err != nil {
+		return nil, ErrInternal(err)
+	}
+
+	return resp, nil
+}
+
+// BeginTOTPEnrollment starts TOTP enrollment for the authenticated user, returning the secret and otpauth:// uri
+// to add to an authenticator app. TOTP is not enabled until the enrollment is confirmed.
+// It takes in a context and a Token, and returns a TOTPEnrollmentResponse and an error.
+func (s *AuthService) BeginTOTPEnrollment(ctx context.Context, in *pb.Token) (*pb.TOTPEnrollmentResponse, error) {
+	if s.MFARepo == nil {
+		return nil, ErrMFANotEnabled
+	}
+
+	user, err := s.authenticate(ctx, in.GetToken())
+	if err != nil {
+		return nil, err
+	}
+
+	secret, uri, err := s.MFARepo.BeginTOTPEnrollment(user)
+	if err != nil {
+		return nil, mfaError(err)
+	}
+
+	return &pb.TOTPEnrollmentResponse{
+		Secret: secret,
+		Uri:    uri,
+	}, nil
+}
+
+// ConfirmTOTPEnrollment enables TOTP for the authenticated user given a valid code from their authenticator app.
+// The returned recovery codes can each be used once in place of a TOTP code and are only shown this once.
+// It takes in a context and a TOTPCodeRequest, and returns a RecoveryCodesResponse and an error.
+func (s *AuthService) ConfirmTOTPEnrollment(ctx context.Context, in *pb.TOTPCodeRequest) (*pb.RecoveryCodesResponse, error) {
+	if s.MFARepo == nil {
+		return nil, ErrMFANotEnabled
+	}
+
+	user, err := s.authenticate(ctx, in.GetToken())
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := s.MFARepo.ConfirmTOTPEnrollment(user, in.GetCode())
+	if err != nil {
+		return nil, mfaError(err)
+	}
+
+	return &pb.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTOTP disables TOTP for the authenticated user given a valid TOTP or recovery code.
+// Invalid codes count as failed logins, so the account is locked like it is by BearerToken.
+// It takes in a context and a TOTPCodeRequest, and returns an Empty response and an error.
+func (s *AuthService) DisableTOTP(ctx context.Context, in *pb.TOTPCodeRequest) (*pb.Empty, error) {
+	if s.MFARepo == nil {
+		return nil, ErrMFANotEnabled
+	}
+
+	user, err := s.authenticate(ctx, in.GetToken())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.verifyMFACode(ctx, user, in.GetCode()); err != nil {
+		return nil, err
+	}
+
+	if err := s.MFARepo.DisableTOTP(user); err != nil {
+		return nil, ErrInternal(err)
+	}
+
+	return &pb.Empty{}, nil
+}
+
+// ListSessions lists the signed in sessions of the authenticated user, newest first.
+// It takes in a context and a Token, and returns a ListSessionsResponse and an error.
+func (s *AuthService) ListSessions(ctx context.Context, in *pb.Token) (*pb.ListSessionsResponse, error) {
+	user, err := s.authenticate(ctx, in.GetToken())
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.TokenRepo.ListSessions(user.ID)
+	if err != nil {
+		return nil, ErrInternal(err)
+	}
+
+	token := bearerToken(ctx, in.GetToken())
+	resp := &pb.ListSessionsResponse{Sessions: make([]*pb.SessionResponse, 0, len(sessions))}
+	for i := range sessions {
+		resp.Sessions = append(resp.Sessions, sessionToResponse(&sessions[i], s.TokenRepo.MatchesAccessToken(&sessions[i], token)))
+	}
+
+	return resp, nil
+}
+
+// RevokeSession signs out one of the sessions of the authenticated user, its refresh token is revoked too.
+// It takes in a context and a RevokeSessionRequest, and returns an Empty response and an error.
+func (s *AuthService) RevokeSession(ctx context.Context, in *pb.RevokeSessionRequest) (*pb.Empty, error) {
+	user, err := s.authenticate(ctx, in.GetToken())
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.Parse(in.GetId())
+	if err != nil {
+		return nil, ErrSessionIdInvalid
+	}
+
+	if err := s.TokenRepo.RevokeSession(user.ID, id); err != nil {
+		switch {
+		case errors.Is(err, repos.ErrSessionNotFound):
+			return nil, ErrSessionNotFound
+		default:
+			return nil, ErrInternal(err)
+		}
+	}
+
+	return &pb.Empty{}, nil
+}
+
+// RevokeAllSessions signs out every session of the authenticated user, optionally keeping the current one.
+// It takes in a context and a RevokeAllSessionsRequest, and returns an Empty response and an error.
+func (s *AuthService) RevokeAllSessions(ctx context.Context, in *pb.RevokeAllSessionsRequest) (*pb.Empty, error) {
+	user, err := s.authenticate(ctx, in.GetToken())
+	if err != nil {
+		return nil, err
+	}
+
+	var except string
+	if in.GetKeepCurrent() {
+		except = bearerToken(ctx, in.GetToken())
+	}
+
+	if err := s.TokenRepo.RevokeAllSessions(user.ID, except); err != nil {
+		return nil, ErrInternal(err)
+	}
+
+	return &pb.Empty{}, nil
+}
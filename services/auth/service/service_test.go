@@ -2,17 +2,36 @@ package service_test
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
 	"gorm.io/gorm"
+	"net"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/suite"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 
+	"github.com/accentdesign/grpc/core/errs"
 	"github.com/accentdesign/grpc/services/auth/helpers"
+	"github.com/accentdesign/grpc/services/auth/internal/keys"
 	"github.com/accentdesign/grpc/services/auth/internal/models"
+	"github.com/accentdesign/grpc/services/auth/internal/notify"
+	"github.com/accentdesign/grpc/services/auth/internal/oidc"
+	"github.com/accentdesign/grpc/services/auth/internal/oidc/oidctest"
+	"github.com/accentdesign/grpc/services/auth/internal/passwords"
 	"github.com/accentdesign/grpc/services/auth/internal/repos"
+	"github.com/accentdesign/grpc/services/auth/internal/secrets"
+	"github.com/accentdesign/grpc/services/auth/internal/totp"
 	pb "github.com/accentdesign/grpc/services/auth/pkg/api/auth"
 	"github.com/accentdesign/grpc/services/auth/service"
 	"github.com/accentdesign/grpc/testutils"
@@ -59,9 +78,10 @@ func (suite *TestSuite) TestAuthService_BearerToken() {
 	tokenRepo := &repos.TokenRepository{
 		DB: suite.db,
 		Config: &repos.TokenConfig{
-			BearerDuration: 3600 * time.Second,
-			ResetDuration:  3600 * time.Second,
-			VerifyDuration: 3600 * time.Second,
+			BearerDuration:  3600 * time.Second,
+			RefreshDuration: 3600 * time.Second,
+			ResetDuration:   3600 * time.Second,
+			VerifyDuration:  3600 * time.Second,
 		},
 	}
 
@@ -101,13 +121,217 @@ func (suite *TestSuite) TestAuthService_BearerToken() {
 	err = suite.db.Where("user_id = ?", user.ID).First(&token).Error
 	suite.NoError(err)
 
+	var refreshToken models.RefreshToken
+	err = suite.db.Where("user_id = ?", user.ID).First(&refreshToken).Error
+	suite.NoError(err)
+
+	// only the digests of the tokens are stored
+	hasher := &repos.TokenHasher{}
+	suite.Equal(hasher.Hash(resp.AccessToken), token.Token)
+	suite.Equal(hasher.Hash(resp.RefreshToken), refreshToken.Token)
+
 	suite.Equal(&pb.BearerTokenResponse{
-		AccessToken: token.Token,
-		TokenType:   "bearer",
-		Expiry:      3600,
+		AccessToken:   resp.AccessToken,
+		TokenType:     "bearer",
+		Expiry:        3600,
+		RefreshToken:  resp.RefreshToken,
+		RefreshExpiry: 3600,
 	}, resp)
 }
 
+func (suite *TestSuite) TestAuthService_BearerTokenRehash() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+	suite.True(strings.HasPrefix(user.HashedPassword, "$2a$"))
+
+	// create the auth service
+	authService := &service.AuthService{
+		UserRepo: &repos.UserRepository{DB: suite.db},
+		TokenRepo: &repos.TokenRepository{
+			DB: suite.db,
+			Config: &repos.TokenConfig{
+				BearerDuration:  3600 * time.Second,
+				RefreshDuration: 3600 * time.Second,
+			},
+		},
+	}
+
+	defer func(hasher passwords.Hasher) { models.PasswordHasher = hasher }(models.PasswordHasher)
+	models.PasswordHasher = &passwords.Argon2id{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+	// a failed login does not rehash
+	_, err = authService.BearerToken(context.Background(), &pb.BearerTokenRequest{Email: user.Email, Password: "invalid"})
+	suite.EqualError(err, service.ErrInvalidCredentials.Error())
+
+	var fetched models.User
+	suite.NoError(suite.db.First(&fetched, "id = ?", user.ID).Error)
+	suite.Equal(user.HashedPassword, fetched.HashedPassword)
+
+	// a successful login upgrades the bcrypt hash to argon2id
+	_, err = authService.BearerToken(context.Background(), &pb.BearerTokenRequest{Email: user.Email, Password: "password"})
+	suite.NoError(err)
+
+	suite.NoError(suite.db.First(&fetched, "id = ?", user.ID).Error)
+	suite.True(strings.HasPrefix(fetched.HashedPassword, "$argon2id$"))
+	suite.True(fetched.VerifyPassword("password"))
+
+	_, err = authService.BearerToken(context.Background(), &pb.BearerTokenRequest{Email: user.Email, Password: "password"})
+	suite.NoError(err)
+}
+
+func (suite *TestSuite) TestAuthService_BearerTokenLockout() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	// create the auth service
+	authService := &service.AuthService{
+		UserRepo: &repos.UserRepository{DB: suite.db},
+		TokenRepo: &repos.TokenRepository{
+			DB: suite.db,
+			Config: &repos.TokenConfig{
+				BearerDuration:  3600 * time.Second,
+				RefreshDuration: 3600 * time.Second,
+			},
+		},
+		LoginAttemptRepo: &repos.LoginAttemptRepository{
+			DB: suite.db,
+			Config: &repos.LoginAttemptConfig{
+				MaxAccountFailures: 3,
+				MaxIPFailures:      5,
+				LockoutDuration:    time.Minute,
+				FailureWindow:      time.Minute,
+				BaseDelay:          10 * time.Millisecond,
+				MaxDelay:           20 * time.Millisecond,
+			},
+		},
+	}
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1234}})
+	invalid := &pb.BearerTokenRequest{Email: user.Email, Password: "invalid"}
+	valid := &pb.BearerTokenRequest{Email: user.Email, Password: "password"}
+
+	// the first failure is delayed
+	_, err = authService.BearerToken(ctx, invalid)
+	suite.EqualError(err, service.ErrInvalidCredentials.Error())
+
+	_, err = authService.BearerToken(ctx, valid)
+	suite.Equal(codes.Unavailable, status.Code(err))
+	suite.Equal("LOGIN_DELAYED", errs.Reason(err))
+	suite.Len(status.Convert(err).Details(), 2)
+
+	// a successful login resets the account failures
+	time.Sleep(20 * time.Millisecond)
+	_, err = authService.BearerToken(ctx, valid)
+	suite.NoError(err)
+
+	// the account is locked after too many failures
+	for range 3 {
+		time.Sleep(20 * time.Millisecond)
+		_, err = authService.BearerToken(ctx, invalid)
+		suite.EqualError(err, service.ErrInvalidCredentials.Error())
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	_, err = authService.BearerToken(ctx, valid)
+	suite.Equal(codes.ResourceExhausted, status.Code(err))
+	suite.Equal("LOGIN_LOCKED", errs.Reason(err))
+
+	// the ip address is locked after too many failures, across accounts
+	time.Sleep(20 * time.Millisecond)
+	_, err = authService.BearerToken(ctx, &pb.BearerTokenRequest{Email: "other@example.com", Password: "invalid"})
+	suite.EqualError(err, service.ErrInvalidCredentials.Error())
+
+	time.Sleep(20 * time.Millisecond)
+	_, err = authService.BearerToken(ctx, &pb.BearerTokenRequest{Email: "other@example.com", Password: "invalid"})
+	suite.Equal(codes.ResourceExhausted, status.Code(err))
+
+	// other ip addresses are not affected by the ip lock
+	time.Sleep(20 * time.Millisecond)
+	otherCtx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("192.0.2.2"), Port: 1234}})
+	_, err = authService.BearerToken(otherCtx, &pb.BearerTokenRequest{Email: "other@example.com", Password: "invalid"})
+	suite.EqualError(err, service.ErrInvalidCredentials.Error())
+}
+
+func (suite *TestSuite) TestAuthService_RefreshBearerToken() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	// create the repos
+	userRepo := &repos.UserRepository{DB: suite.db}
+	tokenRepo := &repos.TokenRepository{
+		DB: suite.db,
+		Config: &repos.TokenConfig{
+			BearerDuration:  3600 * time.Second,
+			RefreshDuration: 3600 * time.Second,
+			ResetDuration:   3600 * time.Second,
+			VerifyDuration:  3600 * time.Second,
+		},
+	}
+
+	// create the auth service
+	authService := &service.AuthService{
+		UserRepo:  userRepo,
+		TokenRepo: tokenRepo,
+	}
+
+	ctx := context.Background()
+
+	expired := &models.RefreshToken{UserId: user.ID, FamilyId: uuid.New(), Token: "expired-token", ExpiresAt: time.Now().Add(-1 * time.Second)}
+	err = suite.db.Create(expired).Error
+	suite.NoError(err)
+
+	testCases := []struct {
+		desc          string
+		request       *pb.Token
+		expectedError error
+	}{
+		{"missing token", &pb.Token{}, status.Error(codes.InvalidArgument, "token is required")},
+		{"invalid token", &pb.Token{Token: "invalid"}, status.Error(codes.InvalidArgument, "invalid token")},
+		{"expired token", &pb.Token{Token: expired.Token}, status.Error(codes.InvalidArgument, "invalid token")},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.desc, func() {
+			resp, err := authService.RefreshBearerToken(ctx, tc.request)
+			suite.EqualError(err, tc.expectedError.Error())
+			suite.Nil(resp)
+		})
+	}
+
+	// Test valid refresh token
+	login, err := authService.BearerToken(ctx, &pb.BearerTokenRequest{Email: user.Email, Password: "password"})
+	suite.NoError(err)
+
+	resp, err := authService.RefreshBearerToken(ctx, &pb.Token{Token: login.RefreshToken})
+	suite.NoError(err)
+	suite.NotEqual(login.AccessToken, resp.AccessToken)
+	suite.NotEqual(login.RefreshToken, resp.RefreshToken)
+	suite.Equal("bearer", resp.TokenType)
+
+	fetchedUser, err := userRepo.GetUserByAccessToken(resp.AccessToken)
+	suite.NoError(err)
+	suite.Equal(user.ID, fetchedUser.ID)
+
+	// Test reuse revokes the family
+	resp, err = authService.RefreshBearerToken(ctx, &pb.Token{Token: login.RefreshToken})
+	suite.EqualError(err, status.Error(codes.InvalidArgument, "invalid token").Error())
+	suite.Nil(resp)
+
+	var count int64
+	err = suite.db.Model(&models.RefreshToken{}).Where("user_id = ? AND token != ?", user.ID, expired.Token).Count(&count).Error
+	suite.NoError(err)
+	suite.Equal(int64(0), count)
+}
+
 func (suite *TestSuite) TestAuthService_RevokeBearerToken() {
 	teardown := suite.Setup()
 	defer teardown()
@@ -120,9 +344,10 @@ func (suite *TestSuite) TestAuthService_RevokeBearerToken() {
 	tokenRepo := &repos.TokenRepository{
 		DB: suite.db,
 		Config: &repos.TokenConfig{
-			BearerDuration: 3600 * time.Second,
-			ResetDuration:  3600 * time.Second,
-			VerifyDuration: 3600 * time.Second,
+			BearerDuration:  3600 * time.Second,
+			RefreshDuration: 3600 * time.Second,
+			ResetDuration:   3600 * time.Second,
+			VerifyDuration:  3600 * time.Second,
 		},
 	}
 
@@ -157,14 +382,14 @@ func (suite *TestSuite) TestAuthService_RevokeBearerToken() {
 	}
 
 	// Test with valid token
-	token, tokenErr := tokenRepo.CreateAccessToken(user.ID)
+	token, tokenErr := tokenRepo.CreateAccessToken(user.ID, repos.SessionInfo{})
 	suite.NoError(tokenErr)
 
 	resp, err := authService.RevokeBearerToken(ctx, &pb.Token{Token: token.Token})
 	suite.NoError(err)
 	suite.Equal(&pb.Empty{}, resp)
 
-	err = suite.db.First(&token).Error
+	err = suite.db.First(&models.AccessToken{}, "user_id = ?", user.ID).Error
 	suite.Error(err)
 	suite.Equal(err.Error(), "record not found")
 }
@@ -178,9 +403,10 @@ func (suite *TestSuite) TestAuthService_Register() {
 	tokenRepo := &repos.TokenRepository{
 		DB: suite.db,
 		Config: &repos.TokenConfig{
-			BearerDuration: 3600 * time.Second,
-			ResetDuration:  3600 * time.Second,
-			VerifyDuration: 3600 * time.Second,
+			BearerDuration:  3600 * time.Second,
+			RefreshDuration: 3600 * time.Second,
+			ResetDuration:   3600 * time.Second,
+			VerifyDuration:  3600 * time.Second,
 		},
 	}
 
@@ -209,7 +435,6 @@ func (suite *TestSuite) TestAuthService_Register() {
 		{"invalid email", &pb.RegisterRequest{Email: "invalid"}, status.Error(codes.InvalidArgument, "invalid email format")},
 		{"missing first name", &pb.RegisterRequest{Email: "test@test.com"}, status.Error(codes.InvalidArgument, "first_name is required")},
 		{"missing last name", &pb.RegisterRequest{Email: "test@test.com", FirstName: "Some"}, status.Error(codes.InvalidArgument, "last_name is required")},
-		{"missing password", &pb.RegisterRequest{Email: "test@test.com", FirstName: "Some", LastName: "One"}, status.Error(codes.InvalidArgument, "password is required")},
 		{"invalid password", &pb.RegisterRequest{Email: "test@test.com", FirstName: "Some", LastName: "One", Password: "123"}, status.Error(codes.InvalidArgument, "password must be between 6 and 72 characters in length")},
 	}
 
@@ -279,6 +504,50 @@ func (suite *TestSuite) TestAuthService_Register() {
 	suite.Nil(resp)
 }
 
+func (suite *TestSuite) TestAuthService_RegisterWithoutPassword() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	authService := &service.AuthService{
+		UserRepo: &repos.UserRepository{DB: suite.db},
+		TokenRepo: &repos.TokenRepository{
+			DB: suite.db,
+			Config: &repos.TokenConfig{
+				BearerDuration: 3600 * time.Second,
+				LoginDuration:  900 * time.Second,
+			},
+		},
+	}
+
+	_, err := suite.helpers.CreateTestUserType()
+	suite.NoError(err)
+
+	registered, err := authService.Register(context.Background(), &pb.RegisterRequest{Email: "test@test.com", FirstName: "Some", LastName: "One"})
+	suite.NoError(err)
+	suite.Equal("test@test.com", registered.Email)
+
+	var fetchUser models.User
+	suite.NoError(suite.db.First(&fetchUser, "email = ?", registered.Email).Error)
+	suite.Empty(fetchUser.HashedPassword)
+
+	// the user cannot log in with a password
+	_, err = authService.BearerToken(context.Background(), &pb.BearerTokenRequest{Email: registered.Email, Password: "password"})
+	suite.EqualError(err, service.ErrInvalidCredentials.Error())
+
+	// but logs in with a login token
+	link, err := authService.RequestLoginLink(context.Background(), &pb.LoginTokenRequest{Email: registered.Email})
+	suite.NoError(err)
+
+	resp, err := authService.RedeemLoginToken(context.Background(), &pb.RedeemLoginTokenRequest{Token: link.Token})
+	suite.NoError(err)
+	suite.NotEmpty(resp.AccessToken)
+
+	user, err := authService.User(context.Background(), &pb.Token{Token: resp.AccessToken})
+	suite.NoError(err)
+	suite.Equal(registered.Id, user.Id)
+	suite.True(user.IsVerified)
+}
+
 func (suite *TestSuite) TestAuthService_ResetPassword() {
 	teardown := suite.Setup()
 	defer teardown()
@@ -288,9 +557,10 @@ func (suite *TestSuite) TestAuthService_ResetPassword() {
 	tokenRepo := &repos.TokenRepository{
 		DB: suite.db,
 		Config: &repos.TokenConfig{
-			BearerDuration: 3600 * time.Second,
-			ResetDuration:  3600 * time.Second,
-			VerifyDuration: 3600 * time.Second,
+			BearerDuration:  3600 * time.Second,
+			RefreshDuration: 3600 * time.Second,
+			ResetDuration:   3600 * time.Second,
+			VerifyDuration:  3600 * time.Second,
 		},
 	}
 
@@ -328,14 +598,29 @@ func (suite *TestSuite) TestAuthService_ResetPassword() {
 		})
 	}
 
+	session, err := tokenRepo.CreateAccessToken(user.ID, repos.SessionInfo{})
+	suite.NoError(err)
+
 	// Test reset password
 	resp, err := authService.ResetPassword(ctx, &pb.ResetPasswordRequest{Token: token.Token, Password: "password"})
 	suite.NoError(err)
 	suite.Equal(&pb.Empty{}, resp)
 
+	// Test sessions are revoked
+	err = suite.db.First(session).Error
+	suite.EqualError(err, "record not found")
+
 	// Test cannot reuse token
 	resp, err = authService.ResetPassword(ctx, &pb.ResetPasswordRequest{Token: token.Token, Password: "password"})
-	suite.EqualError(err, status.Error(codes.InvalidArgument, "invalid token").Error())
+	suite.EqualError(err, status.Error(codes.InvalidArgument, "token has already been used").Error())
+	suite.Nil(resp)
+
+	// Test expired token
+	expired := &models.ResetToken{UserId: user.ID, Token: "expired-token", ExpiresAt: time.Now().Add(-1 * time.Second)}
+	suite.NoError(suite.db.Create(expired).Error)
+
+	resp, err = authService.ResetPassword(ctx, &pb.ResetPasswordRequest{Token: expired.Token, Password: "password"})
+	suite.EqualError(err, status.Error(codes.InvalidArgument, "token has expired").Error())
 	suite.Nil(resp)
 }
 
@@ -348,9 +633,10 @@ func (suite *TestSuite) TestAuthService_ResetPasswordToken() {
 	tokenRepo := &repos.TokenRepository{
 		DB: suite.db,
 		Config: &repos.TokenConfig{
-			BearerDuration: 3600 * time.Second,
-			ResetDuration:  3600 * time.Second,
-			VerifyDuration: 3600 * time.Second,
+			BearerDuration:  3600 * time.Second,
+			RefreshDuration: 3600 * time.Second,
+			ResetDuration:   3600 * time.Second,
+			VerifyDuration:  3600 * time.Second,
 		},
 	}
 
@@ -390,9 +676,10 @@ func (suite *TestSuite) TestAuthService_ResetPasswordToken() {
 	var token models.ResetToken
 	err = suite.db.Where("user_id = ?", user.ID).First(&token).Error
 	suite.NoError(err)
+	suite.Equal((&repos.TokenHasher{}).Hash(resp.Token), token.Token)
 
 	suite.Equal(&pb.TokenWithEmail{
-		Token:     token.Token,
+		Token:     resp.Token,
 		Email:     user.Email,
 		FirstName: user.FirstName,
 		LastName:  user.LastName,
@@ -408,9 +695,10 @@ func (suite *TestSuite) TestAuthService_User() {
 	tokenRepo := &repos.TokenRepository{
 		DB: suite.db,
 		Config: &repos.TokenConfig{
-			BearerDuration: 3600 * time.Second,
-			ResetDuration:  3600 * time.Second,
-			VerifyDuration: 3600 * time.Second,
+			BearerDuration:  3600 * time.Second,
+			RefreshDuration: 3600 * time.Second,
+			ResetDuration:   3600 * time.Second,
+			VerifyDuration:  3600 * time.Second,
 		},
 	}
 
@@ -433,7 +721,7 @@ func (suite *TestSuite) TestAuthService_User() {
 	}{
 		{"missing token", nil, &pb.Token{}, status.Error(codes.InvalidArgument, "token is required")},
 		{"invalid token", nil, &pb.Token{Token: "invalid"}, status.Error(codes.InvalidArgument, "invalid token")},
-		{"expired token", &models.AccessToken{UserId: user.ID, Token: "expired-token", ExpiresAt: time.Now().Add(-1 * time.Second)}, &pb.Token{Token: "expired-token"}, status.Error(codes.InvalidArgument, "invalid token")},
+		{"expired token", &models.AccessToken{UserId: user.ID, Token: "expired-token", ExpiresAt: time.Now().Add(-1 * time.Second)}, &pb.Token{Token: "expired-token"}, status.Error(codes.InvalidArgument, "token has expired")},
 	}
 
 	for _, tc := range testCases {
@@ -482,9 +770,10 @@ func (suite *TestSuite) TestAuthService_UpdateUser() {
 	tokenRepo := &repos.TokenRepository{
 		DB: suite.db,
 		Config: &repos.TokenConfig{
-			BearerDuration: 3600 * time.Second,
-			ResetDuration:  3600 * time.Second,
-			VerifyDuration: 3600 * time.Second,
+			BearerDuration:  3600 * time.Second,
+			RefreshDuration: 3600 * time.Second,
+			ResetDuration:   3600 * time.Second,
+			VerifyDuration:  3600 * time.Second,
 		},
 	}
 
@@ -508,9 +797,11 @@ func (suite *TestSuite) TestAuthService_UpdateUser() {
 	}{
 		{"missing token", nil, &pb.UpdateUserRequest{}, status.Error(codes.InvalidArgument, "token is required")},
 		{"invalid token", nil, &pb.UpdateUserRequest{Token: "123"}, status.Error(codes.InvalidArgument, "invalid token")},
-		{"expired token", &models.AccessToken{UserId: user.ID, Token: "expired-token", ExpiresAt: time.Now().Add(-1 * time.Second)}, &pb.UpdateUserRequest{Token: "expired-token"}, status.Error(codes.InvalidArgument, "invalid token")},
-		{"short password", &models.AccessToken{UserId: user.ID, Token: "valid-one", ExpiresAt: time.Now().Add(1 * time.Minute)}, &pb.UpdateUserRequest{Token: "valid-one", Password: "short"}, status.Error(codes.InvalidArgument, "password must be between 6 and 72 characters in length")},
-		{"invalid email", &models.AccessToken{UserId: user.ID, Token: "valid-two", ExpiresAt: time.Now().Add(1 * time.Minute)}, &pb.UpdateUserRequest{Token: "valid-two", Email: "invalid"}, status.Error(codes.InvalidArgument, "invalid email format")},
+		{"expired token", &models.AccessToken{UserId: user.ID, Token: "expired-token", ExpiresAt: time.Now().Add(-1 * time.Second)}, &pb.UpdateUserRequest{Token: "expired-token"}, status.Error(codes.InvalidArgument, "token has expired")},
+		{"short password", &models.AccessToken{UserId: user.ID, Token: "valid-one", ExpiresAt: time.Now().Add(1 * time.Minute)}, &pb.UpdateUserRequest{Token: "valid-one", Password: "short", CurrentPassword: "password"}, status.Error(codes.InvalidArgument, "password must be between 6 and 72 characters in length")},
+		{"changed email", &models.AccessToken{UserId: user.ID, Token: "valid-two", ExpiresAt: time.Now().Add(1 * time.Minute)}, &pb.UpdateUserRequest{Token: "valid-two", Email: "some@one.com", CurrentPassword: "password"}, status.Error(codes.InvalidArgument, "email can only be changed with RequestEmailChange")},
+		{"missing current password", &models.AccessToken{UserId: user.ID, Token: "valid-three", ExpiresAt: time.Now().Add(1 * time.Minute)}, &pb.UpdateUserRequest{Token: "valid-three", Password: "changed"}, status.Error(codes.InvalidArgument, "current_password is required")},
+		{"incorrect current password", &models.AccessToken{UserId: user.ID, Token: "valid-four", ExpiresAt: time.Now().Add(1 * time.Minute)}, &pb.UpdateUserRequest{Token: "valid-four", Password: "changed", CurrentPassword: "incorrect"}, status.Error(codes.InvalidArgument, "current password is incorrect")},
 	}
 
 	for _, tc := range failTestCases {
@@ -536,12 +827,12 @@ func (suite *TestSuite) TestAuthService_UpdateUser() {
 		desc    string
 		request *pb.UpdateUserRequest
 	}{
-		{"only email", &pb.UpdateUserRequest{Token: "another-token", Email: "some@one.com"}},
+		{"unchanged email", &pb.UpdateUserRequest{Token: "another-token", Email: strings.ToUpper(user.Email)}},
 		{"only first name", &pb.UpdateUserRequest{Token: "another-token", FirstName: "Some"}},
 		{"only last name", &pb.UpdateUserRequest{Token: "another-token", LastName: "One"}},
-		{"only password", &pb.UpdateUserRequest{Token: "another-token", Password: "changed"}},
+		{"only password", &pb.UpdateUserRequest{Token: "another-token", Password: "changed", CurrentPassword: "password"}},
 		{"first and last name", &pb.UpdateUserRequest{Token: "another-token", FirstName: "Someone", LastName: "Else"}},
-		{"email and password", &pb.UpdateUserRequest{Token: "another-token", Email: "some@another.com", Password: "again?"}},
+		{"unchanged email and password", &pb.UpdateUserRequest{Token: "another-token", Email: user.Email, Password: "again?", CurrentPassword: "changed"}},
 	}
 
 	for _, tc := range successTestCases {
@@ -556,11 +847,7 @@ func (suite *TestSuite) TestAuthService_UpdateUser() {
 			var fetchedUser models.User
 			err = suite.db.Preload("UserType").Preload("UserType.Scopes").First(&fetchedUser, "id = ?", user.ID).Error
 
-			if tc.request.Email != "" {
-				suite.Equal(tc.request.Email, fetchedUser.Email)
-			} else {
-				suite.Equal(originalUser.Email, fetchedUser.Email)
-			}
+			suite.Equal(originalUser.Email, fetchedUser.Email)
 			if tc.request.FirstName != "" {
 				suite.Equal(tc.request.FirstName, fetchedUser.FirstName)
 			} else {
@@ -600,9 +887,10 @@ func (suite *TestSuite) TestAuthService_VerifyUser() {
 	tokenRepo := &repos.TokenRepository{
 		DB: suite.db,
 		Config: &repos.TokenConfig{
-			BearerDuration: 3600 * time.Second,
-			ResetDuration:  3600 * time.Second,
-			VerifyDuration: 3600 * time.Second,
+			BearerDuration:  3600 * time.Second,
+			RefreshDuration: 3600 * time.Second,
+			ResetDuration:   3600 * time.Second,
+			VerifyDuration:  3600 * time.Second,
 		},
 	}
 
@@ -617,6 +905,7 @@ func (suite *TestSuite) TestAuthService_VerifyUser() {
 	suite.False(user.IsVerified)
 
 	ctx := context.Background()
+	usedAt := time.Now().Add(-1 * time.Minute)
 
 	testCases := []struct {
 		desc          string
@@ -626,7 +915,8 @@ func (suite *TestSuite) TestAuthService_VerifyUser() {
 	}{
 		{"missing token", nil, &pb.Token{}, status.Error(codes.InvalidArgument, "token is required")},
 		{"invalid token", nil, &pb.Token{Token: "invalid"}, status.Error(codes.InvalidArgument, "invalid token")},
-		{"expired token", &models.VerifyToken{UserId: user.ID, Token: "expired-token", ExpiresAt: time.Now().Add(-1 * time.Second)}, &pb.Token{Token: "expired-token"}, status.Error(codes.InvalidArgument, "invalid token")},
+		{"expired token", &models.VerifyToken{UserId: user.ID, Token: "expired-token", ExpiresAt: time.Now().Add(-1 * time.Second)}, &pb.Token{Token: "expired-token"}, status.Error(codes.InvalidArgument, "token has expired")},
+		{"used token", &models.VerifyToken{UserId: user.ID, Token: "used-token", ExpiresAt: time.Now().Add(time.Minute), UsedAt: &usedAt}, &pb.Token{Token: "used-token"}, status.Error(codes.InvalidArgument, "token has already been used")},
 	}
 
 	for _, tc := range testCases {
@@ -650,6 +940,10 @@ func (suite *TestSuite) TestAuthService_VerifyUser() {
 	resp, err := authService.VerifyUser(ctx, &pb.Token{Token: token.Token})
 	suite.NoError(err)
 
+	// Test cannot reuse token
+	_, err = authService.VerifyUser(ctx, &pb.Token{Token: token.Token})
+	suite.EqualError(err, status.Error(codes.InvalidArgument, "token has already been used").Error())
+
 	err = suite.db.Preload("UserType").Preload("UserType.Scopes").First(&user, "id = ?", user.ID).Error
 	suite.NoError(err)
 	suite.True(user.IsVerified)
@@ -677,9 +971,10 @@ func (suite *TestSuite) TestAuthService_VerifyUserToken() {
 	tokenRepo := &repos.TokenRepository{
 		DB: suite.db,
 		Config: &repos.TokenConfig{
-			BearerDuration: 3600 * time.Second,
-			ResetDuration:  3600 * time.Second,
-			VerifyDuration: 3600 * time.Second,
+			BearerDuration:  3600 * time.Second,
+			RefreshDuration: 3600 * time.Second,
+			ResetDuration:   3600 * time.Second,
+			VerifyDuration:  3600 * time.Second,
 		},
 	}
 
@@ -720,9 +1015,10 @@ func (suite *TestSuite) TestAuthService_VerifyUserToken() {
 	var token models.VerifyToken
 	err = suite.db.Where("user_id = ?", user.ID).First(&token).Error
 	suite.NoError(err)
+	suite.Equal((&repos.TokenHasher{}).Hash(resp.Token), token.Token)
 
 	suite.Equal(&pb.TokenWithEmail{
-		Token:     token.Token,
+		Token:     resp.Token,
 		Email:     user.Email,
 		FirstName: user.FirstName,
 		LastName:  user.LastName,
@@ -737,3 +1033,1261 @@ func (suite *TestSuite) TestAuthService_VerifyUserToken() {
 	suite.EqualError(err, status.Error(codes.FailedPrecondition, "user is already verified").Error())
 	suite.Nil(resp)
 }
+
+func (suite *TestSuite) TestAuthService_GetJWKS() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	// create the repos
+	userRepo := &repos.UserRepository{DB: suite.db}
+	tokenRepo := &repos.TokenRepository{
+		DB: suite.db,
+		Config: &repos.TokenConfig{
+			BearerDuration:  3600 * time.Second,
+			RefreshDuration: 3600 * time.Second,
+			ResetDuration:   3600 * time.Second,
+			VerifyDuration:  3600 * time.Second,
+		},
+	}
+
+	// create the auth service
+	authService := &service.AuthService{
+		UserRepo:  userRepo,
+		TokenRepo: tokenRepo,
+	}
+
+	ctx := context.Background()
+
+	// Test jwt not enabled
+	resp, err := authService.GetJWKS(ctx, &pb.Empty{})
+	suite.EqualError(err, status.Error(codes.FailedPrecondition, "jwt access tokens are not enabled").Error())
+	suite.Nil(resp)
+
+	// Test jwt enabled
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	suite.NoError(err)
+	signer, err := keys.NewKeySet([]*keys.Key{{ID: "test", PrivateKey: privateKey}}, "")
+	suite.NoError(err)
+	signer.Issuer = "auth"
+	tokenRepo.Signer = signer
+
+	resp, err = authService.GetJWKS(ctx, &pb.Empty{})
+	suite.NoError(err)
+	suite.Equal(&pb.JWKSResponse{
+		Keys: []*pb.JWK{
+			{
+				Kty: "OKP",
+				Kid: "test",
+				Use: "sig",
+				Alg: "EdDSA",
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(privateKey.Public().(ed25519.PublicKey)),
+			},
+		},
+	}, resp)
+
+	// Test bearer tokens are signed
+	token, err := authService.BearerToken(ctx, &pb.BearerTokenRequest{Email: user.Email, Password: "password"})
+	suite.NoError(err)
+
+	claims, err := signer.Parse(token.AccessToken)
+	suite.NoError(err)
+	suite.Equal(user.ID.String(), claims.Subject)
+	suite.Equal("standard", claims.UserType)
+	suite.Empty(claims.Scopes)
+
+	// Test signed bearer tokens are still accepted by the service
+	userResp, err := authService.User(ctx, &pb.Token{Token: token.AccessToken})
+	suite.NoError(err)
+	suite.Equal(user.ID.String(), userResp.Id)
+}
+
+func (suite *TestSuite) TestAuthService_InactiveUser() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	// create the repos
+	userRepo := &repos.UserRepository{DB: suite.db}
+	tokenRepo := &repos.TokenRepository{
+		DB: suite.db,
+		Config: &repos.TokenConfig{
+			BearerDuration:  3600 * time.Second,
+			RefreshDuration: 3600 * time.Second,
+			ResetDuration:   3600 * time.Second,
+			VerifyDuration:  3600 * time.Second,
+		},
+	}
+
+	// create the auth service
+	authService := &service.AuthService{
+		UserRepo:  userRepo,
+		TokenRepo: tokenRepo,
+	}
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	ctx := context.Background()
+
+	login, err := authService.BearerToken(ctx, &pb.BearerTokenRequest{Email: user.Email, Password: "password"})
+	suite.NoError(err)
+
+	// deactivate the user
+	user.IsActive = false
+	err = suite.db.Save(user).Error
+	suite.NoError(err)
+
+	// existing tokens are invalidated
+	var count int64
+	err = suite.db.Model(&models.AccessToken{}).Where("user_id = ?", user.ID).Count(&count).Error
+	suite.NoError(err)
+	suite.Equal(int64(0), count)
+	err = suite.db.Model(&models.RefreshToken{}).Where("user_id = ?", user.ID).Count(&count).Error
+	suite.NoError(err)
+	suite.Equal(int64(0), count)
+
+	resp, err := authService.RefreshBearerToken(ctx, &pb.Token{Token: login.RefreshToken})
+	suite.EqualError(err, status.Error(codes.InvalidArgument, "invalid token").Error())
+	suite.Nil(resp)
+
+	// tokens created while inactive are rejected
+	for _, token := range []interface{}{
+		&models.AccessToken{UserId: user.ID, Token: "access-token", ExpiresAt: time.Now().Add(time.Minute)},
+		&models.RefreshToken{UserId: user.ID, FamilyId: uuid.New(), Token: "refresh-token", ExpiresAt: time.Now().Add(time.Minute)},
+		&models.ResetToken{UserId: user.ID, Token: "reset-token", ExpiresAt: time.Now().Add(time.Minute)},
+		&models.VerifyToken{UserId: user.ID, Token: "verify-token", ExpiresAt: time.Now().Add(time.Minute)},
+	} {
+		err = suite.db.Create(token).Error
+		suite.NoError(err)
+	}
+
+	expectedError := status.Error(codes.PermissionDenied, "user is inactive").Error()
+
+	_, err = authService.BearerToken(ctx, &pb.BearerTokenRequest{Email: user.Email, Password: "password"})
+	suite.EqualError(err, expectedError)
+
+	_, err = authService.RefreshBearerToken(ctx, &pb.Token{Token: "refresh-token"})
+	suite.EqualError(err, expectedError)
+
+	_, err = authService.User(ctx, &pb.Token{Token: "access-token"})
+	suite.EqualError(err, expectedError)
+
+	_, err = authService.UpdateUser(ctx, &pb.UpdateUserRequest{Token: "access-token", FirstName: "Some"})
+	suite.EqualError(err, expectedError)
+
+	_, err = authService.ResetPasswordToken(ctx, &pb.ResetPasswordTokenRequest{Email: user.Email})
+	suite.EqualError(err, expectedError)
+
+	_, err = authService.ResetPassword(ctx, &pb.ResetPasswordRequest{Token: "reset-token", Password: "password"})
+	suite.EqualError(err, expectedError)
+
+	_, err = authService.VerifyUserToken(ctx, &pb.VerifyUserTokenRequest{Email: user.Email})
+	suite.EqualError(err, expectedError)
+
+	_, err = authService.VerifyUser(ctx, &pb.Token{Token: "verify-token"})
+	suite.EqualError(err, expectedError)
+}
+
+func (suite *TestSuite) TestAuthService_BearerMetadata() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	// create the repos
+	userRepo := &repos.UserRepository{DB: suite.db}
+	tokenRepo := &repos.TokenRepository{
+		DB: suite.db,
+		Config: &repos.TokenConfig{
+			BearerDuration:  3600 * time.Second,
+			RefreshDuration: 3600 * time.Second,
+			ResetDuration:   3600 * time.Second,
+			VerifyDuration:  3600 * time.Second,
+		},
+	}
+
+	// create the auth service
+	authService := &service.AuthService{
+		UserRepo:  userRepo,
+		TokenRepo: tokenRepo,
+	}
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	token, err := tokenRepo.CreateAccessToken(user.ID, repos.SessionInfo{})
+	suite.NoError(err)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token.Token))
+
+	// Test the metadata token is used without a token field
+	resp, err := authService.User(ctx, &pb.Token{})
+	suite.NoError(err)
+	suite.Equal(user.Email, resp.Email)
+
+	// Test the metadata token takes precedence over the token field
+	resp, err = authService.User(ctx, &pb.Token{Token: "invalid"})
+	suite.NoError(err)
+	suite.Equal(user.Email, resp.Email)
+
+	invalidCtx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer invalid"))
+	resp, err = authService.User(invalidCtx, &pb.Token{Token: token.Token})
+	suite.EqualError(err, status.Error(codes.InvalidArgument, "invalid token").Error())
+	suite.Nil(resp)
+
+	resp, err = authService.UpdateUser(ctx, &pb.UpdateUserRequest{FirstName: "Some"})
+	suite.NoError(err)
+	suite.Equal("Some", resp.FirstName)
+
+	// Test the user interceptor stores the user in the context
+	interceptor := authService.UserInterceptor()
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		user, ok := service.UserFromContext(ctx)
+		if !ok {
+			return nil, nil
+		}
+		return user.Email, nil
+	}
+
+	email, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	suite.NoError(err)
+	suite.Equal(user.Email, email)
+
+	email, err = interceptor(invalidCtx, nil, &grpc.UnaryServerInfo{}, handler)
+	suite.NoError(err)
+	suite.Nil(email)
+
+	email, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+	suite.NoError(err)
+	suite.Nil(email)
+
+	// Test a user in the context is used as is
+	resp, err = authService.User(service.NewContextWithUser(context.Background(), user), &pb.Token{})
+	suite.NoError(err)
+	suite.Equal(user.ID.String(), resp.Id)
+
+	// Test revoking the metadata token
+	_, err = authService.RevokeBearerToken(ctx, &pb.Token{})
+	suite.NoError(err)
+
+	resp, err = authService.User(ctx, &pb.Token{})
+	suite.EqualError(err, status.Error(codes.InvalidArgument, "invalid token").Error())
+	suite.Nil(resp)
+}
+
+func (suite *TestSuite) TestAuthService_TOTP() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	// create the repos
+	userRepo := &repos.UserRepository{DB: suite.db}
+	tokenRepo := &repos.TokenRepository{
+		DB: suite.db,
+		Config: &repos.TokenConfig{
+			BearerDuration:  3600 * time.Second,
+			RefreshDuration: 3600 * time.Second,
+			ResetDuration:   3600 * time.Second,
+			VerifyDuration:  3600 * time.Second,
+			MFADuration:     300 * time.Second,
+		},
+	}
+	cipher, err := secrets.NewCipher([]byte(strings.Repeat("k", 32)))
+	suite.NoError(err)
+
+	// create the auth service
+	authService := &service.AuthService{
+		UserRepo:  userRepo,
+		TokenRepo: tokenRepo,
+		MFARepo:   &repos.MFARepository{DB: suite.db, Cipher: cipher, Issuer: "auth"},
+	}
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	accessToken, err := tokenRepo.CreateAccessToken(user.ID, repos.SessionInfo{})
+	suite.NoError(err)
+
+	ctx := context.Background()
+	login := &pb.BearerTokenRequest{Email: user.Email, Password: "password"}
+
+	// Test mfa disabled on the server
+	disabled := &service.AuthService{UserRepo: userRepo, TokenRepo: tokenRepo}
+	_, err = disabled.BeginTOTPEnrollment(ctx, &pb.Token{Token: accessToken.Token})
+	suite.EqualError(err, status.Error(codes.FailedPrecondition, "two-factor authentication is not enabled").Error())
+
+	// Test enrollment
+	_, err = authService.BeginTOTPEnrollment(ctx, &pb.Token{Token: "invalid"})
+	suite.EqualError(err, status.Error(codes.InvalidArgument, "invalid token").Error())
+
+	_, err = authService.ConfirmTOTPEnrollment(ctx, &pb.TOTPCodeRequest{Token: accessToken.Token, Code: "123456"})
+	suite.EqualError(err, status.Error(codes.FailedPrecondition, "totp enrollment has not been started").Error())
+
+	enrollment, err := authService.BeginTOTPEnrollment(ctx, &pb.Token{Token: accessToken.Token})
+	suite.NoError(err)
+	suite.NotEmpty(enrollment.Secret)
+	suite.Contains(enrollment.Uri, "otpauth://totp/")
+
+	// login is not challenged until the enrollment is confirmed
+	resp, err := authService.BearerToken(ctx, login)
+	suite.NoError(err)
+	suite.False(resp.MfaRequired)
+	suite.NotEmpty(resp.AccessToken)
+
+	_, err = authService.ConfirmTOTPEnrollment(ctx, &pb.TOTPCodeRequest{Token: accessToken.Token, Code: "000000"})
+	suite.EqualError(err, status.Error(codes.InvalidArgument, "invalid code").Error())
+
+	step := totp.Step(time.Now())
+	code, err := totp.Code(enrollment.Secret, step-1)
+	suite.NoError(err)
+
+	recovery, err := authService.ConfirmTOTPEnrollment(ctx, &pb.TOTPCodeRequest{Token: accessToken.Token, Code: code})
+	suite.NoError(err)
+	suite.Len(recovery.RecoveryCodes, 10)
+
+	userResp, err := authService.User(ctx, &pb.Token{Token: accessToken.Token})
+	suite.NoError(err)
+	suite.True(userResp.TotpEnabled)
+
+	// Test login returns an mfa challenge
+	resp, err = authService.BearerToken(ctx, login)
+	suite.NoError(err)
+	suite.True(resp.MfaRequired)
+	suite.NotEmpty(resp.MfaToken)
+	suite.Equal(int32(300), resp.MfaExpiry)
+	suite.Empty(resp.AccessToken)
+	suite.Empty(resp.RefreshToken)
+
+	testCases := []struct {
+		desc          string
+		request       *pb.CompleteMFARequest
+		expectedError error
+	}{
+		{"missing token", &pb.CompleteMFARequest{Code: "123456"}, status.Error(codes.InvalidArgument, "token is required")},
+		{"invalid token", &pb.CompleteMFARequest{MfaToken: "invalid", Code: "123456"}, status.Error(codes.InvalidArgument, "invalid token")},
+		{"invalid code", &pb.CompleteMFARequest{MfaToken: resp.MfaToken, Code: "000000"}, status.Error(codes.InvalidArgument, "invalid code")},
+		{"used code", &pb.CompleteMFARequest{MfaToken: resp.MfaToken, Code: code}, status.Error(codes.InvalidArgument, "invalid code")},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.desc, func() {
+			resp, err := authService.CompleteMFA(ctx, tc.request)
+			suite.EqualError(err, tc.expectedError.Error())
+			suite.Nil(resp)
+		})
+	}
+
+	// Test completing with a totp code
+	code, err = totp.Code(enrollment.Secret, step)
+	suite.NoError(err)
+
+	completed, err := authService.CompleteMFA(ctx, &pb.CompleteMFARequest{MfaToken: resp.MfaToken, Code: code})
+	suite.NoError(err)
+	suite.NotEmpty(completed.AccessToken)
+	suite.NotEmpty(completed.RefreshToken)
+	suite.False(completed.MfaRequired)
+
+	// the mfa token can only be used once
+	_, err = authService.CompleteMFA(ctx, &pb.CompleteMFARequest{MfaToken: resp.MfaToken, Code: recovery.RecoveryCodes[0]})
+	suite.EqualError(err, status.Error(codes.InvalidArgument, "invalid token").Error())
+
+	// Test completing with a recovery code
+	resp, err = authService.BearerToken(ctx, login)
+	suite.NoError(err)
+
+	completed, err = authService.CompleteMFA(ctx, &pb.CompleteMFARequest{MfaToken: resp.MfaToken, Code: recovery.RecoveryCodes[0]})
+	suite.NoError(err)
+	suite.NotEmpty(completed.AccessToken)
+
+	// Test the mfa token is revoked after too many invalid codes
+	resp, err = authService.BearerToken(ctx, login)
+	suite.NoError(err)
+
+	for i := 0; i < 5; i++ {
+		_, err = authService.CompleteMFA(ctx, &pb.CompleteMFARequest{MfaToken: resp.MfaToken, Code: "000000"})
+		suite.EqualError(err, status.Error(codes.InvalidArgument, "invalid code").Error())
+	}
+
+	_, err = authService.CompleteMFA(ctx, &pb.CompleteMFARequest{MfaToken: resp.MfaToken, Code: recovery.RecoveryCodes[1]})
+	suite.EqualError(err, status.Error(codes.InvalidArgument, "invalid token").Error())
+
+	// Test disable
+	_, err = authService.DisableTOTP(ctx, &pb.TOTPCodeRequest{Token: accessToken.Token, Code: "000000"})
+	suite.EqualError(err, status.Error(codes.InvalidArgument, "invalid code").Error())
+
+	empty, err := authService.DisableTOTP(ctx, &pb.TOTPCodeRequest{Token: accessToken.Token, Code: recovery.RecoveryCodes[2]})
+	suite.NoError(err)
+	suite.Equal(&pb.Empty{}, empty)
+
+	resp, err = authService.BearerToken(ctx, login)
+	suite.NoError(err)
+	suite.False(resp.MfaRequired)
+	suite.NotEmpty(resp.AccessToken)
+
+	_, err = authService.DisableTOTP(ctx, &pb.TOTPCodeRequest{Token: accessToken.Token, Code: recovery.RecoveryCodes[3]})
+	suite.EqualError(err, status.Error(codes.FailedPrecondition, "totp is not enabled").Error())
+}
+
+func (suite *TestSuite) TestAuthService_MFALockout() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	tokenRepo := &repos.TokenRepository{
+		DB: suite.db,
+		Config: &repos.TokenConfig{
+			BearerDuration:  3600 * time.Second,
+			RefreshDuration: 3600 * time.Second,
+			MFADuration:     300 * time.Second,
+		},
+	}
+	cipher, err := secrets.NewCipher([]byte(strings.Repeat("k", 32)))
+	suite.NoError(err)
+
+	// create the auth service, failures are not delayed so only the lockout applies
+	loginAttemptRepo := &repos.LoginAttemptRepository{
+		DB: suite.db,
+		Config: &repos.LoginAttemptConfig{
+			MaxAccountFailures: 3,
+			MaxIPFailures:      100,
+			LockoutDuration:    time.Minute,
+			FailureWindow:      time.Minute,
+		},
+	}
+	authService := &service.AuthService{
+		UserRepo:         &repos.UserRepository{DB: suite.db},
+		TokenRepo:        tokenRepo,
+		MFARepo:          &repos.MFARepository{DB: suite.db, Cipher: cipher, Issuer: "auth"},
+		LoginAttemptRepo: loginAttemptRepo,
+	}
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	accessToken, err := tokenRepo.CreateAccessToken(user.ID, repos.SessionInfo{})
+	suite.NoError(err)
+
+	ctx := context.Background()
+	login := &pb.BearerTokenRequest{Email: user.Email, Password: "password"}
+	invalidCode := status.Error(codes.InvalidArgument, "invalid code").Error()
+
+	enrollment, err := authService.BeginTOTPEnrollment(ctx, &pb.Token{Token: accessToken.Token})
+	suite.NoError(err)
+	code, err := totp.Code(enrollment.Secret, totp.Step(time.Now()))
+	suite.NoError(err)
+	recovery, err := authService.ConfirmTOTPEnrollment(ctx, &pb.TOTPCodeRequest{Token: accessToken.Token, Code: code})
+	suite.NoError(err)
+
+	// invalid codes count against the account across mfa tokens, a correct password does not reset them
+	resp, err := authService.BearerToken(ctx, login)
+	suite.NoError(err)
+	for range 2 {
+		_, err = authService.CompleteMFA(ctx, &pb.CompleteMFARequest{MfaToken: resp.MfaToken, Code: "000000"})
+		suite.EqualError(err, invalidCode)
+	}
+
+	resp, err = authService.BearerToken(ctx, login)
+	suite.NoError(err)
+	suite.True(resp.MfaRequired)
+
+	_, err = authService.CompleteMFA(ctx, &pb.CompleteMFARequest{MfaToken: resp.MfaToken, Code: "000000"})
+	suite.EqualError(err, invalidCode)
+
+	// the account is locked, even for a correct code
+	_, err = authService.CompleteMFA(ctx, &pb.CompleteMFARequest{MfaToken: resp.MfaToken, Code: recovery.RecoveryCodes[0]})
+	suite.Equal("LOGIN_LOCKED", errs.Reason(err))
+
+	_, err = authService.BearerToken(ctx, login)
+	suite.Equal("LOGIN_LOCKED", errs.Reason(err))
+
+	// completing the login resets the account failures
+	suite.NoError(loginAttemptRepo.Reset(repos.AccountKey(user.Email)))
+
+	resp, err = authService.BearerToken(ctx, login)
+	suite.NoError(err)
+	_, err = authService.CompleteMFA(ctx, &pb.CompleteMFARequest{MfaToken: resp.MfaToken, Code: "000000"})
+	suite.EqualError(err, invalidCode)
+
+	completed, err := authService.CompleteMFA(ctx, &pb.CompleteMFARequest{MfaToken: resp.MfaToken, Code: recovery.RecoveryCodes[1]})
+	suite.NoError(err)
+	suite.NotEmpty(completed.AccessToken)
+
+	// invalid codes disabling totp count against the account too, from no failures as the login reset them
+	for range 3 {
+		_, err = authService.DisableTOTP(ctx, &pb.TOTPCodeRequest{Token: accessToken.Token, Code: "000000"})
+		suite.EqualError(err, invalidCode)
+	}
+
+	_, err = authService.DisableTOTP(ctx, &pb.TOTPCodeRequest{Token: accessToken.Token, Code: recovery.RecoveryCodes[2]})
+	suite.Equal("LOGIN_LOCKED", errs.Reason(err))
+}
+
+func (suite *TestSuite) TestAuthService_Sessions() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	// create the auth service
+	authService := &service.AuthService{
+		UserRepo: &repos.UserRepository{DB: suite.db},
+		TokenRepo: &repos.TokenRepository{
+			DB: suite.db,
+			Config: &repos.TokenConfig{
+				BearerDuration:  3600 * time.Second,
+				RefreshDuration: 3600 * time.Second,
+			},
+		},
+	}
+
+	login := func(agent string) *pb.BearerTokenResponse {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("user-agent", agent))
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1234}})
+		resp, err := authService.BearerToken(ctx, &pb.BearerTokenRequest{Email: user.Email, Password: "password"})
+		suite.NoError(err)
+		return resp
+	}
+
+	phone := login("phone")
+	laptop := login("laptop")
+	tablet := login("tablet")
+
+	// list the sessions
+	_, err = authService.ListSessions(context.Background(), &pb.Token{})
+	suite.EqualError(err, service.ErrTokenRequired.Error())
+
+	resp, err := authService.ListSessions(withBearer(context.Background(), laptop.AccessToken), &pb.Token{})
+	suite.NoError(err)
+	suite.Len(resp.Sessions, 3)
+	suite.Equal("tablet", resp.Sessions[0].UserAgent)
+	suite.Equal("192.0.2.1", resp.Sessions[0].IpAddress)
+	suite.False(resp.Sessions[0].Current)
+	suite.Equal("laptop", resp.Sessions[1].UserAgent)
+	suite.True(resp.Sessions[1].Current)
+	suite.NotZero(resp.Sessions[1].LastUsedAt)
+
+	// revoke a session
+	ctx := withBearer(context.Background(), laptop.AccessToken)
+	testCases := []struct {
+		desc          string
+		request       *pb.RevokeSessionRequest
+		expectedError error
+	}{
+		{"invalid id", &pb.RevokeSessionRequest{Id: "invalid"}, status.Error(codes.InvalidArgument, "invalid session id")},
+		{"unknown id", &pb.RevokeSessionRequest{Id: "3f4b2b87-d7b1-4b9f-b207-ae00b112382f"}, status.Error(codes.NotFound, "session not found")},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.desc, func() {
+			resp, err := authService.RevokeSession(ctx, tc.request)
+			suite.EqualError(err, tc.expectedError.Error())
+			suite.Nil(resp)
+		})
+	}
+
+	_, err = authService.RevokeSession(ctx, &pb.RevokeSessionRequest{Id: resp.Sessions[0].Id})
+	suite.NoError(err)
+
+	_, err = authService.User(withBearer(context.Background(), tablet.AccessToken), &pb.Token{})
+	suite.EqualError(err, service.ErrTokenInvalid.Error())
+	_, err = authService.RefreshBearerToken(context.Background(), &pb.Token{Token: tablet.RefreshToken})
+	suite.EqualError(err, service.ErrTokenInvalid.Error())
+
+	// revoke all sessions but the current one
+	_, err = authService.RevokeAllSessions(ctx, &pb.RevokeAllSessionsRequest{KeepCurrent: true})
+	suite.NoError(err)
+
+	_, err = authService.User(withBearer(context.Background(), phone.AccessToken), &pb.Token{})
+	suite.EqualError(err, service.ErrTokenInvalid.Error())
+	_, err = authService.User(ctx, &pb.Token{})
+	suite.NoError(err)
+
+	// changing the password keeps the current session only
+	phone = login("phone")
+	_, err = authService.UpdateUser(ctx, &pb.UpdateUserRequest{Password: "new-password", CurrentPassword: "password"})
+	suite.NoError(err)
+
+	_, err = authService.User(withBearer(context.Background(), phone.AccessToken), &pb.Token{})
+	suite.EqualError(err, service.ErrTokenInvalid.Error())
+	_, err = authService.User(ctx, &pb.Token{})
+	suite.NoError(err)
+
+	// revoke all sessions
+	_, err = authService.RevokeAllSessions(ctx, &pb.RevokeAllSessionsRequest{})
+	suite.NoError(err)
+
+	_, err = authService.User(ctx, &pb.Token{})
+	suite.EqualError(err, service.ErrTokenInvalid.Error())
+}
+
+func (suite *TestSuite) TestAuthService_PasswordPolicy() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	// create the auth service
+	authService := &service.AuthService{
+		UserRepo: &repos.UserRepository{DB: suite.db},
+		TokenRepo: &repos.TokenRepository{
+			DB: suite.db,
+			Config: &repos.TokenConfig{
+				BearerDuration:  3600 * time.Second,
+				RefreshDuration: 3600 * time.Second,
+				ResetDuration:   3600 * time.Second,
+			},
+		},
+		PasswordPolicy: &passwords.Policy{
+			MinLength:        8,
+			Classes:          []string{passwords.ClassDigit},
+			DisallowPersonal: true,
+			Common:           map[string]struct{}{"password1": {}},
+			History:          2,
+		},
+	}
+
+	violations := func(err error) []string {
+		st := status.Convert(err)
+		suite.Equal(codes.InvalidArgument, st.Code())
+		suite.Equal("password does not meet the password policy", st.Message())
+		suite.Equal("PASSWORD_POLICY", errs.Reason(err))
+
+		var descriptions []string
+		for _, violation := range errs.FieldViolations(err) {
+			suite.Equal("password", violation.GetField())
+			descriptions = append(descriptions, violation.GetDescription())
+		}
+		return descriptions
+	}
+
+	// register
+	_, err = authService.Register(context.Background(), &pb.RegisterRequest{
+		Email: "jane.smith@example.com", Password: "smith", FirstName: "Jane", LastName: "Smith",
+	})
+	suite.Equal([]string{
+		"must be at least 8 characters in length",
+		"must contain a digit",
+		"must not contain your email or name",
+	}, violations(err))
+
+	_, err = authService.Register(context.Background(), &pb.RegisterRequest{
+		Email: "jane.smith@example.com", Password: "Password1", FirstName: "Jane", LastName: "Smith",
+	})
+	suite.Equal([]string{"is too common"}, violations(err))
+
+	_, err = authService.Register(context.Background(), &pb.RegisterRequest{
+		Email: "jane.smith@example.com", Password: "sunny-day-42", FirstName: "Jane", LastName: "Smith",
+	})
+	suite.NoError(err)
+
+	// the current and previous passwords cannot be reused
+	ctx := withBearer(context.Background(), "token")
+	suite.NoError(suite.db.Create(&models.AccessToken{Token: "token", ID: uuid.New(), UserId: user.ID, ExpiresAt: time.Now().Add(time.Hour)}).Error)
+
+	_, err = authService.UpdateUser(ctx, &pb.UpdateUserRequest{Password: "password", CurrentPassword: "password"})
+	suite.Equal([]string{"must contain a digit", "must not be one of your last 2 passwords"}, violations(err))
+
+	_, err = authService.UpdateUser(ctx, &pb.UpdateUserRequest{Password: "rainy-day-1", CurrentPassword: "password"})
+	suite.NoError(err)
+
+	reset, err := authService.ResetPasswordToken(context.Background(), &pb.ResetPasswordTokenRequest{Email: user.Email})
+	suite.NoError(err)
+
+	_, err = authService.ResetPassword(context.Background(), &pb.ResetPasswordRequest{Token: reset.Token, Password: "rainy-day-1"})
+	suite.Equal([]string{"must not be one of your last 2 passwords"}, violations(err))
+
+	_, err = authService.ResetPassword(context.Background(), &pb.ResetPasswordRequest{Token: reset.Token, Password: "snowy-day-2"})
+	suite.NoError(err)
+
+	// only the last two passwords are remembered
+	reset, err = authService.ResetPasswordToken(context.Background(), &pb.ResetPasswordTokenRequest{Email: user.Email})
+	suite.NoError(err)
+
+	_, err = authService.ResetPassword(context.Background(), &pb.ResetPasswordRequest{Token: reset.Token, Password: "rainy-day-1"})
+	suite.Equal([]string{"must not be one of your last 2 passwords"}, violations(err))
+
+	_, err = authService.ResetPassword(context.Background(), &pb.ResetPasswordRequest{Token: reset.Token, Password: "password-3"})
+	suite.NoError(err)
+}
+
+func (suite *TestSuite) TestAuthService_ErrorDetails() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	// create the auth service
+	authService := &service.AuthService{
+		UserRepo:  &repos.UserRepository{DB: suite.db},
+		TokenRepo: &repos.TokenRepository{DB: suite.db, Config: &repos.TokenConfig{}},
+	}
+
+	testCases := []struct {
+		desc           string
+		call           func() error
+		expectedReason string
+		expectedField  string
+	}{
+		{"invalid email", func() error {
+			_, err := authService.Register(context.Background(), &pb.RegisterRequest{Email: "invalid"})
+			return err
+		}, "FIELD_INVALID", "email"},
+		{"missing first name", func() error {
+			_, err := authService.Register(context.Background(), &pb.RegisterRequest{Email: "new@example.com"})
+			return err
+		}, "FIELD_INVALID", "first_name"},
+		{"invalid password", func() error {
+			_, err := authService.Register(context.Background(), &pb.RegisterRequest{Email: "new@example.com", FirstName: "Some", LastName: "One", Password: "123"})
+			return err
+		}, "FIELD_INVALID", "password"},
+		{"email taken", func() error {
+			_, err := authService.Register(context.Background(), &pb.RegisterRequest{Email: user.Email, FirstName: "Some", LastName: "One", Password: "password"})
+			return err
+		}, "EMAIL_TAKEN", ""},
+		{"missing token", func() error {
+			_, err := authService.ResetPassword(context.Background(), &pb.ResetPasswordRequest{})
+			return err
+		}, "TOKEN_REQUIRED", "token"},
+		{"invalid token", func() error {
+			_, err := authService.ResetPassword(context.Background(), &pb.ResetPasswordRequest{Token: "invalid"})
+			return err
+		}, "TOKEN_INVALID", ""},
+		{"invalid credentials", func() error {
+			_, err := authService.BearerToken(context.Background(), &pb.BearerTokenRequest{Email: user.Email, Password: "invalid"})
+			return err
+		}, "INVALID_CREDENTIALS", ""},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.desc, func() {
+			err := tc.call()
+			suite.Equal(tc.expectedReason, errs.Reason(err))
+
+			st := status.Convert(err)
+			suite.Equal(service.ErrorDomain, st.Details()[0].(*errdetails.ErrorInfo).GetDomain())
+
+			violations := errs.FieldViolations(err)
+			if tc.expectedField == "" {
+				suite.Empty(violations)
+				return
+			}
+			suite.Len(violations, 1)
+			suite.Equal(tc.expectedField, violations[0].GetField())
+			suite.Equal(st.Message(), violations[0].GetDescription())
+		})
+	}
+}
+
+func (suite *TestSuite) TestAuthService_EmailChange() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	// create the auth service
+	authService := &service.AuthService{
+		UserRepo: &repos.UserRepository{DB: suite.db},
+		TokenRepo: &repos.TokenRepository{
+			DB: suite.db,
+			Config: &repos.TokenConfig{
+				BearerDuration:      3600 * time.Second,
+				EmailChangeDuration: 3600 * time.Second,
+			},
+		},
+	}
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+	suite.NoError(suite.db.Model(user).UpdateColumn("is_verified", true).Error)
+
+	other := &models.User{Email: "other@example.com", FirstName: "Other", LastName: "User", UserTypeId: user.UserTypeId, IsActive: true}
+	suite.NoError(other.SetPassword("password"))
+	suite.NoError(suite.db.Create(other).Error)
+
+	ctx := withBearer(context.Background(), "token")
+	suite.NoError(suite.db.Create(&models.AccessToken{Token: "token", ID: uuid.New(), UserId: user.ID, ExpiresAt: time.Now().Add(time.Hour)}).Error)
+
+	testCases := []struct {
+		desc          string
+		request       *pb.EmailChangeRequest
+		expectedError error
+	}{
+		{"invalid email", &pb.EmailChangeRequest{Email: "invalid", CurrentPassword: "password"}, service.ErrEmailInvalid},
+		{"unchanged email", &pb.EmailChangeRequest{Email: user.Email, CurrentPassword: "password"}, service.ErrEmailUnchanged},
+		{"missing current password", &pb.EmailChangeRequest{Email: "new@example.com"}, service.ErrCurrentPasswordRequired},
+		{"incorrect current password", &pb.EmailChangeRequest{Email: "new@example.com", CurrentPassword: "incorrect"}, service.ErrCurrentPasswordInvalid},
+		{"existing email", &pb.EmailChangeRequest{Email: other.Email, CurrentPassword: "password"}, service.ErrEmailAlreadyExists},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.desc, func() {
+			resp, err := authService.RequestEmailChange(ctx, tc.request)
+			suite.EqualError(err, tc.expectedError.Error())
+			suite.Nil(resp)
+		})
+	}
+
+	// request the change
+	resp, err := authService.RequestEmailChange(ctx, &pb.EmailChangeRequest{Email: " New@Example.com ", CurrentPassword: "password"})
+	suite.NoError(err)
+	suite.NotEmpty(resp.Token)
+	suite.Equal("new@example.com", resp.Email)
+	suite.Equal(user.Email, resp.PreviousEmail)
+	suite.Equal(user.FirstName, resp.FirstName)
+	suite.Equal(user.LastName, resp.LastName)
+
+	// the email is not changed until it is confirmed
+	fetchedUser, err := authService.User(ctx, &pb.Token{})
+	suite.NoError(err)
+	suite.Equal(user.Email, fetchedUser.Email)
+
+	_, err = authService.ConfirmEmailChange(context.Background(), &pb.Token{})
+	suite.EqualError(err, service.ErrTokenRequired.Error())
+
+	_, err = authService.ConfirmEmailChange(context.Background(), &pb.Token{Token: "invalid"})
+	suite.EqualError(err, service.ErrTokenInvalid.Error())
+
+	// the token is not used up when the change fails, e.g. when the email was taken after it was requested
+	taken := &models.User{Email: "new@example.com", FirstName: "Taken", LastName: "User", UserTypeId: user.UserTypeId, IsActive: true}
+	suite.NoError(suite.db.Create(taken).Error)
+
+	_, err = authService.ConfirmEmailChange(context.Background(), &pb.Token{Token: resp.Token})
+	suite.EqualError(err, service.ErrEmailAlreadyExists.Error())
+
+	var emailChange models.EmailChangeToken
+	suite.NoError(suite.db.First(&emailChange, "user_id = ?", user.ID).Error)
+	suite.Nil(emailChange.UsedAt)
+	suite.NoError(suite.db.Delete(taken).Error)
+
+	confirmed, err := authService.ConfirmEmailChange(context.Background(), &pb.Token{Token: resp.Token})
+	suite.NoError(err)
+	suite.Equal("new@example.com", confirmed.Email)
+	suite.True(confirmed.IsVerified)
+
+	// the token can only be used once
+	_, err = authService.ConfirmEmailChange(context.Background(), &pb.Token{Token: resp.Token})
+	suite.EqualError(err, service.ErrTokenUsed.Error())
+
+	// UpdateUser does not change the email, it has to be confirmed
+	_, err = authService.UpdateUser(ctx, &pb.UpdateUserRequest{Email: "updated@example.com", CurrentPassword: "password"})
+	suite.EqualError(err, service.ErrEmailChangeRequired.Error())
+
+	fetchedUser, err = authService.User(ctx, &pb.Token{})
+	suite.NoError(err)
+	suite.Equal("new@example.com", fetchedUser.Email)
+
+	// a pending change is dropped when the email changes, and an expired token is reported as such
+	pending, err := authService.RequestEmailChange(ctx, &pb.EmailChangeRequest{Email: "pending@example.com", CurrentPassword: "password"})
+	suite.NoError(err)
+
+	updated, err := authService.RequestEmailChange(ctx, &pb.EmailChangeRequest{Email: "updated@example.com", CurrentPassword: "password"})
+	suite.NoError(err)
+	_, err = authService.ConfirmEmailChange(context.Background(), &pb.Token{Token: updated.Token})
+	suite.NoError(err)
+
+	_, err = authService.ConfirmEmailChange(context.Background(), &pb.Token{Token: pending.Token})
+	suite.EqualError(err, service.ErrTokenUsed.Error())
+
+	expired := &models.EmailChangeToken{Token: "expired", UserId: user.ID, Email: "expired@example.com", ExpiresAt: time.Now().Add(-time.Second)}
+	suite.NoError(suite.db.Create(expired).Error)
+
+	_, err = authService.ConfirmEmailChange(context.Background(), &pb.Token{Token: expired.Token})
+	suite.EqualError(err, service.ErrTokenExpired.Error())
+}
+
+type sentNotification struct {
+	kind notify.Kind
+	data notify.Data
+}
+
+// fakeNotifier records the notifications sent, failing with err when it is set.
+type fakeNotifier struct {
+	sent []sentNotification
+	err  error
+}
+
+func (n *fakeNotifier) Notify(_ context.Context, kind notify.Kind, data notify.Data) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.sent = append(n.sent, sentNotification{kind: kind, data: data})
+	return nil
+}
+
+func (n *fakeNotifier) last() sentNotification {
+	if len(n.sent) == 0 {
+		return sentNotification{}
+	}
+	return n.sent[len(n.sent)-1]
+}
+
+func (suite *TestSuite) TestAuthService_Notifications() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	notifier := &fakeNotifier{}
+	authService := &service.AuthService{
+		UserRepo: &repos.UserRepository{DB: suite.db},
+		TokenRepo: &repos.TokenRepository{
+			DB: suite.db,
+			Config: &repos.TokenConfig{
+				BearerDuration:      3600 * time.Second,
+				ResetDuration:       3600 * time.Second,
+				VerifyDuration:      3600 * time.Second,
+				EmailChangeDuration: 3600 * time.Second,
+			},
+		},
+		Notifier: notifier,
+	}
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	ctx := withBearer(context.Background(), "token")
+	suite.NoError(suite.db.Create(&models.AccessToken{Token: "token", ID: uuid.New(), UserId: user.ID, ExpiresAt: time.Now().Add(time.Hour)}).Error)
+
+	// the verify token is emailed instead of returned
+	verifyResp, err := authService.VerifyUserToken(context.Background(), &pb.VerifyUserTokenRequest{Email: user.Email})
+	suite.NoError(err)
+	suite.Empty(verifyResp.Token)
+	suite.Equal(user.Email, verifyResp.Email)
+	suite.Equal(notify.KindVerify, notifier.last().kind)
+	suite.Equal(user.Email, notifier.last().data.Email)
+	suite.Equal(user.FirstName, notifier.last().data.FirstName)
+	suite.NotEmpty(notifier.last().data.Token)
+
+	_, err = authService.VerifyUser(context.Background(), &pb.Token{Token: notifier.last().data.Token})
+	suite.NoError(err)
+
+	// the reset token is emailed, and the password change is notified
+	resetResp, err := authService.ResetPasswordToken(context.Background(), &pb.ResetPasswordTokenRequest{Email: user.Email})
+	suite.NoError(err)
+	suite.Empty(resetResp.Token)
+	suite.Equal(notify.KindResetPassword, notifier.last().kind)
+
+	_, err = authService.ResetPassword(context.Background(), &pb.ResetPasswordRequest{Token: notifier.last().data.Token, Password: "new-password"})
+	suite.NoError(err)
+	suite.Equal(notify.KindPasswordChanged, notifier.last().kind)
+	suite.Equal(user.Email, notifier.last().data.Email)
+	suite.Empty(notifier.last().data.Token)
+
+	suite.NoError(suite.db.Create(&models.AccessToken{Token: "token", ID: uuid.New(), UserId: user.ID, ExpiresAt: time.Now().Add(time.Hour)}).Error)
+
+	// the email change token is emailed to the new email, and the previous email is notified of the request
+	sent := len(notifier.sent)
+	changeResp, err := authService.RequestEmailChange(ctx, &pb.EmailChangeRequest{Email: "new@example.com", CurrentPassword: "new-password"})
+	suite.NoError(err)
+	suite.Empty(changeResp.Token)
+	suite.Len(notifier.sent, sent+2)
+	changeToken := notifier.sent[sent]
+	suite.Equal(notify.KindEmailChange, changeToken.kind)
+	suite.Equal("new@example.com", changeToken.data.Email)
+	suite.Equal("new@example.com", changeToken.data.NewEmail)
+	suite.NotEmpty(changeToken.data.Token)
+	suite.Equal(notify.KindEmailChangeRequested, notifier.last().kind)
+	suite.Equal(user.Email, notifier.last().data.Email)
+	suite.Equal("new@example.com", notifier.last().data.NewEmail)
+	suite.Empty(notifier.last().data.Token)
+
+	// and the previous email is notified of the change once it is confirmed
+	_, err = authService.ConfirmEmailChange(context.Background(), &pb.Token{Token: changeToken.data.Token})
+	suite.NoError(err)
+	suite.Equal(notify.KindEmailChanged, notifier.last().kind)
+	suite.Equal(user.Email, notifier.last().data.Email)
+	suite.Equal("new@example.com", notifier.last().data.NewEmail)
+
+	// changing the password in UpdateUser is notified
+	sent = len(notifier.sent)
+	_, err = authService.UpdateUser(ctx, &pb.UpdateUserRequest{Password: "newer-password", CurrentPassword: "new-password"})
+	suite.NoError(err)
+	suite.Len(notifier.sent, sent+1)
+	suite.Equal(notify.KindPasswordChanged, notifier.last().kind)
+	suite.Equal("new@example.com", notifier.last().data.Email)
+
+	// a notification that fails after a change is made does not fail the change
+	notifier.err = errors.New("email service unavailable")
+	_, err = authService.UpdateUser(ctx, &pb.UpdateUserRequest{FirstName: "Johnny"})
+	suite.NoError(err)
+	_, err = authService.UpdateUser(ctx, &pb.UpdateUserRequest{Password: "newest-password", CurrentPassword: "newer-password"})
+	suite.NoError(err)
+
+	// a token that cannot be sent fails the request
+	_, err = authService.ResetPasswordToken(context.Background(), &pb.ResetPasswordTokenRequest{Email: "new@example.com"})
+	suite.EqualError(err, service.ErrNotificationFailed.Error())
+}
+
+func (suite *TestSuite) TestAuthService_PasswordlessLogin() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	authService := &service.AuthService{
+		UserRepo: &repos.UserRepository{DB: suite.db},
+		TokenRepo: &repos.TokenRepository{
+			DB: suite.db,
+			Config: &repos.TokenConfig{
+				BearerDuration: 3600 * time.Second,
+				LoginDuration:  900 * time.Second,
+			},
+		},
+		LoginCodeMaxAttempts: 2,
+	}
+
+	user, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	testCases := []struct {
+		desc          string
+		email         string
+		expectedError error
+	}{
+		{"invalid email", "invalid", service.ErrEmailInvalid},
+		{"unknown email", "unknown@example.com", service.ErrUserNotFound},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.desc, func() {
+			_, err := authService.RequestLoginLink(context.Background(), &pb.LoginTokenRequest{Email: tc.email})
+			suite.EqualError(err, tc.expectedError.Error())
+			_, err = authService.RequestLoginCode(context.Background(), &pb.LoginTokenRequest{Email: tc.email})
+			suite.EqualError(err, tc.expectedError.Error())
+		})
+	}
+
+	_, err = authService.RedeemLoginToken(context.Background(), &pb.RedeemLoginTokenRequest{})
+	suite.EqualError(err, service.ErrTokenRequired.Error())
+	_, err = authService.RedeemLoginToken(context.Background(), &pb.RedeemLoginTokenRequest{Token: "unknown"})
+	suite.EqualError(err, service.ErrTokenInvalid.Error())
+
+	// a login link logs the user in once, verifying their email
+	link, err := authService.RequestLoginLink(context.Background(), &pb.LoginTokenRequest{Email: " Test@Example.com "})
+	suite.NoError(err)
+	suite.NotEmpty(link.Token)
+	suite.Equal(user.Email, link.Email)
+	suite.Equal(user.FirstName, link.FirstName)
+
+	resp, err := authService.RedeemLoginToken(context.Background(), &pb.RedeemLoginTokenRequest{Token: link.Token})
+	suite.NoError(err)
+	suite.NotEmpty(resp.AccessToken)
+	suite.Equal("bearer", resp.TokenType)
+
+	fetchedUser, err := authService.User(context.Background(), &pb.Token{Token: resp.AccessToken})
+	suite.NoError(err)
+	suite.True(fetchedUser.IsVerified)
+
+	_, err = authService.RedeemLoginToken(context.Background(), &pb.RedeemLoginTokenRequest{Token: link.Token})
+	suite.EqualError(err, service.ErrTokenUsed.Error())
+
+	// a login code is redeemed along with its token
+	code, err := authService.RequestLoginCode(context.Background(), &pb.LoginTokenRequest{Email: user.Email})
+	suite.NoError(err)
+	suite.NotEmpty(code.Token)
+	suite.Len(code.Code, 6)
+
+	wrong := "000000"
+	if code.Code == wrong {
+		wrong = "000001"
+	}
+	_, err = authService.RedeemLoginToken(context.Background(), &pb.RedeemLoginTokenRequest{Token: code.Token, Code: wrong})
+	suite.EqualError(err, service.ErrLoginCodeInvalid.Error())
+
+	resp, err = authService.RedeemLoginToken(context.Background(), &pb.RedeemLoginTokenRequest{Token: code.Token, Code: code.Code})
+	suite.NoError(err)
+	suite.NotEmpty(resp.AccessToken)
+
+	// the code token is revoked after the maximum attempts
+	code, err = authService.RequestLoginCode(context.Background(), &pb.LoginTokenRequest{Email: user.Email})
+	suite.NoError(err)
+	for i := 0; i < 2; i++ {
+		_, err = authService.RedeemLoginToken(context.Background(), &pb.RedeemLoginTokenRequest{Token: code.Token})
+		suite.EqualError(err, service.ErrLoginCodeInvalid.Error())
+	}
+	_, err = authService.RedeemLoginToken(context.Background(), &pb.RedeemLoginTokenRequest{Token: code.Token, Code: code.Code})
+	suite.EqualError(err, service.ErrTokenInvalid.Error())
+
+	// with a notifier the link and code are emailed instead of returned
+	notifier := &fakeNotifier{}
+	authService.Notifier = notifier
+
+	link, err = authService.RequestLoginLink(context.Background(), &pb.LoginTokenRequest{Email: user.Email})
+	suite.NoError(err)
+	suite.Empty(link.Token)
+	suite.Equal(notify.KindLoginLink, notifier.last().kind)
+	suite.NotEmpty(notifier.last().data.Token)
+
+	code, err = authService.RequestLoginCode(context.Background(), &pb.LoginTokenRequest{Email: user.Email})
+	suite.NoError(err)
+	suite.NotEmpty(code.Token)
+	suite.Empty(code.Code)
+	suite.Equal(notify.KindLoginCode, notifier.last().kind)
+	suite.Empty(notifier.last().data.Token)
+
+	resp, err = authService.RedeemLoginToken(context.Background(), &pb.RedeemLoginTokenRequest{Token: code.Token, Code: notifier.last().data.Code})
+	suite.NoError(err)
+	suite.NotEmpty(resp.AccessToken)
+
+	// inactive users cannot log in
+	_, err = authService.RequestLoginLink(context.Background(), &pb.LoginTokenRequest{Email: user.Email})
+	suite.NoError(err)
+	suite.NoError(suite.db.Model(user).UpdateColumn("is_active", false).Error)
+	_, err = authService.RedeemLoginToken(context.Background(), &pb.RedeemLoginTokenRequest{Token: notifier.last().data.Token})
+	suite.EqualError(err, service.ErrUserInactive.Error())
+	_, err = authService.RequestLoginLink(context.Background(), &pb.LoginTokenRequest{Email: user.Email})
+	suite.EqualError(err, service.ErrUserInactive.Error())
+}
+
+func (suite *TestSuite) TestAuthService_OIDC() {
+	teardown := suite.Setup()
+	defer teardown()
+
+	_, err := suite.helpers.CreateTestUserType()
+	suite.NoError(err)
+
+	server := oidctest.NewServer("client", "secret")
+	defer server.Close()
+
+	authService := &service.AuthService{
+		UserRepo: &repos.UserRepository{DB: suite.db},
+		TokenRepo: &repos.TokenRepository{
+			DB:     suite.db,
+			Config: &repos.TokenConfig{BearerDuration: 3600 * time.Second},
+		},
+	}
+
+	_, err = authService.BeginOIDCLogin(context.Background(), &pb.OIDCProviderRequest{Provider: "stub"})
+	suite.EqualError(err, service.ErrOIDCNotEnabled.Error())
+
+	authService.IdentityRepo = &repos.IdentityRepository{DB: suite.db}
+	authService.OIDCProviders = oidc.Providers{
+		"stub": oidc.NewProvider("stub", server.Config("https://app.example.com/callback")),
+	}
+
+	_, err = authService.BeginOIDCLogin(context.Background(), &pb.OIDCProviderRequest{Provider: "unknown"})
+	suite.EqualError(err, service.ErrOIDCProviderInvalid.Error())
+
+	// login returns the code of the provider to exchange
+	login := func(claims oidctest.Claims) (*pb.OIDCCodeRequest, error) {
+		auth, err := authService.BeginOIDCLogin(context.Background(), &pb.OIDCProviderRequest{Provider: "stub"})
+		if err != nil {
+			return nil, err
+		}
+		code, err := server.Authorize(auth.AuthorizationUrl, claims)
+		if err != nil {
+			return nil, err
+		}
+		return &pb.OIDCCodeRequest{Provider: "stub", Code: code, CodeVerifier: auth.CodeVerifier, Nonce: auth.Nonce}, nil
+	}
+
+	// a user without a password is created for an identity that is not linked
+	claims := oidctest.Claims{Subject: "new", Email: "New@Example.com", EmailVerified: true, Name: "Jane Mary Doe"}
+	req, err := login(claims)
+	suite.NoError(err)
+	resp, err := authService.OIDCLogin(context.Background(), req)
+	suite.NoError(err)
+	suite.NotEmpty(resp.AccessToken)
+
+	user, err := authService.User(context.Background(), &pb.Token{Token: resp.AccessToken})
+	suite.NoError(err)
+	suite.Equal("new@example.com", user.Email)
+	suite.Equal("Jane Mary", user.FirstName)
+	suite.Equal("Doe", user.LastName)
+	suite.True(user.IsVerified)
+
+	// codes are single use
+	_, err = authService.OIDCLogin(context.Background(), req)
+	suite.EqualError(err, service.ErrOIDCCodeInvalid.Error())
+
+	// a user without a password has to set one with a password reset before changing the email or password
+	_, err = authService.RequestEmailChange(context.Background(), &pb.EmailChangeRequest{Token: resp.AccessToken, Email: "changed@example.com"})
+	suite.EqualError(err, service.ErrPasswordNotSet.Error())
+	_, err = authService.UpdateUser(context.Background(), &pb.UpdateUserRequest{Token: resp.AccessToken, Password: "new-password"})
+	suite.EqualError(err, service.ErrPasswordNotSet.Error())
+
+	// logging in again logs the linked user in
+	req, err = login(claims)
+	suite.NoError(err)
+	resp, err = authService.OIDCLogin(context.Background(), req)
+	suite.NoError(err)
+	fetchedUser, err := authService.User(context.Background(), &pb.Token{Token: resp.AccessToken})
+	suite.NoError(err)
+	suite.Equal(user.Id, fetchedUser.Id)
+
+	// the nonce and code verifier of the request have to match
+	req, err = login(claims)
+	suite.NoError(err)
+	_, err = authService.OIDCLogin(context.Background(), &pb.OIDCCodeRequest{Provider: "stub", Code: req.Code, CodeVerifier: req.CodeVerifier})
+	suite.EqualError(err, service.ErrOIDCCodeInvalid.Error())
+	_, err = authService.OIDCLogin(context.Background(), &pb.OIDCCodeRequest{Provider: "stub"})
+	suite.EqualError(err, service.ErrOIDCCodeInvalid.Error())
+
+	// an email is required to create a user
+	req, err = login(oidctest.Claims{Subject: "no-email"})
+	suite.NoError(err)
+	_, err = authService.OIDCLogin(context.Background(), req)
+	suite.EqualError(err, service.ErrOIDCEmailRequired.Error())
+
+	// an existing user has to log in to link the identity
+	existing, err := suite.helpers.CreateTestUser()
+	suite.NoError(err)
+
+	existingClaims := oidctest.Claims{Subject: "existing", Email: existing.Email, EmailVerified: true}
+	req, err = login(existingClaims)
+	suite.NoError(err)
+	_, err = authService.OIDCLogin(context.Background(), req)
+	suite.EqualError(err, service.ErrIdentityNotLinked.Error())
+
+	bearer, err := authService.BearerToken(context.Background(), &pb.BearerTokenRequest{Email: existing.Email, Password: "password"})
+	suite.NoError(err)
+
+	req, err = login(existingClaims)
+	suite.NoError(err)
+	_, err = authService.LinkIdentity(context.Background(), req)
+	suite.EqualError(err, service.ErrTokenRequired.Error())
+
+	req.Token = bearer.AccessToken
+	identity, err := authService.LinkIdentity(context.Background(), req)
+	suite.NoError(err)
+	suite.Equal("stub", identity.Provider)
+	suite.Equal("existing", identity.Subject)
+	suite.Equal(existing.Email, identity.Email)
+
+	// an identity is linked to one user
+	req, err = login(claims)
+	suite.NoError(err)
+	req.Token = bearer.AccessToken
+	_, err = authService.LinkIdentity(context.Background(), req)
+	suite.EqualError(err, service.ErrIdentityAlreadyLinked.Error())
+
+	req, err = login(existingClaims)
+	suite.NoError(err)
+	resp, err = authService.OIDCLogin(context.Background(), req)
+	suite.NoError(err)
+	fetchedUser, err = authService.User(context.Background(), &pb.Token{Token: resp.AccessToken})
+	suite.NoError(err)
+	suite.Equal(existing.ID.String(), fetchedUser.Id)
+
+	identities, err := authService.ListIdentities(context.Background(), &pb.Token{Token: bearer.AccessToken})
+	suite.NoError(err)
+	suite.Len(identities.Identities, 1)
+	suite.Equal("existing", identities.Identities[0].Subject)
+
+	_, err = authService.UnlinkIdentity(context.Background(), &pb.UnlinkIdentityRequest{Token: bearer.AccessToken, Provider: "stub"})
+	suite.NoError(err)
+	_, err = authService.UnlinkIdentity(context.Background(), &pb.UnlinkIdentityRequest{Token: bearer.AccessToken, Provider: "stub"})
+	suite.EqualError(err, service.ErrIdentityNotFound.Error())
+
+	identities, err = authService.ListIdentities(context.Background(), &pb.Token{Token: bearer.AccessToken})
+	suite.NoError(err)
+	suite.Empty(identities.Identities)
+
+	// inactive users cannot log in
+	suite.NoError(suite.db.Model(&models.User{}).Where("email = ?", user.Email).UpdateColumn("is_active", false).Error)
+	req, err = login(claims)
+	suite.NoError(err)
+	_, err = authService.OIDCLogin(context.Background(), req)
+	suite.EqualError(err, service.ErrUserInactive.Error())
+
+	// an unreachable provider is reported as unavailable
+	server.Close()
+	_, err = authService.OIDCLogin(context.Background(), &pb.OIDCCodeRequest{Provider: "stub", Code: "code"})
+	suite.EqualError(err, service.ErrOIDCProviderUnavailable.Error())
+}
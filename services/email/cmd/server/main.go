@@ -2,14 +2,19 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/accentdesign/grpc/core/healthcheck"
+	"github.com/accentdesign/grpc/services/email/internal/queue"
+	"github.com/accentdesign/grpc/services/email/internal/templates"
+	"github.com/accentdesign/grpc/services/email/internal/transport"
 	emailpb "github.com/accentdesign/grpc/services/email/pkg/api/email"
 	"github.com/accentdesign/grpc/services/email/service"
 	"google.golang.org/grpc"
@@ -21,11 +26,31 @@ var (
 	helpFlag         = flag.Bool("help", false, "Display help information")
 	enableReflection = flag.Bool("reflection", false, "Enable reflection")
 	port             = flag.Int("port", 50051, "The server port")
+	transportName    = flag.String("transport", "smtp", "How emails are sent: smtp, sendgrid, mailgun, ses, or file and stdout for development")
+	transportDir     = flag.String("transport-dir", "mail", "Maildir the file transport writes emails to")
+	templatesDir     = flag.String("templates", "", "Directory of email templates, with shared templates in its layouts and partials directories")
+	poolSize         = flag.Int("smtp-pool-size", 4, "Number of SMTP connections open at once, 0 is no limit")
+	poolIdleTimeout  = flag.Duration("smtp-idle-timeout", time.Minute, "How long an unused SMTP connection is kept open")
+	poolMaxMessages  = flag.Int("smtp-max-messages", 100, "Number of emails sent over an SMTP connection before it is closed, 0 is no limit")
+	queueDir         = flag.String("queue-dir", "", "Directory of the email queue, enables queued sending when set")
+	queueWorkers     = flag.Int("queue-workers", 4, "Number of emails the queue delivers at once")
+	queueAttempts    = flag.Int("queue-max-attempts", 8, "Number of delivery attempts after which a queued email fails")
+	queueBackoff     = flag.Duration("queue-backoff", 30*time.Second, "Delay before retrying a queued email, doubling with every attempt")
+	queueMaxBackoff  = flag.Duration("queue-max-backoff", time.Hour, "Maximum delay before retrying a queued email")
+	queueRetention   = flag.Duration("queue-retention", 7*24*time.Hour, "How long sent and failed emails are kept, 0 keeps them forever")
 	smtpHost         = os.Getenv("SMTP_HOST")
 	smtpPort         = os.Getenv("SMTP_PORT")
 	smtpUsername     = os.Getenv("SMTP_USERNAME")
 	smtpPassword     = os.Getenv("SMTP_PASSWORD")
 	smtpStartTLS     = os.Getenv("SMTP_STARTTLS")
+	sendGridAPIKey   = os.Getenv("SENDGRID_API_KEY")
+	mailgunDomain    = os.Getenv("MAILGUN_DOMAIN")
+	mailgunAPIKey    = os.Getenv("MAILGUN_API_KEY")
+	mailgunRegion    = os.Getenv("MAILGUN_REGION")
+	awsRegion        = os.Getenv("AWS_REGION")
+	awsAccessKeyID   = os.Getenv("AWS_ACCESS_KEY_ID")
+	awsSecretKey     = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	awsSessionToken  = os.Getenv("AWS_SESSION_TOKEN")
 )
 
 func displayHelp() {
@@ -36,6 +61,88 @@ func displayHelp() {
 	fmt.Println("  SMTP_USERNAME - SMTP server username (e.g. apikey)")
 	fmt.Println("  SMTP_PASSWORD - SMTP server password (e.g. my-sendgrid-key)")
 	fmt.Println("  SMTP_STARTTLS - SMTP server start TLS (e.g. t,1,true or f,0,false)")
+	fmt.Println("  SENDGRID_API_KEY - SendGrid api key, for the sendgrid transport")
+	fmt.Println("  MAILGUN_DOMAIN - Mailgun sending domain (e.g. mg.example.com), for the mailgun transport")
+	fmt.Println("  MAILGUN_API_KEY - Mailgun api key, for the mailgun transport")
+	fmt.Println("  MAILGUN_REGION - Mailgun region of the domain, us (default) or eu")
+	fmt.Println("  AWS_REGION - AWS region of SES (e.g. eu-west-1), for the ses transport")
+	fmt.Println("  AWS_ACCESS_KEY_ID - AWS access key id, for the ses transport")
+	fmt.Println("  AWS_SECRET_ACCESS_KEY - AWS secret access key, for the ses transport")
+	fmt.Println("  AWS_SESSION_TOKEN - AWS session token of temporary credentials, for the ses transport")
+}
+
+// newTransport returns the transport emails are sent with, selected by the transport flag.
+func newTransport() (transport.Transport, error) {
+	switch *transportName {
+	case "smtp":
+		// ensure env vars convert to their proper types
+		sTLS := false
+		if smtpStartTLS != "" {
+			var err error
+			sTLS, err = strconv.ParseBool(smtpStartTLS)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for SMTP_STARTTLS: %v", err)
+			}
+		}
+		sPort, err := strconv.ParseInt(smtpPort, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for SMTP_PORT: %v", err)
+		}
+
+		smtp := transport.NewSMTP(transport.SMTPConfig{
+			Host:     smtpHost,
+			Port:     sPort,
+			Username: smtpUsername,
+			Password: smtpPassword,
+			StartTLS: sTLS,
+		})
+		// emails are sent over a pool of SMTP connections
+		smtp.Pool.MaxOpen = *poolSize
+		smtp.Pool.IdleTimeout = *poolIdleTimeout
+		smtp.Pool.MaxMessages = *poolMaxMessages
+
+		log.Print("checking email server settings..")
+		if err := smtp.Check(); err != nil {
+			return nil, fmt.Errorf("error setting up SMTP connection: %v", err)
+		}
+		return smtp, nil
+	case "sendgrid":
+		if sendGridAPIKey == "" {
+			return nil, errors.New("SENDGRID_API_KEY is required")
+		}
+		return transport.NewSendGrid(sendGridAPIKey), nil
+	case "mailgun":
+		if mailgunDomain == "" || mailgunAPIKey == "" {
+			return nil, errors.New("MAILGUN_DOMAIN and MAILGUN_API_KEY are required")
+		}
+		mailgun := transport.NewMailgun(mailgunDomain, mailgunAPIKey)
+		switch mailgunRegion {
+		case "", "us":
+		case "eu":
+			mailgun.BaseURL = transport.MailgunEUURL
+		default:
+			return nil, fmt.Errorf("invalid value for MAILGUN_REGION: %q", mailgunRegion)
+		}
+		return mailgun, nil
+	case "ses":
+		if awsRegion == "" || awsAccessKeyID == "" || awsSecretKey == "" {
+			return nil, errors.New("AWS_REGION, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required")
+		}
+		return transport.NewSES(transport.SESConfig{
+			Region:          awsRegion,
+			AccessKeyID:     awsAccessKeyID,
+			SecretAccessKey: awsSecretKey,
+			SessionToken:    awsSessionToken,
+		}), nil
+	case "file":
+		log.Printf("writing emails to %s", *transportDir)
+		return transport.NewFile(*transportDir)
+	case "stdout":
+		log.Print("writing emails to stdout")
+		return transport.NewLog(os.Stdout), nil
+	default:
+		return nil, fmt.Errorf("unknown transport %q", *transportName)
+	}
 }
 
 func main() {
@@ -62,25 +169,38 @@ func main() {
 		grpc.UnaryInterceptor(errHandler),
 	)
 
-	// ensure env vars convert to their proper types
-	sTLS := false
-	if smtpStartTLS != "" {
-		var err error
-		sTLS, err = strconv.ParseBool(smtpStartTLS)
+	// define the service
+	emailTransport, err := newTransport()
+	if err != nil {
+		log.Fatalf("failed to initialize email service: %v", err)
+	}
+	emailService := service.NewEmailServerWithTransport(emailTransport)
+
+	// load the templates emails can be rendered from, more can be registered at runtime
+	if *templatesDir != "" {
+		store, err := templates.Load(*templatesDir)
 		if err != nil {
-			log.Fatalf("Invalid value for SMTP_STARTTLS: %v", err.Error())
+			log.Fatalf("error loading templates: %v", err)
 		}
-	}
-	sPort, err := strconv.ParseInt(smtpPort, 10, 64)
-	if err != nil {
-		log.Fatalf("Invalid value for SMTP_PORT: %v", err.Error())
+		emailService.SetTemplates(store)
+		log.Printf("loaded templates from %s", *templatesDir)
 	}
 
-	// define the service
-	log.Print("checking email server settings..")
-	emailService, err := service.NewEmailServer(smtpHost, sPort, smtpUsername, smtpPassword, sTLS)
-	if err != nil {
-		log.Fatalf("failed to initialize email service: %v", err)
+	// queue emails to deliver them in the background, with retries
+	if *queueDir != "" {
+		store, err := queue.Open(*queueDir)
+		if err != nil {
+			log.Fatalf("error opening the email queue: %v", err)
+		}
+		q := queue.New(store, emailService.Deliver)
+		q.Workers = *queueWorkers
+		q.MaxAttempts = *queueAttempts
+		q.Backoff = *queueBackoff
+		q.MaxBackoff = *queueMaxBackoff
+		q.Retention = *queueRetention
+		emailService.SetQueue(q)
+		go q.Run(context.Background())
+		log.Printf("queueing emails in %s", *queueDir)
 	}
 
 	// register the email service
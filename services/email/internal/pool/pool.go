@@ -0,0 +1,216 @@
+// Package pool keeps a bounded pool of smtp connections, so emails are sent over connections that are already
+// established and authenticated instead of dialing the smtp server for every email.
+//
+// A connection is checked with a NOOP before it is reused and reset with a RSET when it is returned, connections
+// that fail either are closed and replaced by a new one.
+package pool
+
+import (
+	"context"
+	"errors"
+	"net/smtp"
+	"net/textproto"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxOpen     = 4
+	defaultIdleTimeout = time.Minute
+	defaultMaxMessages = 100
+)
+
+var ErrClosed = errors.New("smtp connection pool is closed")
+
+// DialFunc opens a new connection, ready to send emails.
+type DialFunc func() (*smtp.Client, error)
+
+// Conn is a connection of the pool, it must be returned with Put.
+type Conn struct {
+	*smtp.Client
+	messages int
+	idleAt   time.Time
+}
+
+// Pool is a pool of smtp connections, it is safe for concurrent use. The exported fields can be changed before the
+// pool is used.
+type Pool struct {
+	Dial DialFunc
+	// MaxOpen is the number of connections open at once, Get waits for a connection once it is reached. 0 is no limit.
+	MaxOpen int
+	// IdleTimeout is how long a connection is kept unused before it is closed, 0 keeps it until the server closes it.
+	IdleTimeout time.Duration
+	// MaxMessages is the number of emails sent over a connection before it is closed, 0 is no limit.
+	MaxMessages int
+
+	mu       sync.Mutex
+	open     int
+	idle     []*Conn
+	released chan struct{}
+	closed   bool
+}
+
+// New returns a pool opening connections with dial, with the default settings.
+func New(dial DialFunc) *Pool {
+	return &Pool{
+		Dial:        dial,
+		MaxOpen:     defaultMaxOpen,
+		IdleTimeout: defaultIdleTimeout,
+		MaxMessages: defaultMaxMessages,
+		released:    make(chan struct{}),
+	}
+}
+
+// Get returns an idle connection that answers a NOOP, or opens a new one. It waits for a connection to be returned
+// when MaxOpen connections are in use, until the context is done.
+func (p *Pool) Get(ctx context.Context) (*Conn, error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, ErrClosed
+		}
+		expired := p.expired(time.Now())
+
+		if n := len(p.idle); n > 0 {
+			c := p.idle[n-1]
+			p.idle = p.idle[:n-1]
+			p.mu.Unlock()
+			closeAll(expired)
+
+			// the server may have closed the connection while it was idle
+			if err := c.Noop(); err != nil {
+				c.Close()
+				p.release()
+				continue
+			}
+			return c, nil
+		}
+
+		if p.MaxOpen <= 0 || p.open < p.MaxOpen {
+			p.open++
+			p.mu.Unlock()
+			closeAll(expired)
+
+			client, err := p.Dial()
+			if err != nil {
+				p.release()
+				return nil, err
+			}
+			return &Conn{Client: client}, nil
+		}
+
+		released := p.released
+		p.mu.Unlock()
+		closeAll(expired)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-released:
+		}
+	}
+}
+
+// Put returns a connection after sending an email, err being the error it failed with. The connection is closed
+// when the error is not a reply of the server, when it has sent MaxMessages emails or when the pool is closed.
+func (p *Pool) Put(c *Conn, err error) {
+	var reply *textproto.Error
+	if err != nil && !errors.As(err, &reply) {
+		c.Close()
+		p.release()
+		return
+	}
+
+	if err == nil {
+		c.messages++
+	}
+	if p.MaxMessages > 0 && c.messages >= p.MaxMessages {
+		quit(c)
+		p.release()
+		return
+	}
+
+	// the server is ready for the next email once the transaction is reset
+	if resetErr := c.Reset(); resetErr != nil {
+		c.Close()
+		p.release()
+		return
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		quit(c)
+		p.release()
+		return
+	}
+	c.idleAt = time.Now()
+	p.idle = append(p.idle, c)
+	p.signal()
+	p.mu.Unlock()
+}
+
+// Close closes the idle connections, the connections in use are closed when they are returned.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.open -= len(idle)
+	p.closed = true
+	p.signal()
+	p.mu.Unlock()
+
+	closeAll(idle)
+}
+
+// expired removes the connections idle for longer than IdleTimeout, which the caller closes once it unlocked the
+// pool. It must be called with the pool locked.
+func (p *Pool) expired(now time.Time) []*Conn {
+	if p.IdleTimeout <= 0 {
+		return nil
+	}
+
+	var expired []*Conn
+	kept := p.idle[:0]
+	for _, c := range p.idle {
+		if now.Sub(c.idleAt) >= p.IdleTimeout {
+			expired = append(expired, c)
+		} else {
+			kept = append(kept, c)
+		}
+	}
+	p.idle = kept
+	p.open -= len(expired)
+	if len(expired) > 0 {
+		p.signal()
+	}
+	return expired
+}
+
+// release frees the slot of a connection that was closed.
+func (p *Pool) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.open--
+	p.signal()
+}
+
+// signal wakes the calls of Get waiting for a connection. It must be called with the pool locked.
+func (p *Pool) signal() {
+	close(p.released)
+	p.released = make(chan struct{})
+}
+
+// quit ends the session of a connection, closing it when the server does not answer, as it may have closed it.
+func quit(c *Conn) {
+	if err := c.Quit(); err != nil {
+		c.Close()
+	}
+}
+
+func closeAll(conns []*Conn) {
+	for _, c := range conns {
+		quit(c)
+	}
+}
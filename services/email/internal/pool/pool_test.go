@@ -0,0 +1,186 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/smtp"
+	"net/textproto"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	smtpmock "github.com/mocktools/go-smtp-mock/v2"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/accentdesign/grpc/services/email/internal/pool"
+)
+
+type TestSuite struct {
+	suite.Suite
+	server *smtpmock.Server
+	dials  atomic.Int32
+	pool   *pool.Pool
+}
+
+func TestTestSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+func (suite *TestSuite) SetupTest() {
+	suite.server = smtpmock.New(smtpmock.ConfigurationAttr{MultipleMessageReceiving: true})
+	suite.Require().NoError(suite.server.Start())
+
+	suite.dials.Store(0)
+	suite.pool = pool.New(func() (*smtp.Client, error) {
+		suite.dials.Add(1)
+		return smtp.Dial(fmt.Sprintf("127.0.0.1:%d", suite.server.PortNumber()))
+	})
+}
+
+func (suite *TestSuite) TearDownTest() {
+	suite.pool.Close()
+	suite.NoError(suite.server.Stop())
+}
+
+func (suite *TestSuite) send(c *pool.Conn) error {
+	if err := c.Mail("from@example.com"); err != nil {
+		return err
+	}
+	if err := c.Rcpt("to@example.com"); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte("Subject: Test\r\n\r\nThis is a test email\r\n")); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func (suite *TestSuite) sendMessages(count int) {
+	for i := 0; i < count; i++ {
+		c, err := suite.pool.Get(context.Background())
+		suite.Require().NoError(err)
+		err = suite.send(c)
+		suite.NoError(err)
+		suite.pool.Put(c, err)
+	}
+}
+
+func (suite *TestSuite) TestReuse() {
+	suite.sendMessages(3)
+	suite.Equal(int32(1), suite.dials.Load())
+
+	// the session ends when the pool is closed
+	suite.pool.Close()
+	messages, err := suite.server.WaitForMessages(3, time.Second)
+	suite.NoError(err)
+	suite.Len(messages, 3)
+	for _, message := range messages {
+		suite.Contains(message.MsgRequest(), "This is a test email")
+	}
+
+	_, err = suite.pool.Get(context.Background())
+	suite.ErrorIs(err, pool.ErrClosed)
+}
+
+func (suite *TestSuite) TestMaxMessages() {
+	suite.pool.MaxMessages = 2
+
+	suite.sendMessages(5)
+	suite.Equal(int32(3), suite.dials.Load())
+}
+
+func (suite *TestSuite) TestIdleTimeout() {
+	suite.pool.IdleTimeout = 10 * time.Millisecond
+
+	suite.sendMessages(1)
+	time.Sleep(20 * time.Millisecond)
+	suite.sendMessages(1)
+	suite.Equal(int32(2), suite.dials.Load())
+}
+
+func (suite *TestSuite) TestMaxOpen() {
+	suite.pool.MaxOpen = 1
+
+	c, err := suite.pool.Get(context.Background())
+	suite.Require().NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = suite.pool.Get(ctx)
+	suite.ErrorIs(err, context.DeadlineExceeded)
+
+	// a waiting call gets the connection once it is returned
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		suite.pool.Put(c, nil)
+	}()
+	reused, err := suite.pool.Get(context.Background())
+	suite.Require().NoError(err)
+	suite.Same(c, reused)
+	suite.pool.Put(reused, nil)
+
+	suite.Equal(int32(1), suite.dials.Load())
+}
+
+func (suite *TestSuite) TestConcurrent() {
+	suite.pool.MaxOpen = 2
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			suite.sendMessages(1)
+		}()
+	}
+	wg.Wait()
+	suite.LessOrEqual(suite.dials.Load(), int32(2))
+
+	suite.pool.Close()
+	messages, err := suite.server.WaitForMessages(10, time.Second)
+	suite.NoError(err)
+	suite.Len(messages, 10)
+}
+
+func (suite *TestSuite) TestFailedConnection() {
+	// replies of the server leave the connection usable
+	c, err := suite.pool.Get(context.Background())
+	suite.Require().NoError(err)
+	suite.pool.Put(c, &textproto.Error{Code: 550, Msg: "Mailbox unavailable"})
+	suite.sendMessages(1)
+	suite.Equal(int32(1), suite.dials.Load())
+
+	// other errors mean the connection failed
+	c, err = suite.pool.Get(context.Background())
+	suite.Require().NoError(err)
+	suite.pool.Put(c, errors.New("broken pipe"))
+	suite.sendMessages(1)
+	suite.Equal(int32(2), suite.dials.Load())
+
+	// idle connections the server closed fail the NOOP check and are replaced
+	c, err = suite.pool.Get(context.Background())
+	suite.Require().NoError(err)
+	suite.pool.Put(c, nil)
+	suite.NoError(c.Close())
+	suite.sendMessages(1)
+	suite.Equal(int32(3), suite.dials.Load())
+}
+
+func (suite *TestSuite) TestDialError() {
+	suite.pool.MaxOpen = 1
+	suite.pool.Dial = func() (*smtp.Client, error) {
+		return nil, errors.New("connection refused")
+	}
+
+	// failed dials do not hold a connection of the pool
+	for i := 0; i < 2; i++ {
+		_, err := suite.pool.Get(context.Background())
+		suite.EqualError(err, "connection refused")
+	}
+}
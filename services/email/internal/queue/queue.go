@@ -0,0 +1,241 @@
+// Package queue delivers emails in the background from a persistent queue, retrying failed deliveries with
+// exponential backoff until they are sent, fail permanently or run out of attempts.
+//
+// Messages are delivered at least once, a message being delivered when the server stops is delivered again
+// when it restarts.
+package queue
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWorkers      = 4
+	defaultMaxAttempts  = 8
+	defaultBackoff      = 30 * time.Second
+	defaultMaxBackoff   = time.Hour
+	defaultPollInterval = time.Second
+	purgeInterval       = time.Hour
+)
+
+// DeliverFunc delivers the payload of a message, returning the result of each recipient. Errors that retrying
+// cannot fix should be wrapped with Permanent.
+type DeliverFunc func(payload []byte) ([]Recipient, error)
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks err as permanent, a message failing with it is not retried.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked permanent.
+func IsPermanent(err error) bool {
+	var pe *permanentError
+	return errors.As(err, &pe)
+}
+
+// Queue delivers the messages of a store with a pool of workers. The exported fields can be changed before Run.
+type Queue struct {
+	Store   *Store
+	Deliver DeliverFunc
+	// Workers is the number of messages delivered at once.
+	Workers int
+	// MaxAttempts is the number of attempts after which a message fails.
+	MaxAttempts int
+	// Backoff is the delay before the first retry, doubling with every further attempt up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Retention is how long sent and failed messages are kept, 0 keeps them forever.
+	Retention    time.Duration
+	PollInterval time.Duration
+
+	wake     chan struct{}
+	mu       sync.Mutex
+	inflight map[string]bool
+}
+
+// New returns a queue delivering the messages of the store with deliver, with the default settings.
+func New(store *Store, deliver DeliverFunc) *Queue {
+	return &Queue{
+		Store:        store,
+		Deliver:      deliver,
+		Workers:      defaultWorkers,
+		MaxAttempts:  defaultMaxAttempts,
+		Backoff:      defaultBackoff,
+		MaxBackoff:   defaultMaxBackoff,
+		PollInterval: defaultPollInterval,
+		wake:         make(chan struct{}, 1),
+		inflight:     make(map[string]bool),
+	}
+}
+
+// Enqueue stores a message with the payload, to be delivered as soon as a worker is free.
+func (q *Queue) Enqueue(payload []byte) (*Message, error) {
+	m, err := q.Store.Create(payload)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return m, nil
+}
+
+// Get returns the message with the id, or ErrNotFound.
+func (q *Queue) Get(id string) (*Message, error) {
+	return q.Store.Get(id)
+}
+
+// Run delivers due messages until the context is done, then waits for the deliveries in progress.
+func (q *Queue) Run(ctx context.Context) {
+	jobs := make(chan *Message)
+	var wg sync.WaitGroup
+	for i := 0; i < q.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for m := range jobs {
+				q.process(m)
+			}
+		}()
+	}
+	defer func() {
+		close(jobs)
+		wg.Wait()
+	}()
+
+	ticker := time.NewTicker(q.PollInterval)
+	defer ticker.Stop()
+
+	var lastPurge time.Time
+	for {
+		if q.Retention > 0 && time.Since(lastPurge) >= purgeInterval {
+			lastPurge = time.Now()
+			if deleted, err := q.Store.Purge(lastPurge.Add(-q.Retention)); err != nil {
+				log.Printf("error purging sent and failed emails: %v", err)
+			} else if deleted > 0 {
+				log.Printf("purged %d sent and failed emails", deleted)
+			}
+		}
+
+		if !q.dispatch(ctx, jobs) {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		}
+	}
+}
+
+// dispatch hands the due messages that are not being delivered to the workers, it returns false when the context
+// is done.
+func (q *Queue) dispatch(ctx context.Context, jobs chan<- *Message) bool {
+	due, err := q.Store.Due(time.Now())
+	if err != nil {
+		log.Printf("error reading the email queue: %v", err)
+		return true
+	}
+
+	for _, m := range due {
+		q.mu.Lock()
+		if q.inflight[m.ID] {
+			q.mu.Unlock()
+			continue
+		}
+		q.inflight[m.ID] = true
+		q.mu.Unlock()
+
+		select {
+		case jobs <- m:
+		case <-ctx.Done():
+			q.done(m.ID)
+			return false
+		}
+	}
+	return true
+}
+
+func (q *Queue) done(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.inflight, id)
+}
+
+// process delivers the message and records the result.
+func (q *Queue) process(m *Message) {
+	defer q.done(m.ID)
+
+	// the message was read before an earlier attempt finished, when it was dispatched from an older scan
+	current, err := q.Store.Get(m.ID)
+	if err != nil {
+		log.Printf("error reading email %s: %v", m.ID, err)
+		return
+	}
+	if current.Status != StatusQueued || current.NextAttemptAt.After(time.Now()) {
+		return
+	}
+	m = current
+
+	payload, err := q.Store.Payload(m.ID)
+	var recipients []Recipient
+	if err != nil {
+		err = Permanent(err)
+	} else {
+		recipients, err = q.Deliver(payload)
+	}
+
+	now := time.Now()
+	m.Attempts++
+	m.Recipients = recipients
+	m.UpdatedAt = now
+	switch {
+	case err == nil:
+		m.Status = StatusSent
+		m.SentAt = now
+		m.LastError = ""
+	case IsPermanent(err) || m.Attempts >= q.MaxAttempts:
+		m.Status = StatusFailed
+		m.LastError = err.Error()
+		log.Printf("email %s failed after %d attempts: %v", m.ID, m.Attempts, err)
+	default:
+		m.NextAttemptAt = now.Add(q.backoff(m.Attempts))
+		m.LastError = err.Error()
+		log.Printf("email %s attempt %d failed, retrying at %s: %v", m.ID, m.Attempts, m.NextAttemptAt.Format(time.RFC3339), err)
+	}
+
+	if err := q.Store.Save(m); err != nil {
+		log.Printf("error saving email %s: %v", m.ID, err)
+	}
+}
+
+// backoff returns the delay before the next attempt, after the number of attempts made.
+func (q *Queue) backoff(attempts int) time.Duration {
+	delay := q.Backoff
+	for i := 1; i < attempts && delay < q.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > q.MaxBackoff {
+		delay = q.MaxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,256 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/accentdesign/grpc/services/email/internal/queue"
+)
+
+type TestSuite struct {
+	suite.Suite
+	store *queue.Store
+}
+
+func TestTestSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+func (suite *TestSuite) SetupTest() {
+	store, err := queue.Open(suite.T().TempDir())
+	suite.Require().NoError(err)
+	suite.store = store
+}
+
+// run runs a queue with short delays until the context is done.
+func (suite *TestSuite) run(deliver queue.DeliverFunc) (*queue.Queue, func()) {
+	q := queue.New(suite.store, deliver)
+	q.MaxAttempts = 3
+	q.Backoff = 10 * time.Millisecond
+	q.MaxBackoff = 20 * time.Millisecond
+	q.PollInterval = 5 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		q.Run(ctx)
+		close(done)
+	}()
+	return q, func() {
+		cancel()
+		<-done
+	}
+}
+
+func (suite *TestSuite) waitFor(q *queue.Queue, id string, status queue.Status) *queue.Message {
+	var m *queue.Message
+	suite.Eventually(func() bool {
+		var err error
+		m, err = q.Get(id)
+		return err == nil && m.Status == status
+	}, 2*time.Second, 5*time.Millisecond)
+	return m
+}
+
+func (suite *TestSuite) TestStore() {
+	m, err := suite.store.Create([]byte("payload"))
+	suite.NoError(err)
+	suite.Equal(queue.StatusQueued, m.Status)
+
+	fetched, err := suite.store.Get(m.ID)
+	suite.NoError(err)
+	suite.Equal(m.ID, fetched.ID)
+
+	payload, err := suite.store.Payload(m.ID)
+	suite.NoError(err)
+	suite.Equal("payload", string(payload))
+
+	due, err := suite.store.Due(time.Now())
+	suite.NoError(err)
+	suite.Len(due, 1)
+
+	// retries are not due until their next attempt
+	m.Attempts = 1
+	m.NextAttemptAt = time.Now().Add(time.Minute)
+	suite.NoError(suite.store.Save(m))
+
+	due, err = suite.store.Due(time.Now())
+	suite.NoError(err)
+	suite.Empty(due)
+
+	due, err = suite.store.Due(time.Now().Add(2 * time.Minute))
+	suite.NoError(err)
+	suite.Len(due, 1)
+
+	// sent messages leave the queue and lose their payload
+	m.Status = queue.StatusSent
+	suite.NoError(suite.store.Save(m))
+
+	due, err = suite.store.Due(time.Now().Add(2 * time.Minute))
+	suite.NoError(err)
+	suite.Empty(due)
+
+	fetched, err = suite.store.Get(m.ID)
+	suite.NoError(err)
+	suite.Equal(queue.StatusSent, fetched.Status)
+	suite.Equal(1, fetched.Attempts)
+
+	_, err = suite.store.Payload(m.ID)
+	suite.Error(err)
+
+	_, err = suite.store.Get("00000000-0000-0000-0000-000000000000")
+	suite.ErrorIs(err, queue.ErrNotFound)
+
+	_, err = suite.store.Get("../queued/" + m.ID)
+	suite.ErrorIs(err, queue.ErrNotFound)
+}
+
+func (suite *TestSuite) TestStore_Purge() {
+	sent, err := suite.store.Create([]byte("sent"))
+	suite.NoError(err)
+	sent.Status = queue.StatusSent
+	suite.NoError(suite.store.Save(sent))
+
+	failed, err := suite.store.Create([]byte("failed"))
+	suite.NoError(err)
+	failed.Status = queue.StatusFailed
+	suite.NoError(suite.store.Save(failed))
+
+	queued, err := suite.store.Create([]byte("queued"))
+	suite.NoError(err)
+
+	deleted, err := suite.store.Purge(time.Now().Add(-time.Minute))
+	suite.NoError(err)
+	suite.Zero(deleted)
+
+	// failed payloads are kept until they are purged
+	_, err = suite.store.Payload(failed.ID)
+	suite.NoError(err)
+
+	deleted, err = suite.store.Purge(time.Now().Add(time.Minute))
+	suite.NoError(err)
+	suite.Equal(2, deleted)
+
+	_, err = suite.store.Get(sent.ID)
+	suite.ErrorIs(err, queue.ErrNotFound)
+	_, err = suite.store.Get(failed.ID)
+	suite.ErrorIs(err, queue.ErrNotFound)
+	_, err = suite.store.Payload(failed.ID)
+	suite.Error(err)
+
+	// queued messages are never purged
+	_, err = suite.store.Get(queued.ID)
+	suite.NoError(err)
+}
+
+func (suite *TestSuite) TestQueue_Deliver() {
+	var mu sync.Mutex
+	delivered := map[string]int{}
+	q, stop := suite.run(func(payload []byte) ([]queue.Recipient, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		delivered[string(payload)]++
+		return []queue.Recipient{{Address: "a@b.com", Accepted: true, Message: "250 OK"}}, nil
+	})
+	defer stop()
+
+	var ids []string
+	for _, payload := range []string{"one", "two", "three"} {
+		m, err := q.Enqueue([]byte(payload))
+		suite.NoError(err)
+		ids = append(ids, m.ID)
+	}
+
+	for _, id := range ids {
+		m := suite.waitFor(q, id, queue.StatusSent)
+		suite.Equal(1, m.Attempts)
+		suite.False(m.SentAt.IsZero())
+		suite.Equal([]queue.Recipient{{Address: "a@b.com", Accepted: true, Message: "250 OK"}}, m.Recipients)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	suite.Equal(map[string]int{"one": 1, "two": 1, "three": 1}, delivered)
+}
+
+func (suite *TestSuite) TestQueue_Retry() {
+	var mu sync.Mutex
+	attempts := 0
+	q, stop := suite.run(func(payload []byte) ([]queue.Recipient, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts < 3 {
+			return nil, errors.New("421 try again later")
+		}
+		return nil, nil
+	})
+	defer stop()
+
+	m, err := q.Enqueue([]byte("payload"))
+	suite.NoError(err)
+
+	m = suite.waitFor(q, m.ID, queue.StatusSent)
+	suite.Equal(3, m.Attempts)
+	suite.Empty(m.LastError)
+}
+
+func (suite *TestSuite) TestQueue_MaxAttempts() {
+	q, stop := suite.run(func(payload []byte) ([]queue.Recipient, error) {
+		return nil, errors.New("421 try again later")
+	})
+	defer stop()
+
+	m, err := q.Enqueue([]byte("payload"))
+	suite.NoError(err)
+
+	m = suite.waitFor(q, m.ID, queue.StatusFailed)
+	suite.Equal(3, m.Attempts)
+	suite.Equal("421 try again later", m.LastError)
+	suite.True(m.SentAt.IsZero())
+}
+
+func (suite *TestSuite) TestQueue_Permanent() {
+	q, stop := suite.run(func(payload []byte) ([]queue.Recipient, error) {
+		return []queue.Recipient{{Address: "a@b.com", Message: "550 Mailbox unavailable"}}, queue.Permanent(errors.New("no recipients were accepted"))
+	})
+	defer stop()
+
+	m, err := q.Enqueue([]byte("payload"))
+	suite.NoError(err)
+
+	m = suite.waitFor(q, m.ID, queue.StatusFailed)
+	suite.Equal(1, m.Attempts)
+	suite.Equal("no recipients were accepted", m.LastError)
+	suite.Equal([]queue.Recipient{{Address: "a@b.com", Message: "550 Mailbox unavailable"}}, m.Recipients)
+
+	// failed payloads are kept for inspection
+	_, err = suite.store.Payload(m.ID)
+	suite.NoError(err)
+}
+
+func (suite *TestSuite) TestQueue_Restart() {
+	// messages queued while the queue was stopped are delivered when it runs
+	m, err := suite.store.Create([]byte("payload"))
+	suite.NoError(err)
+
+	q, stop := suite.run(func(payload []byte) ([]queue.Recipient, error) {
+		return nil, nil
+	})
+	defer stop()
+
+	suite.waitFor(q, m.ID, queue.StatusSent)
+}
+
+func (suite *TestSuite) TestPermanent() {
+	err := errors.New("550 Mailbox unavailable")
+	suite.True(queue.IsPermanent(queue.Permanent(err)))
+	suite.ErrorIs(queue.Permanent(err), err)
+	suite.False(queue.IsPermanent(err))
+	suite.False(queue.IsPermanent(nil))
+}
@@ -0,0 +1,230 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrNotFound = errors.New("message not found")
+
+// Status is the delivery status of a message.
+type Status string
+
+const (
+	// StatusQueued messages are waiting to be delivered, or retried.
+	StatusQueued Status = "queued"
+	// StatusSent messages were delivered to the smtp server.
+	StatusSent Status = "sent"
+	// StatusFailed messages failed permanently or ran out of attempts, they are kept until they are purged.
+	StatusFailed Status = "failed"
+)
+
+// Recipient is the result of delivering a message to a recipient.
+type Recipient struct {
+	Address  string `json:"address"`
+	Accepted bool   `json:"accepted"`
+	Message  string `json:"message,omitempty"`
+}
+
+// Message is a queued email, its payload is stored separately so the queue can be scanned without reading it.
+type Message struct {
+	ID            string      `json:"id"`
+	Status        Status      `json:"status"`
+	Attempts      int         `json:"attempts"`
+	LastError     string      `json:"last_error,omitempty"`
+	Recipients    []Recipient `json:"recipients,omitempty"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+	NextAttemptAt time.Time   `json:"next_attempt_at"`
+	SentAt        time.Time   `json:"sent_at"`
+}
+
+// Store keeps messages on disk, in a directory of queued messages, a directory of sent and failed messages and a
+// directory of payloads. Files are written to a temporary file and renamed, so a crash never leaves a partial file.
+type Store struct {
+	dir string
+}
+
+// Open returns the store in dir, creating its directories when they do not exist.
+func Open(dir string) (*Store, error) {
+	s := &Store{dir: dir}
+	for _, sub := range []string{"queued", "done", "payloads"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o700); err != nil {
+			return nil, fmt.Errorf("error creating queue directory: %v", err)
+		}
+	}
+	return s, nil
+}
+
+func (s *Store) path(sub string, id string, ext string) string {
+	return filepath.Join(s.dir, sub, id+ext)
+}
+
+// Create stores a new queued message with the payload, due now.
+func (s *Store) Create(payload []byte) (*Message, error) {
+	now := time.Now()
+	m := &Message{
+		ID:            uuid.New().String(),
+		Status:        StatusQueued,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		NextAttemptAt: now,
+	}
+	if err := writeFile(s.path("payloads", m.ID, ".bin"), payload); err != nil {
+		return nil, fmt.Errorf("error storing payload: %v", err)
+	}
+	if err := s.write("queued", m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Get returns the message with the id, or ErrNotFound.
+func (s *Store) Get(id string) (*Message, error) {
+	// ids are uuids, anything else could escape the directory
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrNotFound
+	}
+	for _, sub := range []string{"queued", "done"} {
+		m, err := s.read(s.path(sub, id, ".json"))
+		if err == nil {
+			return m, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Payload returns the payload of the message with the id.
+func (s *Store) Payload(id string) ([]byte, error) {
+	return os.ReadFile(s.path("payloads", id, ".bin"))
+}
+
+// Due returns the queued messages due at now, the longest due first.
+func (s *Store) Due(now time.Time) ([]*Message, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, "queued", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var due []*Message
+	for _, file := range files {
+		m, err := s.read(file)
+		if err != nil {
+			// the message was sent while scanning
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		if !m.NextAttemptAt.After(now) {
+			due = append(due, m)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	return due, nil
+}
+
+// Save stores the message, moving it out of the queue once it is sent or failed. The payload of a sent message
+// is deleted, the payload of a failed message is kept for inspection until it is purged.
+func (s *Store) Save(m *Message) error {
+	if m.Status == StatusQueued {
+		return s.write("queued", m)
+	}
+
+	// written before it is removed from the queue, so it can always be found
+	if err := s.write("done", m); err != nil {
+		return err
+	}
+	if err := os.Remove(s.path("queued", m.ID, ".json")); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if m.Status == StatusSent {
+		if err := os.Remove(s.path("payloads", m.ID, ".bin")); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Purge deletes the sent and failed messages last updated before, returning how many were deleted.
+func (s *Store) Purge(before time.Time) (int, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, "done", "*.json"))
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, file := range files {
+		m, err := s.read(file)
+		if err != nil {
+			return deleted, err
+		}
+		if !m.UpdatedAt.Before(before) {
+			continue
+		}
+		if err := os.Remove(s.path("payloads", m.ID, ".bin")); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return deleted, err
+		}
+		if err := os.Remove(file); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+func (s *Store) read(path string) (*Message, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Message
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", filepath.Base(path), err)
+	}
+	return &m, nil
+}
+
+func (s *Store) write(sub string, m *Message) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(s.path(sub, m.ID, ".json"), data); err != nil {
+		return fmt.Errorf("error storing message: %v", err)
+	}
+	return nil
+}
+
+// writeFile writes the file atomically, with a temporary file that is synced and renamed.
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,243 @@
+// Package templates renders the subject, plain text and html of emails from named templates, loaded from a
+// directory or registered at runtime.
+//
+// A template defines a "subject" and a "text" and/or "html" template, the subject and text are rendered with
+// text/template and the html with html/template. Shared templates, such as layouts and partials, are available
+// to every template:
+//
+//	{{define "subject"}}Welcome {{.name}}{{end}}
+//	{{define "text"}}Hi {{.name}}, welcome aboard.{{end}}
+//	{{define "html"}}{{template "layout" .}}{{end}}
+//	{{define "content"}}<p>Hi {{.name}}, welcome aboard.</p>{{end}}
+package templates
+
+import (
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+)
+
+var (
+	ErrIDInvalid       = errors.New("template id must be 1 to 64 lowercase letters, digits, dots, dashes or underscores")
+	ErrNotFound        = errors.New("template not found")
+	ErrSubjectRequired = errors.New("template does not define a subject")
+	ErrBodyRequired    = errors.New("template does not define a text or html body")
+)
+
+// sharedDirs are the directories of shared templates in a templates directory.
+var sharedDirs = []string{"layouts", "partials"}
+
+var idPattern = regexp.MustCompile(`^[a-z0-9_.-]{1,64}$`)
+
+// Message is a rendered email.
+type Message struct {
+	Subject   string
+	PlainText string
+	Html      string
+}
+
+// Source is the source of a template registered at runtime, Text or Html may be empty.
+type Source struct {
+	Subject string
+	Text    string
+	Html    string
+}
+
+type template struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// Store holds the templates emails are rendered from, it is safe for concurrent use.
+type Store struct {
+	sharedText *texttemplate.Template
+	sharedHtml *htmltemplate.Template
+
+	mu        sync.RWMutex
+	templates map[string]*template
+}
+
+// NewStore returns an empty store without shared templates.
+func NewStore() *Store {
+	return &Store{
+		sharedText: texttemplate.New("").Option("missingkey=error"),
+		sharedHtml: htmltemplate.New("").Option("missingkey=error"),
+		templates:  make(map[string]*template),
+	}
+}
+
+// Load returns a store of the templates in dir. Every <id>.tmpl file in dir is a template, and the templates
+// defined by the files in its layouts and partials directories are shared by all of them.
+func Load(dir string) (*Store, error) {
+	s := NewStore()
+
+	for _, shared := range sharedDirs {
+		files, err := filepath.Glob(filepath.Join(dir, shared, "*.tmpl"))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := s.sharedText.New(file).Parse(string(data)); err != nil {
+				return nil, fmt.Errorf("error parsing %s: %v", file, err)
+			}
+			if _, err := s.sharedHtml.New(file).Parse(string(data)); err != nil {
+				return nil, fmt.Errorf("error parsing %s: %v", file, err)
+			}
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		id := strings.TrimSuffix(filepath.Base(file), ".tmpl")
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		t, err := s.parseFile(id, string(data))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %v", file, err)
+		}
+		s.templates[id] = t
+	}
+
+	return s, nil
+}
+
+// parseFile parses a template file defining the subject, text and html templates.
+func (s *Store) parseFile(id string, data string) (*template, error) {
+	if !idPattern.MatchString(id) {
+		return nil, ErrIDInvalid
+	}
+
+	text, err := s.cloneText()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := text.New(id).Parse(data); err != nil {
+		return nil, err
+	}
+	html, err := s.cloneHtml()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := html.New(id).Parse(data); err != nil {
+		return nil, err
+	}
+
+	t := &template{subject: text.Lookup("subject"), text: text.Lookup("text"), html: html.Lookup("html")}
+	return t, t.validate()
+}
+
+// Register adds the template with the id, replacing any template with the same id.
+func (s *Store) Register(id string, source Source) error {
+	if !idPattern.MatchString(id) {
+		return ErrIDInvalid
+	}
+
+	if strings.TrimSpace(source.Subject) == "" {
+		return ErrSubjectRequired
+	}
+	if strings.TrimSpace(source.Text) == "" && strings.TrimSpace(source.Html) == "" {
+		return ErrBodyRequired
+	}
+
+	text, err := s.cloneText()
+	if err != nil {
+		return err
+	}
+	t := &template{}
+	if t.subject, err = text.New("subject").Parse(source.Subject); err != nil {
+		return err
+	}
+	if strings.TrimSpace(source.Text) != "" {
+		if t.text, err = text.New("text").Parse(source.Text); err != nil {
+			return err
+		}
+	}
+	if strings.TrimSpace(source.Html) != "" {
+		html, err := s.cloneHtml()
+		if err != nil {
+			return err
+		}
+		if t.html, err = html.New("html").Parse(source.Html); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.templates[id] = t
+	return nil
+}
+
+// Render renders the template with the id for the variables, returning ErrNotFound when there is none.
+// Variables the template uses that are missing are an error.
+func (s *Store) Render(id string, vars map[string]interface{}) (*Message, error) {
+	s.mu.RLock()
+	t, ok := s.templates[id]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	var message Message
+	var buf strings.Builder
+	if err := t.subject.Execute(&buf, vars); err != nil {
+		return nil, fmt.Errorf("error rendering subject: %v", err)
+	}
+	// the subject is a header, so it is kept to a single line
+	message.Subject = strings.Join(strings.Fields(buf.String()), " ")
+
+	if t.text != nil {
+		buf.Reset()
+		if err := t.text.Execute(&buf, vars); err != nil {
+			return nil, fmt.Errorf("error rendering text: %v", err)
+		}
+		message.PlainText = strings.TrimSpace(buf.String())
+	}
+
+	if t.html != nil {
+		buf.Reset()
+		if err := t.html.Execute(&buf, vars); err != nil {
+			return nil, fmt.Errorf("error rendering html: %v", err)
+		}
+		message.Html = strings.TrimSpace(buf.String())
+	}
+
+	return &message, nil
+}
+
+func (t *template) validate() error {
+	if t.subject == nil {
+		return ErrSubjectRequired
+	}
+	if t.text == nil && t.html == nil {
+		return ErrBodyRequired
+	}
+	return nil
+}
+
+// cloneText returns a copy of the shared text templates to parse a template into.
+func (s *Store) cloneText() (*texttemplate.Template, error) {
+	return s.sharedText.Clone()
+}
+
+// cloneHtml returns a copy of the shared html templates to parse a template into, shared templates are never
+// executed so they can always be cloned.
+func (s *Store) cloneHtml() (*htmltemplate.Template, error) {
+	return s.sharedHtml.Clone()
+}
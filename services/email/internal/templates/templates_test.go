@@ -0,0 +1,123 @@
+package templates_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/accentdesign/grpc/services/email/internal/templates"
+)
+
+type TestSuite struct {
+	suite.Suite
+}
+
+func TestTestSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+func (suite *TestSuite) writeFile(dir string, name string, data string) {
+	path := filepath.Join(dir, name)
+	suite.NoError(os.MkdirAll(filepath.Dir(path), 0o755))
+	suite.NoError(os.WriteFile(path, []byte(data), 0o644))
+}
+
+func (suite *TestSuite) TestLoad() {
+	dir := suite.T().TempDir()
+	suite.writeFile(dir, "layouts/base.tmpl", `{{define "layout"}}<html><body>{{template "content" .}}{{template "footer" .}}</body></html>{{end}}`)
+	suite.writeFile(dir, "partials/footer.tmpl", `{{define "footer"}}<p>{{.company}}</p>{{end}}`)
+	suite.writeFile(dir, "welcome.tmpl", `{{define "subject"}}Welcome
+{{.name}}{{end}}
+{{define "text"}}
+Hi {{.name}}, welcome to {{.company}}.
+{{end}}
+{{define "html"}}{{template "layout" .}}{{end}}
+{{define "content"}}<p>Hi {{.name}}</p>{{end}}`)
+	suite.writeFile(dir, "plain.tmpl", `{{define "subject"}}Hi{{end}}{{define "text"}}Hi {{.name}}{{end}}`)
+	suite.writeFile(dir, "ignored.txt", `not a template`)
+
+	store, err := templates.Load(dir)
+	suite.NoError(err)
+
+	message, err := store.Render("welcome", map[string]interface{}{"name": "<Jane>", "company": "Acme"})
+	suite.NoError(err)
+	suite.Equal("Welcome <Jane>", message.Subject)
+	suite.Equal("Hi <Jane>, welcome to Acme.", message.PlainText)
+	suite.Equal("<html><body><p>Hi &lt;Jane&gt;</p><p>Acme</p></body></html>", message.Html)
+
+	message, err = store.Render("plain", map[string]interface{}{"name": "Jane"})
+	suite.NoError(err)
+	suite.Equal("Hi Jane", message.PlainText)
+	suite.Empty(message.Html)
+
+	_, err = store.Render("ignored", nil)
+	suite.ErrorIs(err, templates.ErrNotFound)
+
+	// missing variables are an error
+	_, err = store.Render("welcome", map[string]interface{}{"name": "Jane"})
+	suite.ErrorContains(err, `map has no entry for key "company"`)
+}
+
+func (suite *TestSuite) TestLoad_Errors() {
+	testCases := []struct {
+		desc     string
+		file     string
+		data     string
+		expected string
+	}{
+		{"no subject", "welcome.tmpl", `{{define "text"}}Hi{{end}}`, templates.ErrSubjectRequired.Error()},
+		{"no body", "welcome.tmpl", `{{define "subject"}}Hi{{end}}`, templates.ErrBodyRequired.Error()},
+		{"invalid id", "Welcome.tmpl", `{{define "subject"}}Hi{{end}}{{define "text"}}Hi{{end}}`, templates.ErrIDInvalid.Error()},
+		{"syntax", "welcome.tmpl", `{{define "subject"}}Hi{{.name{{end}}`, "error parsing"},
+		{"shared syntax", "partials/footer.tmpl", `{{define "footer"}}{{end}`, "error parsing"},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.desc, func() {
+			dir := suite.T().TempDir()
+			suite.writeFile(dir, tc.file, tc.data)
+
+			_, err := templates.Load(dir)
+			suite.ErrorContains(err, tc.expected)
+		})
+	}
+}
+
+func (suite *TestSuite) TestRegister() {
+	dir := suite.T().TempDir()
+	suite.writeFile(dir, "layouts/base.tmpl", `{{define "layout"}}<div>{{.name}}</div>{{end}}`)
+
+	store, err := templates.Load(dir)
+	suite.NoError(err)
+
+	err = store.Register("invoice", templates.Source{Subject: "Invoice {{.number}}", Html: `{{template "layout" .}}<a href="{{.url}}">pay</a>`})
+	suite.NoError(err)
+
+	message, err := store.Render("invoice", map[string]interface{}{"number": 12, "name": "Jane", "url": "javascript:alert(1)"})
+	suite.NoError(err)
+	suite.Equal("Invoice 12", message.Subject)
+	suite.Empty(message.PlainText)
+	suite.Equal(`<div>Jane</div><a href="#ZgotmplZ">pay</a>`, message.Html)
+
+	// registering replaces the template
+	err = store.Register("invoice", templates.Source{Subject: "Invoice", Text: "Hi {{.name}}"})
+	suite.NoError(err)
+
+	message, err = store.Render("invoice", map[string]interface{}{"name": "Jane"})
+	suite.NoError(err)
+	suite.Equal("Hi Jane", message.PlainText)
+	suite.Empty(message.Html)
+
+	suite.ErrorIs(store.Register("", templates.Source{Subject: "Hi", Text: "Hi"}), templates.ErrIDInvalid)
+	suite.ErrorIs(store.Register("a/b", templates.Source{Subject: "Hi", Text: "Hi"}), templates.ErrIDInvalid)
+	suite.ErrorIs(store.Register("hi", templates.Source{Text: "Hi"}), templates.ErrSubjectRequired)
+	suite.ErrorIs(store.Register("hi", templates.Source{Subject: "Hi", Text: " "}), templates.ErrBodyRequired)
+	suite.Error(store.Register("hi", templates.Source{Subject: "Hi", Text: "{{.name"}))
+
+	// templates that are not defined fail to render
+	suite.NoError(store.Register("hi", templates.Source{Subject: "Hi", Html: `{{template "missing" .}}`}))
+	_, err = store.Render("hi", nil)
+	suite.ErrorContains(err, "error rendering html")
+}
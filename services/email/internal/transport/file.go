@@ -0,0 +1,85 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// File writes emails to a maildir for development, each email is a .eml file in its new directory that mail
+// clients can open. The envelope is recorded with Return-Path and Delivered-To headers, so bcc recipients are
+// visible.
+type File struct {
+	dir string
+}
+
+// NewFile returns a transport writing emails to the maildir dir, creating it when it does not exist.
+func NewFile(dir string) (*File, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o700); err != nil {
+			return nil, fmt.Errorf("error creating maildir: %v", err)
+		}
+	}
+	return &File{dir: dir}, nil
+}
+
+// Send writes the message to a new file, every recipient is accepted.
+func (t *File) Send(_ context.Context, m *Message) ([]Result, error) {
+	var buf bytes.Buffer
+	writeEnvelope(&buf, m)
+	buf.Write(m.Raw)
+
+	// written to tmp and moved to new once it is complete, as maildir readers expect
+	name := fmt.Sprintf("%d.%s.eml", time.Now().UnixNano(), uuid.New().String())
+	tmp := filepath.Join(t.dir, "tmp", name)
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, filepath.Join(t.dir, "new", name)); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	return accepted(m), nil
+}
+
+// Log writes emails to a writer, such as stdout, for development.
+type Log struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+// NewLog returns a transport writing emails to w.
+func NewLog(w io.Writer) *Log {
+	return &Log{writer: w}
+}
+
+// Send writes the message, every recipient is accepted.
+func (t *Log) Send(_ context.Context, m *Message) ([]Result, error) {
+	var buf bytes.Buffer
+	buf.WriteString("----- email -----\r\n")
+	writeEnvelope(&buf, m)
+	buf.Write(m.Raw)
+	buf.WriteString("\r\n----- end of email -----\r\n")
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, err := t.writer.Write(buf.Bytes()); err != nil {
+		return nil, err
+	}
+	return accepted(m), nil
+}
+
+// writeEnvelope writes the sender and recipients of the message as the headers a delivery agent adds.
+func writeEnvelope(buf *bytes.Buffer, m *Message) {
+	fmt.Fprintf(buf, "Return-Path: <%s>\r\n", m.From.Address)
+	for _, recipient := range m.Recipients() {
+		fmt.Fprintf(buf, "Delivered-To: %s\r\n", recipient)
+	}
+}
@@ -0,0 +1,32 @@
+package transport
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxErrorBody is the length of an error response kept in a StatusError.
+const maxErrorBody = 512
+
+func defaultHTTPClient() *http.Client {
+	return &http.Client{Timeout: 30 * time.Second}
+}
+
+// do sends the request to the provider, returning a StatusError for a response that is not a 2xx.
+func do(client *http.Client, provider string, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return &StatusError{Provider: provider, StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+}
@@ -0,0 +1,65 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+)
+
+// MailgunURL is the url of the Mailgun api in the US region, MailgunEUURL in the EU region.
+const (
+	MailgunURL   = "https://api.mailgun.net"
+	MailgunEUURL = "https://api.eu.mailgun.net"
+)
+
+// Mailgun sends emails with the Mailgun messages api, as MIME messages.
+type Mailgun struct {
+	domain string
+	apiKey string
+	// BaseURL is the url of the api, MailgunURL unless the domain is in the EU region. It can be changed to send
+	// to a stand-in.
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewMailgun returns a transport sending emails from the Mailgun domain with the api key.
+func NewMailgun(domain string, apiKey string) *Mailgun {
+	return &Mailgun{domain: domain, apiKey: apiKey, BaseURL: MailgunURL, Client: defaultHTTPClient()}
+}
+
+// Send sends the message, Mailgun accepts or rejects every recipient at once.
+func (t *Mailgun) Send(ctx context.Context, m *Message) ([]Result, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	// the recipients are the envelope, so bcc recipients are sent to without being in the message
+	for _, recipient := range m.Recipients() {
+		if err := w.WriteField("to", recipient); err != nil {
+			return nil, err
+		}
+	}
+	part, err := w.CreateFormFile("message", "message.eml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(m.Raw); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	endpoint := t.BaseURL + "/v3/" + url.PathEscape(t.domain) + "/messages.mime"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth("api", t.apiKey)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	if err := do(t.Client, "mailgun", req); err != nil {
+		return nil, err
+	}
+	return accepted(m), nil
+}
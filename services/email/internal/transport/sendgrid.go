@@ -0,0 +1,130 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/mail"
+)
+
+const sendGridURL = "https://api.sendgrid.com"
+
+// SendGrid sends emails with the SendGrid v3 mail send api.
+type SendGrid struct {
+	apiKey string
+	// BaseURL is the url of the api, it can be changed to send to a stand-in.
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewSendGrid returns a transport sending emails with the SendGrid api key.
+func NewSendGrid(apiKey string) *SendGrid {
+	return &SendGrid{apiKey: apiKey, BaseURL: sendGridURL, Client: defaultHTTPClient()}
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridPersonalization struct {
+	To  []sendGridAddress `json:"to"`
+	Cc  []sendGridAddress `json:"cc,omitempty"`
+	Bcc []sendGridAddress `json:"bcc,omitempty"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridAttachment struct {
+	Content     string `json:"content"`
+	Type        string `json:"type"`
+	Filename    string `json:"filename"`
+	Disposition string `json:"disposition"`
+}
+
+type sendGridMail struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	ReplyToList      []sendGridAddress         `json:"reply_to_list,omitempty"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+	Attachments      []sendGridAttachment      `json:"attachments,omitempty"`
+}
+
+func sendGridAddresses(list []*mail.Address) []sendGridAddress {
+	var addresses []sendGridAddress
+	for _, address := range list {
+		addresses = append(addresses, sendGridAddress{Email: address.Address, Name: address.Name})
+	}
+	return addresses
+}
+
+// sendGridPersonalizations returns the recipients of the message, SendGrid requires every personalization to have
+// a to address. Without to addresses the cc addresses are sent to as to addresses, and without either every bcc
+// address is sent its own copy, addressed to it, so the bcc recipients are not disclosed to each other.
+func sendGridPersonalizations(m *Message) []sendGridPersonalization {
+	// SendGrid rejects an address listed more than once
+	to, cc, bcc := m.destinations()
+	if len(to) == 0 {
+		to, cc = cc, nil
+	}
+	if len(to) > 0 {
+		return []sendGridPersonalization{{
+			To:  sendGridAddresses(to),
+			Cc:  sendGridAddresses(cc),
+			Bcc: sendGridAddresses(bcc),
+		}}
+	}
+
+	personalizations := make([]sendGridPersonalization, 0, len(bcc))
+	for _, address := range bcc {
+		personalizations = append(personalizations, sendGridPersonalization{To: sendGridAddresses([]*mail.Address{address})})
+	}
+	return personalizations
+}
+
+// Send sends the message, SendGrid accepts or rejects every recipient at once.
+func (t *SendGrid) Send(ctx context.Context, m *Message) ([]Result, error) {
+	body := sendGridMail{
+		Personalizations: sendGridPersonalizations(m),
+		From:             sendGridAddress{Email: m.From.Address, Name: m.From.Name},
+		ReplyToList:      sendGridAddresses(m.ReplyTo),
+		Subject:          m.Subject,
+	}
+	// the plain text has to be the first content
+	if m.PlainText != "" {
+		body.Content = append(body.Content, sendGridContent{Type: "text/plain", Value: m.PlainText})
+	}
+	if m.Html != "" {
+		body.Content = append(body.Content, sendGridContent{Type: "text/html", Value: m.Html})
+	}
+	for _, attachment := range m.Attachments {
+		body.Attachments = append(body.Attachments, sendGridAttachment{
+			Content:     base64.StdEncoding.EncodeToString(attachment.Data),
+			Type:        attachment.ContentType,
+			Filename:    attachment.Filename,
+			Disposition: "attachment",
+		})
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.BaseURL+"/v3/mail/send", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	if err := do(t.Client, "sendgrid", req); err != nil {
+		return nil, err
+	}
+	return accepted(m), nil
+}
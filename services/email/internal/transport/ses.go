@@ -0,0 +1,167 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SESConfig is the region and credentials of a SES transport, SessionToken is only set for temporary credentials.
+type SESConfig struct {
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// SES sends emails with the Amazon SES v2 api, as MIME messages.
+type SES struct {
+	config SESConfig
+	// BaseURL is the url of the api in the region, it can be changed to send to a stand-in.
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewSES returns a transport sending emails with the SES api of the region.
+func NewSES(config SESConfig) *SES {
+	return &SES{
+		config:  config,
+		BaseURL: fmt.Sprintf("https://email.%s.amazonaws.com", config.Region),
+		Client:  defaultHTTPClient(),
+	}
+}
+
+type sesDestination struct {
+	ToAddresses  []string `json:"ToAddresses,omitempty"`
+	CcAddresses  []string `json:"CcAddresses,omitempty"`
+	BccAddresses []string `json:"BccAddresses,omitempty"`
+}
+
+type sesRaw struct {
+	// Data is encoded as base64, as the api expects
+	Data []byte `json:"Data"`
+}
+
+type sesContent struct {
+	Raw sesRaw `json:"Raw"`
+}
+
+type sesEmail struct {
+	FromEmailAddress string         `json:"FromEmailAddress"`
+	Destination      sesDestination `json:"Destination"`
+	ReplyToAddresses []string       `json:"ReplyToAddresses,omitempty"`
+	Content          sesContent     `json:"Content"`
+}
+
+func sesAddresses(list []*mail.Address) []string {
+	var addresses []string
+	for _, address := range list {
+		addresses = append(addresses, address.String())
+	}
+	return addresses
+}
+
+// Send sends the message, SES accepts or rejects every recipient at once.
+func (t *SES) Send(ctx context.Context, m *Message) ([]Result, error) {
+	to, cc, bcc := m.destinations()
+	data, err := json.Marshal(sesEmail{
+		FromEmailAddress: m.From.String(),
+		Destination: sesDestination{
+			ToAddresses:  sesAddresses(to),
+			CcAddresses:  sesAddresses(cc),
+			BccAddresses: sesAddresses(bcc),
+		},
+		ReplyToAddresses: sesAddresses(m.ReplyTo),
+		Content:          sesContent{Raw: sesRaw{Data: m.Raw}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.BaseURL+"/v2/email/outbound-emails", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	signV4(req, data, "ses", t.config, time.Now())
+
+	if err := do(t.Client, "ses", req); err != nil {
+		return nil, err
+	}
+	return accepted(m), nil
+}
+
+// signV4 signs the request with the AWS Signature Version 4 of the service, signing its host, content type and
+// amz headers.
+func signV4(req *http.Request, body []byte, service string, config SESConfig, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", config.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "content-type" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hashHex(body),
+	}, "\n")
+
+	scope := date + "/" + config.Region + "/" + service + "/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hashHex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+config.SecretAccessKey), date)
+	for _, part := range []string{config.Region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
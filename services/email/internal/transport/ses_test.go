@@ -0,0 +1,34 @@
+package transport
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SignV4Suite struct {
+	suite.Suite
+}
+
+func TestSignV4Suite(t *testing.T) {
+	suite.Run(t, new(SignV4Suite))
+}
+
+// TestSignV4 signs the get-vanilla request of the AWS Signature Version 4 test suite.
+func (suite *SignV4Suite) TestSignV4() {
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	suite.NoError(err)
+
+	signV4(req, nil, "service", SESConfig{
+		Region:          "us-east-1",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}, time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	suite.Equal("20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	suite.Equal("AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		req.Header.Get("Authorization"))
+}
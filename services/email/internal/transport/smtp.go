@@ -0,0 +1,154 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net/smtp"
+	"net/textproto"
+
+	"github.com/accentdesign/grpc/services/email/internal/pool"
+)
+
+// SMTPConfig is the smtp server a SMTP transport sends to.
+type SMTPConfig struct {
+	Host     string
+	Port     int64
+	Username string
+	Password string
+	StartTLS bool
+}
+
+// SMTP sends emails to an smtp server over a pool of connections, reporting whether each recipient was accepted.
+type SMTP struct {
+	config SMTPConfig
+	// Pool is the pool of connections, its settings can be changed before the transport is used.
+	Pool *pool.Pool
+}
+
+// NewSMTP returns a transport sending emails to the smtp server of the config.
+func NewSMTP(config SMTPConfig) *SMTP {
+	t := &SMTP{config: config}
+	t.Pool = pool.New(t.dial)
+	return t
+}
+
+// Check connects to the smtp server to check the settings.
+func (t *SMTP) Check() error {
+	conn, err := t.dial()
+	if err != nil {
+		return err
+	}
+	if err := conn.Quit(); err != nil {
+		conn.Close()
+	}
+	return nil
+}
+
+// Send sends the message to each recipient the smtp server accepts. It fails when no recipient is accepted.
+func (t *SMTP) Send(ctx context.Context, m *Message) ([]Result, error) {
+	conn, err := t.Pool.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	results, err := transact(conn, m)
+	t.Pool.Put(conn, err)
+	return results, err
+}
+
+// Close closes the connections of the pool.
+func (t *SMTP) Close() {
+	t.Pool.Close()
+}
+
+// rejectedError is returned when the smtp server rejects every recipient, reply is the rejection that is the
+// most likely to be temporary.
+type rejectedError struct {
+	reply *textproto.Error
+}
+
+func (e *rejectedError) Error() string {
+	return "no recipients were accepted"
+}
+
+func (e *rejectedError) Unwrap() error {
+	return e.reply
+}
+
+// transact sends the message in a mail transaction of the connection.
+func transact(conn *pool.Conn, m *Message) ([]Result, error) {
+	if err := conn.Mail(m.From.Address); err != nil {
+		return nil, err
+	}
+
+	recipients := m.Recipients()
+	results := make([]Result, 0, len(recipients))
+	accepted := 0
+	rejected := &rejectedError{}
+	for _, recipient := range recipients {
+		result := Result{Address: recipient, Accepted: true}
+		if err := conn.Rcpt(recipient); err != nil {
+			// a rejected recipient is a reply of the server, anything else means the connection failed
+			var replyErr *textproto.Error
+			if !errors.As(err, &replyErr) {
+				return nil, err
+			}
+			result.Accepted = false
+			result.Message = fmt.Sprintf("%d %s", replyErr.Code, replyErr.Msg)
+			if rejected.reply == nil || replyErr.Code < rejected.reply.Code {
+				rejected.reply = replyErr
+			}
+		} else {
+			accepted++
+		}
+		results = append(results, result)
+	}
+	if accepted == 0 {
+		return results, rejected
+	}
+
+	w, err := conn.Data()
+	if err != nil {
+		return results, err
+	}
+	if _, err := w.Write(m.Raw); err != nil {
+		return results, err
+	}
+	return results, w.Close()
+}
+
+// dial opens a connection to the smtp server, authenticated when there is a username and password.
+func (t *SMTP) dial() (*smtp.Client, error) {
+	serverAddr := fmt.Sprintf("%s:%d", t.config.Host, t.config.Port)
+
+	conn, err := smtp.Dial(serverAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.config.StartTLS {
+		tlsConfig := &tls.Config{ServerName: t.config.Host}
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			closeAfterSetupError(conn)
+			return nil, err
+		}
+	}
+
+	if t.config.Username != "" && t.config.Password != "" {
+		auth := smtp.PlainAuth("", t.config.Username, t.config.Password, t.config.Host)
+		if err := conn.Auth(auth); err != nil {
+			closeAfterSetupError(conn)
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+func closeAfterSetupError(conn *smtp.Client) {
+	if err := conn.Close(); err != nil {
+		log.Printf("Error closing SMTP connection after setup error: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+// Package transport delivers emails through a backend, such as an smtp server, an http api provider or a local
+// sink for development, so the backend can be changed without changing how emails are built and sent.
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// Transport delivers emails.
+type Transport interface {
+	// Send delivers the message, returning the result of every recipient. Recipients a transport cannot report on
+	// separately are all accepted, or all rejected with the error.
+	Send(ctx context.Context, m *Message) ([]Result, error)
+}
+
+// Message is an email to deliver. Transports sending MIME messages send Raw, the others build the email from the
+// other fields.
+type Message struct {
+	From        *mail.Address
+	To          []*mail.Address
+	Cc          []*mail.Address
+	Bcc         []*mail.Address
+	ReplyTo     []*mail.Address
+	Subject     string
+	PlainText   string
+	Html        string
+	Attachments []Attachment
+	// Raw is the MIME encoded message, with every header but Bcc.
+	Raw []byte
+}
+
+// Attachment is a file attached to a message.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Result is the result of delivering a message to a recipient, Message is the reply of the backend when the
+// recipient was not accepted.
+type Result struct {
+	Address  string
+	Accepted bool
+	Message  string
+}
+
+// destinations returns the to, cc and bcc addresses, an address listed more than once is only kept where it is
+// first listed.
+func (m *Message) destinations() (to, cc, bcc []*mail.Address) {
+	seen := make(map[string]bool)
+	unique := func(list []*mail.Address) []*mail.Address {
+		var kept []*mail.Address
+		for _, address := range list {
+			key := strings.ToLower(address.Address)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			kept = append(kept, address)
+		}
+		return kept
+	}
+	return unique(m.To), unique(m.Cc), unique(m.Bcc)
+}
+
+// Recipients returns the addresses the message is delivered to, the to, cc and bcc addresses without duplicates.
+func (m *Message) Recipients() []string {
+	to, cc, bcc := m.destinations()
+	var recipients []string
+	for _, list := range [][]*mail.Address{to, cc, bcc} {
+		for _, address := range list {
+			recipients = append(recipients, address.Address)
+		}
+	}
+	return recipients
+}
+
+// accepted returns the results of a message every recipient accepted.
+func accepted(m *Message) []Result {
+	recipients := m.Recipients()
+	results := make([]Result, 0, len(recipients))
+	for _, recipient := range recipients {
+		results = append(results, Result{Address: recipient, Accepted: true})
+	}
+	return results
+}
+
+// StatusError is the error response of an http api provider.
+type StatusError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: %d %s", e.Provider, e.StatusCode, e.Body)
+}
+
+// IsPermanent reports whether err is a failure that sending the message again cannot fix, a 5xx reply of an smtp
+// server or a 4xx response of an http api provider. Rejected credentials and rate limits are not permanent, as
+// they do not depend on the message.
+func IsPermanent(err error) bool {
+	var reply *textproto.Error
+	if errors.As(err, &reply) {
+		return reply.Code >= 500
+	}
+
+	var status *StatusError
+	if errors.As(err, &status) {
+		switch status.StatusCode {
+		case 401, 403, 408, 429:
+			return false
+		}
+		return status.StatusCode >= 400 && status.StatusCode < 500
+	}
+
+	return false
+}
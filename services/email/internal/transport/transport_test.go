@@ -0,0 +1,293 @@
+package transport_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/accentdesign/grpc/services/email/internal/transport"
+)
+
+type TestSuite struct {
+	suite.Suite
+}
+
+func TestTestSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
+
+func newMessage() *transport.Message {
+	return &transport.Message{
+		From:        &mail.Address{Name: "Example App", Address: "from@example.com"},
+		To:          []*mail.Address{{Name: "Jane", Address: "jane@example.com"}, {Address: "john@example.com"}},
+		Cc:          []*mail.Address{{Address: "JANE@example.com"}, {Address: "ann@example.com"}},
+		Bcc:         []*mail.Address{{Address: "bcc@example.com"}},
+		ReplyTo:     []*mail.Address{{Address: "support@example.com"}},
+		Subject:     "Test email",
+		PlainText:   "This is a test email",
+		Html:        "<p>This is a test email</p>",
+		Attachments: []transport.Attachment{{Filename: "test.txt", ContentType: "text/plain", Data: []byte("attached")}},
+		Raw:         []byte("From: \"Example App\" <from@example.com>\r\nSubject: Test email\r\n\r\nThis is a test email\r\n"),
+	}
+}
+
+func accepted(addresses ...string) []transport.Result {
+	var results []transport.Result
+	for _, address := range addresses {
+		results = append(results, transport.Result{Address: address, Accepted: true})
+	}
+	return results
+}
+
+func (suite *TestSuite) TestRecipients() {
+	// duplicates are only sent to once, where they are first listed
+	suite.Equal([]string{"jane@example.com", "john@example.com", "ann@example.com", "bcc@example.com"}, newMessage().Recipients())
+}
+
+func (suite *TestSuite) TestIsPermanent() {
+	testCases := []struct {
+		err       error
+		permanent bool
+	}{
+		{&textproto.Error{Code: 550, Msg: "Mailbox unavailable"}, true},
+		{&textproto.Error{Code: 451, Msg: "Try again later"}, false},
+		{&transport.StatusError{Provider: "sendgrid", StatusCode: 400}, true},
+		{&transport.StatusError{Provider: "sendgrid", StatusCode: 401}, false},
+		{&transport.StatusError{Provider: "sendgrid", StatusCode: 429}, false},
+		{&transport.StatusError{Provider: "sendgrid", StatusCode: 503}, false},
+		{errors.New("connection refused"), false},
+		{nil, false},
+	}
+
+	for _, tc := range testCases {
+		suite.Equal(tc.permanent, transport.IsPermanent(tc.err), "%v", tc.err)
+	}
+}
+
+func (suite *TestSuite) TestFile() {
+	dir := filepath.Join(suite.T().TempDir(), "mail")
+	file, err := transport.NewFile(dir)
+	suite.NoError(err)
+
+	results, err := file.Send(context.Background(), newMessage())
+	suite.NoError(err)
+	suite.Equal(accepted("jane@example.com", "john@example.com", "ann@example.com", "bcc@example.com"), results)
+
+	files, err := filepath.Glob(filepath.Join(dir, "new", "*.eml"))
+	suite.NoError(err)
+	suite.Len(files, 1)
+
+	data, err := os.ReadFile(files[0])
+	suite.NoError(err)
+	suite.True(strings.HasPrefix(string(data), "Return-Path: <from@example.com>\r\nDelivered-To: jane@example.com\r\n"), string(data))
+	suite.Contains(string(data), "Delivered-To: bcc@example.com\r\nFrom: \"Example App\" <from@example.com>\r\n")
+
+	tmp, err := os.ReadDir(filepath.Join(dir, "tmp"))
+	suite.NoError(err)
+	suite.Empty(tmp)
+}
+
+func (suite *TestSuite) TestLog() {
+	var buf bytes.Buffer
+	results, err := transport.NewLog(&buf).Send(context.Background(), newMessage())
+	suite.NoError(err)
+	suite.Len(results, 4)
+	suite.Contains(buf.String(), "Delivered-To: bcc@example.com\r\n")
+	suite.Contains(buf.String(), "Subject: Test email\r\n")
+}
+
+func (suite *TestSuite) TestSendGrid() {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.Equal("/v3/mail/send", r.URL.Path)
+		suite.Equal("Bearer key", r.Header.Get("Authorization"))
+		suite.NoError(json.NewDecoder(r.Body).Decode(&body))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	sendGrid := transport.NewSendGrid("key")
+	sendGrid.BaseURL = server.URL
+
+	results, err := sendGrid.Send(context.Background(), newMessage())
+	suite.NoError(err)
+	suite.Len(results, 4)
+
+	suite.Equal(map[string]interface{}{
+		"personalizations": []interface{}{map[string]interface{}{
+			"to":  []interface{}{map[string]interface{}{"email": "jane@example.com", "name": "Jane"}, map[string]interface{}{"email": "john@example.com"}},
+			"cc":  []interface{}{map[string]interface{}{"email": "ann@example.com"}},
+			"bcc": []interface{}{map[string]interface{}{"email": "bcc@example.com"}},
+		}},
+		"from":          map[string]interface{}{"email": "from@example.com", "name": "Example App"},
+		"reply_to_list": []interface{}{map[string]interface{}{"email": "support@example.com"}},
+		"subject":       "Test email",
+		"content": []interface{}{
+			map[string]interface{}{"type": "text/plain", "value": "This is a test email"},
+			map[string]interface{}{"type": "text/html", "value": "<p>This is a test email</p>"},
+		},
+		"attachments": []interface{}{map[string]interface{}{
+			"content":     base64.StdEncoding.EncodeToString([]byte("attached")),
+			"type":        "text/plain",
+			"filename":    "test.txt",
+			"disposition": "attachment",
+		}},
+	}, body)
+}
+
+func (suite *TestSuite) TestSendGrid_NoTo() {
+	var body struct {
+		Personalizations []map[string]interface{} `json:"personalizations"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.NoError(json.NewDecoder(r.Body).Decode(&body))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	sendGrid := transport.NewSendGrid("key")
+	sendGrid.BaseURL = server.URL
+
+	testCases := []struct {
+		name             string
+		cc               []*mail.Address
+		bcc              []*mail.Address
+		personalizations []map[string]interface{}
+	}{
+		{
+			// the cc recipients are sent to as to recipients
+			name: "cc only",
+			cc:   []*mail.Address{{Address: "ann@example.com"}, {Address: "bob@example.com"}},
+			bcc:  []*mail.Address{{Address: "bcc@example.com"}},
+			personalizations: []map[string]interface{}{{
+				"to":  []interface{}{map[string]interface{}{"email": "ann@example.com"}, map[string]interface{}{"email": "bob@example.com"}},
+				"bcc": []interface{}{map[string]interface{}{"email": "bcc@example.com"}},
+			}},
+		},
+		{
+			// every bcc recipient gets its own copy, so they are not disclosed to each other
+			name: "bcc only",
+			bcc:  []*mail.Address{{Address: "bcc@example.com"}, {Address: "other@example.com"}},
+			personalizations: []map[string]interface{}{
+				{"to": []interface{}{map[string]interface{}{"email": "bcc@example.com"}}},
+				{"to": []interface{}{map[string]interface{}{"email": "other@example.com"}}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			m := newMessage()
+			m.To, m.Cc, m.Bcc = nil, tc.cc, tc.bcc
+			body.Personalizations = nil
+
+			results, err := sendGrid.Send(context.Background(), m)
+			suite.NoError(err)
+			suite.Len(results, len(tc.cc)+len(tc.bcc))
+			suite.Equal(tc.personalizations, body.Personalizations)
+		})
+	}
+}
+
+func (suite *TestSuite) TestMailgun() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.Equal("/v3/mg.example.com/messages.mime", r.URL.Path)
+		username, password, ok := r.BasicAuth()
+		suite.True(ok)
+		suite.Equal("api", username)
+		suite.Equal("key", password)
+
+		suite.NoError(r.ParseMultipartForm(1 << 20))
+		suite.Equal([]string{"jane@example.com", "john@example.com", "ann@example.com", "bcc@example.com"}, r.MultipartForm.Value["to"])
+		file, _, err := r.FormFile("message")
+		suite.NoError(err)
+		data, err := io.ReadAll(file)
+		suite.NoError(err)
+		suite.Equal(string(newMessage().Raw), string(data))
+
+		_, _ = w.Write([]byte(`{"id": "<id@mg.example.com>", "message": "Queued. Thank you."}`))
+	}))
+	defer server.Close()
+
+	mailgun := transport.NewMailgun("mg.example.com", "key")
+	mailgun.BaseURL = server.URL
+
+	results, err := mailgun.Send(context.Background(), newMessage())
+	suite.NoError(err)
+	suite.Len(results, 4)
+}
+
+func (suite *TestSuite) TestSES() {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.Equal("/v2/email/outbound-emails", r.URL.Path)
+		suite.True(strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/"), r.Header.Get("Authorization"))
+		suite.Contains(r.Header.Get("Authorization"), "/eu-west-1/ses/aws4_request, SignedHeaders=content-type;host;x-amz-date;x-amz-security-token, Signature=")
+		suite.Equal("token", r.Header.Get("X-Amz-Security-Token"))
+		suite.NoError(json.NewDecoder(r.Body).Decode(&body))
+		_, _ = w.Write([]byte(`{"MessageId": "id"}`))
+	}))
+	defer server.Close()
+
+	ses := transport.NewSES(transport.SESConfig{Region: "eu-west-1", AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "token"})
+	suite.Equal("https://email.eu-west-1.amazonaws.com", ses.BaseURL)
+	ses.BaseURL = server.URL
+
+	results, err := ses.Send(context.Background(), newMessage())
+	suite.NoError(err)
+	suite.Len(results, 4)
+
+	suite.Equal(map[string]interface{}{
+		"FromEmailAddress": `"Example App" <from@example.com>`,
+		"Destination": map[string]interface{}{
+			"ToAddresses":  []interface{}{`"Jane" <jane@example.com>`, "<john@example.com>"},
+			"CcAddresses":  []interface{}{"<ann@example.com>"},
+			"BccAddresses": []interface{}{"<bcc@example.com>"},
+		},
+		"ReplyToAddresses": []interface{}{"<support@example.com>"},
+		"Content":          map[string]interface{}{"Raw": map[string]interface{}{"Data": base64.StdEncoding.EncodeToString(newMessage().Raw)}},
+	}, body)
+}
+
+func (suite *TestSuite) TestHTTPErrors() {
+	status := http.StatusBadRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"errors": [{"message": "invalid"}]}` + "\n"))
+	}))
+	defer server.Close()
+
+	sendGrid := transport.NewSendGrid("key")
+	sendGrid.BaseURL = server.URL
+	mailgun := transport.NewMailgun("mg.example.com", "key")
+	mailgun.BaseURL = server.URL
+	ses := transport.NewSES(transport.SESConfig{Region: "eu-west-1", AccessKeyID: "AKID", SecretAccessKey: "secret"})
+	ses.BaseURL = server.URL
+
+	for name, t := range map[string]transport.Transport{"sendgrid": sendGrid, "mailgun": mailgun, "ses": ses} {
+		suite.Run(name, func() {
+			status = http.StatusBadRequest
+			_, err := t.Send(context.Background(), newMessage())
+			suite.EqualError(err, name+`: 400 {"errors": [{"message": "invalid"}]}`)
+			suite.True(transport.IsPermanent(err))
+
+			status = http.StatusTooManyRequests
+			_, err = t.Send(context.Background(), newMessage())
+			suite.Error(err)
+			suite.False(transport.IsPermanent(err))
+		})
+	}
+}
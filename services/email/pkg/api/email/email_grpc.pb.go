@@ -23,6 +23,9 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type EmailServiceClient interface {
 	SendEmail(ctx context.Context, opts ...grpc.CallOption) (EmailService_SendEmailClient, error)
+	RegisterTemplate(ctx context.Context, in *Template, opts ...grpc.CallOption) (*Template, error)
+	RenderTemplate(ctx context.Context, in *RenderTemplateRequest, opts ...grpc.CallOption) (*RenderTemplateResponse, error)
+	GetEmailStatus(ctx context.Context, in *EmailStatusRequest, opts ...grpc.CallOption) (*EmailStatusResponse, error)
 }
 
 type emailServiceClient struct {
@@ -67,11 +70,41 @@ func (x *emailServiceSendEmailClient) CloseAndRecv() (*EmailResponse, error) {
 	return m, nil
 }
 
+func (c *emailServiceClient) RegisterTemplate(ctx context.Context, in *Template, opts ...grpc.CallOption) (*Template, error) {
+	out := new(Template)
+	err := c.cc.Invoke(ctx, "/pkg.email.EmailService/RegisterTemplate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *emailServiceClient) RenderTemplate(ctx context.Context, in *RenderTemplateRequest, opts ...grpc.CallOption) (*RenderTemplateResponse, error) {
+	out := new(RenderTemplateResponse)
+	err := c.cc.Invoke(ctx, "/pkg.email.EmailService/RenderTemplate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *emailServiceClient) GetEmailStatus(ctx context.Context, in *EmailStatusRequest, opts ...grpc.CallOption) (*EmailStatusResponse, error) {
+	out := new(EmailStatusResponse)
+	err := c.cc.Invoke(ctx, "/pkg.email.EmailService/GetEmailStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // EmailServiceServer is the server API for EmailService service.
 // All implementations must embed UnimplementedEmailServiceServer
 // for forward compatibility
 type EmailServiceServer interface {
 	SendEmail(EmailService_SendEmailServer) error
+	RegisterTemplate(context.Context, *Template) (*Template, error)
+	RenderTemplate(context.Context, *RenderTemplateRequest) (*RenderTemplateResponse, error)
+	GetEmailStatus(context.Context, *EmailStatusRequest) (*EmailStatusResponse, error)
 	mustEmbedUnimplementedEmailServiceServer()
 }
 
@@ -82,6 +115,15 @@ type UnimplementedEmailServiceServer struct {
 func (UnimplementedEmailServiceServer) SendEmail(EmailService_SendEmailServer) error {
 	return status.Errorf(codes.Unimplemented, "method SendEmail not implemented")
 }
+func (UnimplementedEmailServiceServer) RegisterTemplate(context.Context, *Template) (*Template, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterTemplate not implemented")
+}
+func (UnimplementedEmailServiceServer) RenderTemplate(context.Context, *RenderTemplateRequest) (*RenderTemplateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenderTemplate not implemented")
+}
+func (UnimplementedEmailServiceServer) GetEmailStatus(context.Context, *EmailStatusRequest) (*EmailStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEmailStatus not implemented")
+}
 func (UnimplementedEmailServiceServer) mustEmbedUnimplementedEmailServiceServer() {}
 
 // UnsafeEmailServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -121,13 +163,80 @@ func (x *emailServiceSendEmailServer) Recv() (*EmailRequest, error) {
 	return m, nil
 }
 
+func _EmailService_RegisterTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Template)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EmailServiceServer).RegisterTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.email.EmailService/RegisterTemplate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EmailServiceServer).RegisterTemplate(ctx, req.(*Template))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EmailService_RenderTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenderTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EmailServiceServer).RenderTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.email.EmailService/RenderTemplate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EmailServiceServer).RenderTemplate(ctx, req.(*RenderTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EmailService_GetEmailStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmailStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EmailServiceServer).GetEmailStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pkg.email.EmailService/GetEmailStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EmailServiceServer).GetEmailStatus(ctx, req.(*EmailStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // EmailService_ServiceDesc is the grpc.ServiceDesc for EmailService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var EmailService_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "pkg.email.EmailService",
 	HandlerType: (*EmailServiceServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RegisterTemplate",
+			Handler:    _EmailService_RegisterTemplate_Handler,
+		},
+		{
+			MethodName: "RenderTemplate",
+			Handler:    _EmailService_RenderTemplate_Handler,
+		},
+		{
+			MethodName: "GetEmailStatus",
+			Handler:    _EmailService_GetEmailStatus_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "SendEmail",
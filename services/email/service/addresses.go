@@ -0,0 +1,70 @@
+package service
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+
+	pb "github.com/accentdesign/grpc/services/email/pkg/api/email"
+)
+
+// addresses are the parsed addresses of an EmailInfo.
+type addresses struct {
+	from    *mail.Address
+	to      []*mail.Address
+	cc      []*mail.Address
+	bcc     []*mail.Address
+	replyTo []*mail.Address
+}
+
+// parseAddresses parses the addresses of the email, returning ErrAddressInvalid for the first invalid one.
+// The deprecated to_address is sent to along with the to addresses.
+func parseAddresses(info *pb.EmailInfo) (*addresses, error) {
+	from, err := mail.ParseAddress(info.GetFromAddress())
+	if err != nil {
+		return nil, ErrAddressInvalid("email_info.from_address")
+	}
+	addrs := &addresses{from: from}
+
+	if toAddress := info.GetToAddress(); strings.TrimSpace(toAddress) != "" {
+		address, err := mail.ParseAddress(toAddress)
+		if err != nil {
+			return nil, ErrAddressInvalid("email_info.to_address")
+		}
+		addrs.to = append(addrs.to, address)
+	}
+
+	for _, list := range []struct {
+		field  string
+		values []string
+		dst    *[]*mail.Address
+	}{
+		{"to", info.GetTo(), &addrs.to},
+		{"cc", info.GetCc(), &addrs.cc},
+		{"bcc", info.GetBcc(), &addrs.bcc},
+		{"reply_to", info.GetReplyTo(), &addrs.replyTo},
+	} {
+		for i, value := range list.values {
+			address, err := mail.ParseAddress(value)
+			if err != nil {
+				return nil, ErrAddressInvalid(fmt.Sprintf("email_info.%s[%d]", list.field, i))
+			}
+			*list.dst = append(*list.dst, address)
+		}
+	}
+
+	return addrs, nil
+}
+
+// formatAddresses formats the addresses for a header, an address without a display name is written as is.
+func formatAddresses(list []*mail.Address) string {
+	formatted := make([]string, 0, len(list))
+	for _, address := range list {
+		if address.Name == "" {
+			formatted = append(formatted, address.Address)
+		} else {
+			formatted = append(formatted, address.String())
+		}
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+
+	pb "github.com/accentdesign/grpc/services/email/pkg/api/email"
+)
+
+// encodePayload encodes a queued email as the requests of its stream, the email info followed by the attachments.
+func encodePayload(info *pb.EmailInfo, attachments []*pb.Attachment) ([]byte, error) {
+	var buf bytes.Buffer
+	requests := []*pb.EmailRequest{{Payload: &pb.EmailRequest_EmailInfo{EmailInfo: info}}}
+	for _, attachment := range attachments {
+		requests = append(requests, &pb.EmailRequest{Payload: &pb.EmailRequest_Attachment{Attachment: attachment}})
+	}
+	for _, req := range requests {
+		if _, err := protodelim.MarshalTo(&buf, req); err != nil {
+			return nil, fmt.Errorf("error encoding email: %v", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// decodePayload decodes a queued email encoded by encodePayload.
+func decodePayload(payload []byte) (*pb.EmailInfo, []*pb.Attachment, error) {
+	var info *pb.EmailInfo
+	var attachments []*pb.Attachment
+
+	r := bufio.NewReader(bytes.NewReader(payload))
+	for {
+		req := &pb.EmailRequest{}
+		err := protodelim.UnmarshalOptions{MaxSize: -1}.UnmarshalFrom(r, req)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("error decoding email: %v", err)
+		}
+		if req.GetEmailInfo() != nil {
+			info = req.GetEmailInfo()
+		} else if req.GetAttachment() != nil {
+			attachments = append(attachments, req.GetAttachment())
+		}
+	}
+
+	if info == nil {
+		return nil, nil, errors.New("error decoding email: no email info")
+	}
+	return info, attachments, nil
+}
@@ -2,85 +2,148 @@ package service
 
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"log"
-	"net/smtp"
+	"net/mail"
 	"strings"
+	"time"
 
 	"github.com/asaskevich/govalidator"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
 
+	"github.com/accentdesign/grpc/core/errs"
 	"github.com/accentdesign/grpc/services/email/internal"
+	"github.com/accentdesign/grpc/services/email/internal/queue"
+	"github.com/accentdesign/grpc/services/email/internal/templates"
+	"github.com/accentdesign/grpc/services/email/internal/transport"
 	pb "github.com/accentdesign/grpc/services/email/pkg/api/email"
 )
 
+// ErrorDomain is the domain of the ErrorInfo details of errors returned by the service.
+const ErrorDomain = "email"
+
+var (
+	ErrAttachmentContentTypeRequired = errs.FieldError(codes.InvalidArgument, ErrorDomain, "CONTENT_TYPE_REQUIRED", "attachment.content_type", "content_type is required")
+	ErrAttachmentDataRequired        = errs.FieldError(codes.InvalidArgument, ErrorDomain, "DATA_REQUIRED", "attachment.data", "data is required")
+	ErrAttachmentFilenameRequired    = errs.FieldError(codes.InvalidArgument, ErrorDomain, "FILENAME_REQUIRED", "attachment.filename", "filename is required")
+	ErrBodyRequired                  = errs.WithFieldViolations(
+		errs.New(codes.InvalidArgument, ErrorDomain, "BODY_REQUIRED", "plain_text or html is required"),
+		errs.FieldViolation("email_info.plain_text", "plain_text or html is required"),
+		errs.FieldViolation("email_info.html", "plain_text or html is required"),
+	)
+	ErrBodyWithTemplate = errs.WithFieldViolations(
+		errs.New(codes.InvalidArgument, ErrorDomain, "BODY_WITH_TEMPLATE", "plain_text and html cannot be set with template_id"),
+		errs.FieldViolation("email_info.plain_text", "plain_text and html cannot be set with template_id"),
+		errs.FieldViolation("email_info.html", "plain_text and html cannot be set with template_id"),
+	)
+	ErrEmailInfoRequired    = errs.FieldError(codes.InvalidArgument, ErrorDomain, "EMAIL_INFO_REQUIRED", "email_info", "EmailInfo not found in stream")
+	ErrFromAddressRequired  = errs.FieldError(codes.InvalidArgument, ErrorDomain, "FROM_ADDRESS_REQUIRED", "email_info.from_address", "from_address is required")
+	ErrMessageIDRequired    = errs.FieldError(codes.InvalidArgument, ErrorDomain, "MESSAGE_ID_REQUIRED", "message_id", "message_id is required")
+	ErrMessageNotFound      = errs.Error(codes.NotFound, ErrorDomain, "MESSAGE_NOT_FOUND", "message not found")
+	ErrQueueFailed          = errs.Error(codes.Unavailable, ErrorDomain, "QUEUE_FAILED", "the email could not be queued, try again later")
+	ErrQueueNotEnabled      = errs.Error(codes.FailedPrecondition, ErrorDomain, "QUEUE_NOT_ENABLED", "the email queue is not enabled")
+	ErrSubjectRequired      = errs.FieldError(codes.InvalidArgument, ErrorDomain, "SUBJECT_REQUIRED", "email_info.subject", "subject is required")
+	ErrTemplateBodyRequired = errs.WithFieldViolations(
+		errs.New(codes.InvalidArgument, ErrorDomain, "BODY_REQUIRED", "plain_text or html is required"),
+		errs.FieldViolation("plain_text", "plain_text or html is required"),
+		errs.FieldViolation("html", "plain_text or html is required"),
+	)
+	ErrTemplateIDInvalid       = errs.FieldError(codes.InvalidArgument, ErrorDomain, "TEMPLATE_ID_INVALID", "id", templates.ErrIDInvalid.Error())
+	ErrTemplateIDRequired      = errs.FieldError(codes.InvalidArgument, ErrorDomain, "TEMPLATE_ID_REQUIRED", "template_id", "template_id is required")
+	ErrTemplateNotFound        = errs.Error(codes.NotFound, ErrorDomain, "TEMPLATE_NOT_FOUND", "template not found")
+	ErrTemplateSubjectRequired = errs.FieldError(codes.InvalidArgument, ErrorDomain, "SUBJECT_REQUIRED", "subject", "subject is required")
+	ErrToAddressRequired       = errs.WithFieldViolations(
+		errs.New(codes.InvalidArgument, ErrorDomain, "TO_ADDRESS_REQUIRED", "to, cc or bcc is required"),
+		errs.FieldViolation("email_info.to", "to, cc or bcc is required"),
+		errs.FieldViolation("email_info.cc", "to, cc or bcc is required"),
+		errs.FieldViolation("email_info.bcc", "to, cc or bcc is required"),
+	)
+)
+
+// ErrTemplateInvalid returns the error of a template that does not parse.
+func ErrTemplateInvalid(err error) error {
+	return errs.Error(codes.InvalidArgument, ErrorDomain, "TEMPLATE_INVALID", err.Error())
+}
+
+// ErrTemplateRender returns the error of a template that fails to render with the variables, such as when a
+// variable is missing.
+func ErrTemplateRender(err error) error {
+	return errs.Error(codes.InvalidArgument, ErrorDomain, "TEMPLATE_RENDER_FAILED", err.Error())
+}
+
+// ErrAddressInvalid returns the error of an address that is not a valid RFC 5322 address, field is the path of
+// the address in the request, e.g. email_info.to[1].
+func ErrAddressInvalid(field string) error {
+	return errs.FieldError(codes.InvalidArgument, ErrorDomain, "ADDRESS_INVALID", field, fmt.Sprintf("%s is not a valid address", field))
+}
+
 type EmailServer struct {
 	pb.UnimplementedEmailServiceServer
 	boundaryGenerator internal.BoundaryGenerator
-	host              string
-	port              int64
-	username          string
-	password          string
-	startTLS          bool
-
-	auth smtp.Auth
-	conn *smtp.Client
+	templates         *templates.Store
+	queue             *queue.Queue
+	transport         transport.Transport
 }
 
+// NewEmailServer returns a server sending emails to the smtp server, after connecting to it to check the settings.
 func NewEmailServer(host string, port int64, username string, password string, startTLS bool) (*EmailServer, error) {
-	s := &EmailServer{
-		boundaryGenerator: &internal.DefaultBoundaryGenerator{},
-		host:              host,
-		port:              port,
-		username:          username,
-		password:          password,
-		startTLS:          startTLS,
+	smtp := transport.NewSMTP(transport.SMTPConfig{Host: host, Port: port, Username: username, Password: password, StartTLS: startTLS})
+	if err := smtp.Check(); err != nil {
+		return nil, fmt.Errorf("failed to initialize email server: error setting up SMTP connection: %v", err)
 	}
-	if err := s.init(); err != nil {
-		return nil, fmt.Errorf("failed to initialize email server: %v", err)
-	}
-	return s, nil
+	return NewEmailServerWithTransport(smtp), nil
 }
 
-func (s *EmailServer) init() error {
-	var err error
-	s.auth, s.conn, err = s.setupSMTPConnection()
-	if err != nil {
-		return fmt.Errorf("error setting up SMTP connection: %v", err)
+// NewEmailServerWithTransport returns a server sending emails with the transport.
+func NewEmailServerWithTransport(t transport.Transport) *EmailServer {
+	return &EmailServer{
+		boundaryGenerator: &internal.DefaultBoundaryGenerator{},
+		templates:         templates.NewStore(),
+		transport:         t,
 	}
-	return nil
 }
 
 func (s *EmailServer) SetBoundaryGenerator(boundaryGenerator internal.BoundaryGenerator) {
 	s.boundaryGenerator = boundaryGenerator
 }
 
+// SetTemplates replaces the templates emails are rendered from, such as with the templates loaded from a directory.
+func (s *EmailServer) SetTemplates(store *templates.Store) {
+	s.templates = store
+}
+
+// SetQueue enables the queued mode, emails are stored in the queue and delivered in the background instead of
+// being sent by SendEmail. The queue should deliver with Deliver.
+func (s *EmailServer) SetQueue(q *queue.Queue) {
+	s.queue = q
+}
+
 func (s *EmailServer) SendEmail(stream pb.EmailService_SendEmailServer) error {
 	var emailInfo *pb.EmailInfo
+	var addrs *addresses
 	var attachments []*pb.Attachment
 
 	for {
 		req, err := stream.Recv()
 		if err == io.EOF {
 			if emailInfo == nil {
-				return status.Error(codes.InvalidArgument, "EmailInfo not found in stream")
+				return ErrEmailInfoRequired
 			}
-			sendErr := s.send(emailInfo, attachments)
-			success := sendErr == nil
-			message := ""
-			if success {
-				message = "Email sent successfully"
-			} else {
-				message = sendErr.Error()
+			if s.queue != nil {
+				resp, err := s.enqueue(emailInfo, attachments)
+				if err != nil {
+					return err
+				}
+				return stream.SendAndClose(resp)
 			}
-			return stream.SendAndClose(&pb.EmailResponse{
-				Success: success,
-				Message: message,
-			})
+			results, sendErr := s.send(stream.Context(), emailInfo, addrs, attachments)
+			return stream.SendAndClose(sendResponse(results, sendErr))
 		}
 		if err != nil {
 			return err
@@ -88,104 +151,287 @@ func (s *EmailServer) SendEmail(stream pb.EmailService_SendEmailServer) error {
 
 		switch payload := req.Payload.(type) {
 		case *pb.EmailRequest_EmailInfo:
-			if govalidator.IsNull(payload.EmailInfo.GetFromAddress()) {
-				return status.Error(codes.InvalidArgument, "from_address is required")
-			}
-			if govalidator.IsNull(payload.EmailInfo.GetToAddress()) {
-				return status.Error(codes.InvalidArgument, "to_address is required")
-			}
-			if govalidator.IsNull(payload.EmailInfo.GetSubject()) {
-				return status.Error(codes.InvalidArgument, "subject is required")
+			emailInfo, addrs, err = s.prepareEmailInfo(payload.EmailInfo)
+			if err != nil {
+				return err
 			}
-			if govalidator.IsNull(payload.EmailInfo.GetPlainText()) && govalidator.IsNull(payload.EmailInfo.GetHtml()) {
-				return status.Error(codes.InvalidArgument, "plain_text or html is required")
-			}
-			emailInfo = payload.EmailInfo
 		case *pb.EmailRequest_Attachment:
 			if govalidator.IsNull(payload.Attachment.GetFilename()) {
-				return status.Error(codes.InvalidArgument, "filename is required")
+				return ErrAttachmentFilenameRequired
 			}
 			if len(payload.Attachment.GetData()) == 0 {
-				return status.Error(codes.InvalidArgument, "data is required")
+				return ErrAttachmentDataRequired
 			}
 			if govalidator.IsNull(payload.Attachment.GetContentType()) {
-				return status.Error(codes.InvalidArgument, "content_type is required")
+				return ErrAttachmentContentTypeRequired
 			}
 			attachments = append(attachments, payload.Attachment)
 		default:
-			return status.Errorf(codes.InvalidArgument, "unknown payload received: %T", payload)
+			return errs.Error(codes.InvalidArgument, ErrorDomain, "UNKNOWN_PAYLOAD", fmt.Sprintf("unknown payload received: %T", payload))
 		}
 	}
 }
 
-func (s *EmailServer) send(info *pb.EmailInfo, attachments []*pb.Attachment) error {
-	log.Printf("EmailInfo: %v", info)
-	log.Printf("Attachments: %v", len(attachments))
+// prepareEmailInfo validates the email, rendering its template when it has one, and parses its addresses.
+func (s *EmailServer) prepareEmailInfo(info *pb.EmailInfo) (*pb.EmailInfo, *addresses, error) {
+	if govalidator.IsNull(info.GetFromAddress()) {
+		return nil, nil, ErrFromAddressRequired
+	}
+	addrs, err := parseAddresses(info)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(addrs.to)+len(addrs.cc)+len(addrs.bcc) == 0 {
+		return nil, nil, ErrToAddressRequired
+	}
+
+	if info.GetTemplateId() != "" {
+		if !govalidator.IsNull(info.GetPlainText()) || !govalidator.IsNull(info.GetHtml()) {
+			return nil, nil, ErrBodyWithTemplate
+		}
+		message, err := s.render(info.GetTemplateId(), info.GetVariables())
+		if err != nil {
+			return nil, nil, err
+		}
+		info = proto.Clone(info).(*pb.EmailInfo)
+		if govalidator.IsNull(info.GetSubject()) {
+			info.Subject = message.Subject
+		}
+		info.PlainText = message.PlainText
+		info.Html = message.Html
+	}
 
-	serverAddr := fmt.Sprintf("%s:%d", s.host, s.port)
-	from := info.GetFromAddress()
-	to := []string{info.GetToAddress()}
-	subject := info.GetSubject()
-	plainText := info.GetPlainText()
-	htmlBody := info.GetHtml()
+	if govalidator.IsNull(info.GetSubject()) {
+		return nil, nil, ErrSubjectRequired
+	}
+	if govalidator.IsNull(info.GetPlainText()) && govalidator.IsNull(info.GetHtml()) {
+		return nil, nil, ErrBodyRequired
+	}
+	return info, addrs, nil
+}
 
-	var err error
-	s.auth, s.conn, err = s.setupSMTPConnection()
+// render renders the template with the id for the variables.
+func (s *EmailServer) render(id string, variables *structpb.Struct) (*templates.Message, error) {
+	message, err := s.templates.Render(id, variables.AsMap())
 	if err != nil {
-		return err
+		if errors.Is(err, templates.ErrNotFound) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, ErrTemplateRender(err)
 	}
+	return message, nil
+}
 
-	boundary, err := s.boundaryGenerator.GetBoundary()
+// RegisterTemplate adds a template emails can be rendered from, replacing the template with the same id.
+// Registered templates are kept in memory, so they are lost when the server restarts.
+func (s *EmailServer) RegisterTemplate(_ context.Context, in *pb.Template) (*pb.Template, error) {
+	err := s.templates.Register(in.GetId(), templates.Source{
+		Subject: in.GetSubject(),
+		Text:    in.GetPlainText(),
+		Html:    in.GetHtml(),
+	})
 	if err != nil {
-		return err
+		switch {
+		case errors.Is(err, templates.ErrIDInvalid):
+			return nil, ErrTemplateIDInvalid
+		case errors.Is(err, templates.ErrSubjectRequired):
+			return nil, ErrTemplateSubjectRequired
+		case errors.Is(err, templates.ErrBodyRequired):
+			return nil, ErrTemplateBodyRequired
+		default:
+			return nil, ErrTemplateInvalid(err)
+		}
 	}
+	return in, nil
+}
 
-	message, err := createEmailMessage(from, to, subject, plainText, htmlBody, boundary, attachments)
+// RenderTemplate renders a template with the variables without sending it, to preview it.
+func (s *EmailServer) RenderTemplate(_ context.Context, in *pb.RenderTemplateRequest) (*pb.RenderTemplateResponse, error) {
+	if govalidator.IsNull(in.GetTemplateId()) {
+		return nil, ErrTemplateIDRequired
+	}
+	message, err := s.render(in.GetTemplateId(), in.GetVariables())
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	return smtp.SendMail(serverAddr, s.auth, from, to, message.Bytes())
+	return &pb.RenderTemplateResponse{
+		Subject:   message.Subject,
+		PlainText: message.PlainText,
+		Html:      message.Html,
+	}, nil
 }
 
-func (s *EmailServer) setupSMTPConnection() (smtp.Auth, *smtp.Client, error) {
-	serverAddr := fmt.Sprintf("%s:%d", s.host, s.port)
+// enqueue stores the email in the queue, returning the response with its message id.
+func (s *EmailServer) enqueue(info *pb.EmailInfo, attachments []*pb.Attachment) (*pb.EmailResponse, error) {
+	payload, err := encodePayload(info, attachments)
+	if err != nil {
+		return nil, errs.Error(codes.Internal, ErrorDomain, "INTERNAL", err.Error())
+	}
+	m, err := s.queue.Enqueue(payload)
+	if err != nil {
+		log.Printf("error queueing email: %v", err)
+		return nil, ErrQueueFailed
+	}
+	return &pb.EmailResponse{Success: true, Message: "Email queued", MessageId: m.ID}, nil
+}
 
-	conn, err := smtp.Dial(serverAddr)
+// Deliver sends a queued email, it is the queue.DeliverFunc of the queue set with SetQueue. Replies of the smtp
+// server that are permanent failures are not retried.
+func (s *EmailServer) Deliver(payload []byte) ([]queue.Recipient, error) {
+	info, attachments, err := decodePayload(payload)
 	if err != nil {
-		return nil, nil, err
+		return nil, queue.Permanent(err)
+	}
+	addrs, err := parseAddresses(info)
+	if err != nil {
+		return nil, queue.Permanent(err)
 	}
 
-	defer func() {
-		if err != nil {
-			if closeErr := conn.Close(); closeErr != nil {
-				log.Printf("Error closing SMTP connection after setup error: %v", closeErr)
-			}
-		}
-	}()
+	results, err := s.send(context.Background(), info, addrs, attachments)
+	recipients := make([]queue.Recipient, 0, len(results))
+	for _, result := range results {
+		recipients = append(recipients, queue.Recipient{
+			Address:  result.GetAddress(),
+			Accepted: result.GetAccepted(),
+			Message:  result.GetMessage(),
+		})
+	}
 
-	if s.startTLS {
-		tlsConfig := &tls.Config{ServerName: s.host}
-		if err := conn.StartTLS(tlsConfig); err != nil {
-			return nil, nil, err
+	if transport.IsPermanent(err) {
+		return recipients, queue.Permanent(err)
+	}
+	return recipients, err
+}
+
+var emailStatuses = map[queue.Status]pb.EmailStatus{
+	queue.StatusQueued: pb.EmailStatus_EMAIL_STATUS_QUEUED,
+	queue.StatusSent:   pb.EmailStatus_EMAIL_STATUS_SENT,
+	queue.StatusFailed: pb.EmailStatus_EMAIL_STATUS_FAILED,
+}
+
+func unix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// GetEmailStatus returns the delivery status of a queued email.
+func (s *EmailServer) GetEmailStatus(_ context.Context, in *pb.EmailStatusRequest) (*pb.EmailStatusResponse, error) {
+	if s.queue == nil {
+		return nil, ErrQueueNotEnabled
+	}
+	if govalidator.IsNull(in.GetMessageId()) {
+		return nil, ErrMessageIDRequired
+	}
+
+	m, err := s.queue.Get(in.GetMessageId())
+	if err != nil {
+		if errors.Is(err, queue.ErrNotFound) {
+			return nil, ErrMessageNotFound
 		}
+		return nil, errs.Error(codes.Internal, ErrorDomain, "INTERNAL", err.Error())
 	}
 
-	var auth smtp.Auth
-	if s.username != "" && s.password != "" {
-		auth = smtp.PlainAuth("", s.username, s.password, s.host)
-		if err := conn.Auth(auth); err != nil {
-			return nil, nil, err
+	resp := &pb.EmailStatusResponse{
+		MessageId: m.ID,
+		Status:    emailStatuses[m.Status],
+		Attempts:  int32(m.Attempts),
+		LastError: m.LastError,
+		CreatedAt: unix(m.CreatedAt),
+		SentAt:    unix(m.SentAt),
+	}
+	if m.Status == queue.StatusQueued {
+		resp.NextAttemptAt = unix(m.NextAttemptAt)
+	}
+	for _, recipient := range m.Recipients {
+		resp.Recipients = append(resp.Recipients, &pb.RecipientResult{
+			Address:  recipient.Address,
+			Accepted: recipient.Accepted,
+			Message:  recipient.Message,
+		})
+	}
+	return resp, nil
+}
+
+// sendResponse returns the response to an email sent to the recipients of results, or that failed to send with err.
+func sendResponse(results []*pb.RecipientResult, err error) *pb.EmailResponse {
+	if err != nil {
+		return &pb.EmailResponse{Success: false, Message: err.Error(), Recipients: results}
+	}
+
+	accepted := 0
+	for _, result := range results {
+		if result.GetAccepted() {
+			accepted++
 		}
 	}
+	message := "Email sent successfully"
+	if accepted < len(results) {
+		message = fmt.Sprintf("Email sent to %d of %d recipients", accepted, len(results))
+	}
+	return &pb.EmailResponse{Success: true, Message: message, Recipients: results}
+}
+
+// send sends the email with the transport, returning the result of every recipient.
+func (s *EmailServer) send(ctx context.Context, info *pb.EmailInfo, addrs *addresses, attachments []*pb.Attachment) ([]*pb.RecipientResult, error) {
+	subject := info.GetSubject()
+	plainText := info.GetPlainText()
+	htmlBody := info.GetHtml()
+
+	boundary, err := s.boundaryGenerator.GetBoundary()
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := createEmailMessage(addrs, subject, plainText, htmlBody, boundary, attachments)
+	if err != nil {
+		return nil, err
+	}
 
-	return auth, conn, nil
+	m := &transport.Message{
+		From:      addrs.from,
+		To:        addrs.to,
+		Cc:        addrs.cc,
+		Bcc:       addrs.bcc,
+		ReplyTo:   addrs.replyTo,
+		Subject:   subject,
+		PlainText: plainText,
+		Html:      htmlBody,
+		Raw:       message.Bytes(),
+	}
+	for _, attachment := range attachments {
+		m.Attachments = append(m.Attachments, transport.Attachment{
+			Filename:    attachment.GetFilename(),
+			ContentType: attachment.GetContentType(),
+			Data:        attachment.GetData(),
+		})
+	}
+
+	// the content is never logged, emails carry tokens and login codes
+	log.Printf("sending email to %s with %d attachments", strings.Join(m.Recipients(), ", "), len(m.Attachments))
+
+	sent, err := s.transport.Send(ctx, m)
+	results := make([]*pb.RecipientResult, 0, len(sent))
+	for _, result := range sent {
+		results = append(results, &pb.RecipientResult{Address: result.Address, Accepted: result.Accepted, Message: result.Message})
+	}
+	return results, err
 }
 
-func createEmailMessage(from string, to []string, subject, plainText, htmlBody, boundary string, attachments []*pb.Attachment) (*bytes.Buffer, error) {
+func createEmailMessage(addrs *addresses, subject, plainText, htmlBody, boundary string, attachments []*pb.Attachment) (*bytes.Buffer, error) {
 	message := bytes.NewBuffer(nil)
-	message.WriteString(fmt.Sprintf("From: %s\r\n", from))
-	message.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(to, ",")))
+	message.WriteString(fmt.Sprintf("From: %s\r\n", formatAddresses([]*mail.Address{addrs.from})))
+	// bcc addresses are left out of the headers, they are only recipients of the envelope
+	if len(addrs.to) > 0 {
+		message.WriteString(fmt.Sprintf("To: %s\r\n", formatAddresses(addrs.to)))
+	}
+	if len(addrs.cc) > 0 {
+		message.WriteString(fmt.Sprintf("Cc: %s\r\n", formatAddresses(addrs.cc)))
+	}
+	if len(addrs.replyTo) > 0 {
+		message.WriteString(fmt.Sprintf("Reply-To: %s\r\n", formatAddresses(addrs.replyTo)))
+	}
 	message.WriteString(fmt.Sprintf("Subject: %s\r\n", subject))
 	message.WriteString("MIME-Version: 1.0\r\n")
 	message.WriteString(fmt.Sprintf("Content-Type: multipart/mixed; boundary=outer-%s\r\n\r\n", boundary))
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"os"
 	"runtime"
 	"strings"
 	"testing"
@@ -16,11 +17,13 @@ import (
 	smtpmock "github.com/mocktools/go-smtp-mock/v2"
 	"github.com/stretchr/testify/suite"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
 
+	"github.com/accentdesign/grpc/core/errs"
+	"github.com/accentdesign/grpc/services/email/internal/queue"
+	"github.com/accentdesign/grpc/services/email/internal/transport"
 	pb "github.com/accentdesign/grpc/services/email/pkg/api/email"
 	"github.com/accentdesign/grpc/services/email/service"
 )
@@ -29,6 +32,14 @@ func last[E any](s []E) E {
 	return s[len(s)-1]
 }
 
+func fieldNames(err error) []string {
+	var fields []string
+	for _, violation := range errs.FieldViolations(err) {
+		fields = append(fields, violation.GetField())
+	}
+	return fields
+}
+
 type MockBoundaryGenerator struct{}
 
 func (g *MockBoundaryGenerator) GetBoundary() (string, error) {
@@ -50,7 +61,11 @@ func (suite *TestSuite) SetupSuite() {
 }
 
 func setupServer() (*smtpmock.Server, *bufconn.Listener) {
-	mockServer := smtpmock.New(smtpmock.ConfigurationAttr{})
+	mockServer := smtpmock.New(smtpmock.ConfigurationAttr{
+		MultipleRcptto:            true,
+		BlacklistedRcpttoEmails:   []string{"rejected@example.com", "also-rejected@example.com"},
+		MsgRcpttoBlacklistedEmail: "550 Mailbox unavailable",
+	})
 	if err := mockServer.Start(); err != nil {
 		fmt.Println(err)
 	}
@@ -58,11 +73,7 @@ func setupServer() (*smtpmock.Server, *bufconn.Listener) {
 	lis := bufconn.Listen(1024 * 1024)
 	srv := grpc.NewServer()
 
-	hostAddress, portNumber := "127.0.0.1", mockServer.PortNumber()
-	emailServer, sErr := service.NewEmailServer(hostAddress, int64(portNumber), "", "", false)
-	if sErr != nil {
-		log.Fatalf("Error defining service: %v", sErr)
-	}
+	emailServer := service.NewEmailServerWithTransport(newTransport(mockServer))
 	emailServer.SetBoundaryGenerator(&MockBoundaryGenerator{})
 	pb.RegisterEmailServiceServer(srv, emailServer)
 
@@ -75,6 +86,13 @@ func setupServer() (*smtpmock.Server, *bufconn.Listener) {
 	return mockServer, lis
 }
 
+func newTransport(mockServer *smtpmock.Server) *transport.SMTP {
+	smtp := transport.NewSMTP(transport.SMTPConfig{Host: "127.0.0.1", Port: int64(mockServer.PortNumber())})
+	// the mock server records a message when its session ends
+	smtp.Pool.MaxMessages = 1
+	return smtp
+}
+
 func setupClientConn(lis *bufconn.Listener) (*grpc.ClientConn, error) {
 	conn, err := grpc.DialContext(context.Background(), "",
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
@@ -112,6 +130,15 @@ func (suite *TestSuite) waitForMessages() {
 	}
 }
 
+func (suite *TestSuite) TestNewEmailServer() {
+	_, err := service.NewEmailServer("127.0.0.1", int64(suite.emailServer.PortNumber()), "", "", false)
+	suite.NoError(err)
+
+	// the smtp server is checked
+	_, err = service.NewEmailServer("127.0.0.1", 1, "", "", false)
+	suite.ErrorContains(err, "error setting up SMTP connection")
+}
+
 func (suite *TestSuite) TestSendEmail_Validity() {
 	client := pb.NewEmailServiceClient(suite.grpcConn)
 
@@ -121,14 +148,20 @@ func (suite *TestSuite) TestSendEmail_Validity() {
 		attachment    *pb.Attachment
 		expectedError error
 	}{
-		{"no email info", nil, &pb.Attachment{Filename: "test.txt", Data: []byte("123"), ContentType: "text/plain"}, status.Error(codes.InvalidArgument, "EmailInfo not found in stream")},
-		{"missing from address", &pb.EmailInfo{}, nil, status.Error(codes.InvalidArgument, "from_address is required")},
-		{"missing to address", &pb.EmailInfo{FromAddress: "from@mail.com"}, nil, status.Error(codes.InvalidArgument, "to_address is required")},
-		{"missing subject", &pb.EmailInfo{FromAddress: "from@mail.com", ToAddress: "to@mail.com"}, nil, status.Error(codes.InvalidArgument, "subject is required")},
-		{"missing html or plain", &pb.EmailInfo{FromAddress: "from@mail.com", ToAddress: "to@mail.com", Subject: "Hi"}, nil, status.Error(codes.InvalidArgument, "plain_text or html is required")},
-		{"missing filename", &pb.EmailInfo{FromAddress: "from@mail.com", ToAddress: "to@mail.com", Subject: "Hi", PlainText: "hi"}, &pb.Attachment{}, status.Error(codes.InvalidArgument, "filename is required")},
-		{"missing data", &pb.EmailInfo{FromAddress: "from@mail.com", ToAddress: "to@mail.com", Subject: "Hi", PlainText: "hi"}, &pb.Attachment{Filename: "test.txt"}, status.Error(codes.InvalidArgument, "data is required")},
-		{"missing content type", &pb.EmailInfo{FromAddress: "from@mail.com", ToAddress: "to@mail.com", Subject: "Hi", PlainText: "hi"}, &pb.Attachment{Filename: "test.txt", Data: []byte("123")}, status.Error(codes.InvalidArgument, "content_type is required")},
+		{"no email info", nil, &pb.Attachment{Filename: "test.txt", Data: []byte("123"), ContentType: "text/plain"}, service.ErrEmailInfoRequired},
+		{"missing from address", &pb.EmailInfo{}, nil, service.ErrFromAddressRequired},
+		{"missing to address", &pb.EmailInfo{FromAddress: "from@mail.com"}, nil, service.ErrToAddressRequired},
+		{"invalid from address", &pb.EmailInfo{FromAddress: "from"}, nil, service.ErrAddressInvalid("email_info.from_address")},
+		{"invalid to address", &pb.EmailInfo{FromAddress: "from@mail.com", ToAddress: "to"}, nil, service.ErrAddressInvalid("email_info.to_address")},
+		{"invalid to", &pb.EmailInfo{FromAddress: "from@mail.com", To: []string{"to@mail.com", "to"}}, nil, service.ErrAddressInvalid("email_info.to[1]")},
+		{"invalid cc", &pb.EmailInfo{FromAddress: "from@mail.com", To: []string{"to@mail.com"}, Cc: []string{"Cc <cc"}}, nil, service.ErrAddressInvalid("email_info.cc[0]")},
+		{"invalid bcc", &pb.EmailInfo{FromAddress: "from@mail.com", Bcc: []string{""}}, nil, service.ErrAddressInvalid("email_info.bcc[0]")},
+		{"invalid reply to", &pb.EmailInfo{FromAddress: "from@mail.com", To: []string{"to@mail.com"}, ReplyTo: []string{"reply"}}, nil, service.ErrAddressInvalid("email_info.reply_to[0]")},
+		{"missing subject", &pb.EmailInfo{FromAddress: "from@mail.com", ToAddress: "to@mail.com"}, nil, service.ErrSubjectRequired},
+		{"missing html or plain", &pb.EmailInfo{FromAddress: "from@mail.com", ToAddress: "to@mail.com", Subject: "Hi"}, nil, service.ErrBodyRequired},
+		{"missing filename", &pb.EmailInfo{FromAddress: "from@mail.com", ToAddress: "to@mail.com", Subject: "Hi", PlainText: "hi"}, &pb.Attachment{}, service.ErrAttachmentFilenameRequired},
+		{"missing data", &pb.EmailInfo{FromAddress: "from@mail.com", ToAddress: "to@mail.com", Subject: "Hi", PlainText: "hi"}, &pb.Attachment{Filename: "test.txt"}, service.ErrAttachmentDataRequired},
+		{"missing content type", &pb.EmailInfo{FromAddress: "from@mail.com", ToAddress: "to@mail.com", Subject: "Hi", PlainText: "hi"}, &pb.Attachment{Filename: "test.txt", Data: []byte("123")}, service.ErrAttachmentContentTypeRequired},
 	}
 
 	for _, tc := range testErrorCases {
@@ -148,6 +181,8 @@ func (suite *TestSuite) TestSendEmail_Validity() {
 
 			_, err = stream.CloseAndRecv()
 			suite.EqualError(err, tc.expectedError.Error())
+			suite.Equal(errs.Reason(tc.expectedError), errs.Reason(err))
+			suite.Equal(fieldNames(tc.expectedError), fieldNames(err))
 		})
 	}
 
@@ -159,6 +194,8 @@ func (suite *TestSuite) TestSendEmail_Validity() {
 		{"plain", &pb.EmailInfo{FromAddress: "from@mail.com", ToAddress: "to@mail.com", Subject: "Hi", PlainText: "Hi"}, nil},
 		{"html", &pb.EmailInfo{FromAddress: "from@mail.com", ToAddress: "to@mail.com", Subject: "Hi", Html: "<p>Hi</p>"}, nil},
 		{"both", &pb.EmailInfo{FromAddress: "from@mail.com", ToAddress: "to@mail.com", Subject: "Hi", PlainText: "Hi", Html: "<p>Hi</p>"}, nil},
+		{"to", &pb.EmailInfo{FromAddress: "From <from@mail.com>", To: []string{"To <to@mail.com>"}, Subject: "Hi", PlainText: "Hi"}, nil},
+		{"bcc only", &pb.EmailInfo{FromAddress: "from@mail.com", Bcc: []string{"bcc@mail.com"}, Subject: "Hi", PlainText: "Hi"}, nil},
 		{"with attachment", &pb.EmailInfo{FromAddress: "from@mail.com", ToAddress: "to@mail.com", Subject: "Hi", PlainText: "Hi", Html: "<p>Hi</p>"}, &pb.Attachment{Filename: "test.txt", Data: []byte("123"), ContentType: "text/plain"}},
 	}
 
@@ -521,6 +558,301 @@ func (suite *TestSuite) TestSendEmail_MissingEmailInfo() {
 	suite.NoError(err)
 
 	_, err = stream.CloseAndRecv()
-	expected := status.Error(codes.InvalidArgument, "EmailInfo not found in stream")
+	expected := service.ErrEmailInfoRequired
 	suite.EqualError(err, expected.Error())
+	suite.Equal("EMAIL_INFO_REQUIRED", errs.Reason(err))
+}
+
+func (suite *TestSuite) TestSendEmail_Recipients() {
+	client := pb.NewEmailServiceClient(suite.grpcConn)
+
+	stream, err := client.SendEmail(context.Background())
+	suite.NoError(err)
+
+	err = stream.Send(&pb.EmailRequest{
+		Payload: &pb.EmailRequest_EmailInfo{
+			EmailInfo: &pb.EmailInfo{
+				FromAddress: `"Example App" <from@example.com>`,
+				To:          []string{`"Doe, Jane" <jane@example.com>`, "john@example.com"},
+				Cc:          []string{"Ann <ann@example.com>", "rejected@example.com"},
+				Bcc:         []string{"bcc@example.com", "JANE@example.com"},
+				ReplyTo:     []string{"Support <support@example.com>"},
+				Subject:     "Test email",
+				PlainText:   "This is a test email",
+			},
+		},
+	})
+	suite.NoError(err)
+
+	response, err := stream.CloseAndRecv()
+	suite.NoError(err)
+
+	// the email is sent to the accepted recipients, duplicates are only sent to once
+	suite.True(response.Success)
+	suite.Equal("Email sent to 4 of 5 recipients", response.Message)
+	suite.Len(response.Recipients, 5)
+	var rejected []string
+	for _, recipient := range response.Recipients {
+		if !recipient.Accepted {
+			rejected = append(rejected, recipient.Address)
+			suite.Contains(recipient.Message, "550 Mailbox unavailable")
+		}
+	}
+	suite.Equal([]string{"rejected@example.com"}, rejected)
+
+	suite.waitForMessages()
+
+	message := last(suite.emailServer.Messages())
+
+	suite.Equal("MAIL FROM:<from@example.com>", message.MailfromRequest())
+	var rcpts []string
+	for _, rcpt := range message.RcpttoRequestResponse() {
+		rcpts = append(rcpts, rcpt[0])
+	}
+	suite.Equal([]string{
+		"RCPT TO:<jane@example.com>",
+		"RCPT TO:<john@example.com>",
+		"RCPT TO:<ann@example.com>",
+		"RCPT TO:<rejected@example.com>",
+		"RCPT TO:<bcc@example.com>",
+	}, rcpts)
+
+	// bcc addresses are not in the headers
+	headers := strings.ReplaceAll(`From: "Example App" <from@example.com>
+To: "Doe, Jane" <jane@example.com>, john@example.com
+Cc: "Ann" <ann@example.com>, rejected@example.com
+Reply-To: "Support" <support@example.com>
+Subject: Test email
+MIME-Version: 1.0
+`, "\n", "\r\n")
+	suite.True(strings.HasPrefix(message.MsgRequest(), headers), message.MsgRequest())
+	suite.NotContains(message.MsgRequest(), "bcc@example.com")
+}
+
+func (suite *TestSuite) TestSendEmail_AllRecipientsRejected() {
+	client := pb.NewEmailServiceClient(suite.grpcConn)
+
+	stream, err := client.SendEmail(context.Background())
+	suite.NoError(err)
+
+	err = stream.Send(&pb.EmailRequest{
+		Payload: &pb.EmailRequest_EmailInfo{
+			EmailInfo: &pb.EmailInfo{
+				FromAddress: "from@example.com",
+				To:          []string{"rejected@example.com", "also-rejected@example.com"},
+				Subject:     "Test email",
+				PlainText:   "This is a test email",
+			},
+		},
+	})
+	suite.NoError(err)
+
+	response, err := stream.CloseAndRecv()
+	suite.NoError(err)
+
+	suite.False(response.Success)
+	suite.Equal("no recipients were accepted", response.Message)
+	suite.Len(response.Recipients, 2)
+	for _, recipient := range response.Recipients {
+		suite.False(recipient.Accepted)
+	}
+}
+
+func (suite *TestSuite) TestTemplates() {
+	client := pb.NewEmailServiceClient(suite.grpcConn)
+
+	testErrorCases := []struct {
+		desc          string
+		template      *pb.Template
+		expectedError error
+	}{
+		{"invalid id", &pb.Template{Id: "Welcome", Subject: "Hi", PlainText: "Hi"}, service.ErrTemplateIDInvalid},
+		{"missing subject", &pb.Template{Id: "welcome", PlainText: "Hi"}, service.ErrTemplateSubjectRequired},
+		{"missing body", &pb.Template{Id: "welcome", Subject: "Hi"}, service.ErrTemplateBodyRequired},
+	}
+
+	for _, tc := range testErrorCases {
+		suite.Run(tc.desc, func() {
+			_, err := client.RegisterTemplate(context.Background(), tc.template)
+			suite.EqualError(err, tc.expectedError.Error())
+			suite.Equal(errs.Reason(tc.expectedError), errs.Reason(err))
+			suite.Equal(fieldNames(tc.expectedError), fieldNames(err))
+		})
+	}
+
+	_, err := client.RegisterTemplate(context.Background(), &pb.Template{Id: "welcome", Subject: "Hi", PlainText: "{{.name"})
+	suite.Equal("TEMPLATE_INVALID", errs.Reason(err))
+
+	_, err = client.RegisterTemplate(context.Background(), &pb.Template{
+		Id:        "welcome",
+		Subject:   "Welcome {{.name}}",
+		PlainText: "Hi {{.name}}, you have {{.count}} messages",
+		Html:      "<p>Hi {{.name}}</p>",
+	})
+	suite.NoError(err)
+
+	variables, err := structpb.NewStruct(map[string]interface{}{"name": "<Jane>", "count": 2})
+	suite.NoError(err)
+
+	// templates can be previewed
+	preview, err := client.RenderTemplate(context.Background(), &pb.RenderTemplateRequest{TemplateId: "welcome", Variables: variables})
+	suite.NoError(err)
+	suite.Equal("Welcome <Jane>", preview.Subject)
+	suite.Equal("Hi <Jane>, you have 2 messages", preview.PlainText)
+	suite.Equal("<p>Hi &lt;Jane&gt;</p>", preview.Html)
+
+	_, err = client.RenderTemplate(context.Background(), &pb.RenderTemplateRequest{})
+	suite.EqualError(err, service.ErrTemplateIDRequired.Error())
+	_, err = client.RenderTemplate(context.Background(), &pb.RenderTemplateRequest{TemplateId: "unknown"})
+	suite.EqualError(err, service.ErrTemplateNotFound.Error())
+	_, err = client.RenderTemplate(context.Background(), &pb.RenderTemplateRequest{TemplateId: "welcome"})
+	suite.Equal("TEMPLATE_RENDER_FAILED", errs.Reason(err))
+
+	send := func(info *pb.EmailInfo) error {
+		stream, err := client.SendEmail(context.Background())
+		suite.NoError(err)
+		err = stream.Send(&pb.EmailRequest{Payload: &pb.EmailRequest_EmailInfo{EmailInfo: info}})
+		suite.NoError(err)
+		_, err = stream.CloseAndRecv()
+		return err
+	}
+
+	err = send(&pb.EmailInfo{FromAddress: "from@example.com", To: []string{"to@example.com"}, TemplateId: "welcome", PlainText: "Hi"})
+	suite.EqualError(err, service.ErrBodyWithTemplate.Error())
+	err = send(&pb.EmailInfo{FromAddress: "from@example.com", To: []string{"to@example.com"}, TemplateId: "unknown"})
+	suite.EqualError(err, service.ErrTemplateNotFound.Error())
+
+	// emails are rendered from the template, the subject can be overridden
+	err = send(&pb.EmailInfo{FromAddress: "from@example.com", To: []string{"template@example.com"}, TemplateId: "welcome", Variables: variables})
+	suite.NoError(err)
+
+	suite.waitForMessages()
+	message := last(suite.emailServer.Messages())
+	suite.Contains(message.MsgRequest(), "To: template@example.com\r\nSubject: Welcome <Jane>\r\n")
+	suite.Contains(message.MsgRequest(), "Hi <Jane>, you have 2 messages")
+	suite.Contains(message.MsgRequest(), "<p>Hi &lt;Jane&gt;</p>")
+
+	err = send(&pb.EmailInfo{FromAddress: "from@example.com", To: []string{"template@example.com"}, Subject: "Hello", TemplateId: "welcome", Variables: variables})
+	suite.NoError(err)
+
+	suite.waitForMessages()
+	message = last(suite.emailServer.Messages())
+	suite.Contains(message.MsgRequest(), "Subject: Hello\r\n")
+}
+
+func (suite *TestSuite) TestQueue() {
+	client := pb.NewEmailServiceClient(suite.grpcConn)
+
+	_, err := client.GetEmailStatus(context.Background(), &pb.EmailStatusRequest{MessageId: "00000000-0000-0000-0000-000000000000"})
+	suite.EqualError(err, service.ErrQueueNotEnabled.Error())
+
+	// a server queueing emails to the same smtp server
+	emailServer := service.NewEmailServerWithTransport(newTransport(suite.emailServer))
+	store, err := queue.Open(suite.T().TempDir())
+	suite.NoError(err)
+	q := queue.New(store, emailServer.Deliver)
+	q.PollInterval = 10 * time.Millisecond
+	emailServer.SetQueue(q)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.Run(ctx)
+
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer()
+	pb.RegisterEmailServiceServer(srv, emailServer)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	defer srv.Stop()
+
+	conn, err := setupClientConn(lis)
+	suite.NoError(err)
+	client = pb.NewEmailServiceClient(conn)
+
+	send := func(to ...string) *pb.EmailResponse {
+		stream, err := client.SendEmail(context.Background())
+		suite.NoError(err)
+		err = stream.Send(&pb.EmailRequest{
+			Payload: &pb.EmailRequest_EmailInfo{
+				EmailInfo: &pb.EmailInfo{FromAddress: "from@example.com", To: to, Subject: "Queued email", PlainText: "This is a queued email"},
+			},
+		})
+		suite.NoError(err)
+		response, err := stream.CloseAndRecv()
+		suite.NoError(err)
+		return response
+	}
+
+	waitForStatus := func(id string, status pb.EmailStatus) *pb.EmailStatusResponse {
+		var response *pb.EmailStatusResponse
+		suite.Eventually(func() bool {
+			var err error
+			response, err = client.GetEmailStatus(context.Background(), &pb.EmailStatusRequest{MessageId: id})
+			return err == nil && response.Status == status
+		}, 5*time.Second, 10*time.Millisecond)
+		return response
+	}
+
+	response := send("queued@example.com")
+	suite.True(response.Success)
+	suite.Equal("Email queued", response.Message)
+	suite.NotEmpty(response.MessageId)
+
+	status := waitForStatus(response.MessageId, pb.EmailStatus_EMAIL_STATUS_SENT)
+	suite.Equal(int32(1), status.Attempts)
+	suite.NotZero(status.CreatedAt)
+	suite.NotZero(status.SentAt)
+	suite.Zero(status.NextAttemptAt)
+	suite.Len(status.Recipients, 1)
+	suite.True(status.Recipients[0].Accepted)
+
+	suite.waitForMessages()
+	suite.Contains(last(suite.emailServer.Messages()).MsgRequest(), "Subject: Queued email\r\n")
+
+	// rejections of every recipient are permanent, they are not retried
+	response = send("rejected@example.com")
+	suite.True(response.Success)
+
+	status = waitForStatus(response.MessageId, pb.EmailStatus_EMAIL_STATUS_FAILED)
+	suite.Equal(int32(1), status.Attempts)
+	suite.Equal("no recipients were accepted", status.LastError)
+	suite.Zero(status.SentAt)
+	suite.Equal("550 Mailbox unavailable", status.Recipients[0].Message)
+
+	_, err = client.GetEmailStatus(context.Background(), &pb.EmailStatusRequest{})
+	suite.EqualError(err, service.ErrMessageIDRequired.Error())
+	_, err = client.GetEmailStatus(context.Background(), &pb.EmailStatusRequest{MessageId: "00000000-0000-0000-0000-000000000000"})
+	suite.EqualError(err, service.ErrMessageNotFound.Error())
+	_, err = client.GetEmailStatus(context.Background(), &pb.EmailStatusRequest{MessageId: "unknown"})
+	suite.EqualError(err, service.ErrMessageNotFound.Error())
+}
+
+func (suite *TestSuite) TestSendEmail_NotLogged() {
+	client := pb.NewEmailServiceClient(suite.grpcConn)
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	stream, err := client.SendEmail(context.Background())
+	suite.NoError(err)
+	err = stream.Send(&pb.EmailRequest{
+		Payload: &pb.EmailRequest_EmailInfo{
+			EmailInfo: &pb.EmailInfo{
+				FromAddress: "from@example.com",
+				To:          []string{"logged@example.com"},
+				Subject:     "Reset your password",
+				PlainText:   "Your reset token is secret-token",
+			},
+		},
+	})
+	suite.NoError(err)
+	_, err = stream.CloseAndRecv()
+	suite.NoError(err)
+
+	// the recipients are logged, the content of the email is not
+	suite.Contains(logs.String(), "sending email to logged@example.com")
+	suite.NotContains(logs.String(), "secret-token")
+	suite.NotContains(logs.String(), "Reset your password")
 }